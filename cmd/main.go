@@ -2,15 +2,19 @@ package main
 
 import (
 	"crypto-final/internal/handler"
+	"crypto-final/internal/model"
 	"crypto-final/internal/repository"
 	"crypto-final/internal/scheduler"
 	"crypto-final/internal/service"
+	"crypto-final/internal/vault"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath" // ← 添加这行
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -60,10 +64,82 @@ func main() {
 
 	log.Printf("✓ 数据库初始化成功")
 
+	// 交易所API地址（可选，离线测试时指向本地模拟服务器）
+	endpoints := service.DefaultExchangeEndpoints()
+	if baseURL := os.Getenv("EXCHANGE_BASE_URL"); baseURL != "" {
+		endpoints = service.SingleHostEndpoints(baseURL)
+		log.Printf("✓ 交易所API指向: %s", baseURL)
+	}
+	envOverride(&endpoints.BinanceSpot, "BINANCE_SPOT_URL")
+	envOverride(&endpoints.BinanceFutures, "BINANCE_FUTURES_URL")
+	envOverride(&endpoints.BinanceCoinFutures, "BINANCE_COIN_FUTURES_URL")
+	envOverride(&endpoints.OKX, "OKX_API_URL")
+	envOverride(&endpoints.Bybit, "BYBIT_API_URL")
+	envOverride(&endpoints.Bitget, "BITGET_API_URL")
+	envOverride(&endpoints.Gate, "GATE_API_URL")
+	envOverride(&endpoints.Etherscan, "ETHERSCAN_API_URL")
+	envOverride(&endpoints.EthereumRPC, "ETHEREUM_RPC_URL")
+	envOverride(&endpoints.TronGrid, "TRONGRID_API_URL")
+	envOverride(&endpoints.BSCRPC, "BSC_RPC_URL")
+	envOverride(&endpoints.ArbitrumRPC, "ARBITRUM_RPC_URL")
+	envOverride(&endpoints.PolygonRPC, "POLYGON_RPC_URL")
+	envOverride(&endpoints.SolanaRPC, "SOLANA_RPC_URL")
+
+	// 交易所密钥加密用的主密钥（必须）
+	masterKey, err := vault.LoadKey(os.Getenv("MASTER_KEY"), os.Getenv("MASTER_KEY_FILE"))
+	if err != nil {
+		log.Fatalf("❌ 读取主密钥失败: %v", err)
+	}
+	if masterKey == nil {
+		log.Fatal("❌ 错误: 必须设置 MASTER_KEY 或 MASTER_KEY_FILE 环境变量（生成: go run ./cmd/rotatekeys -generate）")
+	}
+	keyring, err := vault.NewKeyring(masterKey)
+	if err != nil {
+		log.Fatalf("❌ 主密钥无效: %v", err)
+	}
+
 	// 初始化服务层
-	svc := service.NewService(repo)
+	walletService := service.NewWalletServiceWithEndpoints(endpoints)
+	walletService.SetKeyring(keyring)
+	svc := service.NewServiceWithWallet(repo, walletService)
 	svc.SetUserDefaultPassword(userPassword) // 设置用户默认密码
 
+	// 加密升级前以明文保存的交易所密钥，v1 密文升级为绑定存储位置的 v2 密文
+	encrypted, err := svc.EncryptStoredSecrets()
+	if err != nil {
+		log.Fatalf("❌ 加密已保存的密钥失败: %v", err)
+	}
+	if encrypted > 0 {
+		log.Printf("🔐 已加密/升级 %d 个保存的密钥", encrypted)
+	}
+	log.Printf("✓ 主密钥已加载: %s", keyring.PrimaryID())
+
+	// 会话有效期（可选，如 12h、30m）
+	if ttl := os.Getenv("SESSION_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("❌ SESSION_TTL 格式错误: %v", err)
+		}
+		svc.SetSessionTTL(d)
+		log.Printf("✓ 会话有效期: %s", d)
+	}
+
+	// 夏普/索提诺比率的年化无风险利率（可选，如 0.04 表示 4%）
+	if rf := os.Getenv("RISK_FREE_RATE"); rf != "" {
+		rate, err := strconv.ParseFloat(rf, 64)
+		if err != nil || rate < 0 || rate >= 1 {
+			log.Fatalf("❌ RISK_FREE_RATE 格式错误，应为0到1之间的小数: %s", rf)
+		}
+		svc.SetRiskFreeRate(rate)
+		log.Printf("✓ 无风险利率: %.2f%%", rate*100)
+	}
+
+	// 管理后台角色必须启用两步验证（仅本地离线测试时可关闭）
+	if os.Getenv("ADMIN_2FA_REQUIRED") == "false" {
+		svc.SetRequireStaff2FA(false)
+		log.Printf("⚠️  已关闭管理员强制两步验证")
+	}
+
 	// 初始化处理器
 	h := handler.NewHandler(svc)
 
@@ -126,6 +202,8 @@ func main() {
 	{
 		// 公开接口
 		api.POST("/login", h.Login)
+		api.POST("/refresh", h.RefreshSession) // 用旧令牌换新令牌
+		api.POST("/logout", h.Logout)
 
 		// 需要认证的接口
 		auth := api.Group("", h.AuthMiddleware())
@@ -140,32 +218,79 @@ func main() {
 			auth.POST("/dashboard/api/keys", h.SaveAPIKeys) // 保存API密钥
 			auth.POST("/dashboard/api/initial-balance", h.UpdateAPIInitialBalance)
 
-			// 管理员接口
-			admin := auth.Group("", h.AdminMiddleware())
+			// 撤资申请（审核通过后在下一次净值结算时成交）
+			auth.POST("/dashboard/withdrawal-requests", h.SubmitWithdrawalRequest)
+			auth.GET("/dashboard/withdrawal-requests", h.GetMyWithdrawalRequests)
+			auth.POST("/dashboard/withdrawal-requests/:id/cancel", h.CancelWithdrawalRequest)
+			auth.GET("/dashboard/withdrawals", h.GetWithdrawals) // 撤资记录（已实现盈亏）
+			auth.GET("/dashboard/fees", h.GetMyPerformanceFees)  // 业绩报酬明细
+			auth.GET("/dashboard/risk", h.GetMyRiskStats)        // 风险指标
+			auth.GET("/dashboard/benchmarks", h.GetMyBenchmarks) // 相对BTC/ETH等基准的表现
+
+			// 两步验证
+			auth.GET("/2fa", h.GetTwoFactorStatus)
+			auth.POST("/2fa/setup", h.SetupTwoFactor)   // 生成密钥和 otpauth:// 地址
+			auth.POST("/2fa/enable", h.EnableTwoFactor) // 提交验证码完成绑定，返回恢复码
+			auth.POST("/2fa/disable", h.DisableTwoFactor)
+			auth.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+
+			// 净值曲线（投资人只能查看自己持有的资金池）
+			auth.GET("/nav/:accountId", h.GetNAVHistory)
+
+			// 管理员接口（每个路由对应一个权限，角色与权限的关系见 model/role.go）
+			admin := auth.Group("")
 			{
+				can := h.RequirePermission
+
 				// 用户管理
-				admin.POST("/admin/users", h.AdminCreateUser)
-				admin.GET("/admin/users", h.AdminGetUsers)
-				admin.POST("/admin/users/api", h.AdminCreateAPIUser) // ← 新增
-				admin.GET("/admin/users/:id", h.AdminGetUserDetail)
-				admin.PUT("/admin/users/:id/status", h.AdminToggleUserStatus)
+				admin.POST("/admin/users", can(model.PermManageUsers), h.AdminCreateUser)
+				admin.GET("/admin/users", can(model.PermViewUsers), h.AdminGetUsers)
+				admin.POST("/admin/users/api", can(model.PermManageUsers), h.AdminCreateAPIUser) // ← 新增
+				admin.GET("/admin/users/:id", can(model.PermViewUsers), h.AdminGetUserDetail)
+				admin.PUT("/admin/users/:id/status", can(model.PermManageUsers), h.AdminToggleUserStatus)
+				admin.PUT("/admin/users/:id/role", can(model.PermAssignRoles), h.AdminAssignRole) // 分配角色
+				admin.GET("/admin/roles", can(model.PermViewUsers), h.AdminGetRoles)
 
 				// 充值管理
-				admin.DELETE("/admin/recharge/:id", h.AdminDeleteRecharge)
-				admin.GET("/admin/recharge/stats", h.AdminGetRechargeStats) // 只保留一个
-				admin.POST("/admin/deposit", h.AdminDepositToExchange)      // ← 新增：Admin充值到交易所
-				admin.POST("/admin/recharge", h.AdminRecharge)              // 给用户充值（从系统账户划转）
-				admin.PUT("/admin/recharge/:id", h.AdminUpdateRecharge)
+				admin.DELETE("/admin/recharge/:id", can(model.PermRecordRecharge), h.AdminDeleteRecharge)
+				admin.GET("/admin/recharge/stats", can(model.PermViewLedger), h.AdminGetRechargeStats) // 只保留一个
+				admin.POST("/admin/deposit", can(model.PermRecordRecharge), h.AdminDepositToExchange)  // ← 新增：Admin充值到交易所
+				admin.POST("/admin/recharge", can(model.PermRecordRecharge), h.AdminRecharge)          // 给用户充值（从系统账户划转）
+				admin.PUT("/admin/recharge/:id", can(model.PermRecordRecharge), h.AdminUpdateRecharge)
+				admin.GET("/admin/recharge/:id/journal", can(model.PermViewLedger), h.AdminGetRechargeJournal) // 充值分录
+				admin.GET("/admin/ledger/verify", can(model.PermViewLedger), h.AdminVerifyLedger)              // 核对分录
+				admin.GET("/admin/nav/:accountId/strikes", can(model.PermViewLedger), h.AdminGetNAVStrikes)    // 盘中净值（申购、赎回成交价）
+				admin.GET("/admin/audit", can(model.PermViewAudit), h.AdminGetAuditEvents)                     // 管理操作审计
+				admin.GET("/admin/fees", can(model.PermViewLedger), h.AdminGetFees)                            // 费率和管理人份额
+				admin.PUT("/admin/fees", can(model.PermConfigureAccounts), h.AdminSetFeeRate)                  // 设置/删除费率
+				admin.GET("/admin/fees/performance", can(model.PermViewLedger), h.AdminGetPerformanceFees)     // 业绩报酬明细
+				admin.GET("/admin/fees/management", can(model.PermViewLedger), h.AdminGetManagementFees)       // 管理费计提记录
+				admin.GET("/admin/risk", can(model.PermViewLedger), h.AdminGetRiskStats)                       // 各账户风险指标
+				admin.GET("/admin/risk/users/:id", can(model.PermViewUsers), h.AdminGetUserRiskStats)          // 用户组合风险指标
+
+				// 业绩比较基准
+				admin.GET("/admin/benchmarks", can(model.PermViewLedger), h.AdminGetBenchmarks)                 // 基准及最近价格
+				admin.PUT("/admin/benchmarks", can(model.PermConfigureAccounts), h.AdminSetBenchmark)           // 新增/修改/停用基准
+				admin.GET("/admin/benchmarks/accounts", can(model.PermViewLedger), h.AdminGetAccountBenchmarks) // 各账户相对基准的表现
+				admin.GET("/admin/benchmarks/users/:id", can(model.PermViewUsers), h.AdminGetUserBenchmarks)    // 用户组合相对基准的表现
 
 				// 钱包管理
-				admin.POST("/admin/accounts/config", h.AdminConfigAccount)
-				admin.GET("/admin/accounts/status", h.AdminGetAccountsStatus)
+				admin.POST("/admin/accounts/config", can(model.PermConfigureAccounts), h.AdminConfigAccount)
+				admin.GET("/admin/accounts/status", can(model.PermViewAccounts), h.AdminGetAccountsStatus)
+				admin.GET("/admin/chains", can(model.PermViewAccounts), h.AdminGetChains)                                             // 支持的链
+				admin.GET("/admin/accounts/:id/addresses", can(model.PermViewAccounts), h.AdminGetChainAddresses)                     // Wallet账户的链上地址
+				admin.POST("/admin/accounts/:id/addresses", can(model.PermConfigureAccounts), h.AdminAddChainAddress)                 // 绑定地址
+				admin.DELETE("/admin/accounts/:id/addresses/:addressId", can(model.PermConfigureAccounts), h.AdminDeleteChainAddress) // 解绑地址
 
 				// 系统管理
-				admin.POST("/admin/manual-check", h.AdminManualCheck)
-
-				// ✅ 撤资（仅Admin可用）
-				admin.POST("/admin/withdraw", h.AdminWithdrawRecharge)
+				admin.POST("/admin/manual-check", can(model.PermRunChecks), h.AdminManualCheck)
+
+				// ✅ 撤资
+				admin.POST("/admin/withdraw", can(model.PermRecordRecharge), h.AdminWithdrawRecharge)
+				admin.GET("/admin/withdrawals", can(model.PermViewLedger), h.AdminGetWithdrawals)
+				admin.GET("/admin/withdrawal-requests", can(model.PermViewLedger), h.AdminGetWithdrawalRequests)
+				admin.POST("/admin/withdrawal-requests/:id/approve", can(model.PermRecordRecharge), h.AdminApproveWithdrawalRequest)
+				admin.POST("/admin/withdrawal-requests/:id/reject", can(model.PermRecordRecharge), h.AdminRejectWithdrawalRequest)
 			}
 		}
 	}
@@ -197,3 +322,10 @@ func main() {
 
 	fmt.Println("\n正在关闭服务...")
 }
+
+// envOverride 环境变量不为空时覆盖目标值
+func envOverride(target *string, key string) {
+	if v := os.Getenv(key); v != "" {
+		*target = v
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto-final/internal/mockexchange"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+)
+
+// 本地模拟交易所
+//
+// 启动后把主程序的 EXCHANGE_BASE_URL 指向这里，并在管理后台配置下面打印出的密钥，
+// 即可在没有真实账户的情况下测试余额检查、充值和撤资流程。
+func main() {
+	addr := os.Getenv("MOCK_EXCHANGE_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+
+	binanceKey := getEnv("MOCK_BINANCE_API_KEY", "mock-binance-key")
+	binanceSecret := getEnv("MOCK_BINANCE_API_SECRET", "mock-binance-secret")
+	okxKey := getEnv("MOCK_OKX_API_KEY", "mock-okx-key")
+	okxSecret := getEnv("MOCK_OKX_API_SECRET", "mock-okx-secret")
+	okxPassphrase := getEnv("MOCK_OKX_PASSPHRASE", "mock-okx-passphrase")
+	bybitKey := getEnv("MOCK_BYBIT_API_KEY", "mock-bybit-key")
+	bybitSecret := getEnv("MOCK_BYBIT_API_SECRET", "mock-bybit-secret")
+	bitgetKey := getEnv("MOCK_BITGET_API_KEY", "mock-bitget-key")
+	bitgetSecret := getEnv("MOCK_BITGET_API_SECRET", "mock-bitget-secret")
+	bitgetPassphrase := getEnv("MOCK_BITGET_PASSPHRASE", "mock-bitget-passphrase")
+	gateKey := getEnv("MOCK_GATE_API_KEY", "mock-gate-key")
+	gateSecret := getEnv("MOCK_GATE_API_SECRET", "mock-gate-secret")
+	etherscanKey := getEnv("MOCK_ETHERSCAN_API_KEY", "mock-etherscan-key")
+	walletAddress := getEnv("MOCK_WALLET_ADDRESS", "0x0000000000000000000000000000000000000001")
+	tronAddress := getEnv("MOCK_TRON_ADDRESS", "TLa2f6VPqDgRE67v1736s7bJ8Ray5wYjU7")
+	solanaAddress := getEnv("MOCK_SOLANA_ADDRESS", "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+
+	srv := mockexchange.New()
+	srv.SetBinanceCredentials(binanceKey, binanceSecret)
+	srv.SetOKXCredentials(okxKey, okxSecret, okxPassphrase)
+	srv.SetBybitCredentials(bybitKey, bybitSecret)
+	srv.SetBitgetCredentials(bitgetKey, bitgetSecret, bitgetPassphrase)
+	srv.SetGateCredentials(gateKey, gateSecret)
+	srv.SetEtherscanAPIKey(etherscanKey)
+
+	// 演示数据
+	srv.SetBinanceSpotBalance("USDT", mockexchange.Balance{Wallet: 1000})
+	srv.SetBinanceFuturesBalance("USDC", mockexchange.Balance{Wallet: 10000, UnrealizedPnl: 250})
+	srv.SetBinanceFuturesBalance("USDT", mockexchange.Balance{Wallet: 5000})
+	srv.AddBinancePosition(mockexchange.Position{
+		Symbol: "BTCUSDC", Amount: 0.1, EntryPrice: 60000, MarkPrice: 62500,
+		UnrealizedPnl: 250, Leverage: 5, MarginType: "cross",
+	})
+	srv.SetOKXBalance("USDT", mockexchange.Balance{Wallet: 8000, UnrealizedPnl: -120})
+	srv.SetBybitBalance("USDT", mockexchange.Balance{Wallet: 6000, UnrealizedPnl: 90})
+	srv.SetBybitBalance("USDC", mockexchange.Balance{Wallet: 2000})
+	srv.AddBybitPosition(mockexchange.Position{
+		Symbol: "ETHUSDT", Amount: -2, EntryPrice: 3100, MarkPrice: 3055,
+		UnrealizedPnl: 90, Leverage: 3, MarginType: "cross",
+	})
+	srv.SetBitgetSpotBalance("USDT", mockexchange.Balance{Wallet: 500})
+	srv.SetBitgetFuturesBalance("USDT", mockexchange.Balance{Wallet: 4000, UnrealizedPnl: -60})
+	srv.AddBitgetPosition(mockexchange.Position{
+		Symbol: "SOLUSDT", Amount: 20, EntryPrice: 150, MarkPrice: 147,
+		UnrealizedPnl: -60, Leverage: 10, MarginType: "isolated",
+	})
+	srv.SetGateSpotBalance("USDT", mockexchange.Balance{Wallet: 300})
+	srv.SetGateFuturesBalance(mockexchange.Balance{Wallet: 3000, UnrealizedPnl: 4.5})
+	srv.AddGatePosition(mockexchange.Position{
+		Symbol: "BTC_USDT", Amount: 150, EntryPrice: 60000, MarkPrice: 60300,
+		UnrealizedPnl: 4.5, Leverage: 5, MarginType: "cross",
+	})
+	srv.SetTokenBalance("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", walletAddress, big.NewInt(2500_000000))
+	srv.SetTokenBalance("0xdAC17F958D2ee523a2206206994597C13D831ec7", walletAddress, big.NewInt(1500_000000))
+	srv.SetChainTokenBalance("tron", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", tronAddress, big.NewInt(7000_000000))
+	srv.SetChainTokenBalance("arbitrum", "0xaf88d065e77c8cC2239327C5EDb3A432268e5831", walletAddress, big.NewInt(1200_000000))
+	bep20 := new(big.Int).Mul(big.NewInt(800), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)) // BEP20为18位小数
+	srv.SetChainTokenBalance("bsc", "0x55d398326f99059fF775485246999027B3197955", walletAddress, bep20)
+	srv.SetChainTokenBalance("solana", "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", solanaAddress, big.NewInt(300_000000))
+
+	// 演示行情：最近400天的 BTC/ETH 日K线（业绩比较基准）
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i <= 400; i++ {
+		x := float64(i)
+		day := today.AddDate(0, 0, i-400)
+		srv.SetDailyClose("BTCUSDT", day, math.Round(60000+8000*math.Sin(x/20)+20*x))
+		srv.SetDailyClose("ETHUSDT", day, math.Round((3000+500*math.Sin(x/15)+x)*100)/100)
+	}
+
+	fmt.Println("╔════════════════════════════════════════════════╗")
+	fmt.Println("║   模拟交易所服务器                              ║")
+	fmt.Println("╚════════════════════════════════════════════════╝")
+	fmt.Printf("  监听地址: %s\n", addr)
+	fmt.Printf("  主程序设置: EXCHANGE_BASE_URL=http://localhost%s\n", addr)
+	fmt.Printf("  Binance: key=%s secret=%s\n", binanceKey, binanceSecret)
+	fmt.Printf("  OKX:     key=%s secret=%s passphrase=%s\n", okxKey, okxSecret, okxPassphrase)
+	fmt.Printf("  Bybit:   key=%s secret=%s\n", bybitKey, bybitSecret)
+	fmt.Printf("  Bitget:  key=%s secret=%s passphrase=%s\n", bitgetKey, bitgetSecret, bitgetPassphrase)
+	fmt.Printf("  Gate.io: key=%s secret=%s\n", gateKey, gateSecret)
+	fmt.Printf("  Wallet:  address=%s etherscan=%s\n", walletAddress, etherscanKey)
+	fmt.Printf("           tron=%s solana=%s（EVM地址在bsc/arbitrum上也有余额）\n", tronAddress, solanaAddress)
+	fmt.Printf("           以太坊节点查询: ETHEREUM_RPC_URL=http://localhost%s/rpc/ethereum\n", addr)
+
+	if err := http.ListenAndServe(addr, srv); err != nil {
+		log.Fatalf("模拟服务器启动失败: %v", err)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
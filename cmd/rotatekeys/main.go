@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto-final/internal/repository"
+	"crypto-final/internal/vault"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// 主密钥轮换
+//
+// 用 NEW_MASTER_KEY 重新加密数据库中所有交易所密钥的数据密钥（密文主体不变），
+// 遗留的明文密钥一并加密，v1 密文升级为绑定存储位置（表、列、行ID）的 v2 密文。
+// 所有改动在一个事务中完成，失败时数据库不变。
+//
+//	MASTER_KEY=<旧密钥> NEW_MASTER_KEY=<新密钥> go run ./cmd/rotatekeys -db crypto_final.db
+//
+// 轮换前先停止主程序，完成后把主程序的 MASTER_KEY 换成新密钥再启动。
+func main() {
+	dbPath := flag.String("db", getEnv("DB_PATH", "crypto_final.db"), "数据库路径")
+	generate := flag.Bool("generate", false, "只生成一个新的随机主密钥并退出")
+	flag.Parse()
+
+	if *generate {
+		key, err := vault.GenerateKey()
+		if err != nil {
+			log.Fatalf("❌ 生成主密钥失败: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	oldKey, err := vault.LoadKey(os.Getenv("MASTER_KEY"), os.Getenv("MASTER_KEY_FILE"))
+	if err != nil {
+		log.Fatalf("❌ 读取旧主密钥失败: %v", err)
+	}
+	newKey, err := vault.LoadKey(os.Getenv("NEW_MASTER_KEY"), os.Getenv("NEW_MASTER_KEY_FILE"))
+	if err != nil {
+		log.Fatalf("❌ 读取新主密钥失败: %v", err)
+	}
+	if newKey == nil {
+		log.Fatal("❌ 错误: 必须设置 NEW_MASTER_KEY 或 NEW_MASTER_KEY_FILE")
+	}
+
+	var previous [][]byte
+	if oldKey != nil {
+		previous = append(previous, oldKey)
+	}
+	keyring, err := vault.NewKeyring(newKey, previous...)
+	if err != nil {
+		log.Fatalf("❌ 主密钥无效: %v", err)
+	}
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		log.Fatalf("❌ 数据库不存在: %v", err)
+	}
+	repo, err := repository.OpenRepository(*dbPath)
+	if err != nil {
+		log.Fatalf("❌ 打开数据库失败: %v", err)
+	}
+	defer repo.Close()
+
+	changed, err := repo.RewriteSecrets(func(value, context string) (string, error) {
+		if !vault.IsSealed(value) {
+			return keyring.Seal(value, context)
+		}
+		rewrapped, _, err := keyring.Rewrap(value, context)
+		return rewrapped, err
+	})
+	if err != nil {
+		log.Fatalf("❌ 轮换失败，数据库未改动: %v", err)
+	}
+
+	fmt.Printf("✓ 已用主密钥 %s 重新加密 %d 个密钥\n", keyring.PrimaryID(), changed)
+	fmt.Println("  下一步：把主程序的 MASTER_KEY 换成新密钥后重启")
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
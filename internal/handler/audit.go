@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// audit 记录当前管理员的一次写操作
+// 审计写入失败时请求按失败返回（操作本身已经执行），调用方应直接 return。
+func (h *Handler) audit(c *gin.Context, action, targetType string, targetID interface{}, before, after interface{}) bool {
+	actor := c.MustGet("user").(*model.User)
+	err := h.service.RecordAudit(actor, action, targetType, fmt.Sprint(targetID), before, after, c.ClientIP())
+	if err != nil {
+		fmt.Printf("❌ 审计记录失败 [%s %s:%v]: %v\n", action, targetType, targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("操作已执行，但审计记录失败: %v", err)})
+		return false
+	}
+	return true
+}
+
+// AdminGetAuditEvents 查询管理操作审计记录
+// GET /api/admin/audit?actor_id=1&action=recharge.update&target_type=recharge&target_id=5&from=2024-01-01&to=2024-12-31&limit=50&offset=0
+func (h *Handler) AdminGetAuditEvents(c *gin.Context) {
+	filter := model.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"actor_id", &filter.ActorID},
+		{"limit", &filter.Limit},
+		{"offset", &filter.Offset},
+	}
+	for _, p := range ints {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.name + "参数无效"})
+			return
+		}
+		*p.dst = n
+	}
+
+	// to 包含当天
+	if from := c.Query("from"); from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式应为YYYY-MM-DD"})
+			return
+		}
+		filter.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式应为YYYY-MM-DD"})
+			return
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+
+	events, total, err := h.service.GetAuditEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+	})
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/repository"
+	"crypto-final/internal/service"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAuditFailureFailsRequest 审计写入失败时请求返回500，调用方不再返回成功
+func TestAuditFailureFailsRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	repo, err := repository.NewRepository(dsn, "admin-password")
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	svc := service.NewServiceWithWallet(repo, service.NewWalletService())
+	h := NewHandler(svc)
+
+	request := func() (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/users", nil)
+		c.Set("user", &model.User{ID: 1, Username: "admin"})
+		return c, w
+	}
+
+	c, w := request()
+	if !h.audit(c, "user.create", "user", 2, nil, gin.H{"phone": "13800000000"}) || w.Code != http.StatusOK {
+		t.Fatalf("审计成功时 audit = false, code = %d", w.Code)
+	}
+
+	// 同一个内存数据库的另一个连接：让审计表拒绝写入
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TRIGGER audit_events_fail BEFORE INSERT ON audit_events
+		BEGIN SELECT RAISE(ABORT, 'disk full'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	c, w = request()
+	if h.audit(c, "user.create", "user", 3, nil, nil) {
+		t.Fatal("审计失败时 audit 返回 true")
+	}
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "审计记录失败") {
+		t.Errorf("审计失败时响应 %d %s, want 500 审计记录失败", w.Code, w.Body.String())
+	}
+
+	events, total, err := svc.GetAuditEvents(model.AuditFilter{})
+	if err != nil || total != 1 || events[0].TargetID != "2" {
+		t.Errorf("审计记录 = %v (共%d), %v, want 只有第一条", events, total, err)
+	}
+}
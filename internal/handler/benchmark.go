@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminGetBenchmarks 业绩比较基准及最近记录的价格
+func (h *Handler) AdminGetBenchmarks(c *gin.Context) {
+	benchmarks, err := h.service.GetBenchmarks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"benchmarks": benchmarks})
+}
+
+// AdminSetBenchmark 新增、修改或停用基准
+func (h *Handler) AdminSetBenchmark(c *gin.Context) {
+	var req struct {
+		Symbol       string `json:"symbol" binding:"required"`
+		Name         string `json:"name"`
+		SourceSymbol string `json:"source_symbol" binding:"required"`
+		Enabled      *bool  `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	before, _ := h.service.GetBenchmark(req.Symbol)
+
+	benchmark, err := h.service.SetBenchmark(req.Symbol, req.Name, req.SourceSymbol, enabled)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.audit(c, model.AuditSetBenchmark, "benchmark", benchmark.Symbol, before, benchmark) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "基准已保存", "benchmark": benchmark})
+}
+
+// AdminGetAccountBenchmarks 各Admin账户相对各基准的表现
+func (h *Handler) AdminGetAccountBenchmarks(c *gin.Context) {
+	reports, err := h.service.GetAccountBenchmarkReports()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": reports})
+}
+
+// AdminGetUserBenchmarks 某用户持仓组合相对各基准的表现
+func (h *Handler) AdminGetUserBenchmarks(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID无效"})
+		return
+	}
+
+	report, err := h.service.GetUserBenchmarkReport(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetMyBenchmarks 自己的持仓组合相对各基准的表现
+func (h *Handler) GetMyBenchmarks(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	report, err := h.service.GetUserBenchmarkReport(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
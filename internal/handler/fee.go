@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminGetFees 费率设置和各资金池管理人账户持有的份额
+func (h *Handler) AdminGetFees(c *gin.Context) {
+	rates, err := h.service.GetFeeRates(c.Query("fee_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	holdings, err := h.service.GetManagerHoldings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rates": rates, "manager_holdings": holdings})
+}
+
+// AdminSetFeeRate 设置费率；user_id 为0设置账户默认，rate 为 null 删除该设置
+func (h *Handler) AdminSetFeeRate(c *gin.Context) {
+	var req struct {
+		FeeType        string         `json:"fee_type" binding:"required"`
+		AdminAccountID int            `json:"admin_account_id" binding:"required"`
+		UserID         int            `json:"user_id"`
+		Rate           *money.Decimal `json:"rate"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	targetID := fmt.Sprintf("%s:%d:%d", req.FeeType, req.AdminAccountID, req.UserID)
+	before, _ := h.service.GetFeeRate(req.FeeType, req.AdminAccountID, req.UserID)
+
+	if req.Rate == nil {
+		if err := h.service.DeleteFeeRate(req.FeeType, req.AdminAccountID, req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !h.audit(c, model.AuditSetFeeRate, "fee_rate", targetID, before, nil) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "费率设置已删除"})
+		return
+	}
+
+	actor := c.MustGet("user").(*model.User)
+	rate, err := h.service.SetFeeRate(actor.ID, req.FeeType, req.AdminAccountID, req.UserID, *req.Rate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.audit(c, model.AuditSetFeeRate, "fee_rate", targetID, before, rate) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "费率已保存", "rate": rate})
+}
+
+// AdminGetPerformanceFees 业绩报酬明细（?user_id=&admin_account_id=&recharge_id=）
+func (h *Handler) AdminGetPerformanceFees(c *gin.Context) {
+	var filter model.PerformanceFeeFilter
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"user_id", &filter.UserID},
+		{"admin_account_id", &filter.AdminAccountID},
+		{"recharge_id", &filter.RechargeID},
+	}
+	for _, p := range ints {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.name + "参数无效"})
+			return
+		}
+		*p.dst = n
+	}
+
+	fees, totals, err := h.service.GetPerformanceFees(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fees": fees, "totals": totals})
+}
+
+// GetMyPerformanceFees 投资人自己被收取的业绩报酬明细
+func (h *Handler) GetMyPerformanceFees(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	fees, totals, err := h.service.GetPerformanceFees(model.PerformanceFeeFilter{UserID: user.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fees": fees, "totals": totals})
+}
+
+// AdminGetManagementFees 管理费计提记录（?admin_account_id=&currency=&from=&to=&limit=）
+func (h *Handler) AdminGetManagementFees(c *gin.Context) {
+	filter := model.ManagementFeeFilter{
+		Currency: c.Query("currency"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"admin_account_id", &filter.AdminAccountID},
+		{"limit", &filter.Limit},
+	}
+	for _, p := range ints {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.name + "参数无效"})
+			return
+		}
+		*p.dst = n
+	}
+	for _, d := range []string{filter.From, filter.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式应为YYYY-MM-DD"})
+			return
+		}
+	}
+
+	accruals, err := h.service.GetManagementFeeAccruals(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := money.Zero
+	for _, a := range accruals {
+		total = total.Add(a.FeeAmount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accruals": accruals, "total_fee_amount": total})
+}
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"crypto-final/internal/model"
+	"crypto-final/internal/money"
 	"crypto-final/internal/service"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,16 +22,26 @@ func NewHandler(svc *service.Service) *Handler {
 	return &Handler{service: svc}
 }
 
-// AuthMiddleware 验证用户登录
+// bearerToken 从 Authorization: Bearer <token> 中取出令牌
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	return token, token != ""
+}
+
+// AuthMiddleware 验证会话令牌
 func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		phone, password, ok := c.Request.BasicAuth()
+		token, ok := bearerToken(c)
 		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "需要认证"})
 			c.Abort()
 			return
 		}
-		user, err := h.service.Login(phone, password)
+		user, _, err := h.service.Authenticate(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "认证失败: " + err.Error()})
 			c.Abort()
@@ -39,8 +53,8 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// AdminMiddleware 验证管理员权限
-func (h *Handler) AdminMiddleware() gin.HandlerFunc {
+// RequirePermission 验证当前用户的角色拥有指定权限
+func (h *Handler) RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, exists := c.Get("user")
 		if !exists {
@@ -50,8 +64,13 @@ func (h *Handler) AdminMiddleware() gin.HandlerFunc {
 		}
 
 		u := user.(*model.User)
-		if !u.IsAdmin {
-			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+		if !u.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限: " + permission})
+			c.Abort()
+			return
+		}
+		if h.service.TwoFactorSetupRequired(u) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "请先启用两步验证", "two_factor_setup_required": true})
 			c.Abort()
 			return
 		}
@@ -63,9 +82,11 @@ func (h *Handler) AdminMiddleware() gin.HandlerFunc {
 // Login 用户登录（兼容多种字段名）
 func (h *Handler) Login(c *gin.Context) {
 	var req struct {
-		Username string `json:"username"` // 新字段
-		Phone    string `json:"phone"`    // 旧字段
-		Password string `json:"password" binding:"required"`
+		Username     string `json:"username"` // 新字段
+		Phone        string `json:"phone"`    // 旧字段
+		Password     string `json:"password" binding:"required"`
+		TOTPCode     string `json:"totp_code"`     // 两步验证码
+		RecoveryCode string `json:"recovery_code"` // 或恢复码
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -102,6 +123,26 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账户已停用"})
+		return
+	}
+
+	if err := h.service.VerifySecondFactor(user, req.TOTPCode, req.RecoveryCode); err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, service.ErrTwoFactorLocked) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "two_factor_required": true})
+		return
+	}
+
+	token, expiresAt, err := h.service.CreateSession(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 返回用户信息
 	displayName := user.Phone
 	if user.IsAPIUser && user.Username != "" {
@@ -112,16 +153,57 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
+		"message":    "登录成功",
+		"token":      token,
+		"expires_at": expiresAt.Unix(),
+		// 管理后台角色还没启用两步验证时，只能访问 /api/2fa/* 完成绑定
+		"two_factor_setup_required": h.service.TwoFactorSetupRequired(user),
 		"user": gin.H{
 			"id":        user.ID,
 			"username":  displayName,
 			"is_admin":  user.IsAdmin,
+			"role":      user.Role,
 			"is_active": user.IsActive,
 		},
 	})
 }
 
+// RefreshSession 换发会话令牌，旧令牌失效
+func (h *Handler) RefreshSession(c *gin.Context) {
+	token, ok := bearerToken(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "需要认证"})
+		return
+	}
+
+	newToken, expiresAt, err := h.service.RefreshSession(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "认证失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      newToken,
+		"expires_at": expiresAt.Unix(),
+	})
+}
+
+// Logout 注销当前会话
+func (h *Handler) Logout(c *gin.Context) {
+	token, ok := bearerToken(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "需要认证"})
+		return
+	}
+
+	if err := h.service.Logout(token); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
 // Admin路由
 
 // AdminCreateUser 管理员创建用户
@@ -137,6 +219,9 @@ func (h *Handler) AdminCreateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !h.audit(c, model.AuditCreateUser, "user", userID, nil, gin.H{"phone": req.Phone}) {
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "用户创建成功，密码为：abc123456",
@@ -188,6 +273,7 @@ func (h *Handler) AdminGetUsers(c *gin.Context) {
 		result = append(result, gin.H{
 			"user_id":        user.UserID,
 			"phone":          displayName, // 🔥 使用 displayName
+			"role":           user.Role,
 			"is_active":      user.IsActive,
 			"is_api_user":    isAPIUser,
 			"total_recharge": user.TotalRecharge,
@@ -226,13 +312,17 @@ func (h *Handler) AdminRecharge(c *gin.Context) {
 		return
 	}
 
-	err := h.service.AdminRecharge(req.UserID, req.AdminAccountID, req.Amount, req.Currency)
+	rechargeID, err := h.service.AdminRecharge(req.UserID, req.AdminAccountID, req.Amount, req.Currency)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	after, _ := h.service.GetRecharge(int(rechargeID))
+	if !h.audit(c, model.AuditCreateRecharge, "recharge", rechargeID, nil, after) {
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "充值成功"})
+	c.JSON(http.StatusOK, gin.H{"message": "充值成功", "recharge_id": rechargeID})
 }
 
 // AdminToggleUserStatus 启用/停用用户
@@ -257,7 +347,10 @@ func (h *Handler) AdminToggleUserStatus(c *gin.Context) {
 	// 切换状态
 	newStatus := !user.IsActive
 	if err := h.service.UpdateUserStatus(userID, newStatus); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.audit(c, model.AuditUpdateUserStatus, "user", userID, gin.H{"is_active": user.IsActive}, gin.H{"is_active": newStatus}) {
 		return
 	}
 
@@ -273,6 +366,58 @@ func (h *Handler) AdminToggleUserStatus(c *gin.Context) {
 	})
 }
 
+// AdminGetRoles 获取所有角色及其权限
+func (h *Handler) AdminGetRoles(c *gin.Context) {
+	roles := []string{model.RoleSuperAdmin, model.RoleOperator, model.RoleAuditor, model.RoleInvestor}
+
+	var result []gin.H
+	for _, role := range roles {
+		result = append(result, gin.H{
+			"role":        role,
+			"permissions": model.RolePermissions(role),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": result})
+}
+
+// AdminAssignRole 给用户分配角色
+func (h *Handler) AdminAssignRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID无效"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		return
+	}
+
+	var before interface{}
+	if user, _ := h.service.GetUserByID(userID); user != nil {
+		before = gin.H{"role": user.Role}
+	}
+
+	actor := c.MustGet("user").(*model.User)
+	if err := h.service.AssignRole(actor, userID, req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.audit(c, model.AuditAssignRole, "user", userID, before, gin.H{"role": req.Role}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色已更新",
+		"user_id": userID,
+		"role":    req.Role,
+	})
+}
+
 // AdminGetAccountsStatus 获取Admin账户状态
 func (h *Handler) AdminGetAccountsStatus(c *gin.Context) {
 	defer func() {
@@ -307,11 +452,17 @@ func (h *Handler) AdminConfigAccount(c *gin.Context) {
 		return
 	}
 
+	before, _ := h.service.AccountConfigSnapshot(req.AccountType)
+
 	err := h.service.ConfigAdminAccount(req.AccountType, req.APIKey, req.APISecret, req.WalletAddress, req.Passphrase)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	after, _ := h.service.AccountConfigSnapshot(req.AccountType)
+	if !h.audit(c, model.AuditConfigAccount, "admin_account", req.AccountType, before, after) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "配置保存成功"})
 }
@@ -334,6 +485,9 @@ func (h *Handler) AdminManualCheck(c *gin.Context) {
 	}
 
 	fmt.Println("✓ 余额检查完成")
+	if !h.audit(c, model.AuditManualCheck, "balances", "all", nil, nil) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "余额检查完成"})
 }
 
@@ -342,7 +496,7 @@ func (h *Handler) AdminUpdateRecharge(c *gin.Context) {
 	rechargeID, _ := strconv.Atoi(c.Param("id"))
 
 	var req struct {
-		Amount float64 `json:"amount" binding:"required"`
+		Amount money.Decimal `json:"amount" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -350,11 +504,17 @@ func (h *Handler) AdminUpdateRecharge(c *gin.Context) {
 		return
 	}
 
+	before, _ := h.service.GetRecharge(rechargeID)
+
 	err := h.service.UpdateRechargeAmount(rechargeID, req.Amount)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	after, _ := h.service.GetRecharge(rechargeID)
+	if !h.audit(c, model.AuditUpdateRecharge, "recharge", rechargeID, before, after) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "充值记录已更新"})
 }
@@ -363,15 +523,56 @@ func (h *Handler) AdminUpdateRecharge(c *gin.Context) {
 func (h *Handler) AdminDeleteRecharge(c *gin.Context) {
 	rechargeID, _ := strconv.Atoi(c.Param("id"))
 
+	before, _ := h.service.GetRecharge(rechargeID)
+
 	err := h.service.DeleteRecharge(rechargeID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	after, _ := h.service.GetRecharge(rechargeID)
+	if !h.audit(c, model.AuditDeleteRecharge, "recharge", rechargeID, before, after) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "充值记录已删除"})
 }
 
+// AdminGetRechargeJournal 查看某笔充值的记账分录
+func (h *Handler) AdminGetRechargeJournal(c *gin.Context) {
+	rechargeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的充值ID"})
+		return
+	}
+
+	entries, err := h.service.GetRechargeJournal(rechargeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recharge_id": rechargeID,
+		"entries":     entries,
+	})
+}
+
+// AdminVerifyLedger 核对充值记录与分录
+func (h *Handler) AdminVerifyLedger(c *gin.Context) {
+	mismatches, unbalanced, err := h.service.VerifyLedger()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":                 len(mismatches) == 0 && len(unbalanced) == 0,
+		"mismatches":         mismatches,
+		"unbalanced_entries": unbalanced,
+	})
+}
+
 // Dashboard路由
 
 // GetDashboardSummary 获取Dashboard总览
@@ -429,6 +630,87 @@ func (h *Handler) GetRechargeHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"history": history})
 }
 
+// GetNAVHistory 获取某账户某币种的每日收盘净值序列（用于绘制基金单位净值曲线）
+// GET /api/nav/:accountId?currency=USDT&from=2024-01-01&to=2024-12-31
+// 投资人只能查看自己持有的资金池，工作人员需要账目查看权限。
+func (h *Handler) GetNAVHistory(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	accountID, currency, from, to, ok := navQuery(c)
+	if !ok {
+		return
+	}
+
+	allowed, err := h.service.CanViewNAV(user, accountID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该资金池的净值"})
+		return
+	}
+
+	history, err := h.service.GetNAVHistory(accountID, currency, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"admin_account_id": accountID,
+		"currency":         currency,
+		"history":          history,
+	})
+}
+
+// AdminGetNAVStrikes 获取某账户某币种的盘中净值（每笔申购、赎回的成交价格）
+// GET /api/admin/nav/:accountId/strikes?currency=USDT&from=2024-01-01&to=2024-12-31
+func (h *Handler) AdminGetNAVStrikes(c *gin.Context) {
+	accountID, currency, from, to, ok := navQuery(c)
+	if !ok {
+		return
+	}
+
+	strikes, err := h.service.GetNAVStrikes(accountID, currency, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"admin_account_id": accountID,
+		"currency":         currency,
+		"strikes":          strikes,
+	})
+}
+
+// navQuery 解析净值接口的账户ID、币种和日期范围，参数无效时已写入错误响应
+func navQuery(c *gin.Context) (accountID int, currency, from, to string, ok bool) {
+	accountID, err := strconv.Atoi(c.Param("accountId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "账户ID无效"})
+		return 0, "", "", "", false
+	}
+
+	currency = c.Query("currency")
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少currency参数"})
+		return 0, "", "", "", false
+	}
+
+	from, to = c.Query("from"), c.Query("to")
+	for _, d := range []string{from, to} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式应为YYYY-MM-DD"})
+			return 0, "", "", "", false
+		}
+	}
+	return accountID, currency, from, to, true
+}
+
 // DashboardManualRefresh Dashboard用户手动刷新盈亏
 func (h *Handler) DashboardManualRefresh(c *gin.Context) {
 	// 触发余额更新
@@ -437,6 +719,10 @@ func (h *Handler) DashboardManualRefresh(c *gin.Context) {
 		return
 	}
 
+	// 刷新会写入余额和净值，和手动检查一样记录
+	if !h.audit(c, model.AuditManualCheck, "balances", "dashboard", nil, nil) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "刷新完成"})
 }
 
@@ -468,6 +754,9 @@ func (h *Handler) AdminCreateAPIUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !h.audit(c, model.AuditCreateAPIUser, "user", userID, nil, gin.H{"username": req.Username}) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "API用户创建成功",
@@ -535,9 +824,9 @@ func (h *Handler) SaveAPIKeys(c *gin.Context) {
 // AdminDepositToExchange Admin直接充值到交易所（进入系统账户）
 func (h *Handler) AdminDepositToExchange(c *gin.Context) {
 	var req struct {
-		AdminAccountID int     `json:"admin_account_id" binding:"required"`
-		Amount         float64 `json:"amount" binding:"required"`
-		Currency       string  `json:"currency" binding:"required"`
+		AdminAccountID int           `json:"admin_account_id" binding:"required"`
+		Amount         money.Decimal `json:"amount" binding:"required"`
+		Currency       string        `json:"currency" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -550,6 +839,9 @@ func (h *Handler) AdminDepositToExchange(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !h.audit(c, model.AuditDeposit, "admin_account", req.AdminAccountID, nil, gin.H{"amount": req.Amount, "currency": req.Currency}) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "充值到系统账户成功"})
 }
@@ -569,7 +861,7 @@ func (h *Handler) UpdateAPIInitialBalance(c *gin.Context) {
 	}
 
 	var req struct {
-		InitialBalance float64 `json:"initial_balance" binding:"required"`
+		InitialBalance money.Decimal `json:"initial_balance" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -589,9 +881,9 @@ func (h *Handler) UpdateAPIInitialBalance(c *gin.Context) {
 // AdminWithdrawRecharge Admin帮用户撤资（基于本金）
 func (h *Handler) AdminWithdrawRecharge(c *gin.Context) {
 	var req struct {
-		RechargeID        int     `json:"recharge_id" binding:"required"`
-		UserID            int     `json:"user_id" binding:"required"`
-		WithdrawPrincipal float64 `json:"withdraw_principal" binding:"required,gt=0"`
+		RechargeID        int           `json:"recharge_id" binding:"required"`
+		UserID            int           `json:"user_id" binding:"required"`
+		WithdrawPrincipal money.Decimal `json:"withdraw_principal" binding:"required,gt=0"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -599,11 +891,17 @@ func (h *Handler) AdminWithdrawRecharge(c *gin.Context) {
 		return
 	}
 
+	before, _ := h.service.GetRecharge(req.RechargeID)
+
 	err := h.service.WithdrawRechargePartial(req.RechargeID, req.UserID, req.WithdrawPrincipal)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	after, _ := h.service.GetRecharge(req.RechargeID)
+	if !h.audit(c, model.AuditWithdrawRecharge, "recharge", req.RechargeID, before, gin.H{"withdraw_principal": req.WithdrawPrincipal, "recharge": after}) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "撤资成功"})
 }
@@ -621,11 +919,68 @@ func (h *Handler) GetWithdrawals(c *gin.Context) {
 		return
 	}
 
-	withdrawals, err := h.service.GetWithdrawals(uid)
+	filter := model.WithdrawalFilter{UserID: uid}
+	if err := bindWithdrawalDates(c, &filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawals, totals, err := h.service.GetWithdrawalHistory(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals})
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals, "totals": totals})
+}
+
+// AdminGetWithdrawals 查看所有撤资记录
+// GET /api/admin/withdrawals?user_id=3&admin_account_id=1&from=2024-01-01&to=2024-12-31
+func (h *Handler) AdminGetWithdrawals(c *gin.Context) {
+	var filter model.WithdrawalFilter
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"user_id", &filter.UserID},
+		{"admin_account_id", &filter.AdminAccountID},
+	}
+	for _, p := range ints {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.name + "参数无效"})
+			return
+		}
+		*p.dst = n
+	}
+	if err := bindWithdrawalDates(c, &filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawals, totals, err := h.service.GetWithdrawalHistory(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals, "totals": totals})
+}
+
+// bindWithdrawalDates 读取 from/to 日期参数（YYYY-MM-DD）
+func bindWithdrawalDates(c *gin.Context, filter *model.WithdrawalFilter) error {
+	filter.From, filter.To = c.Query("from"), c.Query("to")
+	for _, d := range []string{filter.From, filter.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return errors.New("日期格式应为YYYY-MM-DD")
+		}
+	}
+	return nil
 }
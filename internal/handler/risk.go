@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// riskFreeRate 请求中的年化无风险利率（?risk_free_rate=0.04），没有时用服务默认值
+func (h *Handler) riskFreeRate(c *gin.Context) (float64, bool) {
+	v := c.Query("risk_free_rate")
+	if v == "" {
+		return h.service.RiskFreeRate(), true
+	}
+	rate, err := strconv.ParseFloat(v, 64)
+	if err != nil || rate < 0 || rate >= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "risk_free_rate参数无效（0到1之间的小数，0.04 表示 4%）"})
+		return 0, false
+	}
+	return rate, true
+}
+
+// AdminGetRiskStats 各Admin账户的风险指标（?risk_free_rate=）
+func (h *Handler) AdminGetRiskStats(c *gin.Context) {
+	rate, ok := h.riskFreeRate(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.service.GetAccountRiskStats(rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts, "risk_free_rate": rate * 100})
+}
+
+// AdminGetUserRiskStats 某用户持仓组合的风险指标（?risk_free_rate=）
+func (h *Handler) AdminGetUserRiskStats(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID无效"})
+		return
+	}
+	rate, ok := h.riskFreeRate(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetUserRiskStats(userID, rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetMyRiskStats 自己持仓组合的风险指标（?risk_free_rate=）
+func (h *Handler) GetMyRiskStats(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	rate, ok := h.riskFreeRate(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetUserRiskStats(user.ID, rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
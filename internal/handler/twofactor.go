@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type twoFactorRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// GetTwoFactorStatus 当前用户的两步验证状态
+func (h *Handler) GetTwoFactorStatus(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	enabled, remaining, err := h.service.TwoFactorStatus(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+		"required":                 h.service.TwoFactorSetupRequired(user) || (enabled && model.IsStaffRole(user.Role)),
+	})
+}
+
+// SetupTwoFactor 生成绑定密钥和 otpauth:// 地址
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	secret, uri, err := h.service.BeginTOTPSetup(user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+		"message":          "请用验证器应用扫描二维码或手动输入密钥，然后提交验证码完成绑定",
+	})
+}
+
+// EnableTwoFactor 提交第一个验证码完成绑定，返回恢复码
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	var req twoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供验证码"})
+		return
+	}
+
+	codes, err := h.service.EnableTOTP(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "两步验证已启用，请妥善保存恢复码，每个只能使用一次",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor 关闭两步验证
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	var req twoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		return
+	}
+
+	if err := h.service.DisableTOTP(user, req.Code, req.RecoveryCode); err != nil {
+		status := http.StatusBadRequest
+		if err == service.ErrTwoFactorRequired {
+			status = http.StatusUnauthorized
+		} else if errors.Is(err, service.ErrTwoFactorLocked) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已关闭"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	var req twoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供验证码"})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrTwoFactorLocked) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "恢复码已重新生成，旧恢复码全部作废",
+		"recovery_codes": codes,
+	})
+}
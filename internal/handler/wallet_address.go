@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminGetChains 支持的链及各链统计的稳定币合约
+func (h *Handler) AdminGetChains(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"chains": service.SupportedChains()})
+}
+
+// AdminGetChainAddresses Wallet账户绑定的链上地址
+func (h *Handler) AdminGetChainAddresses(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "账户ID无效"})
+		return
+	}
+
+	addresses, err := h.service.GetChainAddresses(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+// AdminAddChainAddress 为Wallet账户绑定链上地址
+func (h *Handler) AdminAddChainAddress(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "账户ID无效"})
+		return
+	}
+
+	var req struct {
+		Chain   string `json:"chain" binding:"required"`
+		Address string `json:"address" binding:"required"`
+		Label   string `json:"label"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	addr, err := h.service.AddChainAddress(accountID, req.Chain, req.Address, req.Label)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.audit(c, model.AuditAddChainAddress, "wallet_address", addr.ID, nil, addr) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "地址已绑定", "address": addr})
+}
+
+// AdminDeleteChainAddress 解绑Wallet账户的链上地址
+func (h *Handler) AdminDeleteChainAddress(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "账户ID无效"})
+		return
+	}
+	addressID, err := strconv.Atoi(c.Param("addressId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "地址ID无效"})
+		return
+	}
+
+	before, _ := h.service.GetChainAddress(addressID)
+
+	if err := h.service.DeleteChainAddress(accountID, addressID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.audit(c, model.AuditDeleteChainAddress, "wallet_address", addressID, before, nil) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "地址已解绑"})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitWithdrawalRequest 投资人提交撤资申请
+func (h *Handler) SubmitWithdrawalRequest(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	var req struct {
+		RechargeID int           `json:"recharge_id" binding:"required"`
+		Principal  money.Decimal `json:"principal" binding:"required,gt=0"`
+		Note       string        `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	request, err := h.service.SubmitWithdrawalRequest(user.ID, req.RechargeID, req.Principal, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "撤资申请已提交，等待审核",
+		"request": request,
+	})
+}
+
+// GetMyWithdrawalRequests 投资人自己的撤资申请
+func (h *Handler) GetMyWithdrawalRequests(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	requests, err := h.service.GetUserWithdrawalRequests(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// CancelWithdrawalRequest 投资人撤回尚未结算的申请
+func (h *Handler) CancelWithdrawalRequest(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "申请ID无效"})
+		return
+	}
+
+	if err := h.service.CancelWithdrawalRequest(user.ID, id); err != nil {
+		c.JSON(withdrawalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "撤资申请已撤回"})
+}
+
+// AdminGetWithdrawalRequests 查看撤资申请（?status=pending）
+func (h *Handler) AdminGetWithdrawalRequests(c *gin.Context) {
+	requests, err := h.service.GetWithdrawalRequests(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// AdminApproveWithdrawalRequest 批准撤资申请，下一次净值结算时成交
+func (h *Handler) AdminApproveWithdrawalRequest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "申请ID无效"})
+		return
+	}
+
+	actor := c.MustGet("user").(*model.User)
+	before, _ := h.service.GetWithdrawalRequest(id)
+	if err := h.service.ApproveWithdrawalRequest(actor.ID, id); err != nil {
+		c.JSON(withdrawalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	after, _ := h.service.GetWithdrawalRequest(id)
+	if !h.audit(c, model.AuditApproveWithdrawal, "withdrawal_request", id, before, after) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已批准，将在下一次净值结算时成交"})
+}
+
+// AdminRejectWithdrawalRequest 拒绝撤资申请
+func (h *Handler) AdminRejectWithdrawalRequest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "申请ID无效"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请填写拒绝原因"})
+		return
+	}
+
+	actor := c.MustGet("user").(*model.User)
+	before, _ := h.service.GetWithdrawalRequest(id)
+	if err := h.service.RejectWithdrawalRequest(actor.ID, id, req.Reason); err != nil {
+		c.JSON(withdrawalErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	after, _ := h.service.GetWithdrawalRequest(id)
+	if !h.audit(c, model.AuditRejectWithdrawal, "withdrawal_request", id, before, after) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "撤资申请已拒绝"})
+}
+
+// withdrawalErrorStatus 状态已变化返回409，其余按请求错误处理
+func withdrawalErrorStatus(err error) int {
+	if err == service.ErrWithdrawalStateChanged {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,285 @@
+package mockexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binanceError Binance错误响应格式
+type binanceError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// authBinance 校验 X-MBX-APIKEY、HMAC-SHA256签名和时间戳
+// 签名内容为 signature 参数之前的原始查询字符串
+func (s *Server) authBinance(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	creds := s.binance.creds
+	s.mu.Unlock()
+
+	if r.Header.Get("X-MBX-APIKEY") != creds.APIKey || creds.APIKey == "" {
+		writeJSON(w, http.StatusUnauthorized, binanceError{-2015, "Invalid API-key, IP, or permissions for action."})
+		return false
+	}
+
+	rawQuery := r.URL.RawQuery
+	idx := strings.LastIndex(rawQuery, "&signature=")
+	if idx < 0 {
+		writeJSON(w, http.StatusBadRequest, binanceError{-1102, "Mandatory parameter 'signature' was not sent, was empty/null, or malformed."})
+		return false
+	}
+	payload, signature := rawQuery[:idx], rawQuery[idx+len("&signature="):]
+
+	expected := BinanceSign(payload, creds.APISecret)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		writeJSON(w, http.StatusBadRequest, binanceError{-1022, "Signature for this request is not valid."})
+		return false
+	}
+
+	ts, err := strconv.ParseInt(r.URL.Query().Get("timestamp"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, binanceError{-1102, "Mandatory parameter 'timestamp' was not sent, was empty/null, or malformed."})
+		return false
+	}
+	window := s.RecvWindow
+	if rw, err := strconv.ParseInt(r.URL.Query().Get("recvWindow"), 10, 64); err == nil && rw > 0 {
+		window = time.Duration(rw) * time.Millisecond
+	}
+	now := millis(s.Now())
+	if ts > now+1000 || now-ts > int64(window/time.Millisecond) {
+		writeJSON(w, http.StatusBadRequest, binanceError{-1021, "Timestamp for this request is outside of the recvWindow."})
+		return false
+	}
+
+	return true
+}
+
+// handleBinanceSpotAccount GET /api/v3/account
+func (s *Server) handleBinanceSpotAccount(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	balances := []map[string]string{}
+	for asset, b := range s.binance.spot {
+		balances = append(balances, map[string]string{
+			"asset":  asset,
+			"free":   formatFloat(b.Wallet),
+			"locked": formatFloat(b.Locked),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"balances": balances})
+}
+
+// handleBinanceFuturesBalance GET /fapi/v2/balance
+func (s *Server) handleBinanceFuturesBalance(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, binanceFuturesBalances(s.binance.futures))
+}
+
+// handleBinanceCoinFuturesBalance GET /dapi/v1/balance
+func (s *Server) handleBinanceCoinFuturesBalance(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, binanceFuturesBalances(s.binance.coinM))
+}
+
+func binanceFuturesBalances(balances map[string]Balance) []map[string]string {
+	result := []map[string]string{}
+	for asset, b := range balances {
+		result = append(result, map[string]string{
+			"asset":              asset,
+			"balance":            formatFloat(b.Wallet),
+			"crossWalletBalance": formatFloat(b.Wallet),
+			"crossUnPnl":         formatFloat(b.UnrealizedPnl),
+			"availableBalance":   formatFloat(b.Wallet - b.Locked),
+		})
+	}
+	return result
+}
+
+// handleBinancePositionRisk GET /fapi/v2/positionRisk
+func (s *Server) handleBinancePositionRisk(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []map[string]string{}
+	for _, p := range s.binance.positions {
+		side := "LONG"
+		if p.Amount < 0 {
+			side = "SHORT"
+		}
+		result = append(result, map[string]string{
+			"symbol":           p.Symbol,
+			"positionAmt":      formatFloat(p.Amount),
+			"entryPrice":       formatFloat(p.EntryPrice),
+			"markPrice":        formatFloat(p.MarkPrice),
+			"unRealizedProfit": formatFloat(p.UnrealizedPnl),
+			"leverage":         strconv.Itoa(p.Leverage),
+			"marginType":       p.MarginType,
+			"positionSide":     side,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleBinanceOpenOrders GET /fapi/v1/openOrders
+func (s *Server) handleBinanceOpenOrders(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []map[string]interface{}{}
+	for _, o := range s.binance.orders {
+		result = append(result, map[string]interface{}{
+			"orderId":     o.ID,
+			"symbol":      o.Symbol,
+			"side":        o.Side,
+			"type":        o.Type,
+			"price":       formatFloat(o.Price),
+			"origQty":     formatFloat(o.Quantity),
+			"executedQty": formatFloat(o.ExecutedQty),
+			"status":      o.Status,
+			"time":        millis(o.Time),
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleBinanceUserTrades GET /fapi/v1/userTrades
+func (s *Server) handleBinanceUserTrades(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	limit := 500
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []map[string]interface{}{}
+	for i, t := range s.binance.trades {
+		if i >= limit {
+			break
+		}
+		result = append(result, map[string]interface{}{
+			"id":          i + 1,
+			"symbol":      t.Symbol,
+			"side":        t.Side,
+			"price":       formatFloat(t.Price),
+			"qty":         formatFloat(t.Quantity),
+			"realizedPnl": formatFloat(t.RealizedPnl),
+			"commission":  formatFloat(t.Commission),
+			"time":        millis(t.CloseTime),
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleBinanceAPIRestrictions GET /sapi/v1/account/apiRestrictions
+func (s *Server) handleBinanceAPIRestrictions(w http.ResponseWriter, r *http.Request) {
+	if !s.authBinance(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.binance.perms
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ipRestrict":                 false,
+		"createTime":                 millis(s.Now()),
+		"enableReading":              true,
+		"enableSpotAndMarginTrading": p.Trade,
+		"enableFutures":              p.Trade,
+		"enableMargin":               false,
+		"enableVanillaOptions":       false,
+		"enableWithdrawals":          p.Withdraw,
+		"enableInternalTransfer":     false,
+		"permitsUniversalTransfer":   false,
+	})
+}
+
+// handleBinanceKlines GET /api/v3/klines（公开接口，不校验签名；只支持 interval=1d）
+func (s *Server) handleBinanceKlines(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("interval") != "1d" {
+		writeJSON(w, http.StatusBadRequest, binanceError{-1120, "Invalid interval."})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closes, ok := s.klines[q.Get("symbol")]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, binanceError{-1121, "Invalid symbol."})
+		return
+	}
+
+	day := 24 * time.Hour
+	now := s.Now()
+	start := now.Add(-binanceKlineLimit * day)
+	if v, err := strconv.ParseInt(q.Get("startTime"), 10, 64); err == nil {
+		start = time.UnixMilli(v)
+	}
+	end := now
+	if v, err := strconv.ParseInt(q.Get("endTime"), 10, 64); err == nil && time.UnixMilli(v).Before(now) {
+		end = time.UnixMilli(v)
+	}
+	limit := binanceKlineLimit
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v < limit {
+		limit = v
+	}
+
+	result := [][]interface{}{}
+	for open := start.UTC().Truncate(day); !open.After(end) && len(result) < limit; open = open.Add(day) {
+		if open.Before(start) {
+			continue
+		}
+		price, ok := closes[open.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		p := formatFloat(price)
+		result = append(result, []interface{}{
+			millis(open), p, p, p, p, "0", millis(open.Add(day)) - 1, "0", 0, "0", "0", "0",
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// BinanceSign 计算Binance签名（供测试构造请求使用）
+func BinanceSign(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
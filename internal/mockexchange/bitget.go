@@ -0,0 +1,300 @@
+package mockexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bitgetResponse Bitget V2统一响应格式
+type bitgetResponse struct {
+	Code        string      `json:"code"`
+	Msg         string      `json:"msg"`
+	RequestTime int64       `json:"requestTime"`
+	Data        interface{} `json:"data"`
+}
+
+// bitgetProductCoins 合约产品线对应的保证金币种
+var bitgetProductCoins = map[string]string{
+	"USDT-FUTURES": "USDT",
+	"USDC-FUTURES": "USDC",
+}
+
+func (s *Server) bitgetOK(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, bitgetResponse{"00000", "success", millis(s.Now()), data})
+}
+
+func (s *Server) bitgetFail(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, bitgetResponse{code, msg, millis(s.Now()), nil})
+}
+
+// authBitget 校验 ACCESS-* 请求头
+// 签名内容为 timestamp(毫秒) + method + requestPath(含查询参数) + body
+func (s *Server) authBitget(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	creds := s.bitget.creds
+	s.mu.Unlock()
+
+	if r.Header.Get("ACCESS-KEY") != creds.APIKey || creds.APIKey == "" {
+		s.bitgetFail(w, http.StatusBadRequest, "40037", "Apikey does not exist")
+		return false
+	}
+	if r.Header.Get("ACCESS-PASSPHRASE") != creds.Passphrase {
+		s.bitgetFail(w, http.StatusBadRequest, "40012", "apikey/password is incorrect")
+		return false
+	}
+
+	timestamp := r.Header.Get("ACCESS-TIMESTAMP")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		s.bitgetFail(w, http.StatusBadRequest, "40002", "ACCESS_TIMESTAMP is invalid")
+		return false
+	}
+	if d := millis(s.Now()) - ts; d > 30000 || d < -30000 {
+		s.bitgetFail(w, http.StatusBadRequest, "40008", "Request timestamp expired")
+		return false
+	}
+
+	body := ""
+	if r.Body != nil {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+	}
+
+	expected := BitgetSign(timestamp+r.Method+r.URL.RequestURI()+body, creds.APISecret)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("ACCESS-SIGN"))) {
+		s.bitgetFail(w, http.StatusBadRequest, "40009", "sign signature error")
+		return false
+	}
+
+	return true
+}
+
+// bitgetProductType 校验必填的 productType，返回对应的保证金币种
+func (s *Server) bitgetProductType(w http.ResponseWriter, r *http.Request) (string, bool) {
+	coin, ok := bitgetProductCoins[r.URL.Query().Get("productType")]
+	if !ok {
+		s.bitgetFail(w, http.StatusBadRequest, "40020", "Parameter productType error")
+		return "", false
+	}
+	return coin, true
+}
+
+// handleBitgetSpotAssets GET /api/v2/spot/account/assets[?coin=USDT]
+func (s *Server) handleBitgetSpotAssets(w http.ResponseWriter, r *http.Request) {
+	if !s.authBitget(w, r) {
+		return
+	}
+	coinFilter := r.URL.Query().Get("coin")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	for coin, b := range s.bitget.spot {
+		if coinFilter != "" && coin != coinFilter {
+			continue
+		}
+		data = append(data, map[string]string{
+			"coin":      coin,
+			"available": formatFloat(b.Wallet - b.Locked),
+			"frozen":    formatFloat(b.Locked),
+			"locked":    "0",
+		})
+	}
+	s.bitgetOK(w, data)
+}
+
+// handleBitgetMixAccounts GET /api/v2/mix/account/accounts?productType=USDT-FUTURES
+func (s *Server) handleBitgetMixAccounts(w http.ResponseWriter, r *http.Request) {
+	if !s.authBitget(w, r) {
+		return
+	}
+	marginCoin, ok := s.bitgetProductType(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	if b, ok := s.bitget.futures[marginCoin]; ok {
+		data = append(data, map[string]string{
+			"marginCoin":    marginCoin,
+			"available":     formatFloat(b.Wallet - b.Locked),
+			"locked":        formatFloat(b.Locked),
+			"accountEquity": formatFloat(b.Equity()),
+			"usdtEquity":    formatFloat(b.Equity()),
+			"unrealizedPL":  formatFloat(b.UnrealizedPnl),
+		})
+	}
+	s.bitgetOK(w, data)
+}
+
+// handleBitgetAllPositions GET /api/v2/mix/position/all-position?productType=USDT-FUTURES
+// 模拟服务器按交易对后缀判断所属产品线
+func (s *Server) handleBitgetAllPositions(w http.ResponseWriter, r *http.Request) {
+	if !s.authBitget(w, r) {
+		return
+	}
+	marginCoin, ok := s.bitgetProductType(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	for _, p := range s.bitget.positions {
+		if !strings.HasSuffix(p.Symbol, marginCoin) {
+			continue
+		}
+		holdSide := "long"
+		size := p.Amount
+		if size < 0 {
+			holdSide = "short"
+			size = -size
+		}
+		marginMode := "crossed"
+		if p.MarginType == "isolated" {
+			marginMode = "isolated"
+		}
+		data = append(data, map[string]string{
+			"symbol":       p.Symbol,
+			"marginCoin":   marginCoin,
+			"holdSide":     holdSide,
+			"total":        formatFloat(size),
+			"available":    formatFloat(size),
+			"openPriceAvg": formatFloat(p.EntryPrice),
+			"markPrice":    formatFloat(p.MarkPrice),
+			"unrealizedPL": formatFloat(p.UnrealizedPnl),
+			"leverage":     strconv.Itoa(p.Leverage),
+			"marginMode":   marginMode,
+			"posMode":      "hedge_mode",
+		})
+	}
+	s.bitgetOK(w, data)
+}
+
+// handleBitgetOrdersPending GET /api/v2/mix/order/orders-pending?productType=USDT-FUTURES
+func (s *Server) handleBitgetOrdersPending(w http.ResponseWriter, r *http.Request) {
+	if !s.authBitget(w, r) {
+		return
+	}
+	marginCoin, ok := s.bitgetProductType(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 和真实接口一样，没有委托时 entrustedList 为 null
+	var list []map[string]string
+	for _, o := range s.bitget.orders {
+		if !strings.HasSuffix(o.Symbol, marginCoin) {
+			continue
+		}
+		list = append(list, map[string]string{
+			"orderId":    strconv.FormatInt(o.ID, 10),
+			"symbol":     o.Symbol,
+			"side":       strings.ToLower(o.Side),
+			"orderType":  strings.ToLower(o.Type),
+			"price":      formatFloat(o.Price),
+			"size":       formatFloat(o.Quantity),
+			"baseVolume": formatFloat(o.ExecutedQty),
+			"status":     o.Status,
+			"marginCoin": marginCoin,
+			"cTime":      strconv.FormatInt(millis(o.Time), 10),
+		})
+	}
+	s.bitgetOK(w, map[string]interface{}{"entrustedList": list, "endId": ""})
+}
+
+// handleBitgetHistoryPosition GET /api/v2/mix/position/history-position?productType=USDT-FUTURES&limit=20
+// Trade.Side 为持仓方向（LONG/SHORT），Price 为开仓均价，AvgPrice 为平仓均价
+func (s *Server) handleBitgetHistoryPosition(w http.ResponseWriter, r *http.Request) {
+	if !s.authBitget(w, r) {
+		return
+	}
+	marginCoin, ok := s.bitgetProductType(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		if v > 100 {
+			s.bitgetFail(w, http.StatusBadRequest, "40020", "Parameter limit error")
+			return
+		}
+		limit = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []map[string]string{}
+	for _, t := range s.bitget.trades {
+		if len(list) >= limit {
+			break
+		}
+		if !strings.HasSuffix(t.Symbol, marginCoin) {
+			continue
+		}
+		list = append(list, map[string]string{
+			"symbol":        t.Symbol,
+			"marginCoin":    marginCoin,
+			"holdSide":      strings.ToLower(t.Side),
+			"openAvgPrice":  formatFloat(t.Price),
+			"closeAvgPrice": formatFloat(t.AvgPrice),
+			"openTotalPos":  formatFloat(t.Quantity),
+			"closeTotalPos": formatFloat(t.Quantity),
+			"pnl":           formatFloat(t.RealizedPnl),
+			"netProfit":     formatFloat(t.RealizedPnl - t.Commission),
+			"openFee":       "0",
+			"closeFee":      formatFloat(-t.Commission),
+			"cTime":         strconv.FormatInt(millis(t.OpenTime), 10),
+			"uTime":         strconv.FormatInt(millis(t.CloseTime), 10),
+		})
+	}
+	s.bitgetOK(w, map[string]interface{}{"list": list, "endId": ""})
+}
+
+// handleBitgetAccountInfo GET /api/v2/spot/account/info
+func (s *Server) handleBitgetAccountInfo(w http.ResponseWriter, r *http.Request) {
+	if !s.authBitget(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authorities := []string{"readonly"}
+	if s.bitget.perms.Trade {
+		authorities = append(authorities, "trade")
+	}
+	if s.bitget.perms.Withdraw {
+		authorities = append(authorities, "transfer", "withdraw")
+	}
+	s.bitgetOK(w, map[string]interface{}{
+		"userId":      "1000000001",
+		"ips":         "",
+		"authorities": authorities,
+		"regisTime":   strconv.FormatInt(millis(s.Now().Add(-365*24*time.Hour)), 10),
+	})
+}
+
+// BitgetSign 计算Bitget签名（供测试构造请求使用）
+func BitgetSign(prehash, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(prehash))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
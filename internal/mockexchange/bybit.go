@@ -0,0 +1,295 @@
+package mockexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bybitResponse Bybit V5统一响应格式
+type bybitResponse struct {
+	RetCode int         `json:"retCode"`
+	RetMsg  string      `json:"retMsg"`
+	Result  interface{} `json:"result"`
+	Time    int64       `json:"time"`
+}
+
+// bybitOK 成功响应
+func (s *Server) bybitOK(w http.ResponseWriter, result interface{}) {
+	writeJSON(w, http.StatusOK, bybitResponse{0, "OK", result, millis(s.Now())})
+}
+
+// bybitFail 错误响应（和真实接口一样HTTP状态为200，错误码在 retCode 中）
+func (s *Server) bybitFail(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, http.StatusOK, bybitResponse{code, msg, map[string]interface{}{}, millis(s.Now())})
+}
+
+// authBybit 校验 X-BAPI-* 请求头
+// 签名内容为 timestamp + apiKey + recvWindow + 查询字符串，HMAC-SHA256 十六进制
+func (s *Server) authBybit(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	creds := s.bybit.creds
+	s.mu.Unlock()
+
+	apiKey := r.Header.Get("X-BAPI-API-KEY")
+	if apiKey != creds.APIKey || creds.APIKey == "" {
+		s.bybitFail(w, 10003, "API key is invalid.")
+		return false
+	}
+
+	timestamp := r.Header.Get("X-BAPI-TIMESTAMP")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		s.bybitFail(w, 10002, "invalid request, please check your timestamp")
+		return false
+	}
+	recvWindow := r.Header.Get("X-BAPI-RECV-WINDOW")
+	window := s.RecvWindow
+	if rw, err := strconv.ParseInt(recvWindow, 10, 64); err == nil && rw > 0 {
+		window = time.Duration(rw) * time.Millisecond
+	}
+	now := millis(s.Now())
+	if ts > now+1000 || now-ts > int64(window/time.Millisecond) {
+		s.bybitFail(w, 10002, "invalid request, please check your server timestamp or recv_window param")
+		return false
+	}
+
+	expected := BybitSign(timestamp+apiKey+recvWindow+r.URL.RawQuery, creds.APISecret)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(r.Header.Get("X-BAPI-SIGN")))) {
+		s.bybitFail(w, 10004, "error sign! origin_string["+timestamp+apiKey+recvWindow+r.URL.RawQuery+"]")
+		return false
+	}
+
+	return true
+}
+
+// handleBybitWalletBalance GET /v5/account/wallet-balance?accountType=UNIFIED[&coin=USDT]
+func (s *Server) handleBybitWalletBalance(w http.ResponseWriter, r *http.Request) {
+	if !s.authBybit(w, r) {
+		return
+	}
+	if r.URL.Query().Get("accountType") != "UNIFIED" {
+		s.bybitFail(w, 10001, "accountType only support UNIFIED.")
+		return
+	}
+	coinFilter := r.URL.Query().Get("coin")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totalEquity := 0.0
+	coins := []map[string]string{}
+	for coin, b := range s.bybit.spot {
+		if coinFilter != "" && coin != coinFilter {
+			continue
+		}
+		totalEquity += b.Equity()
+		coins = append(coins, map[string]string{
+			"coin":          coin,
+			"equity":        formatFloat(b.Equity()),
+			"usdValue":      formatFloat(b.Equity()),
+			"walletBalance": formatFloat(b.Wallet),
+			"locked":        formatFloat(b.Locked),
+			"unrealisedPnl": formatFloat(b.UnrealizedPnl),
+		})
+	}
+
+	s.bybitOK(w, map[string]interface{}{
+		"list": []map[string]interface{}{{
+			"accountType": "UNIFIED",
+			"totalEquity": formatFloat(totalEquity),
+			"coin":        coins,
+		}},
+	})
+}
+
+// handleBybitPositions GET /v5/position/list?category=linear&settleCoin=USDT
+// 模拟服务器按交易对后缀判断结算币种
+func (s *Server) handleBybitPositions(w http.ResponseWriter, r *http.Request) {
+	if !s.authBybit(w, r) {
+		return
+	}
+	settleCoin, ok := s.bybitLinearQuery(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []map[string]interface{}{}
+	for _, p := range s.bybit.positions {
+		if !strings.HasSuffix(p.Symbol, settleCoin) {
+			continue
+		}
+		side := "Buy"
+		size := p.Amount
+		if size < 0 {
+			side = "Sell"
+			size = -size
+		}
+		tradeMode := 0
+		if p.MarginType == "isolated" {
+			tradeMode = 1
+		}
+		list = append(list, map[string]interface{}{
+			"symbol":        p.Symbol,
+			"side":          side,
+			"size":          formatFloat(size),
+			"avgPrice":      formatFloat(p.EntryPrice),
+			"markPrice":     formatFloat(p.MarkPrice),
+			"positionValue": formatFloat(size * p.EntryPrice),
+			"unrealisedPnl": formatFloat(p.UnrealizedPnl),
+			"leverage":      strconv.Itoa(p.Leverage),
+			"tradeMode":     tradeMode,
+		})
+	}
+	s.bybitOK(w, map[string]interface{}{"category": "linear", "list": list, "nextPageCursor": ""})
+}
+
+// handleBybitOpenOrders GET /v5/order/realtime?category=linear&settleCoin=USDT
+func (s *Server) handleBybitOpenOrders(w http.ResponseWriter, r *http.Request) {
+	if !s.authBybit(w, r) {
+		return
+	}
+	settleCoin, ok := s.bybitLinearQuery(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []map[string]string{}
+	for _, o := range s.bybit.orders {
+		if !strings.HasSuffix(o.Symbol, settleCoin) {
+			continue
+		}
+		list = append(list, map[string]string{
+			"orderId":     strconv.FormatInt(o.ID, 10),
+			"symbol":      o.Symbol,
+			"side":        bybitCase(o.Side),
+			"orderType":   bybitCase(o.Type),
+			"price":       formatFloat(o.Price),
+			"qty":         formatFloat(o.Quantity),
+			"cumExecQty":  formatFloat(o.ExecutedQty),
+			"orderStatus": o.Status,
+			"createdTime": strconv.FormatInt(millis(o.Time), 10),
+		})
+	}
+	s.bybitOK(w, map[string]interface{}{"category": "linear", "list": list, "nextPageCursor": ""})
+}
+
+// handleBybitClosedPnl GET /v5/position/closed-pnl?category=linear&limit=50
+func (s *Server) handleBybitClosedPnl(w http.ResponseWriter, r *http.Request) {
+	if !s.authBybit(w, r) {
+		return
+	}
+	if r.URL.Query().Get("category") != "linear" {
+		s.bybitFail(w, 10001, "category only support linear.")
+		return
+	}
+
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		if v > 100 {
+			s.bybitFail(w, 10001, "limit must be between 1 and 100.")
+			return
+		}
+		limit = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []map[string]string{}
+	for i, t := range s.bybit.trades {
+		if i >= limit {
+			break
+		}
+		list = append(list, map[string]string{
+			"symbol":        t.Symbol,
+			"side":          bybitCase(t.Side),
+			"qty":           formatFloat(t.Quantity),
+			"closedSize":    formatFloat(t.Quantity),
+			"avgEntryPrice": formatFloat(t.Price),
+			"avgExitPrice":  formatFloat(t.AvgPrice),
+			"closedPnl":     formatFloat(t.RealizedPnl),
+			"openFee":       "0",
+			"closeFee":      formatFloat(t.Commission),
+			"createdTime":   strconv.FormatInt(millis(t.OpenTime), 10),
+			"updatedTime":   strconv.FormatInt(millis(t.CloseTime), 10),
+		})
+	}
+	s.bybitOK(w, map[string]interface{}{"category": "linear", "list": list, "nextPageCursor": ""})
+}
+
+// handleBybitQueryAPI GET /v5/user/query-api
+// 只读密钥返回 readOnly=1；开启交易或提现后变为读写密钥并列出对应权限
+func (s *Server) handleBybitQueryAPI(w http.ResponseWriter, r *http.Request) {
+	if !s.authBybit(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	readOnly := 1
+	permissions := map[string][]string{
+		"ContractTrade": {"Order", "Position"},
+		"Spot":          {},
+		"Wallet":        {},
+		"Options":       {},
+		"Derivatives":   {},
+	}
+	if s.bybit.perms.Trade {
+		readOnly = 0
+		permissions["Spot"] = []string{"SpotTrade"}
+	}
+	if s.bybit.perms.Withdraw {
+		readOnly = 0
+		permissions["Wallet"] = []string{"AccountTransfer", "Withdraw"}
+	}
+
+	s.bybitOK(w, map[string]interface{}{
+		"apiKey":      s.bybit.creds.APIKey,
+		"note":        "mock",
+		"readOnly":    readOnly,
+		"permissions": permissions,
+		"uta":         1,
+	})
+}
+
+// bybitLinearQuery 校验 category=linear 并返回必填的 settleCoin
+func (s *Server) bybitLinearQuery(w http.ResponseWriter, r *http.Request) (string, bool) {
+	q := r.URL.Query()
+	if q.Get("category") != "linear" {
+		s.bybitFail(w, 10001, "category only support linear.")
+		return "", false
+	}
+	settleCoin := q.Get("settleCoin")
+	if settleCoin == "" && q.Get("symbol") == "" {
+		s.bybitFail(w, 10001, "symbol or settleCoin is required.")
+		return "", false
+	}
+	return settleCoin, true
+}
+
+// bybitCase BUY/LIMIT → Buy/Limit
+func bybitCase(v string) string {
+	if v == "" {
+		return v
+	}
+	return strings.ToUpper(v[:1]) + strings.ToLower(v[1:])
+}
+
+// BybitSign 计算Bybit V5签名（供测试构造请求使用）
+func BybitSign(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,206 @@
+package mockexchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// rpcRequest JSON-RPC 2.0 请求
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+// rpcResponse JSON-RPC 2.0 响应
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// erc20BalanceOf balanceOf(address) 的函数选择器
+const erc20BalanceOf = "0x70a08231"
+
+// handleJSONRPC POST /rpc/<链标识>，solana 走 Solana RPC，其余按 EVM 节点处理
+// 请求体可以是单个请求，也可以是批量请求数组。
+func (s *Server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	chain := strings.Trim(strings.TrimPrefix(r.URL.Path, "/rpc/"), "/")
+	if r.Method != http.MethodPost || chain == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", Error: &rpcError{-32700, "parse error"}})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(raw, &reqs); err != nil || len(reqs) == 0 {
+			writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", Error: &rpcError{-32600, "invalid request"}})
+			return
+		}
+		responses := make([]rpcResponse, len(reqs))
+		for i, req := range reqs {
+			responses[i] = s.rpcDispatch(chain, req)
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", Error: &rpcError{-32600, "invalid request"}})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.rpcDispatch(chain, req))
+}
+
+// rpcDispatch 处理一个 JSON-RPC 请求（调用方需持有锁）
+func (s *Server) rpcDispatch(chain string, req rpcRequest) rpcResponse {
+	var result interface{}
+	var rpcErr *rpcError
+	if chain == "solana" {
+		result, rpcErr = s.solanaCall(req)
+	} else {
+		result, rpcErr = s.evmCall(chain, req)
+	}
+	return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+}
+
+// evmCall 支持 eth_blockNumber 和 eth_call 调用 balanceOf（区块参数为 latest 或十六进制高度）
+func (s *Server) evmCall(chain string, req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "eth_blockNumber":
+		return fmt.Sprintf("0x%x", s.blockNumber), nil
+	case "eth_call":
+	default:
+		return nil, &rpcError{-32601, "the method " + req.Method + " does not exist/is not available"}
+	}
+	if len(req.Params) == 0 {
+		return nil, &rpcError{-32602, "missing value for required argument 0"}
+	}
+
+	var call struct {
+		To   string `json:"to"`
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(req.Params[0], &call); err != nil {
+		return nil, &rpcError{-32602, "invalid argument 0"}
+	}
+
+	block := s.blockNumber
+	if len(req.Params) > 1 {
+		var tag string
+		if err := json.Unmarshal(req.Params[1], &tag); err != nil {
+			return nil, &rpcError{-32602, "invalid argument 1"}
+		}
+		switch tag {
+		case "latest", "pending", "safe", "finalized", "":
+		case "earliest":
+			block = 0
+		default:
+			n, err := strconv.ParseUint(strings.TrimPrefix(tag, "0x"), 16, 64)
+			if err != nil || !strings.HasPrefix(tag, "0x") {
+				return nil, &rpcError{-32602, "invalid argument 1: hex string without 0x prefix"}
+			}
+			if n > s.blockNumber {
+				return nil, &rpcError{-32000, "header not found"}
+			}
+			block = n
+		}
+	}
+
+	// 选择器(10) + 32字节地址参数(64)
+	if !strings.HasPrefix(call.Data, erc20BalanceOf) || len(call.Data) != 74 {
+		return nil, &rpcError{3, "execution reverted"}
+	}
+	address := "0x" + call.Data[len(call.Data)-40:]
+
+	raw := s.lookupTokenAt(chain, call.To, address, block)
+	return fmt.Sprintf("0x%064x", raw), nil
+}
+
+// solanaCall 只支持按Mint过滤的 getTokenAccountsByOwner
+func (s *Server) solanaCall(req rpcRequest) (interface{}, *rpcError) {
+	if req.Method != "getTokenAccountsByOwner" {
+		return nil, &rpcError{-32601, "Method not found"}
+	}
+	if len(req.Params) < 2 {
+		return nil, &rpcError{-32602, "Invalid params"}
+	}
+
+	var owner string
+	var filter struct {
+		Mint string `json:"mint"`
+	}
+	if json.Unmarshal(req.Params[0], &owner) != nil || json.Unmarshal(req.Params[1], &filter) != nil || filter.Mint == "" {
+		return nil, &rpcError{-32602, "Invalid params"}
+	}
+
+	value := []interface{}{}
+	if t, ok := s.tokens[tokenKey("solana", filter.Mint, owner)]; ok {
+		value = append(value, map[string]interface{}{
+			"pubkey": "MockTokenAccount" + owner[:8],
+			"account": map[string]interface{}{
+				"data": map[string]interface{}{
+					"program": "spl-token",
+					"parsed": map[string]interface{}{
+						"type": "account",
+						"info": map[string]interface{}{
+							"mint":  filter.Mint,
+							"owner": owner,
+							"tokenAmount": map[string]interface{}{
+								"amount":   t.raw.String(),
+								"decimals": 6,
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+	return map[string]interface{}{"context": map[string]int{"slot": 1}, "value": value}, nil
+}
+
+// handleTronAccount GET /v1/accounts/{address}，返回该地址持有的全部TRC20余额
+func (s *Server) handleTronAccount(w http.ResponseWriter, r *http.Request) {
+	address := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/accounts/"), "/")
+	if address == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid address"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trc20 := []map[string]string{}
+	for _, t := range s.tokens {
+		if t.chain == "tron" && t.address == address {
+			trc20 = append(trc20, map[string]string{t.contract: t.raw.String()})
+		}
+	}
+
+	// 未激活（没有任何资产）的地址 data 为空
+	data := []interface{}{}
+	if len(trc20) > 0 {
+		data = append(data, map[string]interface{}{"address": address, "trc20": trc20})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "data": data})
+}
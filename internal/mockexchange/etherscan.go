@@ -0,0 +1,39 @@
+package mockexchange
+
+import (
+	"net/http"
+)
+
+// etherscanResponse Etherscan响应格式
+type etherscanResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Result  string `json:"result"`
+}
+
+// handleEtherscan GET /api?module=account&action=tokenbalance&contractaddress=&address=&apikey=
+func (s *Server) handleEtherscan(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.etherscanAPIKey != "" && q.Get("apikey") != s.etherscanAPIKey {
+		writeJSON(w, http.StatusOK, etherscanResponse{"0", "NOTOK", "Invalid API Key"})
+		return
+	}
+
+	if q.Get("module") != "account" || q.Get("action") != "tokenbalance" {
+		writeJSON(w, http.StatusOK, etherscanResponse{"0", "NOTOK", "Error! Missing Or invalid Module name"})
+		return
+	}
+
+	contract, address := q.Get("contractaddress"), q.Get("address")
+	if contract == "" || address == "" {
+		writeJSON(w, http.StatusOK, etherscanResponse{"0", "NOTOK", "Error! Missing Or invalid Address"})
+		return
+	}
+
+	balance := s.lookupToken("ethereum", contract, address)
+	writeJSON(w, http.StatusOK, etherscanResponse{"1", "OK", balance.String()})
+}
@@ -0,0 +1,230 @@
+package mockexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gateError Gate.io错误响应格式
+type gateError struct {
+	Label   string `json:"label"`
+	Message string `json:"message"`
+}
+
+// authGate 校验 KEY / Timestamp / SIGN 请求头
+// 签名内容为 method\npath\nquery\nhex(sha512(body))\ntimestamp，HMAC-SHA512 十六进制
+func (s *Server) authGate(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	creds := s.gate.creds
+	s.mu.Unlock()
+
+	if r.Header.Get("KEY") != creds.APIKey || creds.APIKey == "" {
+		writeJSON(w, http.StatusUnauthorized, gateError{"INVALID_KEY", "Invalid key provided"})
+		return false
+	}
+
+	timestamp := r.Header.Get("Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, gateError{"MISSING_REQUIRED_HEADER", "Missing required header: Timestamp"})
+		return false
+	}
+	if d := s.Now().Unix() - ts; d > 60 || d < -60 {
+		writeJSON(w, http.StatusForbidden, gateError{"REQUEST_EXPIRED", "Request timestamp expired"})
+		return false
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+	bodyHash := sha512.Sum512(body)
+
+	expected := GateSign(r.Method+"\n"+r.URL.Path+"\n"+r.URL.RawQuery+"\n"+hex.EncodeToString(bodyHash[:])+"\n"+timestamp, creds.APISecret)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(r.Header.Get("SIGN")))) {
+		writeJSON(w, http.StatusUnauthorized, gateError{"INVALID_SIGNATURE", "Signature mismatch"})
+		return false
+	}
+
+	return true
+}
+
+// handleGateSpotAccounts GET /api/v4/spot/accounts[?currency=USDT]
+func (s *Server) handleGateSpotAccounts(w http.ResponseWriter, r *http.Request) {
+	if !s.authGate(w, r) {
+		return
+	}
+	currency := r.URL.Query().Get("currency")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	for ccy, b := range s.gate.spot {
+		if currency != "" && ccy != currency {
+			continue
+		}
+		data = append(data, map[string]string{
+			"currency":  ccy,
+			"available": formatFloat(b.Wallet - b.Locked),
+			"locked":    formatFloat(b.Locked),
+		})
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// handleGateFuturesAccounts GET /api/v4/futures/usdt/accounts
+func (s *Server) handleGateFuturesAccounts(w http.ResponseWriter, r *http.Request) {
+	if !s.authGate(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.gate.futures["USDT"]
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":          formatFloat(b.Wallet),
+		"unrealised_pnl": formatFloat(b.UnrealizedPnl),
+		"order_margin":   formatFloat(b.Locked),
+		"available":      formatFloat(b.Wallet - b.Locked),
+		"currency":       "USDT",
+		"in_dual_mode":   false,
+	})
+}
+
+// gateQuantoMultiplier 模拟的合约面值（每张对应的币数），所有合约相同
+const gateQuantoMultiplier = 0.0001
+
+// handleGateFuturesPositions GET /api/v4/futures/usdt/positions
+// Position.Amount 为合约张数（负数为空头），全仓时 leverage 返回0
+func (s *Server) handleGateFuturesPositions(w http.ResponseWriter, r *http.Request) {
+	if !s.authGate(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]interface{}{}
+	for _, p := range s.gate.positions {
+		size := int64(p.Amount)
+		leverage := strconv.Itoa(p.Leverage)
+		crossLimit := "0"
+		if p.MarginType == "cross" {
+			leverage, crossLimit = "0", strconv.Itoa(p.Leverage)
+		}
+		value := p.Amount * gateQuantoMultiplier * p.MarkPrice
+		if value < 0 {
+			value = -value
+		}
+		data = append(data, map[string]interface{}{
+			"contract":             p.Symbol,
+			"size":                 size,
+			"entry_price":          formatFloat(p.EntryPrice),
+			"mark_price":           formatFloat(p.MarkPrice),
+			"value":                formatFloat(value),
+			"unrealised_pnl":       formatFloat(p.UnrealizedPnl),
+			"leverage":             leverage,
+			"cross_leverage_limit": crossLimit,
+			"mode":                 "single",
+		})
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// handleGateFuturesOrders GET /api/v4/futures/usdt/orders?status=open
+func (s *Server) handleGateFuturesOrders(w http.ResponseWriter, r *http.Request) {
+	if !s.authGate(w, r) {
+		return
+	}
+	if r.URL.Query().Get("status") == "" {
+		writeJSON(w, http.StatusBadRequest, gateError{"MISSING_REQUIRED_PARAM", "Missing required parameter: status"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]interface{}{}
+	for _, o := range s.gate.orders {
+		size := int64(o.Quantity)
+		left := int64(o.Quantity - o.ExecutedQty)
+		if strings.EqualFold(o.Side, "SELL") {
+			size, left = -size, -left
+		}
+		price, tif := formatFloat(o.Price), "gtc"
+		if strings.EqualFold(o.Type, "MARKET") {
+			price, tif = "0", "ioc"
+		}
+		data = append(data, map[string]interface{}{
+			"id":          o.ID,
+			"contract":    o.Symbol,
+			"size":        size,
+			"left":        left,
+			"price":       price,
+			"tif":         tif,
+			"status":      o.Status,
+			"create_time": float64(millis(o.Time)) / 1000,
+		})
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// handleGatePositionClose GET /api/v4/futures/usdt/position_close
+// Trade.Side 为持仓方向（LONG/SHORT），Price 为开仓均价，AvgPrice 为平仓均价，Quantity 为张数
+func (s *Server) handleGatePositionClose(w http.ResponseWriter, r *http.Request) {
+	if !s.authGate(w, r) {
+		return
+	}
+
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		if v > 1000 {
+			writeJSON(w, http.StatusBadRequest, gateError{"INVALID_PARAM_VALUE", "limit must be less than or equal to 1000"})
+			return
+		}
+		limit = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]interface{}{}
+	for i, t := range s.gate.trades {
+		if i >= limit {
+			break
+		}
+		side, longPrice, shortPrice := "long", t.Price, t.AvgPrice
+		if strings.EqualFold(t.Side, "SHORT") {
+			side, longPrice, shortPrice = "short", t.AvgPrice, t.Price
+		}
+		data = append(data, map[string]interface{}{
+			"time":            float64(millis(t.CloseTime)) / 1000,
+			"first_open_time": t.OpenTime.Unix(),
+			"contract":        t.Symbol,
+			"side":            side,
+			"pnl":             formatFloat(t.RealizedPnl - t.Commission),
+			"pnl_pnl":         formatFloat(t.RealizedPnl),
+			"pnl_fund":        "0",
+			"pnl_fee":         formatFloat(-t.Commission),
+			"accum_size":      formatFloat(t.Quantity),
+			"max_size":        formatFloat(t.Quantity),
+			"long_price":      formatFloat(longPrice),
+			"short_price":     formatFloat(shortPrice),
+		})
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// GateSign 计算Gate.io签名（供测试构造请求使用）
+func GateSign(payload, secret string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,221 @@
+package mockexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// okxResponse OKX统一响应格式
+type okxResponse struct {
+	Code string      `json:"code"`
+	Msg  string      `json:"msg"`
+	Data interface{} `json:"data"`
+}
+
+// authOKX 校验 OK-ACCESS-* 请求头
+// 签名内容为 timestamp + method + requestPath(含查询参数) + body
+func (s *Server) authOKX(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	creds := s.okx.creds
+	s.mu.Unlock()
+
+	if r.Header.Get("OK-ACCESS-KEY") != creds.APIKey || creds.APIKey == "" {
+		writeJSON(w, http.StatusUnauthorized, okxResponse{"50111", "Invalid OK-ACCESS-KEY", []interface{}{}})
+		return false
+	}
+	if r.Header.Get("OK-ACCESS-PASSPHRASE") != creds.Passphrase {
+		writeJSON(w, http.StatusUnauthorized, okxResponse{"50105", "Your OK-ACCESS-PASSPHRASE is incorrect", []interface{}{}})
+		return false
+	}
+
+	timestamp := r.Header.Get("OK-ACCESS-TIMESTAMP")
+	ts, err := time.Parse("2006-01-02T15:04:05.000Z", timestamp)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, okxResponse{"50112", "Invalid OK-ACCESS-TIMESTAMP", []interface{}{}})
+		return false
+	}
+	if d := s.Now().Sub(ts); d > 30*time.Second || d < -30*time.Second {
+		writeJSON(w, http.StatusBadRequest, okxResponse{"50102", "Timestamp request expired", []interface{}{}})
+		return false
+	}
+
+	body := ""
+	if r.Body != nil {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+	}
+
+	expected := OKXSign(timestamp+r.Method+r.URL.RequestURI()+body, creds.APISecret)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("OK-ACCESS-SIGN"))) {
+		writeJSON(w, http.StatusUnauthorized, okxResponse{"50113", "Invalid Sign", []interface{}{}})
+		return false
+	}
+
+	return true
+}
+
+// handleOKXBalance GET /api/v5/account/balance
+func (s *Server) handleOKXBalance(w http.ResponseWriter, r *http.Request) {
+	if !s.authOKX(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totalEq := 0.0
+	details := []map[string]string{}
+	for ccy, b := range s.okx.spot {
+		totalEq += b.Equity()
+		details = append(details, map[string]string{
+			"ccy":       ccy,
+			"eq":        formatFloat(b.Equity()),
+			"availEq":   formatFloat(b.Equity() - b.Locked),
+			"cashBal":   formatFloat(b.Wallet),
+			"availBal":  formatFloat(b.Wallet - b.Locked),
+			"frozenBal": formatFloat(b.Locked),
+			"ordFrozen": formatFloat(b.Locked),
+			"upl":       formatFloat(b.UnrealizedPnl),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, okxResponse{"0", "", []interface{}{
+		map[string]interface{}{
+			"totalEq": formatFloat(totalEq),
+			"details": details,
+		},
+	}})
+}
+
+// handleOKXPositions GET /api/v5/account/positions
+func (s *Server) handleOKXPositions(w http.ResponseWriter, r *http.Request) {
+	if !s.authOKX(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	for _, p := range s.okx.positions {
+		posSide := "long"
+		size := p.Amount
+		if size < 0 {
+			posSide = "short"
+			size = -size
+		}
+		uplRatio := 0.0
+		if p.EntryPrice > 0 && size > 0 {
+			uplRatio = p.UnrealizedPnl / (size * p.EntryPrice)
+		}
+		data = append(data, map[string]string{
+			"instId":   p.Symbol,
+			"posSide":  posSide,
+			"pos":      formatFloat(size),
+			"avgPx":    formatFloat(p.EntryPrice),
+			"markPx":   formatFloat(p.MarkPrice),
+			"upl":      formatFloat(p.UnrealizedPnl),
+			"uplRatio": formatFloat(uplRatio),
+			"lever":    strconv.Itoa(p.Leverage),
+			"mgnMode":  p.MarginType,
+		})
+	}
+	writeJSON(w, http.StatusOK, okxResponse{"0", "", data})
+}
+
+// handleOKXOrdersPending GET /api/v5/trade/orders-pending
+func (s *Server) handleOKXOrdersPending(w http.ResponseWriter, r *http.Request) {
+	if !s.authOKX(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	for _, o := range s.okx.orders {
+		data = append(data, map[string]string{
+			"ordId":     strconv.FormatInt(o.ID, 10),
+			"instId":    o.Symbol,
+			"side":      strings.ToLower(o.Side),
+			"ordType":   strings.ToLower(o.Type),
+			"px":        formatFloat(o.Price),
+			"sz":        formatFloat(o.Quantity),
+			"accFillSz": formatFloat(o.ExecutedQty),
+			"state":     o.Status,
+			"cTime":     strconv.FormatInt(millis(o.Time), 10),
+		})
+	}
+	writeJSON(w, http.StatusOK, okxResponse{"0", "", data})
+}
+
+// handleOKXOrdersHistory GET /api/v5/trade/orders-history
+func (s *Server) handleOKXOrdersHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.authOKX(w, r) {
+		return
+	}
+
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := []map[string]string{}
+	for i, t := range s.okx.trades {
+		if i >= limit {
+			break
+		}
+		data = append(data, map[string]string{
+			"instId": t.Symbol,
+			"side":   strings.ToLower(t.Side),
+			"px":     formatFloat(t.Price),
+			"avgPx":  formatFloat(t.AvgPrice),
+			"sz":     formatFloat(t.Quantity),
+			"pnl":    formatFloat(t.RealizedPnl),
+			"fee":    formatFloat(t.Commission),
+			"cTime":  strconv.FormatInt(millis(t.OpenTime), 10),
+			"uTime":  strconv.FormatInt(millis(t.CloseTime), 10),
+		})
+	}
+	writeJSON(w, http.StatusOK, okxResponse{"0", "", data})
+}
+
+// handleOKXAccountConfig GET /api/v5/account/config
+func (s *Server) handleOKXAccountConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.authOKX(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	perm := []string{"read_only"}
+	if s.okx.perms.Trade {
+		perm = append(perm, "trade")
+	}
+	if s.okx.perms.Withdraw {
+		perm = append(perm, "withdraw")
+	}
+	writeJSON(w, http.StatusOK, okxResponse{"0", "", []map[string]interface{}{{
+		"uid":     "10000001",
+		"acctLv":  "2",
+		"posMode": "net_mode",
+		"perm":    strings.Join(perm, ","),
+	}}})
+}
+
+// OKXSign 计算OKX签名（供测试构造请求使用）
+func OKXSign(prehash, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(prehash))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
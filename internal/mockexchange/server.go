@@ -0,0 +1,534 @@
+// Package mockexchange 本地模拟交易所服务器
+//
+// 实现了系统实际解析的 Binance / OKX / Bybit / Bitget / Gate.io / Etherscan 接口子集，并按真实规则校验签名，
+// 另外模拟了各链的余额接口（EVM JSON-RPC、TronGrid、Solana RPC），
+// 配合 service.SingleHostEndpoints 可以在没有真实密钥的情况下端到端跑通
+// UpdateDailyBalances、撤资等流程。
+package mockexchange
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials 模拟账户的API凭证
+type Credentials struct {
+	APIKey     string
+	APISecret  string
+	Passphrase string // 仅OKX、Bitget使用
+}
+
+// Balance 单个币种的余额
+type Balance struct {
+	Wallet        float64 // 钱包/现金余额
+	UnrealizedPnl float64 // 未实现盈亏（合约）
+	Locked        float64 // 冻结/挂单占用
+}
+
+// Equity 总权益 = 钱包余额 + 未实现盈亏
+func (b Balance) Equity() float64 {
+	return b.Wallet + b.UnrealizedPnl
+}
+
+// Permissions API密钥权限，零值为只读
+type Permissions struct {
+	Trade    bool // Binance enableSpotAndMarginTrading/enableFutures，OKX trade，Bybit 读写密钥的交易权限
+	Withdraw bool // Binance enableWithdrawals，OKX withdraw，Bybit Wallet 权限
+}
+
+// Position 持仓（Amount为负表示空仓）
+type Position struct {
+	Symbol        string
+	Amount        float64
+	EntryPrice    float64
+	MarkPrice     float64
+	UnrealizedPnl float64
+	Leverage      int
+	MarginType    string // cross / isolated
+}
+
+// Order 当前委托
+type Order struct {
+	ID          int64
+	Symbol      string
+	Side        string // BUY / SELL
+	Type        string // LIMIT / MARKET
+	Price       float64
+	Quantity    float64
+	ExecutedQty float64
+	Status      string
+	Time        time.Time
+}
+
+// Trade 历史成交
+type Trade struct {
+	Symbol      string
+	Side        string
+	Price       float64
+	AvgPrice    float64
+	Quantity    float64
+	RealizedPnl float64
+	Commission  float64
+	OpenTime    time.Time
+	CloseTime   time.Time
+}
+
+// venue 单个场所的模拟账户状态
+type venue struct {
+	creds     Credentials
+	perms     Permissions
+	spot      map[string]Balance
+	futures   map[string]Balance
+	coinM     map[string]Balance
+	positions []Position
+	orders    []Order
+	trades    []Trade
+}
+
+func newVenue() *venue {
+	return &venue{
+		spot:    make(map[string]Balance),
+		futures: make(map[string]Balance),
+		coinM:   make(map[string]Balance),
+	}
+}
+
+// Server 模拟交易所服务器（实现 http.Handler）
+type Server struct {
+	mu sync.Mutex
+
+	binance *venue
+	okx     *venue
+	bybit   *venue
+	bitget  *venue
+	gate    *venue
+
+	etherscanAPIKey string
+	tokens          map[string]tokenBalance // key: chain|contract|address（小写）
+	blockNumber     uint64                  // EVM节点的当前区块高度，MineBlock 推进
+
+	klines map[string]map[string]float64 // 交易对 -> UTC日期 -> 当天收盘价
+
+	// RecvWindow Binance时间戳容忍窗口，默认5秒
+	RecvWindow time.Duration
+	// Now 当前时间（可替换以模拟时钟偏差）
+	Now func() time.Time
+
+	mux *http.ServeMux
+}
+
+// New 创建模拟服务器
+func New() *Server {
+	s := &Server{
+		binance:     newVenue(),
+		okx:         newVenue(),
+		bybit:       newVenue(),
+		bitget:      newVenue(),
+		gate:        newVenue(),
+		tokens:      make(map[string]tokenBalance),
+		blockNumber: 1,
+		klines:      make(map[string]map[string]float64),
+		RecvWindow:  5 * time.Second,
+		Now:         time.Now,
+		mux:         http.NewServeMux(),
+	}
+
+	// Binance
+	s.mux.HandleFunc("/api/v3/account", s.handleBinanceSpotAccount)
+	s.mux.HandleFunc("/fapi/v2/balance", s.handleBinanceFuturesBalance)
+	s.mux.HandleFunc("/dapi/v1/balance", s.handleBinanceCoinFuturesBalance)
+	s.mux.HandleFunc("/fapi/v2/positionRisk", s.handleBinancePositionRisk)
+	s.mux.HandleFunc("/fapi/v1/openOrders", s.handleBinanceOpenOrders)
+	s.mux.HandleFunc("/fapi/v1/userTrades", s.handleBinanceUserTrades)
+	s.mux.HandleFunc("/sapi/v1/account/apiRestrictions", s.handleBinanceAPIRestrictions)
+	s.mux.HandleFunc("/api/v3/klines", s.handleBinanceKlines)
+
+	// OKX
+	s.mux.HandleFunc("/api/v5/account/balance", s.handleOKXBalance)
+	s.mux.HandleFunc("/api/v5/account/positions", s.handleOKXPositions)
+	s.mux.HandleFunc("/api/v5/trade/orders-pending", s.handleOKXOrdersPending)
+	s.mux.HandleFunc("/api/v5/trade/orders-history", s.handleOKXOrdersHistory)
+	s.mux.HandleFunc("/api/v5/account/config", s.handleOKXAccountConfig)
+
+	// Bybit
+	s.mux.HandleFunc("/v5/account/wallet-balance", s.handleBybitWalletBalance)
+	s.mux.HandleFunc("/v5/position/list", s.handleBybitPositions)
+	s.mux.HandleFunc("/v5/order/realtime", s.handleBybitOpenOrders)
+	s.mux.HandleFunc("/v5/position/closed-pnl", s.handleBybitClosedPnl)
+	s.mux.HandleFunc("/v5/user/query-api", s.handleBybitQueryAPI)
+
+	// Bitget
+	s.mux.HandleFunc("/api/v2/spot/account/assets", s.handleBitgetSpotAssets)
+	s.mux.HandleFunc("/api/v2/mix/account/accounts", s.handleBitgetMixAccounts)
+	s.mux.HandleFunc("/api/v2/mix/position/all-position", s.handleBitgetAllPositions)
+	s.mux.HandleFunc("/api/v2/mix/order/orders-pending", s.handleBitgetOrdersPending)
+	s.mux.HandleFunc("/api/v2/mix/position/history-position", s.handleBitgetHistoryPosition)
+	s.mux.HandleFunc("/api/v2/spot/account/info", s.handleBitgetAccountInfo)
+
+	// Gate.io（只模拟USDT结算的永续合约）
+	s.mux.HandleFunc("/api/v4/spot/accounts", s.handleGateSpotAccounts)
+	s.mux.HandleFunc("/api/v4/futures/usdt/accounts", s.handleGateFuturesAccounts)
+	s.mux.HandleFunc("/api/v4/futures/usdt/positions", s.handleGateFuturesPositions)
+	s.mux.HandleFunc("/api/v4/futures/usdt/orders", s.handleGateFuturesOrders)
+	s.mux.HandleFunc("/api/v4/futures/usdt/position_close", s.handleGatePositionClose)
+
+	// Etherscan
+	s.mux.HandleFunc("/api", s.handleEtherscan)
+
+	// 链上余额：EVM JSON-RPC（/rpc/<链标识>）、Solana RPC、TronGrid
+	s.mux.HandleFunc("/rpc/", s.handleJSONRPC)
+	s.mux.HandleFunc("/v1/accounts/", s.handleTronAccount)
+
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ==================== 配置 ====================
+
+// SetBinanceCredentials 设置Binance模拟账户凭证
+func (s *Server) SetBinanceCredentials(apiKey, apiSecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.creds = Credentials{APIKey: apiKey, APISecret: apiSecret}
+}
+
+// SetOKXCredentials 设置OKX模拟账户凭证
+func (s *Server) SetOKXCredentials(apiKey, apiSecret, passphrase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.okx.creds = Credentials{APIKey: apiKey, APISecret: apiSecret, Passphrase: passphrase}
+}
+
+// SetBybitCredentials 设置Bybit模拟账户凭证
+func (s *Server) SetBybitCredentials(apiKey, apiSecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bybit.creds = Credentials{APIKey: apiKey, APISecret: apiSecret}
+}
+
+// SetBitgetCredentials 设置Bitget模拟账户凭证
+func (s *Server) SetBitgetCredentials(apiKey, apiSecret, passphrase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.creds = Credentials{APIKey: apiKey, APISecret: apiSecret, Passphrase: passphrase}
+}
+
+// SetGateCredentials 设置Gate.io模拟账户凭证
+func (s *Server) SetGateCredentials(apiKey, apiSecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate.creds = Credentials{APIKey: apiKey, APISecret: apiSecret}
+}
+
+// SetBinancePermissions 设置Binance API密钥权限（默认只读）
+func (s *Server) SetBinancePermissions(p Permissions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.perms = p
+}
+
+// SetOKXPermissions 设置OKX API密钥权限（默认只读）
+func (s *Server) SetOKXPermissions(p Permissions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.okx.perms = p
+}
+
+// SetBybitPermissions 设置Bybit API密钥权限（默认只读）
+func (s *Server) SetBybitPermissions(p Permissions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bybit.perms = p
+}
+
+// SetBitgetPermissions 设置Bitget API密钥权限（默认只读）
+func (s *Server) SetBitgetPermissions(p Permissions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.perms = p
+}
+
+// SetEtherscanAPIKey 设置Etherscan API Key（为空则不校验）
+func (s *Server) SetEtherscanAPIKey(apiKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etherscanAPIKey = apiKey
+}
+
+// SetBinanceSpotBalance 设置Binance现货余额
+func (s *Server) SetBinanceSpotBalance(asset string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.spot[asset] = b
+}
+
+// SetBinanceFuturesBalance 设置Binance U本位合约余额
+func (s *Server) SetBinanceFuturesBalance(asset string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.futures[asset] = b
+}
+
+// SetBinanceCoinFuturesBalance 设置Binance币本位合约余额
+func (s *Server) SetBinanceCoinFuturesBalance(asset string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.coinM[asset] = b
+}
+
+// SetOKXBalance 设置OKX交易账户余额
+func (s *Server) SetOKXBalance(ccy string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.okx.spot[ccy] = b
+}
+
+// SetBybitBalance 设置Bybit统一账户余额
+func (s *Server) SetBybitBalance(coin string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bybit.spot[coin] = b
+}
+
+// SetBitgetSpotBalance 设置Bitget现货余额
+func (s *Server) SetBitgetSpotBalance(coin string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.spot[coin] = b
+}
+
+// SetBitgetFuturesBalance 设置Bitget合约账户余额（USDT/USDC对应USDT-FUTURES/USDC-FUTURES）
+func (s *Server) SetBitgetFuturesBalance(marginCoin string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.futures[marginCoin] = b
+}
+
+// SetGateSpotBalance 设置Gate.io现货余额
+func (s *Server) SetGateSpotBalance(currency string, b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate.spot[currency] = b
+}
+
+// SetGateFuturesBalance 设置Gate.io USDT永续合约账户余额
+func (s *Server) SetGateFuturesBalance(b Balance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate.futures["USDT"] = b
+}
+
+// SetTokenBalance 设置以太坊主网ERC20余额（最小单位）
+func (s *Server) SetTokenBalance(contract, address string, raw *big.Int) {
+	s.SetChainTokenBalance("ethereum", contract, address, raw)
+}
+
+// SetChainTokenBalance 设置某条链上的代币余额（最小单位），从当前区块起生效
+// chain 为 ethereum/bsc/arbitrum/polygon/tron/solana，Solana的 contract 为Mint地址。
+func (s *Server) SetChainTokenBalance(chain, contract, address string, raw *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := tokenKey(chain, contract, address)
+	t := s.tokens[key]
+	t.chain, t.contract, t.address = chain, contract, address
+	t.raw = new(big.Int).Set(raw)
+
+	// 同一区块内多次设置只保留最后一次
+	if n := len(t.history); n > 0 && t.history[n-1].block == s.blockNumber {
+		t.history[n-1].raw = t.raw
+	} else {
+		t.history = append(t.history, tokenSnapshot{block: s.blockNumber, raw: t.raw})
+	}
+	s.tokens[key] = t
+}
+
+// MineBlock 出一个新区块，返回新的区块高度
+// 之后设置的余额只在新区块生效，按旧区块高度 eth_call 仍返回旧余额。
+func (s *Server) MineBlock() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blockNumber++
+	return s.blockNumber
+}
+
+// SetDailyClose 设置某交易对某天（UTC）日K线的收盘价
+func (s *Server) SetDailyClose(symbol string, day time.Time, price float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.klines[symbol] == nil {
+		s.klines[symbol] = make(map[string]float64)
+	}
+	s.klines[symbol][day.UTC().Format("2006-01-02")] = price
+}
+
+// AddBinancePosition 添加Binance合约持仓
+func (s *Server) AddBinancePosition(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.positions = append(s.binance.positions, p)
+}
+
+// AddBinanceOrder 添加Binance当前委托
+func (s *Server) AddBinanceOrder(o Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.orders = append(s.binance.orders, o)
+}
+
+// AddBinanceTrade 添加Binance历史成交
+func (s *Server) AddBinanceTrade(t Trade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binance.trades = append(s.binance.trades, t)
+}
+
+// AddOKXPosition 添加OKX持仓
+func (s *Server) AddOKXPosition(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.okx.positions = append(s.okx.positions, p)
+}
+
+// AddOKXOrder 添加OKX当前委托
+func (s *Server) AddOKXOrder(o Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.okx.orders = append(s.okx.orders, o)
+}
+
+// AddOKXTrade 添加OKX历史订单
+func (s *Server) AddOKXTrade(t Trade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.okx.trades = append(s.okx.trades, t)
+}
+
+// AddBybitPosition 添加Bybit U本位合约持仓
+func (s *Server) AddBybitPosition(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bybit.positions = append(s.bybit.positions, p)
+}
+
+// AddBybitOrder 添加Bybit当前委托
+func (s *Server) AddBybitOrder(o Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bybit.orders = append(s.bybit.orders, o)
+}
+
+// AddBybitTrade 添加Bybit平仓盈亏记录（Price 为开仓均价，AvgPrice 为平仓均价）
+func (s *Server) AddBybitTrade(t Trade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bybit.trades = append(s.bybit.trades, t)
+}
+
+// AddBitgetPosition 添加Bitget合约持仓
+func (s *Server) AddBitgetPosition(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.positions = append(s.bitget.positions, p)
+}
+
+// AddBitgetOrder 添加Bitget合约当前委托
+func (s *Server) AddBitgetOrder(o Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.orders = append(s.bitget.orders, o)
+}
+
+// AddBitgetTrade 添加Bitget历史仓位（Side 为 LONG/SHORT，Price 为开仓均价，AvgPrice 为平仓均价）
+func (s *Server) AddBitgetTrade(t Trade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bitget.trades = append(s.bitget.trades, t)
+}
+
+// AddGatePosition 添加Gate.io永续合约持仓（Amount 为合约张数）
+func (s *Server) AddGatePosition(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate.positions = append(s.gate.positions, p)
+}
+
+// AddGateOrder 添加Gate.io永续合约当前委托（Quantity 为合约张数）
+func (s *Server) AddGateOrder(o Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate.orders = append(s.gate.orders, o)
+}
+
+// AddGateTrade 添加Gate.io平仓记录（Side 为 LONG/SHORT，Price 为开仓均价，AvgPrice 为平仓均价）
+func (s *Server) AddGateTrade(t Trade) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate.trades = append(s.gate.trades, t)
+}
+
+// ==================== 工具函数 ====================
+
+// binanceKlineLimit K线接口单次最多返回的条数
+const binanceKlineLimit = 1000
+
+// tokenBalance 某地址在某条链上持有的代币
+type tokenBalance struct {
+	chain    string
+	contract string // 原始大小写（TronGrid按原样返回合约地址）
+	address  string
+	raw      *big.Int        // 最新余额
+	history  []tokenSnapshot // 按区块高度递增
+}
+
+// tokenSnapshot 某区块起生效的余额
+type tokenSnapshot struct {
+	block uint64
+	raw   *big.Int
+}
+
+func tokenKey(chain, contract, address string) string {
+	return chain + "|" + strings.ToLower(contract) + "|" + strings.ToLower(address)
+}
+
+// lookupToken 查询余额，未设置时为0（调用方需持有锁）
+func (s *Server) lookupToken(chain, contract, address string) *big.Int {
+	if t, ok := s.tokens[tokenKey(chain, contract, address)]; ok {
+		return t.raw
+	}
+	return new(big.Int)
+}
+
+// lookupTokenAt 查询指定区块高度时的余额，当时还没有设置过则为0（调用方需持有锁）
+func (s *Server) lookupTokenAt(chain, contract, address string, block uint64) *big.Int {
+	balance := new(big.Int)
+	for _, snap := range s.tokens[tokenKey(chain, contract, address)].history {
+		if snap.block > block {
+			break
+		}
+		balance = snap.raw
+	}
+	return balance
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
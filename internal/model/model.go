@@ -1,75 +1,252 @@
 package model
 
 import (
+	"crypto-final/internal/money"
+	"encoding/json"
 	"time"
 )
 
 // User Dashboard用户（虚拟账本容器）
 type User struct {
-	ID                int       `json:"id"`
-	Phone             string    `json:"phone"`
-	Username          string    `json:"username"`
-	PasswordHash      string    `json:"-"`
-	IsAdmin           bool      `json:"is_admin"`
-	IsActive          bool      `json:"is_active"`
-	IsAPIUser         bool      `json:"is_api_user"`
-	APIAdminAccountID int       `json:"api_admin_account_id"`
-	InitialBalance    float64   `json:"initial_balance"`
-	APIType           string    `json:"api_type"` // 新增
-	APIKey            string    `json:"-"`        // 新增
-	APISecret         string    `json:"-"`        // 新增
-	APIPassphrase     string    `json:"-"`        // 新增
-	CreatedAt         time.Time `json:"created_at"`
+	ID                int           `json:"id"`
+	Phone             string        `json:"phone"`
+	Username          string        `json:"username"`
+	PasswordHash      string        `json:"-"`
+	IsAdmin           bool          `json:"is_admin"`
+	Role              string        `json:"role"`
+	TOTPEnabled       bool          `json:"totp_enabled"`
+	IsActive          bool          `json:"is_active"`
+	IsAPIUser         bool          `json:"is_api_user"`
+	APIAdminAccountID int           `json:"api_admin_account_id"`
+	InitialBalance    money.Decimal `json:"initial_balance"`
+	APIType           string        `json:"api_type"` // 新增
+	APIKey            string        `json:"-"`        // 新增
+	APISecret         string        `json:"-"`        // 密文
+	APIPassphrase     string        `json:"-"`        // 密文
+	CreatedAt         time.Time     `json:"created_at"`
 }
 
 // AdminAccount Admin绑定的3个真实账户
 type AdminAccount struct {
+	ID             int           `json:"id"`
+	AccountType    string        `json:"account_type"`
+	APIKey         string        `json:"-"`
+	APISecret      string        `json:"-"` // 密文，只在WalletService签名时解密
+	WalletAddress  string        `json:"wallet_address,omitempty"`
+	Passphrase     string        `json:"-"` // 密文，同上
+	CurrentBalance money.Decimal `json:"current_balance"`
+	TotalShares    money.Decimal `json:"total_shares"` // 新增
+	IsActive       bool          `json:"is_active"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+
+	// ChainAddresses Wallet账户绑定的各链地址，余额为全部地址之和
+	ChainAddresses []ChainAddress `json:"chain_addresses,omitempty"`
+	// PinnedBlocks 链标识 → 固定读取的区块高度，只在每日快照中设置，不入库
+	PinnedBlocks map[string]uint64 `json:"-"`
+	// OwnerUserID 用API用户的密钥构造的账户：密钥密文保存在 users 表的这一行（0 表示 admin_accounts）
+	OwnerUserID int `json:"-"`
+}
+
+// ChainAddress Wallet账户绑定的一个链上地址
+type ChainAddress struct {
 	ID             int       `json:"id"`
-	AccountType    string    `json:"account_type"`
-	APIKey         string    `json:"api_key,omitempty"`
-	APISecret      string    `json:"api_secret,omitempty"`
-	WalletAddress  string    `json:"wallet_address,omitempty"`
-	Passphrase     string    `json:"passphrase,omitempty"` // ← 新增
-	CurrentBalance float64   `json:"current_balance"`
-	TotalShares    float64   `json:"total_shares"` // 新增
-	IsActive       bool      `json:"is_active"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	AdminAccountID int       `json:"admin_account_id"`
+	Chain          string    `json:"chain"` // 链标识，见 GET /api/admin/chains
+	Address        string    `json:"address"`
+	Label          string    `json:"label,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ChainToken 某条链上统计的稳定币合约
+type ChainToken struct {
+	Symbol   string `json:"symbol"`   // USDT / USDC
+	Contract string `json:"contract"` // 合约地址（Solana为Mint地址）
+	Decimals int    `json:"decimals"`
+}
+
+// Chain 支持的链及其稳定币合约
+type Chain struct {
+	Name        string       `json:"name"`         // 链标识，如 ethereum、tron
+	DisplayName string       `json:"display_name"` // 显示名称
+	Kind        string       `json:"kind"`         // evm / tron / solana，决定余额查询方式和地址格式
+	Tokens      []ChainToken `json:"tokens"`
 }
 
 // AdminAccountBalance Admin账户每日余额记录
 type AdminAccountBalance struct {
-	ID              int       `json:"id"`
-	AdminAccountID  int       `json:"admin_account_id"`
-	RecordDate      string    `json:"record_date"` // YYYY-MM-DD
-	Balance         float64   `json:"balance"`
-	DailyChange     float64   `json:"daily_change"`
-	DailyChangeRate float64   `json:"daily_change_rate"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              int           `json:"id"`
+	AdminAccountID  int           `json:"admin_account_id"`
+	RecordDate      string        `json:"record_date"` // YYYY-MM-DD
+	Balance         money.Decimal `json:"balance"`
+	DailyChange     money.Decimal `json:"daily_change"`
+	DailyChangeRate float64       `json:"daily_change_rate"`
+	CreatedAt       time.Time     `json:"created_at"`
 }
 
 // Recharge Dashboard用户的充值记录
 type Recharge struct {
-	ID             int       `json:"id"`
-	UserID         int       `json:"user_id"`
-	AdminAccountID int       `json:"admin_account_id"` // 充值到哪个Admin账户
-	Amount         float64   `json:"amount"`
-	Currency       string    `json:"currency"`
-	RechargeAt     time.Time `json:"recharge_at"`
-	BaseBalance    float64   `json:"base_balance"` // 充值时Admin账户的余额（基准）
-	Shares         float64   `json:"shares"`       // 新增
-	IsActive       bool      `json:"is_active"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID             int           `json:"id"`
+	UserID         int           `json:"user_id"`
+	AdminAccountID int           `json:"admin_account_id"` // 充值到哪个Admin账户
+	Amount         money.Decimal `json:"amount"`
+	Currency       string        `json:"currency"`
+	RechargeAt     time.Time     `json:"recharge_at"`
+	BaseBalance    money.Decimal `json:"base_balance"` // 充值时Admin账户的余额（基准）
+	Shares         money.Decimal `json:"shares"`       // 新增
+	IsActive       bool          `json:"is_active"`
+	CreatedAt      time.Time     `json:"created_at"`
 }
 
 // RechargeDailyProfit 每笔充值的每日盈亏
 type RechargeDailyProfit struct {
-	ID                  int       `json:"id"`
-	RechargeID          int       `json:"recharge_id"`
-	RecordDate          string    `json:"record_date"`
-	AdminAccountBalance float64   `json:"admin_account_balance"`
-	Profit              float64   `json:"profit"`
-	ProfitRate          float64   `json:"profit_rate"`
-	CreatedAt           time.Time `json:"created_at"`
+	ID                  int           `json:"id"`
+	RechargeID          int           `json:"recharge_id"`
+	RecordDate          string        `json:"record_date"`
+	AdminAccountBalance money.Decimal `json:"admin_account_balance"`
+	Profit              money.Decimal `json:"profit"`
+	ProfitRate          float64       `json:"profit_rate"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+// NAVRecord 某账户某币种的每日净值
+type NAVRecord struct {
+	ID             int           `json:"id"`
+	AdminAccountID int           `json:"admin_account_id"`
+	Currency       string        `json:"currency"`
+	RecordDate     string        `json:"record_date"` // YYYY-MM-DD
+	Balance        money.Decimal `json:"balance"`
+	TotalShares    money.Decimal `json:"total_shares"`
+	NAV            money.Decimal `json:"nav"` // 每份净值 = Balance / TotalShares
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// 盘中净值的成交事件
+const (
+	NAVEventSubscription = "subscription" // 用户充值（从系统账户划转份额）
+	NAVEventDeposit      = "deposit"      // Admin充值到交易所
+	NAVEventCorrection   = "correction"   // 修改充值金额
+	NAVEventWithdrawal   = "withdrawal"   // 撤资
+)
+
+// NAVStrike 申购、赎回成交时按当前余额计算的盘中净值
+// 每日收盘净值只由每日检查写入 nav_history，盘中净值单独保存，用于核对每笔成交的价格。
+type NAVStrike struct {
+	ID             int           `json:"id"`
+	AdminAccountID int           `json:"admin_account_id"`
+	Currency       string        `json:"currency"`
+	Event          string        `json:"event"`
+	Balance        money.Decimal `json:"balance"`
+	TotalShares    money.Decimal `json:"total_shares"`
+	NAV            money.Decimal `json:"nav"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// Session 登录会话（令牌只保存哈希）
+type Session struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revoked   bool      `json:"revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 分录类型
+const (
+	JournalOpening        = "opening"         // 升级时的期初余额
+	JournalDeposit        = "deposit"         // Admin资金入池，发行份额给系统账户
+	JournalSubscription   = "subscription"    // 用户充值，份额从系统账户划转
+	JournalRedemption     = "redemption"      // 撤资，注销份额
+	JournalCorrection     = "correction"      // 修改充值金额
+	JournalReversal       = "reversal"        // 删除充值，份额退回系统账户
+	JournalPerformanceFee = "performance_fee" // 业绩报酬，份额从持仓划转到管理人账户
+	JournalManagementFee  = "management_fee"  // 管理费，增发份额给管理人账户
+)
+
+// JournalEntry 一笔记账分录，所有行的份额之和、本金之和都为0
+type JournalEntry struct {
+	ID             int               `json:"id"`
+	EntryType      string            `json:"entry_type"`
+	AdminAccountID int               `json:"admin_account_id"`
+	Currency       string            `json:"currency"`
+	Memo           string            `json:"memo"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Postings       []*JournalPosting `json:"postings"`
+}
+
+// JournalPosting 分录中的一行：某个账户的份额与本金变动
+// 账户为 recharge:<充值ID>（持仓）或 pool:<Admin账户ID>:<币种>（资金池对方科目）
+type JournalPosting struct {
+	ID      int           `json:"id"`
+	EntryID int           `json:"entry_id"`
+	Account string        `json:"account"`
+	Shares  money.Decimal `json:"shares"`
+	Amount  money.Decimal `json:"amount"`
+}
+
+// LedgerMismatch 充值记录与分录汇总不一致的项
+type LedgerMismatch struct {
+	RechargeID     int           `json:"recharge_id"`
+	IsActive       bool          `json:"is_active"`
+	RecordedShares money.Decimal `json:"recorded_shares"`
+	RecordedAmount money.Decimal `json:"recorded_amount"`
+	JournalShares  money.Decimal `json:"journal_shares"`
+	JournalAmount  money.Decimal `json:"journal_amount"`
+}
+
+// 审计操作类型
+const (
+	AuditCreateUser         = "user.create"
+	AuditCreateAPIUser      = "user.create_api"
+	AuditUpdateUserStatus   = "user.status"
+	AuditAssignRole         = "user.role"
+	AuditConfigAccount      = "account.config"
+	AuditManualCheck        = "balance.manual_check"
+	AuditDeposit            = "pool.deposit"
+	AuditCreateRecharge     = "recharge.create"
+	AuditUpdateRecharge     = "recharge.update"
+	AuditDeleteRecharge     = "recharge.delete"
+	AuditWithdrawRecharge   = "recharge.withdraw"
+	AuditApproveWithdrawal  = "withdrawal_request.approve"
+	AuditRejectWithdrawal   = "withdrawal_request.reject"
+	AuditSetFeeRate         = "fee.rate"
+	AuditSetBenchmark       = "benchmark.set"
+	AuditAddChainAddress    = "wallet.address_add"
+	AuditDeleteChainAddress = "wallet.address_delete"
+)
+
+// AuditEvent 一条管理操作审计记录
+// Before/After 为操作前后的JSON快照，不适用时为空。
+type AuditEvent struct {
+	ID         int             `json:"id"`
+	ActorID    int             `json:"actor_id"`
+	ActorName  string          `json:"actor_name"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter 审计查询条件，零值表示不限
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// KeyPermissions 交易所API密钥的权限
+type KeyPermissions struct {
+	Read     bool     `json:"read"`
+	Trade    bool     `json:"trade"`
+	Withdraw bool     `json:"withdraw"`
+	Raw      []string `json:"raw"` // 场所返回的已开启权限项
 }
 
 // Request/Response 模型
@@ -84,10 +261,10 @@ type AdminCreateUserRequest struct {
 }
 
 type AdminRechargeRequest struct {
-	UserID         int     `json:"user_id" binding:"required"`
-	AdminAccountID int     `json:"admin_account_id" binding:"required"`
-	Amount         float64 `json:"amount" binding:"required"`
-	Currency       string  `json:"currency" binding:"required"`
+	UserID         int           `json:"user_id" binding:"required"`
+	AdminAccountID int           `json:"admin_account_id" binding:"required"`
+	Amount         money.Decimal `json:"amount" binding:"required"`
+	Currency       string        `json:"currency" binding:"required"`
 }
 
 type AdminAccountConfigRequest struct {
@@ -98,33 +275,33 @@ type AdminAccountConfigRequest struct {
 }
 
 type AdminAccountStatusResponse struct {
-	ID              int     `json:"id"`
-	AccountType     string  `json:"account_type"`
-	Address         string  `json:"address,omitempty"`
-	CurrentBalance  float64 `json:"current_balance"`
-	IsConfigured    bool    `json:"is_configured"`
-	DailyChange     float64 `json:"daily_change"`
-	DailyChangeRate float64 `json:"daily_change_rate"`
+	ID              int           `json:"id"`
+	AccountType     string        `json:"account_type"`
+	Address         string        `json:"address,omitempty"`
+	CurrentBalance  money.Decimal `json:"current_balance"`
+	IsConfigured    bool          `json:"is_configured"`
+	DailyChange     money.Decimal `json:"daily_change"`
+	DailyChangeRate float64       `json:"daily_change_rate"`
 }
 
 type DashboardUserListItem struct {
-	UserID        int     `json:"user_id"`
-	Phone         string  `json:"phone"`
-	TotalRecharge float64 `json:"total_recharge"`
-	CurrentValue  float64 `json:"current_value"`
-	TotalProfit   float64 `json:"total_profit"`
-	ProfitRate    float64 `json:"profit_rate"`
-	RechargeCount int     `json:"recharge_count"`
-	IsActive      bool    `json:"is_active"`
-	CreatedAt     string  `json:"created_at"`
+	UserID        int           `json:"user_id"`
+	Phone         string        `json:"phone"`
+	TotalRecharge money.Decimal `json:"total_recharge"`
+	CurrentValue  money.Decimal `json:"current_value"`
+	TotalProfit   money.Decimal `json:"total_profit"`
+	ProfitRate    float64       `json:"profit_rate"`
+	RechargeCount int           `json:"recharge_count"`
+	IsActive      bool          `json:"is_active"`
+	CreatedAt     string        `json:"created_at"`
 }
 
 type DashboardSummary struct {
-	TotalRecharge   float64 `json:"total_recharge"`
-	CurrentValue    float64 `json:"current_value"`
-	TotalProfit     float64 `json:"total_profit"`
-	TotalProfitRate float64 `json:"total_profit_rate"`
-	RechargeCount   int     `json:"recharge_count"`
+	TotalRecharge   money.Decimal `json:"total_recharge"`
+	CurrentValue    money.Decimal `json:"current_value"`
+	TotalProfit     money.Decimal `json:"total_profit"`
+	TotalProfitRate float64       `json:"total_profit_rate"`
+	RechargeCount   int           `json:"recharge_count"`
 
 	// 新增化率
 	MonthlyRate    float64 `json:"monthly_rate"`     // 月化率
@@ -134,20 +311,141 @@ type DashboardSummary struct {
 	// 持有天数
 	AvgHoldDays int `json:"avg_hold_days"`
 	// 🔥 新增：实际历史收益
-	MonthlyActual       float64 `json:"monthly_actual"`
-	MonthlyActualRate   float64 `json:"monthly_actual_rate"`
-	QuarterlyActual     float64 `json:"quarterly_actual"`
-	QuarterlyActualRate float64 `json:"quarterly_actual_rate"`
-	YearlyActual        float64 `json:"yearly_actual"`
-	YearlyActualRate    float64 `json:"yearly_actual_rate"`
+	MonthlyActual       money.Decimal `json:"monthly_actual"`
+	MonthlyActualRate   float64       `json:"monthly_actual_rate"`
+	QuarterlyActual     money.Decimal `json:"quarterly_actual"`
+	QuarterlyActualRate float64       `json:"quarterly_actual_rate"`
+	YearlyActual        money.Decimal `json:"yearly_actual"`
+	YearlyActualRate    float64       `json:"yearly_actual_rate"`
+	// 已实现（撤资）与未实现（持仓）盈亏，TotalProfit 等于未实现部分
+	RealizedProfit   money.Decimal `json:"realized_profit"`
+	UnrealizedProfit money.Decimal `json:"unrealized_profit"`
+	TotalWithdrawn   money.Decimal `json:"total_withdrawn"`
+	WithdrawalCount  int           `json:"withdrawal_count"`
+	// 时间加权和资金加权收益率（全部持仓，含已撤资部分）
+	Returns *ReturnMetrics `json:"returns"`
 }
 
 type RechargeWithProfit struct {
-	Recharge      *Recharge `json:"recharge"`
-	AccountType   string    `json:"account_type"`
-	CurrentProfit float64   `json:"current_profit"`
-	CurrentRate   float64   `json:"current_rate"`
-	DaysHeld      int       `json:"days_held"`
+	Recharge      *Recharge      `json:"recharge"`
+	AccountType   string         `json:"account_type"`
+	CurrentProfit money.Decimal  `json:"current_profit"`
+	CurrentRate   float64        `json:"current_rate"`
+	DaysHeld      int            `json:"days_held"`
+	Returns       *ReturnMetrics `json:"returns"` // 含部分撤资前的原充值
+}
+
+// ReturnMetrics 收益率指标（百分比）
+// TWR 按每日净值估值逐日连乘，不受充值、撤资时点和金额影响；XIRR 按实际现金流计算，反映投资人自己的收益。
+type ReturnMetrics struct {
+	TWR           float64  `json:"twr"`            // 时间加权累计收益率
+	TWRAnnualized float64  `json:"twr_annualized"` // 时间加权年化收益率
+	XIRR          *float64 `json:"xirr"`           // 资金加权年化收益率，不足1天或无解时为 null
+	Days          int      `json:"days"`           // 从第一笔充值到今天的天数
+	StartDate     string   `json:"start_date"`
+}
+
+// 风险指标的统计对象
+const (
+	RiskScopeAccount = "account" // Admin账户（资金池净值）
+	RiskScopeUser    = "user"    // 用户持仓组合
+)
+
+// 风险指标的数据来源
+const (
+	RiskSourceNAV      = "nav"      // 净值序列，不受充值、撤资影响
+	RiskSourceBalance  = "balance"  // 账户余额日变化（没有净值记录的历史数据，含资金进出）
+	RiskSourceHoldings = "holdings" // 用户持仓按净值逐段计算
+)
+
+// RiskStats 收益风险指标（百分比），按每日收益率计算，年化按365天
+type RiskStats struct {
+	Scope        string  `json:"scope"`
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Source       string  `json:"source"`
+	StartDate    string  `json:"start_date"`
+	EndDate      string  `json:"end_date"`
+	Days         int     `json:"days"`           // 收益率样本数
+	TotalReturn  float64 `json:"total_return"`   // 区间累计收益率
+	RiskFreeRate float64 `json:"risk_free_rate"` // 年化无风险利率
+
+	MaxDrawdown       float64            `json:"max_drawdown"` // 成立以来最大回撤（≤0）
+	MaxDrawdownPeak   string             `json:"max_drawdown_peak"`
+	MaxDrawdownTrough string             `json:"max_drawdown_trough"`
+	CurrentDrawdown   float64            `json:"current_drawdown"`
+	RollingDrawdowns  []*RollingDrawdown `json:"rolling_drawdowns"`
+
+	Volatility float64      `json:"volatility"` // 年化波动率
+	Sharpe     *float64     `json:"sharpe"`     // 样本不足或波动为0时为 null
+	Sortino    *float64     `json:"sortino"`    // 没有低于无风险收益的日子时为 null
+	BestDay    *DailyReturn `json:"best_day"`
+	WorstDay   *DailyReturn `json:"worst_day"`
+	WinRatio   float64      `json:"win_ratio"` // 上涨天数占比
+}
+
+// RollingDrawdown 最近 N 天内的最大回撤
+type RollingDrawdown struct {
+	WindowDays  int     `json:"window_days"`
+	MaxDrawdown float64 `json:"max_drawdown"`
+}
+
+// DailyReturn 某天的收益率（百分比）
+type DailyReturn struct {
+	Date   string  `json:"date"`
+	Return float64 `json:"return"`
+}
+
+// Benchmark 业绩比较基准（买入持有某个币种）
+// SourceSymbol 为行情来源的交易对，如 BTCUSDT；停用的基准不再记录价格，也不参与比较。
+type Benchmark struct {
+	ID           int       `json:"id"`
+	Symbol       string    `json:"symbol"`
+	Name         string    `json:"name"`
+	SourceSymbol string    `json:"source_symbol"`
+	Enabled      bool      `json:"enabled"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	LatestPrice     money.Decimal `json:"latest_price"`
+	LatestPriceDate string        `json:"latest_price_date"`
+}
+
+// BenchmarkPrice 基准某天的参考价格（当天北京时间8:00、即UTC 0点的价格，对齐每日净值结算）
+type BenchmarkPrice struct {
+	Symbol    string        `json:"symbol"`
+	PriceDate string        `json:"price_date"`
+	Price     money.Decimal `json:"price"`
+}
+
+// 基准比较的区间，与Dashboard汇总的月/季/年一致
+const (
+	BenchmarkPeriod30d       = "30d"
+	BenchmarkPeriod90d       = "90d"
+	BenchmarkPeriod365d      = "365d"
+	BenchmarkPeriodInception = "inception"
+)
+
+// BenchmarkComparison 组合在某个区间相对某个基准的表现（百分比）
+type BenchmarkComparison struct {
+	Benchmark       string   `json:"benchmark"`
+	Period          string   `json:"period"`
+	StartDate       string   `json:"start_date"`
+	EndDate         string   `json:"end_date"`
+	Days            int      `json:"days"`             // 收益率样本数
+	PortfolioReturn float64  `json:"portfolio_return"` // 区间累计收益率
+	BenchmarkReturn float64  `json:"benchmark_return"` // 同区间买入持有收益率
+	ExcessReturn    float64  `json:"excess_return"`    // 组合 - 基准
+	Beta            *float64 `json:"beta"`             // 样本不足或基准没有波动时为 null
+	TrackingError   *float64 `json:"tracking_error"`   // 日超额收益标准差 × √365
+}
+
+// BenchmarkReport Admin账户或用户组合相对各基准的表现
+type BenchmarkReport struct {
+	Scope       string                 `json:"scope"`
+	ID          int                    `json:"id"`
+	Name        string                 `json:"name"`
+	Source      string                 `json:"source"`
+	Comparisons []*BenchmarkComparison `json:"comparisons"`
 }
 
 // UserDetailResponse 用户详情（含充值记录）
@@ -155,9 +453,9 @@ type UserDetailResponse struct {
 	UserID        int               `json:"user_id"`
 	Phone         string            `json:"phone"`
 	IsActive      bool              `json:"is_active"`
-	TotalRecharge float64           `json:"total_recharge"`
-	CurrentValue  float64           `json:"current_value"`
-	TotalProfit   float64           `json:"total_profit"`
+	TotalRecharge money.Decimal     `json:"total_recharge"`
+	CurrentValue  money.Decimal     `json:"current_value"`
+	TotalProfit   money.Decimal     `json:"total_profit"`
 	ProfitRate    float64           `json:"profit_rate"`
 	RechargeCount int               `json:"recharge_count"`
 	Recharges     []*RechargeDetail `json:"recharges"`
@@ -165,68 +463,74 @@ type UserDetailResponse struct {
 
 // RechargeDetail 充值详情
 type RechargeDetail struct {
-	ID             int       `json:"id"`
-	Amount         float64   `json:"amount"`
-	Currency       string    `json:"currency"`
-	AdminAccountID int       `json:"admin_account_id"`
-	AccountType    string    `json:"account_type"`
-	RechargeAt     time.Time `json:"recharge_at"`
-	BaseBalance    float64   `json:"base_balance"`
-	CurrentProfit  float64   `json:"current_profit"`
-	CurrentRate    float64   `json:"current_rate"`
-	IsActive       bool      `json:"is_active"`
+	ID             int           `json:"id"`
+	Amount         money.Decimal `json:"amount"`
+	Currency       string        `json:"currency"`
+	AdminAccountID int           `json:"admin_account_id"`
+	AccountType    string        `json:"account_type"`
+	RechargeAt     time.Time     `json:"recharge_at"`
+	BaseBalance    money.Decimal `json:"base_balance"`
+	CurrentProfit  money.Decimal `json:"current_profit"`
+	CurrentRate    float64       `json:"current_rate"`
+	IsActive       bool          `json:"is_active"`
 }
 
 // RechargeStatistics 充值统计
 type RechargeStatistics struct {
-	TotalRecharges    float64                  `json:"total_recharges"`
-	AccountStatistics map[string]*AccountStats `json:"account_statistics"`
+	TotalRecharges      money.Decimal            `json:"total_recharges"`
+	TotalManagementFees money.Decimal            `json:"total_management_fees"`
+	AccountStatistics   map[string]*AccountStats `json:"account_statistics"`
 }
 
 // AccountStats 单个账户的充值统计
 type AccountStats struct {
-	AccountType string  `json:"account_type"`
-	USDC        float64 `json:"usdc"`
-	USDT        float64 `json:"usdt"`
-	Total       float64 `json:"total"`
+	AccountType string        `json:"account_type"`
+	USDC        money.Decimal `json:"usdc"`
+	USDT        money.Decimal `json:"usdt"`
+	Total       money.Decimal `json:"total"`
+	// 管理费：年费率、累计计提金额和最近几天的计提记录
+	ManagementFeeRate money.Decimal           `json:"management_fee_rate"`
+	ManagementFees    money.Decimal           `json:"management_fees"`
+	RecentAccruals    []*ManagementFeeAccrual `json:"recent_accruals"`
 }
 
 type RechargeResponse struct {
-	ID            int       `json:"id"`
-	Amount        float64   `json:"amount"`
-	Currency      string    `json:"currency"`
-	AccountType   string    `json:"account_type"`
-	RechargeAt    time.Time `json:"recharge_at"`
-	CurrentProfit float64   `json:"current_profit"`
-	CurrentRate   float64   `json:"current_rate"`
+	ID            int           `json:"id"`
+	Amount        money.Decimal `json:"amount"`
+	Currency      string        `json:"currency"`
+	AccountType   string        `json:"account_type"`
+	RechargeAt    time.Time     `json:"recharge_at"`
+	CurrentProfit money.Decimal `json:"current_profit"`
+	CurrentRate   float64       `json:"current_rate"`
 }
 
 type UserSummary struct {
-	UserID        int     `json:"user_id"`
-	Phone         string  `json:"phone"`
-	IsActive      bool    `json:"is_active"`      // 确保有这个字段
-	IsAPIUser     bool    `json:"is_api_user"`    // 新增TotalRecharge float64 `json:"total_recharge"`
-	TotalRecharge float64 `json:"total_recharge"` // 必须是float64CurrentValue  float64 `json:"current_value"`
-	CurrentValue  float64 `json:"current_value"`
-	TotalProfit   float64 `json:"total_profit"`
-	RechargeCount int     `json:"recharge_count"`
+	UserID        int           `json:"user_id"`
+	Phone         string        `json:"phone"`
+	Role          string        `json:"role"`
+	IsActive      bool          `json:"is_active"`      // 确保有这个字段
+	IsAPIUser     bool          `json:"is_api_user"`    // 新增TotalRecharge float64 `json:"total_recharge"`
+	TotalRecharge money.Decimal `json:"total_recharge"` // 必须是float64CurrentValue  float64 `json:"current_value"`
+	CurrentValue  money.Decimal `json:"current_value"`
+	TotalProfit   money.Decimal `json:"total_profit"`
+	RechargeCount int           `json:"recharge_count"`
 }
 
 // API用户Dashboard数据
 type APIDashboardData struct {
 	HasAPIKeys     bool              `json:"has_api_keys"`
 	Summary        *DashboardSummary `json:"summary"` // 🔥 添加这个
-	CurrentBalance float64           `json:"current_balance"`
-	InitialBalance float64           `json:"initial_balance"`
-	TotalProfit    float64           `json:"total_profit"`
+	CurrentBalance money.Decimal     `json:"current_balance"`
+	InitialBalance money.Decimal     `json:"initial_balance"`
+	TotalProfit    money.Decimal     `json:"total_profit"`
 	ProfitRate     float64           `json:"profit_rate"`
 	Positions      []Position        `json:"positions"`
 	Orders         []Order           `json:"orders"`
 	HistoryTrades  []HistoryTrade    `json:"history_trades"`
 	LastUpdateTime string            `json:"last_update_time"`
 	// 🔥 新增字段
-	USDCBalance float64
-	USDTBalance float64
+	USDCBalance money.Decimal
+	USDTBalance money.Decimal
 }
 
 // 持仓信息
@@ -268,16 +572,189 @@ type HistoryTrade struct {
 	Commission  float64 `json:"commission"`   // 手续费
 }
 
+// 撤资申请状态
+// pending → approved → settled；pending/approved 可以被拒绝（rejected）或由投资人撤回（cancelled）。
+const (
+	WithdrawalPending   = "pending"   // 待审核
+	WithdrawalApproved  = "approved"  // 已批准，等待下一次净值结算
+	WithdrawalSettled   = "settled"   // 已按结算时的净值完成撤资
+	WithdrawalRejected  = "rejected"  // 已拒绝
+	WithdrawalCancelled = "cancelled" // 投资人已撤回
+)
+
+// WithdrawalRequest 投资人提交的撤资申请
+// 各状态的时间为空表示尚未到达该状态；Settled* 为结算时锁定的价格，结算前为0。
+type WithdrawalRequest struct {
+	ID             int           `json:"id"`
+	UserID         int           `json:"user_id"`
+	RechargeID     int           `json:"recharge_id"`
+	AdminAccountID int           `json:"admin_account_id"`
+	Currency       string        `json:"currency"`
+	Principal      money.Decimal `json:"principal"` // 申请撤出的本金
+	Status         string        `json:"status"`
+	Note           string        `json:"note,omitempty"`
+	RejectReason   string        `json:"reject_reason,omitempty"`
+	ReviewedBy     int           `json:"reviewed_by,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	ApprovedAt     *time.Time    `json:"approved_at,omitempty"`
+	SettledAt      *time.Time    `json:"settled_at,omitempty"`
+	RejectedAt     *time.Time    `json:"rejected_at,omitempty"`
+	CancelledAt    *time.Time    `json:"cancelled_at,omitempty"`
+	SettledNAVDate string        `json:"settled_nav_date,omitempty"`
+	SettledNAV     money.Decimal `json:"settled_nav"`
+	SettledShares  money.Decimal `json:"settled_shares"`
+	SettledAmount  money.Decimal `json:"settled_amount"`
+	SettledProfit  money.Decimal `json:"settled_profit"`
+}
+
+// Redemption 一次撤资的定价结果
+// Full 为全部撤资；否则 Remaining* 转入新的充值记录继续持有。
+type Redemption struct {
+	RechargeID         int
+	UserID             int
+	AdminAccountID     int
+	Currency           string
+	RechargeAt         time.Time
+	NAVDate            string
+	NAV                money.Decimal
+	Principal          money.Decimal // 撤出的本金
+	Shares             money.Decimal // 赎回的份额
+	Amount             money.Decimal // 实际提取金额
+	Profit             money.Decimal
+	ProfitRate         float64
+	RemainingPrincipal money.Decimal
+	RemainingShares    money.Decimal
+	RemainingValue     money.Decimal
+	DaysHeld           int
+	Full               bool
+}
+
 // Withdrawal 撤资记录
+// OriginalAmount 为撤出的本金，FinalProfit 为这部分本金的已实现盈亏。
 type Withdrawal struct {
-	ID              int       `json:"id"`
-	RechargeID      int       `json:"recharge_id"`
-	OriginalAmount  float64   `json:"original_amount"`
-	WithdrawnAmount float64   `json:"withdrawn_amount"`
-	FinalProfit     float64   `json:"final_profit"`
-	FinalProfitRate float64   `json:"final_profit_rate"`
-	DaysHeld        int       `json:"days_held"`
-	WithdrawnAt     time.Time `json:"withdrawn_at"`
-	Currency        string    `json:"currency"`
-	RechargeAt      time.Time `json:"recharge_at"`
+	ID              int           `json:"id"`
+	RechargeID      int           `json:"recharge_id"`
+	UserID          int           `json:"user_id"`
+	AdminAccountID  int           `json:"admin_account_id"`
+	WithdrawalType  string        `json:"withdrawal_type"` // full / partial
+	OriginalAmount  money.Decimal `json:"original_amount"`
+	WithdrawnAmount money.Decimal `json:"withdrawn_amount"`
+	FinalProfit     money.Decimal `json:"final_profit"`
+	FinalProfitRate float64       `json:"final_profit_rate"`
+	RemainingAmount money.Decimal `json:"remaining_amount"` // 部分撤资后继续持有的本金
+	DaysHeld        int           `json:"days_held"`
+	WithdrawnAt     time.Time     `json:"withdrawn_at"`
+	Currency        string        `json:"currency"`
+	RechargeAt      time.Time     `json:"recharge_at"`
+}
+
+// WithdrawalFilter 撤资记录查询条件，零值表示不限；日期为 YYYY-MM-DD（含当天）
+type WithdrawalFilter struct {
+	UserID         int
+	AdminAccountID int
+	From           string
+	To             string
+}
+
+// WithdrawalTotals 撤资记录汇总
+type WithdrawalTotals struct {
+	Count          int           `json:"count"`
+	Principal      money.Decimal `json:"principal"`       // 撤出的本金
+	Withdrawn      money.Decimal `json:"withdrawn"`       // 实际提取金额
+	RealizedProfit money.Decimal `json:"realized_profit"` // 已实现盈亏
+}
+
+// 费用类型
+const (
+	FeePerformance = "performance" // 业绩报酬：超过高水位的收益按比例提取
+	FeeManagement  = "management"  // 管理费：按资产规模的年费率每日计提，只能按账户设置
+)
+
+// 业绩报酬计提时点
+const (
+	FeeEventMonthly    = "monthly"    // 月度计提，每月第一次每日检查时结算上个月
+	FeeEventWithdrawal = "withdrawal" // 撤资前先结算整笔持仓
+)
+
+// FeeRate 费率设置
+// UserID 为0时是该Admin账户的默认费率；用户设置优先于账户默认。Rate 为比例，0.2 即 20%。
+type FeeRate struct {
+	FeeType        string        `json:"fee_type"`
+	AdminAccountID int           `json:"admin_account_id"`
+	UserID         int           `json:"user_id"`
+	Rate           money.Decimal `json:"rate"`
+	UpdatedBy      int           `json:"updated_by"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// PerformanceFee 一次业绩报酬计提
+// Gain = 持有份额 × (净值 - 高水位)，FeeAmount = Gain × Rate，按净值折算成 FeeShares 划转给管理人。
+type PerformanceFee struct {
+	ID             int           `json:"id"`
+	RechargeID     int           `json:"recharge_id"`
+	UserID         int           `json:"user_id"`
+	AdminAccountID int           `json:"admin_account_id"`
+	Currency       string        `json:"currency"`
+	Event          string        `json:"event"`            // monthly / withdrawal
+	Period         string        `json:"period,omitempty"` // 月度计提的月份 YYYY-MM
+	NAVDate        string        `json:"nav_date"`
+	NAV            money.Decimal `json:"nav"`
+	HighWaterMark  money.Decimal `json:"high_water_mark"` // 计提前的高水位，计提后高水位变为 NAV
+	Shares         money.Decimal `json:"shares"`          // 计提前持有的份额
+	Gain           money.Decimal `json:"gain"`
+	Rate           money.Decimal `json:"rate"`
+	FeeAmount      money.Decimal `json:"fee_amount"`
+	FeeShares      money.Decimal `json:"fee_shares"`
+	JournalEntryID int           `json:"journal_entry_id"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// PerformanceFeeFilter 业绩报酬查询条件，零值表示不限
+type PerformanceFeeFilter struct {
+	UserID         int
+	AdminAccountID int
+	RechargeID     int
+}
+
+// PerformanceFeeTotals 业绩报酬汇总
+type PerformanceFeeTotals struct {
+	Count     int           `json:"count"`
+	FeeAmount money.Decimal `json:"fee_amount"`
+}
+
+// ManagerHolding 管理人账户在某资金池持有的份额（收取的费用）
+// Received 为收取时的金额合计，Value 按最近净值估值。
+type ManagerHolding struct {
+	AdminAccountID int           `json:"admin_account_id"`
+	Currency       string        `json:"currency"`
+	Shares         money.Decimal `json:"shares"`
+	Received       money.Decimal `json:"received"`
+	Value          money.Decimal `json:"value"`
+}
+
+// ManagementFeeAccrual 某资金池一次管理费计提（每天最多一次）
+// FeeAmount = AUM × 年费率 × Days / 365，按增发后的净值折算成 FeeShares 增发给管理人；AUM 不含管理人自己的份额。
+type ManagementFeeAccrual struct {
+	ID             int           `json:"id"`
+	AdminAccountID int           `json:"admin_account_id"`
+	Currency       string        `json:"currency"`
+	AccrualDate    string        `json:"accrual_date"` // YYYY-MM-DD
+	Days           int           `json:"days"`         // 距上次计提的天数
+	AUM            money.Decimal `json:"aum"`
+	Rate           money.Decimal `json:"rate"` // 年费率
+	FeeAmount      money.Decimal `json:"fee_amount"`
+	FeeShares      money.Decimal `json:"fee_shares"`
+	NAVBefore      money.Decimal `json:"nav_before"`
+	NAVAfter       money.Decimal `json:"nav_after"`
+	JournalEntryID int           `json:"journal_entry_id"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// ManagementFeeFilter 管理费计提查询条件，零值表示不限；日期为 YYYY-MM-DD（含当天）
+type ManagementFeeFilter struct {
+	AdminAccountID int
+	Currency       string
+	From           string
+	To             string
+	Limit          int
 }
@@ -0,0 +1,77 @@
+package model
+
+// 角色
+const (
+	RoleSuperAdmin = "super_admin" // 超级管理员：全部权限
+	RoleOperator   = "operator"    // 运营：管理用户、记录充值撤资，不能配置交易所密钥
+	RoleAuditor    = "auditor"     // 审计：只读查看所有用户和账目
+	RoleInvestor   = "investor"    // 投资人：只能看自己的Dashboard
+)
+
+// 权限，每个 /api/admin/* 路由对应一个
+const (
+	PermViewUsers         = "users:view"         // 查看用户列表和详情
+	PermManageUsers       = "users:manage"       // 创建用户、启用/停用用户
+	PermAssignRoles       = "users:assign_roles" // 分配角色
+	PermViewLedger        = "ledger:view"        // 查看充值统计、分录、核对账目
+	PermRecordRecharge    = "recharge:record"    // 充值、修改/删除充值、撤资
+	PermViewAccounts      = "accounts:view"      // 查看Admin账户状态
+	PermConfigureAccounts = "accounts:configure" // 配置交易所密钥和钱包地址
+	PermRunChecks         = "system:check"       // 手动触发余额检查
+	PermViewAudit         = "audit:view"         // 查看管理操作审计记录
+)
+
+// rolePermissions 各角色拥有的权限
+var rolePermissions = map[string][]string{
+	RoleSuperAdmin: {
+		PermViewUsers, PermManageUsers, PermAssignRoles,
+		PermViewLedger, PermRecordRecharge,
+		PermViewAccounts, PermConfigureAccounts,
+		PermRunChecks,
+		PermViewAudit,
+	},
+	RoleOperator: {
+		PermViewUsers, PermManageUsers,
+		PermViewLedger, PermRecordRecharge,
+		PermViewAccounts,
+		PermRunChecks,
+	},
+	RoleAuditor: {
+		PermViewUsers,
+		PermViewLedger,
+		PermViewAccounts,
+		PermViewAudit,
+	},
+	RoleInvestor: {},
+}
+
+// ValidRole 是否是已定义的角色
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsStaffRole 管理后台角色（非投资人），对应 users.is_admin
+func IsStaffRole(role string) bool {
+	return ValidRole(role) && role != RoleInvestor
+}
+
+// RoleHasPermission 角色是否拥有某权限
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions 角色拥有的全部权限
+func RolePermissions(role string) []string {
+	return append([]string(nil), rolePermissions[role]...)
+}
+
+// HasPermission 用户是否拥有某权限
+func (u *User) HasPermission(permission string) bool {
+	return RoleHasPermission(u.Role, permission)
+}
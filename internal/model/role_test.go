@@ -0,0 +1,74 @@
+package model
+
+import "testing"
+
+// TestRolePermissionMatrix 每个角色拥有且只拥有约定的权限
+func TestRolePermissionMatrix(t *testing.T) {
+	all := []string{
+		PermViewUsers, PermManageUsers, PermAssignRoles,
+		PermViewLedger, PermRecordRecharge,
+		PermViewAccounts, PermConfigureAccounts,
+		PermRunChecks, PermViewAudit,
+	}
+	granted := map[string]map[string]bool{
+		RoleSuperAdmin: {
+			PermViewUsers: true, PermManageUsers: true, PermAssignRoles: true,
+			PermViewLedger: true, PermRecordRecharge: true,
+			PermViewAccounts: true, PermConfigureAccounts: true,
+			PermRunChecks: true, PermViewAudit: true,
+		},
+		RoleOperator: {
+			PermViewUsers: true, PermManageUsers: true,
+			PermViewLedger: true, PermRecordRecharge: true,
+			PermViewAccounts: true, PermRunChecks: true,
+		},
+		RoleAuditor: {
+			PermViewUsers: true, PermViewLedger: true, PermViewAccounts: true, PermViewAudit: true,
+		},
+		RoleInvestor: {},
+		"unknown":    {},
+	}
+	for role, perms := range granted {
+		for _, perm := range all {
+			if got := RoleHasPermission(role, perm); got != perms[perm] {
+				t.Errorf("%s / %s = %v, want %v", role, perm, got, perms[perm])
+			}
+		}
+		if n := len(RolePermissions(role)); n != len(perms) {
+			t.Errorf("%s 权限 %d 个, want %d", role, n, len(perms))
+		}
+	}
+
+	user := &User{Role: RoleAuditor}
+	if !user.HasPermission(PermViewAudit) || user.HasPermission(PermRecordRecharge) {
+		t.Error("User.HasPermission 与角色权限不一致")
+	}
+}
+
+// TestRoleKinds 只有已定义的非投资人角色是管理后台角色
+func TestRoleKinds(t *testing.T) {
+	tests := []struct {
+		role         string
+		valid, staff bool
+	}{
+		{RoleSuperAdmin, true, true},
+		{RoleOperator, true, true},
+		{RoleAuditor, true, true},
+		{RoleInvestor, true, false},
+		{"admin", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if ValidRole(tt.role) != tt.valid || IsStaffRole(tt.role) != tt.staff {
+			t.Errorf("%q: ValidRole = %v, IsStaffRole = %v, want %v, %v",
+				tt.role, ValidRole(tt.role), IsStaffRole(tt.role), tt.valid, tt.staff)
+		}
+	}
+
+	// 返回的是副本，修改不影响角色定义
+	perms := RolePermissions(RoleOperator)
+	perms[0] = PermAssignRoles
+	if RoleHasPermission(RoleOperator, PermAssignRoles) {
+		t.Error("修改 RolePermissions 的返回值影响了角色定义")
+	}
+}
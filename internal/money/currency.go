@@ -0,0 +1,89 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SharePlaces 份额保留的小数位数
+const SharePlaces = 8
+
+// NAVPlaces 净值保留的小数位数
+const NAVPlaces = 8
+
+// CurrencyPlaces 币种的记账精度
+// 稳定币与链上 USDC/USDT 的 decimals 一致保留6位，其他币种保留8位。
+func CurrencyPlaces(currency string) int {
+	switch strings.ToUpper(currency) {
+	case "USDT", "USDC", "BUSD", "FDUSD", "TUSD", "DAI", "USD":
+		return 6
+	}
+	return Scale
+}
+
+// Amount 把外部输入的金额规整到币种精度（四舍五入）
+func Amount(d Decimal, currency string) Decimal {
+	return d.Round(CurrencyPlaces(currency), RoundHalfUp)
+}
+
+// NAV 每份净值 = 余额 / 总份额，四舍五入到 NAVPlaces
+func NAV(balance, totalShares Decimal) Decimal {
+	return must(NAVChecked(balance, totalShares))
+}
+
+// NAVChecked 同 NAV，溢出时返回 ErrOverflow（余额来自交易所或链上，不能 panic）
+func NAVChecked(balance, totalShares Decimal) (Decimal, error) {
+	if totalShares.IsZero() {
+		return One, nil
+	}
+	nav, err := balance.DivChecked(totalShares)
+	if err != nil {
+		return Zero, err
+	}
+	return nav.Round(NAVPlaces, RoundHalfUp), nil
+}
+
+// IssueShares 申购获得的份额 = 金额 / 净值，向下截断（尾差留在资金池）
+func IssueShares(amount, nav Decimal) Decimal {
+	return must(IssueSharesChecked(amount, nav))
+}
+
+// IssueSharesChecked 同 IssueShares，净值不大于0或溢出时返回错误
+func IssueSharesChecked(amount, nav Decimal) (Decimal, error) {
+	if nav.Sign() <= 0 {
+		return Zero, fmt.Errorf("money: 净值必须大于0，当前 %s", nav)
+	}
+	shares, err := amount.DivDownChecked(nav)
+	if err != nil {
+		return Zero, err
+	}
+	return shares.Round(SharePlaces, RoundDown), nil
+}
+
+// RedeemValue 赎回支付金额 = 份额 × 净值，向下截断到币种精度
+func RedeemValue(shares, nav Decimal, currency string) Decimal {
+	return must(RedeemValueChecked(shares, nav, currency))
+}
+
+// RedeemValueChecked 同 RedeemValue，溢出时返回 ErrOverflow（净值由外部余额算出，不能 panic）
+func RedeemValueChecked(shares, nav Decimal, currency string) (Decimal, error) {
+	v, err := shares.MulDownChecked(nav)
+	if err != nil {
+		return Zero, err
+	}
+	return v.Round(CurrencyPlaces(currency), RoundDown), nil
+}
+
+// MarkValue 持仓估值 = 份额 × 净值，四舍五入到币种精度
+func MarkValue(shares, nav Decimal, currency string) Decimal {
+	return must(MarkValueChecked(shares, nav, currency))
+}
+
+// MarkValueChecked 同 MarkValue，溢出时返回 ErrOverflow
+func MarkValueChecked(shares, nav Decimal, currency string) (Decimal, error) {
+	v, err := shares.MulChecked(nav)
+	if err != nil {
+		return Zero, err
+	}
+	return v.Round(CurrencyPlaces(currency), RoundHalfUp), nil
+}
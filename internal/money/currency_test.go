@@ -0,0 +1,68 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAmount(t *testing.T) {
+	if got := Amount(MustParse("1.2345675"), "USDT").String(); got != "1.234568" {
+		t.Errorf("USDT 金额 = %s", got)
+	}
+	if got := Amount(MustParse("0.123456785"), "BTC").String(); got != "0.12345679" {
+		t.Errorf("BTC 金额 = %s", got)
+	}
+}
+
+func TestNAV(t *testing.T) {
+	if got := NAV(MustParse("1000"), Zero); got != One {
+		t.Errorf("没有份额时净值 = %s", got)
+	}
+	if got := NAV(MustParse("1100"), MustParse("1000")).String(); got != "1.1" {
+		t.Errorf("净值 = %s", got)
+	}
+	if got := NAV(MustParse("1000"), MustParse("3")).String(); got != "333.33333333" {
+		t.Errorf("净值四舍五入 = %s", got)
+	}
+	if _, err := NAVChecked(MustParse("90000000000"), MustParse("0.00000001")); err == nil {
+		t.Error("NAVChecked 溢出没有报错")
+	}
+}
+
+func TestIssueShares(t *testing.T) {
+	if got := IssueShares(MustParse("100"), MustParse("3")).String(); got != "33.33333333" {
+		t.Errorf("份额向下截断 = %s", got)
+	}
+	if got := IssueShares(MustParse("200"), MustParse("3")).String(); got != "66.66666666" {
+		t.Errorf("份额向下截断 = %s", got)
+	}
+	for _, nav := range []Decimal{Zero, MustParse("-1")} {
+		if _, err := IssueSharesChecked(One, nav); err == nil {
+			t.Errorf("净值 %s 没有报错", nav)
+		}
+	}
+}
+
+func TestRedeemAndMark(t *testing.T) {
+	shares, nav := MustParse("33.33333333"), MustParse("3")
+	if got := RedeemValue(shares, nav, "USDT").String(); got != "99.999999" {
+		t.Errorf("赎回金额向下截断 = %s", got)
+	}
+	if got := MarkValue(shares, nav, "USDT").String(); got != "100" {
+		t.Errorf("估值四舍五入 = %s", got)
+	}
+	// 净值由外部余额算出，溢出时返回错误
+	if _, err := RedeemValueChecked(MustParse("90000000000"), FromInt(2), "USDT"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("RedeemValueChecked 溢出 err = %v", err)
+	}
+	if _, err := MarkValueChecked(MustParse("90000000000"), FromInt(2), "USDT"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MarkValueChecked 溢出 err = %v", err)
+	}
+
+	// 申购后立即赎回不会多付
+	amount := MustParse("1000")
+	nav = MustParse("1.23456789")
+	if got := RedeemValue(IssueShares(amount, nav), nav, "USDC"); got.Cmp(amount) > 0 {
+		t.Errorf("赎回 %s 超过申购 %s", got, amount)
+	}
+}
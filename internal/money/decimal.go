@@ -0,0 +1,401 @@
+// Package money 提供账本使用的定点小数类型
+//
+// 金额、份额、余额和净值统一用 Decimal 表示，避免 float64 在多次部分撤资后产生的份额漂移。
+// Decimal 固定保留8位小数，底层是以 1e-8 为单位的 int64，数据库中以 INTEGER 存储同样的单位值。
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale 内部精度（小数位数）
+const Scale = 8
+
+// unit 1.0 对应的内部单位数
+const unit int64 = 100000000
+
+// Decimal 8位小数的定点数，零值即 0
+type Decimal int64
+
+// Zero 0
+const Zero Decimal = 0
+
+// One 1
+const One = Decimal(unit)
+
+// RoundingMode 舍入方式
+type RoundingMode int
+
+const (
+	// RoundHalfUp 四舍五入（估值、展示）
+	RoundHalfUp RoundingMode = iota
+	// RoundDown 向零截断（发行份额、支付金额，多出的尾差留在资金池）
+	RoundDown
+)
+
+// 运算错误：外部数据（链上余额、交易所返回值）可能触发，应使用返回 error 的 *Checked 版本
+var (
+	ErrOverflow       = errors.New("money: 数值溢出")
+	ErrDivisionByZero = errors.New("money: 除数为0")
+)
+
+var (
+	bigUnit    = big.NewInt(unit)
+	maxDecimal = big.NewInt(math.MaxInt64)
+	minDecimal = big.NewInt(math.MinInt64)
+)
+
+// FromUnits 由内部单位（1e-8）构造
+func FromUnits(units int64) Decimal {
+	return Decimal(units)
+}
+
+// FromInt 由整数构造
+func FromInt(i int64) Decimal {
+	return Decimal(i * unit)
+}
+
+// FromFloat 由 float64 构造，按8位小数四舍五入；NaN、Inf 或超出范围时 panic
+// 只用于常量和测试，外部返回的数字请用 FromFloatChecked，账本内部不要再转回 float64 计算。
+func FromFloat(f float64) Decimal {
+	return must(FromFloatChecked(f))
+}
+
+// FromFloatChecked 同 FromFloat，NaN、Inf 或超出范围时返回 ErrOverflow
+func FromFloatChecked(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Zero, fmt.Errorf("%w: %v", ErrOverflow, f)
+	}
+	d, err := Parse(strconv.FormatFloat(f, 'f', Scale, 64))
+	if err != nil {
+		return Zero, fmt.Errorf("%w: %v", ErrOverflow, f)
+	}
+	return d, nil
+}
+
+// FromRaw 由链上整数余额和代币精度构造，例如 USDC 的 decimals=6
+// 超过8位的部分向零截断；超出范围时 panic，节点返回的数据请用 FromRawChecked
+func FromRaw(raw *big.Int, decimals int) Decimal {
+	d, err := FromRawChecked(raw, decimals)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// FromRawChecked 同 FromRaw，超出范围时返回 ErrOverflow
+func FromRawChecked(raw *big.Int, decimals int) (Decimal, error) {
+	v := new(big.Int).Set(raw)
+	if decimals < Scale {
+		v.Mul(v, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Scale-decimals)), nil))
+	} else if decimals > Scale {
+		v.Quo(v, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals-Scale)), nil))
+	}
+	return fromBigChecked(v)
+}
+
+// Parse 解析十进制字符串，如 "123.45"、"-0.001"；超过8位的小数四舍五入
+// 也接受科学计数法（部分交易所对极小值会返回 "1.2E-7"）
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Zero, errors.New("money: 空字符串")
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Zero, fmt.Errorf("money: 无效数字 %q", s)
+		}
+		d, err := FromFloatChecked(f)
+		if err != nil {
+			return Zero, fmt.Errorf("money: 数值超出范围 %q", s)
+		}
+		return d, nil
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Zero, fmt.Errorf("money: 无效数字 %q", s)
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return Zero, fmt.Errorf("money: 无效数字 %q", s)
+	}
+
+	roundUp := false
+	if len(fracPart) > Scale {
+		roundUp = fracPart[Scale] >= '5'
+		fracPart = fracPart[:Scale]
+	}
+	fracPart += strings.Repeat("0", Scale-len(fracPart))
+
+	v, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return Zero, fmt.Errorf("money: 无效数字 %q", s)
+	}
+	if roundUp {
+		v.Add(v, big.NewInt(1))
+	}
+	if neg {
+		v.Neg(v)
+	}
+	if v.Cmp(maxDecimal) > 0 || v.Cmp(minDecimal) < 0 {
+		return Zero, fmt.Errorf("money: 数值超出范围 %q", s)
+	}
+	return Decimal(v.Int64()), nil
+}
+
+// MustParse 解析十进制字符串，失败时 panic（只用于常量）
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Units 内部单位值（1e-8）
+func (d Decimal) Units() int64 {
+	return int64(d)
+}
+
+// Float64 转为 float64，只用于展示和比率计算
+func (d Decimal) Float64() float64 {
+	return float64(d) / float64(unit)
+}
+
+// ==================== 运算 ====================
+
+// Add d + o
+func (d Decimal) Add(o Decimal) Decimal {
+	return d + o
+}
+
+// Sub d - o
+func (d Decimal) Sub(o Decimal) Decimal {
+	return d - o
+}
+
+// Neg -d
+func (d Decimal) Neg() Decimal {
+	return -d
+}
+
+// Abs |d|
+func (d Decimal) Abs() Decimal {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Mul d × o，结果按8位小数四舍五入；溢出时 panic
+func (d Decimal) Mul(o Decimal) Decimal {
+	return must(d.mul(o, RoundHalfUp))
+}
+
+// MulDown d × o，结果向零截断到8位小数；溢出时 panic
+func (d Decimal) MulDown(o Decimal) Decimal {
+	return must(d.mul(o, RoundDown))
+}
+
+// MulChecked 同 Mul，溢出时返回 ErrOverflow
+func (d Decimal) MulChecked(o Decimal) (Decimal, error) {
+	return d.mul(o, RoundHalfUp)
+}
+
+// MulDownChecked 同 MulDown，溢出时返回 ErrOverflow
+func (d Decimal) MulDownChecked(o Decimal) (Decimal, error) {
+	return d.mul(o, RoundDown)
+}
+
+// Div d ÷ o，结果按8位小数四舍五入；除数为0或溢出时 panic
+func (d Decimal) Div(o Decimal) Decimal {
+	return must(d.div(o, RoundHalfUp))
+}
+
+// DivDown d ÷ o，结果向零截断到8位小数；除数为0或溢出时 panic
+func (d Decimal) DivDown(o Decimal) Decimal {
+	return must(d.div(o, RoundDown))
+}
+
+// DivChecked 同 Div，除数为0返回 ErrDivisionByZero，溢出返回 ErrOverflow
+func (d Decimal) DivChecked(o Decimal) (Decimal, error) {
+	return d.div(o, RoundHalfUp)
+}
+
+// DivDownChecked 同 DivDown，出错时返回 error 而不是 panic
+func (d Decimal) DivDownChecked(o Decimal) (Decimal, error) {
+	return d.div(o, RoundDown)
+}
+
+// MulRatio d × num ÷ den，中间结果不丢精度（按比例拆分时使用）；除数为0或溢出时 panic
+func (d Decimal) MulRatio(num, den Decimal, mode RoundingMode) Decimal {
+	return must(d.MulRatioChecked(num, den, mode))
+}
+
+// MulRatioChecked 同 MulRatio，出错时返回 error 而不是 panic
+func (d Decimal) MulRatioChecked(num, den Decimal, mode RoundingMode) (Decimal, error) {
+	if den == 0 {
+		return Zero, ErrDivisionByZero
+	}
+	p := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(num)))
+	return fromBigChecked(quoRound(p, big.NewInt(int64(den)), mode))
+}
+
+func (d Decimal) mul(o Decimal, mode RoundingMode) (Decimal, error) {
+	p := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(o)))
+	return fromBigChecked(quoRound(p, bigUnit, mode))
+}
+
+func (d Decimal) div(o Decimal, mode RoundingMode) (Decimal, error) {
+	if o == 0 {
+		return Zero, ErrDivisionByZero
+	}
+	p := new(big.Int).Mul(big.NewInt(int64(d)), bigUnit)
+	return fromBigChecked(quoRound(p, big.NewInt(int64(o)), mode))
+}
+
+// quoRound 带舍入的整数除法
+func quoRound(n, d *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if mode == RoundHalfUp && r.Sign() != 0 {
+		// |r| * 2 >= |d| 时远离零进位
+		r2 := new(big.Int).Abs(r)
+		r2.Lsh(r2, 1)
+		if r2.Cmp(new(big.Int).Abs(d)) >= 0 {
+			if (n.Sign() < 0) != (d.Sign() < 0) {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	return q
+}
+
+func fromBigChecked(v *big.Int) (Decimal, error) {
+	if v.Cmp(maxDecimal) > 0 || v.Cmp(minDecimal) < 0 {
+		return Zero, ErrOverflow
+	}
+	return Decimal(v.Int64()), nil
+}
+
+// must 把 *Checked 运算的错误转成 panic（只用于内部已知安全的运算）
+func must(d Decimal, err error) Decimal {
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Round 保留 places 位小数（0-8）
+func (d Decimal) Round(places int, mode RoundingMode) Decimal {
+	if places >= Scale {
+		return d
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := int64(math.Pow10(Scale - places))
+	q, r := int64(d)/step, int64(d)%step
+	if mode == RoundHalfUp {
+		if r >= step/2+step%2 {
+			q++
+		} else if -r >= step/2+step%2 {
+			q--
+		}
+	}
+	return Decimal(q * step)
+}
+
+// ==================== 比较 ====================
+
+// Sign 符号：-1、0、1
+func (d Decimal) Sign() int {
+	switch {
+	case d > 0:
+		return 1
+	case d < 0:
+		return -1
+	}
+	return 0
+}
+
+// IsZero d == 0
+func (d Decimal) IsZero() bool {
+	return d == 0
+}
+
+// Cmp 比较：d<o 返回-1，相等返回0，d>o 返回1
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d < o:
+		return -1
+	case d > o:
+		return 1
+	}
+	return 0
+}
+
+// Ratio a ÷ b 的 float64 结果，只用于百分比等展示用途；b为0时返回0
+func Ratio(a, b Decimal) float64 {
+	if b == 0 {
+		return 0
+	}
+	f, _ := new(big.Rat).SetFrac(big.NewInt(int64(a)), big.NewInt(int64(b))).Float64()
+	return f
+}
+
+// Min 较小值
+func Min(a, b Decimal) Decimal {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Max 较大值
+func Max(a, b Decimal) Decimal {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Sum 求和
+func Sum(values ...Decimal) Decimal {
+	var total Decimal
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
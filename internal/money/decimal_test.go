@@ -0,0 +1,277 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"1", 100000000},
+		{"123.45", 12345000000},
+		{"-0.001", -100000},
+		{"+2.5", 250000000},
+		{".5", 50000000},
+		{"5.", 500000000},
+		{" 7 ", 700000000},
+		{"0.000000005", 1},   // 第9位四舍五入
+		{"0.000000004", 0},   // 第9位舍去
+		{"-0.000000005", -1}, // 负数远离零进位
+		{"1.2E-7", 12},
+		{"92233720368.54775807", math.MaxInt64},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got.Units() != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got.Units(), tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "-", ".", "abc", "1.2.3", "1,000", "0x10", "92233720368.54775808", "1e", "1e400", "1e20"} {
+		if d, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %s, want error", in, d)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{Zero, "0"},
+		{One, "1"},
+		{MustParse("1234.5"), "1234.5"},
+		{FromUnits(-1), "-0.00000001"},
+		{MustParse("-12.3400"), "-12.34"},
+		{FromUnits(math.MinInt64), "-92233720368.54775808"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String(%d) = %q, want %q", tt.d.Units(), got, tt.want)
+		}
+	}
+}
+
+func TestStringFixed(t *testing.T) {
+	tests := []struct {
+		d      Decimal
+		places int
+		want   string
+	}{
+		{MustParse("1234.5"), 2, "1234.50"},
+		{MustParse("1.005"), 2, "1.01"},
+		{MustParse("-1.005"), 2, "-1.01"},
+		{MustParse("-0.004"), 2, "0.00"},
+		{MustParse("2.5"), 0, "3"},
+		{MustParse("0.1"), 10, "0.1000000000"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.StringFixed(tt.places); got != tt.want {
+			t.Errorf("StringFixed(%s, %d) = %q, want %q", tt.d, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	d := MustParse("1234.5678")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%.2f", "1234.57"},
+		{"%+.2f", "+1234.57"},
+		{"%v", "1234.5678"},
+		{"%10.1f", "    1234.6"},
+		{"%-8.0f|", "1235    |"},
+		{"%08.1f", "001234.6"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, d); got != tt.want {
+			t.Errorf("Sprintf(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	if got := fmt.Sprintf("%08.2f", MustParse("-1.5")); got != "-0001.50" {
+		t.Errorf("负数补零 = %q", got)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int
+		mode   RoundingMode
+		want   string
+	}{
+		{"1.235", 2, RoundHalfUp, "1.24"},
+		{"1.234", 2, RoundHalfUp, "1.23"},
+		{"-1.235", 2, RoundHalfUp, "-1.24"},
+		{"1.239", 2, RoundDown, "1.23"},
+		{"-1.239", 2, RoundDown, "-1.23"},
+		{"0.5", 0, RoundHalfUp, "1"},
+		{"1.23456789", 8, RoundDown, "1.23456789"},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.in).Round(tt.places, tt.mode).String(); got != tt.want {
+			t.Errorf("Round(%s, %d, %d) = %s, want %s", tt.in, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	a, b := MustParse("10"), MustParse("3")
+	if got := a.Div(b).String(); got != "3.33333333" {
+		t.Errorf("10/3 = %s", got)
+	}
+	if got := MustParse("20").Div(b).String(); got != "6.66666667" {
+		t.Errorf("20/3 = %s", got)
+	}
+	if got := MustParse("20").DivDown(b).String(); got != "6.66666666" {
+		t.Errorf("20/3 向下 = %s", got)
+	}
+	if got := MustParse("-20").Div(b).String(); got != "-6.66666667" {
+		t.Errorf("-20/3 = %s", got)
+	}
+	if got := MustParse("0.00000001").Mul(MustParse("0.5")).Units(); got != 1 {
+		t.Errorf("最小单位×0.5 四舍五入 = %d", got)
+	}
+	if got := MustParse("0.00000001").MulDown(MustParse("0.5")).Units(); got != 0 {
+		t.Errorf("最小单位×0.5 截断 = %d", got)
+	}
+	// 中间结果超出 int64 也不丢精度
+	big := MustParse("90000000000")
+	if got := big.MulRatio(big, big, RoundDown); got != big {
+		t.Errorf("MulRatio 中间溢出 = %s", got)
+	}
+}
+
+func TestCheckedErrors(t *testing.T) {
+	if _, err := One.DivChecked(Zero); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("DivChecked(0) err = %v", err)
+	}
+	if _, err := One.DivDownChecked(Zero); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("DivDownChecked(0) err = %v", err)
+	}
+	if _, err := One.MulRatioChecked(One, Zero, RoundDown); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("MulRatioChecked(den=0) err = %v", err)
+	}
+	if _, err := MustParse("90000000000").DivChecked(MustParse("0.5")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("DivChecked 溢出 err = %v", err)
+	}
+	if _, err := MustParse("90000000000").MulRatioChecked(FromInt(2), One, RoundDown); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulRatioChecked 溢出 err = %v", err)
+	}
+	if _, err := MustParse("90000000000").MulChecked(FromInt(2)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulChecked 溢出 err = %v", err)
+	}
+	if _, err := MustParse("-90000000000").MulDownChecked(FromInt(2)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("MulDownChecked 溢出 err = %v", err)
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e20} {
+		if _, err := FromFloatChecked(f); !errors.Is(err, ErrOverflow) {
+			t.Errorf("FromFloatChecked(%v) err = %v", f, err)
+		}
+	}
+	if d, err := FromFloatChecked(0.1); err != nil || d != MustParse("0.1") {
+		t.Errorf("FromFloatChecked(0.1) = %s, %v", d, err)
+	}
+
+	panics := map[string]func(){
+		"Div(0)":         func() { One.Div(Zero) },
+		"Mul 溢出":         func() { MustParse("90000000000").Mul(FromInt(2)) },
+		"MulDown 溢出":     func() { MustParse("90000000000").MulDown(FromInt(2)) },
+		"FromFloat(NaN)": func() { FromFloat(math.NaN()) },
+	}
+	for name, f := range panics {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s 没有 panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestFromRaw(t *testing.T) {
+	tests := []struct {
+		raw      string
+		decimals int
+		want     string
+	}{
+		{"2500000000", 6, "2500"},
+		{"1", 6, "0.000001"},
+		{"800000000000000000000", 18, "800"},
+		{"123456789123456789", 18, "0.12345678"}, // 超过8位向零截断
+		{"0", 18, "0"},
+		{"5", 0, "5"},
+	}
+	for _, tt := range tests {
+		raw, _ := new(big.Int).SetString(tt.raw, 10)
+		got, err := FromRawChecked(raw, tt.decimals)
+		if err != nil {
+			t.Errorf("FromRawChecked(%s, %d) error: %v", tt.raw, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("FromRawChecked(%s, %d) = %s, want %s", tt.raw, tt.decimals, got, tt.want)
+		}
+	}
+
+	// 节点返回的异常大数（如 uint256 最大值）
+	huge := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	if _, err := FromRawChecked(huge, 6); !errors.Is(err, ErrOverflow) {
+		t.Errorf("FromRawChecked(2^256-1) err = %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+		C Decimal `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":1.5,"b":"0.00000001","c":null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != MustParse("1.5") || v.B != 1 || v.C != 0 {
+		t.Errorf("Unmarshal = %+v", v)
+	}
+	out, _ := json.Marshal(v)
+	if string(out) != `{"a":1.5,"b":0.00000001,"c":0}` {
+		t.Errorf("Marshal = %s", out)
+	}
+	if err := json.Unmarshal([]byte(`{"a":"x"}`), &v); err == nil {
+		t.Error("无效数字没有报错")
+	}
+}
+
+func TestScan(t *testing.T) {
+	var d Decimal
+	for _, src := range []interface{}{int64(150000000), float64(150000000), []byte("150000000"), "150000000"} {
+		if err := d.Scan(src); err != nil || d != MustParse("1.5") {
+			t.Errorf("Scan(%T) = %s, %v", src, d, err)
+		}
+	}
+	if err := d.Scan(nil); err != nil || d != Zero {
+		t.Errorf("Scan(nil) = %s, %v", d, err)
+	}
+	if err := d.Scan(true); err == nil {
+		t.Error("Scan(bool) 没有报错")
+	}
+}
@@ -0,0 +1,169 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// String 去掉末尾多余0的十进制表示，如 "1234.5"、"0"、"-0.00000001"
+func (d Decimal) String() string {
+	s := d.StringFixed(Scale)
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// StringFixed 四舍五入到 places 位小数后的固定格式，如 StringFixed(2) = "1234.50"
+func (d Decimal) StringFixed(places int) string {
+	if places > Scale {
+		return d.StringFixed(Scale) + strings.Repeat("0", places-Scale)
+	}
+	if places < 0 {
+		places = 0
+	}
+
+	r := int64(d.Round(places, RoundHalfUp))
+	neg := r < 0
+	var abs uint64
+	if neg {
+		abs = uint64(-(r + 1)) + 1
+	} else {
+		abs = uint64(r)
+	}
+
+	intPart := abs / uint64(unit)
+	frac := abs % uint64(unit)
+
+	var b strings.Builder
+	if neg && abs != 0 {
+		b.WriteByte('-')
+	}
+	b.WriteString(strconv.FormatUint(intPart, 10))
+	if places > 0 {
+		fracStr := strconv.FormatUint(frac, 10)
+		fracStr = strings.Repeat("0", Scale-len(fracStr)) + fracStr
+		b.WriteByte('.')
+		b.WriteString(fracStr[:places])
+	}
+	return b.String()
+}
+
+// Format 实现 fmt.Formatter，使 %.2f、%+.2f、%v 等格式与 float64 的写法保持一致
+func (d Decimal) Format(f fmt.State, verb rune) {
+	var s string
+	switch verb {
+	case 'f', 'F':
+		places := 6
+		if p, ok := f.Precision(); ok {
+			places = p
+		}
+		s = d.StringFixed(places)
+	case 'v', 's':
+		if p, ok := f.Precision(); ok {
+			s = d.StringFixed(p)
+		} else {
+			s = d.String()
+		}
+	case 'e', 'E', 'g', 'G':
+		format := "%"
+		if p, ok := f.Precision(); ok {
+			format += "." + strconv.Itoa(p)
+		}
+		s = fmt.Sprintf(format+string(verb), d.Float64())
+	case 'q':
+		s = strconv.Quote(d.String())
+	default:
+		fmt.Fprintf(f, "%%!%c(money.Decimal=%s)", verb, d.String())
+		return
+	}
+
+	if f.Flag('+') && d >= 0 {
+		s = "+" + s
+	}
+	if w, ok := f.Width(); ok && len(s) < w {
+		pad := strings.Repeat(" ", w-len(s))
+		if f.Flag('-') {
+			s += pad
+		} else if f.Flag('0') && verb != 's' && verb != 'q' {
+			sign := ""
+			if s[0] == '-' || s[0] == '+' {
+				sign, s = s[:1], s[1:]
+			}
+			s = sign + strings.Repeat("0", w-len(s)-len(sign)) + s
+		} else {
+			s = pad + s
+		}
+	}
+	fmt.Fprint(f, s)
+}
+
+// ==================== JSON ====================
+
+// MarshalJSON 输出为JSON数字（前端无需改动）
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON 接受JSON数字、数字字符串或 null
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		*d = Zero
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	if s == "" {
+		*d = Zero
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// ==================== 数据库 ====================
+
+// Value 以 INTEGER（1e-8 单位）写入数据库
+func (d Decimal) Value() (driver.Value, error) {
+	return int64(d), nil
+}
+
+// Scan 从数据库读取 INTEGER 单位值
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Zero
+	case int64:
+		*d = Decimal(v)
+	case float64:
+		// 聚合函数（如 AVG）可能返回 REAL，仍按单位值解释
+		*d = Decimal(math.Round(v))
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	default:
+		return fmt.Errorf("money: 不支持的数据库类型 %T", src)
+	}
+	return nil
+}
+
+func (d *Decimal) scanString(s string) error {
+	units, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return fmt.Errorf("money: 无效的单位值 %q", s)
+	}
+	*d = Decimal(units)
+	return nil
+}
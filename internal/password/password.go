@@ -0,0 +1,61 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cost 新密码使用的 bcrypt 成本因子
+// 调高后，旧成本的哈希会在下次登录成功时自动重算。
+const Cost = 12
+
+// Hash 计算密码哈希（bcrypt，自带随机盐）
+func Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), Cost)
+	if err != nil {
+		return "", fmt.Errorf("密码哈希失败: %v", err)
+	}
+	return string(hash), nil
+}
+
+// Verify 校验密码
+// 返回 needsRehash=true 表示密码正确但哈希是旧格式（无盐 SHA-256）或成本因子过低，
+// 调用方应该用 Hash 重新计算并保存。
+func Verify(hash, password string) (ok bool, needsRehash bool) {
+	if isBcrypt(hash) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return true, err != nil || cost < Cost
+	}
+
+	if IsLegacy(hash) {
+		sum := sha256.Sum256([]byte(password))
+		legacy := hex.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(legacy), []byte(strings.ToLower(hash))) == 1 {
+			return true, true
+		}
+	}
+
+	return false, false
+}
+
+// IsLegacy 是否为旧版的无盐 SHA-256 十六进制哈希
+func IsLegacy(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// isBcrypt 是否为 bcrypt 哈希（$2a$ / $2b$ / $2y$）
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2")
+}
@@ -0,0 +1,67 @@
+package password
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func legacyHash(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestHashVerify 新哈希是带盐的 bcrypt，同一密码每次结果不同
+func TestHashVerify(t *testing.T) {
+	hash, err := Hash("abc123456")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$2") {
+		t.Errorf("Hash = %s, want bcrypt", hash)
+	}
+	if again, _ := Hash("abc123456"); again == hash {
+		t.Error("同一密码两次哈希相同，没有加盐")
+	}
+
+	if ok, rehash := Verify(hash, "abc123456"); !ok || rehash {
+		t.Errorf("Verify(正确密码) = %v, %v, want true, false", ok, rehash)
+	}
+	if ok, _ := Verify(hash, "abc12345"); ok {
+		t.Error("错误的密码通过了校验")
+	}
+}
+
+// TestVerifyNeedsRehash 旧版 SHA-256 和低成本 bcrypt 在密码正确时要求重算
+func TestVerifyNeedsRehash(t *testing.T) {
+	legacy := legacyHash("abc123456")
+	if !IsLegacy(legacy) || IsLegacy("abc123456") {
+		t.Error("IsLegacy 判断错误")
+	}
+	if ok, rehash := Verify(legacy, "abc123456"); !ok || !rehash {
+		t.Errorf("Verify(旧哈希) = %v, %v, want true, true", ok, rehash)
+	}
+	if ok, rehash := Verify(strings.ToUpper(legacy), "abc123456"); !ok || !rehash {
+		t.Errorf("Verify(大写旧哈希) = %v, %v, want true, true", ok, rehash)
+	}
+	if ok, rehash := Verify(legacy, "wrong"); ok || rehash {
+		t.Errorf("Verify(旧哈希, 错误密码) = %v, %v, want false, false", ok, rehash)
+	}
+
+	cheap, err := bcrypt.GenerateFromPassword([]byte("abc123456"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, rehash := Verify(string(cheap), "abc123456"); !ok || !rehash {
+		t.Errorf("Verify(低成本 bcrypt) = %v, %v, want true, true", ok, rehash)
+	}
+
+	for _, hash := range []string{"", "plaintext", "$2a$invalid"} {
+		if ok, _ := Verify(hash, ""); ok {
+			t.Errorf("Verify(%q) 通过了校验", hash)
+		}
+	}
+}
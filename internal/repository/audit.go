@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"strings"
+	"time"
+)
+
+// InsertAuditEvent 追加一条审计记录
+func (r *Repository) InsertAuditEvent(event *model.AuditEvent) error {
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO audit_events (actor_id, actor_name, action, target_type, target_id, before_json, after_json, ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.ActorID, event.ActorName, event.Action, event.TargetType, event.TargetID,
+		string(event.Before), string(event.After), event.IP, createdAt.Unix(),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, _ := result.LastInsertId()
+	event.ID = int(id)
+	event.CreatedAt = time.Unix(createdAt.Unix(), 0)
+	return nil
+}
+
+// QueryAuditEvents 按条件查询审计记录，最新的在前，同时返回符合条件的总数
+func (r *Repository) QueryAuditEvents(filter model.AuditFilter) ([]*model.AuditEvent, int, error) {
+	var conds []string
+	var args []interface{}
+	if filter.ActorID != 0 {
+		conds = append(conds, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.Action != "" {
+		conds = append(conds, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		conds = append(conds, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conds = append(conds, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.From.Unix())
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.To.Unix())
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM audit_events "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, actor_id, actor_name, action, target_type, target_id, before_json, after_json, ip, created_at
+		FROM audit_events `+where+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`,
+		append(args, filter.Limit, filter.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []*model.AuditEvent
+	for rows.Next() {
+		e := &model.AuditEvent{}
+		var before, after string
+		var createdAt int64
+		err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID, &before, &after, &e.IP, &createdAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		if before != "" {
+			e.Before = []byte(before)
+		}
+		if after != "" {
+			e.After = []byte(after)
+		}
+		e.CreatedAt = time.Unix(createdAt, 0)
+		events = append(events, e)
+	}
+
+	return events, total, rows.Err()
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAuditEventsAppendOnly 触发器禁止修改和删除审计记录
+func TestAuditEventsAppendOnly(t *testing.T) {
+	r := newTestRepository(t)
+	event := &model.AuditEvent{ActorID: 1, ActorName: "admin", Action: "recharge.update", TargetType: "recharge", TargetID: "5"}
+	if err := r.InsertAuditEvent(event); err != nil {
+		t.Fatalf("InsertAuditEvent: %v", err)
+	}
+
+	for _, stmt := range []string{
+		"UPDATE audit_events SET action = 'recharge.delete' WHERE id = ?",
+		"DELETE FROM audit_events WHERE id = ?",
+	} {
+		if _, err := r.db.Exec(stmt, event.ID); err == nil || !strings.Contains(err.Error(), "只允许追加") {
+			t.Errorf("%s: err = %v, want 只允许追加", stmt, err)
+		}
+	}
+	if n := count(t, r, "audit_events"); n != 1 {
+		t.Errorf("审计记录 %d 条, want 1", n)
+	}
+}
+
+// TestQueryAuditEvents 按条件过滤、最新的在前，总数不受分页影响
+func TestQueryAuditEvents(t *testing.T) {
+	r := newTestRepository(t)
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	events := []*model.AuditEvent{
+		{ActorID: 1, Action: "recharge.create", TargetType: "recharge", TargetID: "1", CreatedAt: day},
+		{ActorID: 1, Action: "recharge.update", TargetType: "recharge", TargetID: "1", CreatedAt: day.AddDate(0, 0, 1)},
+		{ActorID: 2, Action: "user.create", TargetType: "user", TargetID: "3", CreatedAt: day.AddDate(0, 0, 2)},
+		{ActorID: 1, Action: "recharge.update", TargetType: "recharge", TargetID: "2", CreatedAt: day.AddDate(0, 0, 3),
+			Before: []byte(`{"amount":100}`), After: []byte(`{"amount":200}`)},
+	}
+	for _, e := range events {
+		if err := r.InsertAuditEvent(e); err != nil {
+			t.Fatalf("InsertAuditEvent: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filter  model.AuditFilter
+		wantIDs []int
+		total   int
+	}{
+		{"全部", model.AuditFilter{Limit: 10}, []int{4, 3, 2, 1}, 4},
+		{"操作人", model.AuditFilter{ActorID: 2, Limit: 10}, []int{3}, 1},
+		{"操作", model.AuditFilter{Action: "recharge.update", Limit: 10}, []int{4, 2}, 2},
+		{"对象", model.AuditFilter{TargetType: "recharge", TargetID: "1", Limit: 10}, []int{2, 1}, 2},
+		{"时间范围", model.AuditFilter{From: day.AddDate(0, 0, 1), To: day.AddDate(0, 0, 3), Limit: 10}, []int{3, 2}, 2},
+		{"分页", model.AuditFilter{Limit: 2, Offset: 1}, []int{3, 2}, 4},
+	}
+	for _, tt := range tests {
+		got, total, err := r.QueryAuditEvents(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var ids []int
+		for _, e := range got {
+			ids = append(ids, e.ID)
+		}
+		if total != tt.total || len(ids) != len(tt.wantIDs) {
+			t.Errorf("%s: %v (共%d), want %v (共%d)", tt.name, ids, total, tt.wantIDs, tt.total)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.wantIDs[i] {
+				t.Errorf("%s: %v, want %v", tt.name, ids, tt.wantIDs)
+				break
+			}
+		}
+	}
+
+	latest, _, _ := r.QueryAuditEvents(model.AuditFilter{Limit: 1})
+	if string(latest[0].Before) != `{"amount":100}` || string(latest[0].After) != `{"amount":200}` {
+		t.Errorf("快照 = %s → %s", latest[0].Before, latest[0].After)
+	}
+}
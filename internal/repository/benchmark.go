@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"database/sql"
+	"time"
+)
+
+// GetBenchmarks 获取业绩比较基准及最近一次记录的价格，enabledOnly 为 true 时只返回启用的
+func (r *Repository) GetBenchmarks(enabledOnly bool) ([]*model.Benchmark, error) {
+	query := `
+		SELECT b.id, b.symbol, b.name, b.source_symbol, b.enabled, b.updated_at,
+		       COALESCE(p.price, 0), COALESCE(p.price_date, '')
+		FROM benchmarks b
+		LEFT JOIN benchmark_prices p ON p.symbol = b.symbol
+		     AND p.price_date = (SELECT MAX(price_date) FROM benchmark_prices WHERE symbol = b.symbol)`
+	if enabledOnly {
+		query += " WHERE b.enabled = 1"
+	}
+	query += " ORDER BY b.id ASC"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	benchmarks := []*model.Benchmark{}
+	for rows.Next() {
+		b := &model.Benchmark{}
+		var updatedAt int64
+		if err := rows.Scan(&b.ID, &b.Symbol, &b.Name, &b.SourceSymbol, &b.Enabled, &updatedAt, &b.LatestPrice, &b.LatestPriceDate); err != nil {
+			return nil, err
+		}
+		b.UpdatedAt = time.Unix(updatedAt, 0)
+		benchmarks = append(benchmarks, b)
+	}
+	return benchmarks, rows.Err()
+}
+
+// GetBenchmark 按代码获取基准，不存在时返回 nil
+func (r *Repository) GetBenchmark(symbol string) (*model.Benchmark, error) {
+	b := &model.Benchmark{}
+	var updatedAt int64
+	err := r.db.QueryRow(`
+		SELECT id, symbol, name, source_symbol, enabled, updated_at
+		FROM benchmarks WHERE symbol = ?`,
+		symbol,
+	).Scan(&b.ID, &b.Symbol, &b.Name, &b.SourceSymbol, &b.Enabled, &updatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.UpdatedAt = time.Unix(updatedAt, 0)
+	return b, nil
+}
+
+// SetBenchmark 新增或修改基准（按代码覆盖）
+func (r *Repository) SetBenchmark(b *model.Benchmark) error {
+	now := time.Now().Unix()
+	_, err := r.db.Exec(`
+		INSERT INTO benchmarks (symbol, name, source_symbol, enabled, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(symbol)
+		DO UPDATE SET name = excluded.name, source_symbol = excluded.source_symbol,
+		              enabled = excluded.enabled, updated_at = excluded.updated_at`,
+		b.Symbol, b.Name, b.SourceSymbol, b.Enabled, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	saved, err := r.GetBenchmark(b.Symbol)
+	if err != nil || saved == nil {
+		return err
+	}
+	b.ID = saved.ID
+	b.UpdatedAt = saved.UpdatedAt
+	return nil
+}
+
+// SaveBenchmarkPrices 保存基准的每日价格（同一天重复保存时覆盖）
+func (r *Repository) SaveBenchmarkPrices(prices []*model.BenchmarkPrice) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for _, p := range prices {
+		_, err := tx.Exec(`
+			INSERT INTO benchmark_prices (symbol, price_date, price, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(symbol, price_date)
+			DO UPDATE SET price = excluded.price, created_at = excluded.created_at`,
+			p.Symbol, p.PriceDate, p.Price, now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetBenchmarkPrices 获取基准的全部每日价格（按日期升序）
+func (r *Repository) GetBenchmarkPrices(symbol string) ([]*model.BenchmarkPrice, error) {
+	rows, err := r.db.Query(`
+		SELECT symbol, price_date, price
+		FROM benchmark_prices
+		WHERE symbol = ?
+		ORDER BY price_date ASC`,
+		symbol,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []*model.BenchmarkPrice{}
+	for rows.Next() {
+		p := &model.BenchmarkPrice{}
+		if err := rows.Scan(&p.Symbol, &p.PriceDate, &p.Price); err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// GetEarliestRecordDate 最早的净值或余额记录日期（YYYY-MM-DD），没有记录时为空
+func (r *Repository) GetEarliestRecordDate() (string, error) {
+	var date sql.NullString
+	err := r.db.QueryRow(`
+		SELECT MIN(d) FROM (
+			SELECT MIN(record_date) AS d FROM nav_history
+			UNION ALL
+			SELECT strftime('%Y-%m-%d', MIN(record_date)) FROM admin_account_balances
+		)`).Scan(&date)
+	if err != nil {
+		return "", err
+	}
+	return date.String, nil
+}
+
+// DeleteBenchmarkPrices 删除基准的全部价格（更换行情交易对后重新补录）
+func (r *Repository) DeleteBenchmarkPrices(symbol string) error {
+	_, err := r.db.Exec("DELETE FROM benchmark_prices WHERE symbol = ?", symbol)
+	return err
+}
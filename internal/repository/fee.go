@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetFeeRate 保存费率设置（同一费用类型、账户、用户只有一条，重复保存时覆盖）
+func (r *Repository) SetFeeRate(rate *model.FeeRate) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO fee_rates (fee_type, admin_account_id, user_id, rate, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(fee_type, admin_account_id, user_id)
+		DO UPDATE SET rate = excluded.rate, updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+		rate.FeeType, rate.AdminAccountID, rate.UserID, rate.Rate, rate.UpdatedBy, now.Unix(),
+	)
+	if err != nil {
+		return err
+	}
+	rate.UpdatedAt = time.Unix(now.Unix(), 0)
+	return nil
+}
+
+// DeleteFeeRate 删除费率设置，返回是否存在
+func (r *Repository) DeleteFeeRate(feeType string, adminAccountID, userID int) (bool, error) {
+	result, err := r.db.Exec(
+		"DELETE FROM fee_rates WHERE fee_type = ? AND admin_account_id = ? AND user_id = ?",
+		feeType, adminAccountID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetFeeRate 获取某条费率设置，不存在时返回 nil
+func (r *Repository) GetFeeRate(feeType string, adminAccountID, userID int) (*model.FeeRate, error) {
+	rate := &model.FeeRate{}
+	var updatedAt int64
+	err := r.db.QueryRow(`
+		SELECT fee_type, admin_account_id, user_id, rate, updated_by, updated_at
+		FROM fee_rates
+		WHERE fee_type = ? AND admin_account_id = ? AND user_id = ?`,
+		feeType, adminAccountID, userID,
+	).Scan(&rate.FeeType, &rate.AdminAccountID, &rate.UserID, &rate.Rate, &rate.UpdatedBy, &updatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rate.UpdatedAt = time.Unix(updatedAt, 0)
+	return rate, nil
+}
+
+// GetFeeRates 全部费率设置，feeType 为空不限类型
+func (r *Repository) GetFeeRates(feeType string) ([]*model.FeeRate, error) {
+	query := "SELECT fee_type, admin_account_id, user_id, rate, updated_by, updated_at FROM fee_rates"
+	var args []interface{}
+	if feeType != "" {
+		query += " WHERE fee_type = ?"
+		args = append(args, feeType)
+	}
+	query += " ORDER BY fee_type, admin_account_id, user_id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*model.FeeRate
+	for rows.Next() {
+		rate := &model.FeeRate{}
+		var updatedAt int64
+		if err := rows.Scan(&rate.FeeType, &rate.AdminAccountID, &rate.UserID, &rate.Rate, &rate.UpdatedBy, &updatedAt); err != nil {
+			return nil, err
+		}
+		rate.UpdatedAt = time.Unix(updatedAt, 0)
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// GetHighWaterMark 充值记录的高水位，0 表示尚未计提过业绩报酬
+func (r *Repository) GetHighWaterMark(rechargeID int) (money.Decimal, error) {
+	var hwm money.Decimal
+	err := r.db.QueryRow("SELECT high_water_mark FROM recharges WHERE id = ?", rechargeID).Scan(&hwm)
+	return hwm, err
+}
+
+// ChargePerformanceFee 计提业绩报酬（同一事务）：
+// 份额从持仓划转到管理人账户，写入报酬明细，并把持仓的高水位提高到计提时的净值。
+func (r *Repository) ChargePerformanceFee(fee *model.PerformanceFee) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := chargePerformanceFeeTx(tx, fee); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RedeemWithFee 撤资前计提的业绩报酬和撤资本身在同一事务中保存，fee 为 nil 表示不收取
+// rd 必须按扣除报酬后的份额定价。
+func (r *Repository) RedeemWithFee(fee *model.PerformanceFee, rd *model.Redemption) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if fee != nil {
+		if err := chargePerformanceFeeTx(tx, fee); err != nil {
+			return err
+		}
+	}
+	if err := recordRedemptionTx(tx, rd); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// chargePerformanceFeeTx 计提业绩报酬：划转份额、写明细、提高高水位
+func chargePerformanceFeeTx(tx *sql.Tx, fee *model.PerformanceFee) error {
+	entry := &model.JournalEntry{
+		EntryType:      model.JournalPerformanceFee,
+		AdminAccountID: fee.AdminAccountID,
+		Currency:       fee.Currency,
+		Memo:           fmt.Sprintf("充值%d 业绩报酬 (%s)", fee.RechargeID, fee.Event),
+		Postings: []*model.JournalPosting{
+			{Account: RechargeAccount(fee.RechargeID), Shares: fee.FeeShares.Neg()},
+			{Account: ManagerAccount(fee.AdminAccountID, fee.Currency), Shares: fee.FeeShares, Amount: fee.FeeAmount},
+			{Account: PoolAccount(fee.AdminAccountID, fee.Currency), Amount: fee.FeeAmount.Neg()},
+		},
+	}
+	if err := postJournalTx(tx, entry, 0); err != nil {
+		return err
+	}
+	fee.JournalEntryID = entry.ID
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO performance_fees
+		(recharge_id, user_id, admin_account_id, currency, event, period, nav_date, nav,
+		 high_water_mark, shares, gain, rate, fee_amount, fee_shares, journal_entry_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fee.RechargeID, fee.UserID, fee.AdminAccountID, fee.Currency, fee.Event, fee.Period, fee.NAVDate, fee.NAV,
+		fee.HighWaterMark, fee.Shares, fee.Gain, fee.Rate, fee.FeeAmount, fee.FeeShares, fee.JournalEntryID, now.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存业绩报酬明细失败: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	fee.ID = int(id)
+	fee.CreatedAt = time.Unix(now.Unix(), 0)
+
+	if _, err := tx.Exec("UPDATE recharges SET high_water_mark = ? WHERE id = ?", fee.NAV, fee.RechargeID); err != nil {
+		return fmt.Errorf("更新高水位失败: %v", err)
+	}
+	return nil
+}
+
+// HasFeeCrystallization 某资金池某月的月度计提是否已完成
+func (r *Repository) HasFeeCrystallization(adminAccountID int, currency, period string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM fee_crystallizations WHERE admin_account_id = ? AND currency = ? AND period = ?",
+		adminAccountID, currency, period,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// GetChargedRecharges 某资金池某个期间已经计提过该类业绩报酬的充值记录
+func (r *Repository) GetChargedRecharges(adminAccountID int, currency, event, period string) (map[int]bool, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT recharge_id FROM performance_fees
+		WHERE admin_account_id = ? AND currency = ? AND event = ? AND period = ?`,
+		adminAccountID, currency, event, period,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charged := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		charged[id] = true
+	}
+	return charged, rows.Err()
+}
+
+// RecordFeeCrystallization 记录某资金池某月的月度计提已完成
+func (r *Repository) RecordFeeCrystallization(adminAccountID int, currency, period, navDate string) error {
+	_, err := r.db.Exec(`
+		INSERT OR IGNORE INTO fee_crystallizations (admin_account_id, currency, period, nav_date, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		adminAccountID, currency, period, navDate, time.Now().Unix(),
+	)
+	return err
+}
+
+// performanceFeeFilterSQL 把查询条件转成 WHERE 子句
+func performanceFeeFilterSQL(filter model.PerformanceFeeFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if filter.UserID != 0 {
+		conds = append(conds, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.AdminAccountID != 0 {
+		conds = append(conds, "admin_account_id = ?")
+		args = append(args, filter.AdminAccountID)
+	}
+	if filter.RechargeID != 0 {
+		conds = append(conds, "recharge_id = ?")
+		args = append(args, filter.RechargeID)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// QueryPerformanceFees 按条件查询业绩报酬明细，最新的在前
+func (r *Repository) QueryPerformanceFees(filter model.PerformanceFeeFilter) ([]*model.PerformanceFee, error) {
+	where, args := performanceFeeFilterSQL(filter)
+	rows, err := r.db.Query(`
+		SELECT id, recharge_id, user_id, admin_account_id, currency, event, period, nav_date, nav,
+		       high_water_mark, shares, gain, rate, fee_amount, fee_shares, journal_entry_id, created_at
+		FROM performance_fees`+where+`
+		ORDER BY id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fees []*model.PerformanceFee
+	for rows.Next() {
+		f := &model.PerformanceFee{}
+		var createdAt int64
+		err := rows.Scan(
+			&f.ID, &f.RechargeID, &f.UserID, &f.AdminAccountID, &f.Currency, &f.Event, &f.Period, &f.NAVDate, &f.NAV,
+			&f.HighWaterMark, &f.Shares, &f.Gain, &f.Rate, &f.FeeAmount, &f.FeeShares, &f.JournalEntryID, &createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		f.CreatedAt = time.Unix(createdAt, 0)
+		fees = append(fees, f)
+	}
+	return fees, rows.Err()
+}
+
+// SumPerformanceFees 按条件汇总业绩报酬
+func (r *Repository) SumPerformanceFees(filter model.PerformanceFeeFilter) (*model.PerformanceFeeTotals, error) {
+	where, args := performanceFeeFilterSQL(filter)
+	totals := &model.PerformanceFeeTotals{}
+	err := r.db.QueryRow(
+		"SELECT COUNT(*), COALESCE(SUM(fee_amount), 0) FROM performance_fees"+where,
+		args...,
+	).Scan(&totals.Count, &totals.FeeAmount)
+	return totals, err
+}
+
+// GetManagerHoldings 各资金池管理人账户的分录余额（不含估值）
+func (r *Repository) GetManagerHoldings() ([]*model.ManagerHolding, error) {
+	rows, err := r.db.Query(`
+		SELECT account, SUM(shares), SUM(amount)
+		FROM journal_postings
+		WHERE account LIKE 'manager:%'
+		GROUP BY account
+		ORDER BY account`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []*model.ManagerHolding
+	for rows.Next() {
+		var account string
+		h := &model.ManagerHolding{}
+		if err := rows.Scan(&account, &h.Shares, &h.Received); err != nil {
+			return nil, err
+		}
+		parts := strings.SplitN(account, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if h.AdminAccountID, err = strconv.Atoi(parts[1]); err != nil {
+			continue
+		}
+		h.Currency = parts[2]
+		holdings = append(holdings, h)
+	}
+	return holdings, rows.Err()
+}
@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewRechargeAccount 分录中指代"本次新开立的充值记录"的占位账户，
+// PostJournal 开立记录后替换为真实账户。
+const NewRechargeAccount = "recharge:new"
+
+// RechargeAccount 充值记录对应的持仓账户
+func RechargeAccount(rechargeID int) string {
+	return fmt.Sprintf("recharge:%d", rechargeID)
+}
+
+// PoolAccount 资金池对方科目，余额是该池流通份额和本金的相反数
+func PoolAccount(adminAccountID int, currency string) string {
+	return fmt.Sprintf("pool:%d:%s", adminAccountID, currency)
+}
+
+// ManagerAccount 管理人账户，持有以份额形式收取的费用，不计入任何充值记录
+// 业绩报酬从充值记录转入份额，总份额和净值不变；管理费由资金池增发份额，总份额增加、净值下降。
+func ManagerAccount(adminAccountID int, currency string) string {
+	return fmt.Sprintf("manager:%d:%s", adminAccountID, currency)
+}
+
+// PostJournal 记一笔分录，并把涉及的充值记录的 amount/shares 更新为分录汇总
+// newRecharge 不为空时在同一事务中先开立充值记录，返回它的ID（否则返回0）。
+func (r *Repository) PostJournal(entry *model.JournalEntry, newRecharge *model.Recharge) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var newRechargeID int64
+	if newRecharge != nil {
+		newRechargeID, err = insertRechargeTx(tx, newRecharge.UserID, newRecharge.AdminAccountID, newRecharge.Currency, newRecharge.RechargeAt)
+		if err != nil {
+			return 0, fmt.Errorf("创建充值记录失败: %v", err)
+		}
+	}
+
+	if err := postJournalTx(tx, entry, newRechargeID); err != nil {
+		return 0, err
+	}
+
+	return newRechargeID, tx.Commit()
+}
+
+// PostClosingJournal 停用充值记录并记一笔分录（同一事务）
+// 先停用再记账，充值记录保留停用时的金额和份额。
+func (r *Repository) PostClosingJournal(rechargeID int, entry *model.JournalEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE recharges SET is_active = 0 WHERE id = ?", rechargeID); err != nil {
+		return err
+	}
+	if err := postJournalTx(tx, entry, 0); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertRechargeTx 开立一条空的充值记录，金额和份额由随后的分录写入
+func insertRechargeTx(tx *sql.Tx, userID, adminAccountID int, currency string, rechargeAt time.Time) (int64, error) {
+	var result sql.Result
+	var err error
+	if rechargeAt.IsZero() {
+		result, err = tx.Exec(`
+			INSERT INTO recharges (user_id, admin_account_id, amount, currency, base_balance, shares, recharge_at, is_active)
+			VALUES (?, ?, 0, ?, 0, 0, datetime('now'), 1)`,
+			userID, adminAccountID, currency,
+		)
+	} else {
+		result, err = tx.Exec(`
+			INSERT INTO recharges (user_id, admin_account_id, amount, currency, base_balance, shares, recharge_at, is_active)
+			VALUES (?, ?, 0, ?, 0, 0, ?, 1)`,
+			userID, adminAccountID, currency, rechargeAt,
+		)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// postJournalTx 校验借贷平衡后写入分录，再重算涉及的活跃充值记录
+// 已停用的充值记录保留停用时的金额和份额作为历史，不再随分录变化。
+func postJournalTx(tx *sql.Tx, entry *model.JournalEntry, newRechargeID int64) error {
+	if len(entry.Postings) < 2 {
+		return errors.New("分录至少需要两行")
+	}
+
+	var sharesSum, amountSum money.Decimal
+	for _, p := range entry.Postings {
+		if p.Account == NewRechargeAccount {
+			if newRechargeID == 0 {
+				return errors.New("分录引用了新充值记录，但没有开立")
+			}
+			p.Account = RechargeAccount(int(newRechargeID))
+		}
+		sharesSum = sharesSum.Add(p.Shares)
+		amountSum = amountSum.Add(p.Amount)
+	}
+	if !sharesSum.IsZero() || !amountSum.IsZero() {
+		return fmt.Errorf("分录不平衡: 份额合计 %s, 本金合计 %s", sharesSum, amountSum)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO journal_entries (entry_type, admin_account_id, currency, memo)
+		VALUES (?, ?, ?, ?)`,
+		entry.EntryType, entry.AdminAccountID, entry.Currency, entry.Memo,
+	)
+	if err != nil {
+		return fmt.Errorf("写入分录失败: %v", err)
+	}
+	entryID, _ := result.LastInsertId()
+	entry.ID = int(entryID)
+
+	for _, p := range entry.Postings {
+		result, err := tx.Exec(`
+			INSERT INTO journal_postings (entry_id, account, shares, amount)
+			VALUES (?, ?, ?, ?)`,
+			entryID, p.Account, p.Shares, p.Amount,
+		)
+		if err != nil {
+			return fmt.Errorf("写入分录行失败: %v", err)
+		}
+		postingID, _ := result.LastInsertId()
+		p.ID = int(postingID)
+		p.EntryID = entry.ID
+	}
+
+	for _, p := range entry.Postings {
+		rechargeID, ok := ParseRechargeAccount(p.Account)
+		if !ok {
+			continue
+		}
+		_, err := tx.Exec(`
+			UPDATE recharges
+			SET shares = (SELECT COALESCE(SUM(shares), 0) FROM journal_postings WHERE account = ?),
+			    amount = (SELECT COALESCE(SUM(amount), 0) FROM journal_postings WHERE account = ?)
+			WHERE id = ? AND is_active = 1`,
+			p.Account, p.Account, rechargeID,
+		)
+		if err != nil {
+			return fmt.Errorf("更新充值记录失败: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ParseRechargeAccount 从 recharge:<ID> 中取出充值ID
+func ParseRechargeAccount(account string) (int, bool) {
+	if !strings.HasPrefix(account, "recharge:") {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(account, "recharge:"))
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// ledgerBalanceTx 某账户在分录中的余额
+func ledgerBalanceTx(tx *sql.Tx, account string) (shares, amount money.Decimal, err error) {
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(shares), 0), COALESCE(SUM(amount), 0)
+		FROM journal_postings
+		WHERE account = ?`,
+		account,
+	).Scan(&shares, &amount)
+	return shares, amount, err
+}
+
+// closeRechargeTx 撤资分录：注销原充值记录的全部余额，remainingShares/remainingPrincipal 转入新充值记录，差额回到资金池
+// newRechargeID 为0表示全部撤资，此时 remaining 应为0。
+func closeRechargeTx(tx *sql.Tx, rechargeID int, newRechargeID int64, remainingShares, remainingPrincipal money.Decimal, memo string) error {
+	var adminAccountID int
+	var currency string
+	err := tx.QueryRow(
+		"SELECT admin_account_id, currency FROM recharges WHERE id = ?",
+		rechargeID,
+	).Scan(&adminAccountID, &currency)
+	if err != nil {
+		return fmt.Errorf("获取充值记录失败: %v", err)
+	}
+
+	account := RechargeAccount(rechargeID)
+	shares, amount, err := ledgerBalanceTx(tx, account)
+	if err != nil {
+		return fmt.Errorf("读取分录余额失败: %v", err)
+	}
+
+	entry := &model.JournalEntry{
+		EntryType:      model.JournalRedemption,
+		AdminAccountID: adminAccountID,
+		Currency:       currency,
+		Memo:           memo,
+		Postings: []*model.JournalPosting{
+			{Account: account, Shares: shares.Neg(), Amount: amount.Neg()},
+			{Account: PoolAccount(adminAccountID, currency), Shares: shares.Sub(remainingShares), Amount: amount.Sub(remainingPrincipal)},
+		},
+	}
+	if newRechargeID != 0 {
+		entry.Postings = append(entry.Postings, &model.JournalPosting{
+			Account: NewRechargeAccount, Shares: remainingShares, Amount: remainingPrincipal,
+		})
+	}
+
+	return postJournalTx(tx, entry, newRechargeID)
+}
+
+// GetLedgerBalance 某账户在分录中的余额（份额、本金）
+func (r *Repository) GetLedgerBalance(account string) (money.Decimal, money.Decimal, error) {
+	var shares, amount money.Decimal
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(shares), 0), COALESCE(SUM(amount), 0)
+		FROM journal_postings
+		WHERE account = ?`,
+		account,
+	).Scan(&shares, &amount)
+	return shares, amount, err
+}
+
+// GetJournalByAccount 获取涉及某账户的全部分录（含每笔分录的所有行），按记账顺序
+func (r *Repository) GetJournalByAccount(account string) ([]*model.JournalEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT e.id, e.entry_type, e.admin_account_id, e.currency, e.memo, e.created_at,
+		       p.id, p.account, p.shares, p.amount
+		FROM journal_entries e
+		JOIN journal_postings p ON p.entry_id = e.id
+		WHERE e.id IN (SELECT entry_id FROM journal_postings WHERE account = ?)
+		ORDER BY e.id, p.id`,
+		account,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*model.JournalEntry
+	var current *model.JournalEntry
+	for rows.Next() {
+		var e model.JournalEntry
+		p := &model.JournalPosting{}
+		err := rows.Scan(
+			&e.ID, &e.EntryType, &e.AdminAccountID, &e.Currency, &e.Memo, &e.CreatedAt,
+			&p.ID, &p.Account, &p.Shares, &p.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || current.ID != e.ID {
+			current = &e
+			entries = append(entries, current)
+		}
+		p.EntryID = current.ID
+		current.Postings = append(current.Postings, p)
+	}
+
+	return entries, rows.Err()
+}
+
+// VerifyLedger 核对充值记录与分录
+// 返回金额/份额与分录汇总不一致的充值记录，以及借贷不平衡的分录ID。
+func (r *Repository) VerifyLedger() ([]*model.LedgerMismatch, []int, error) {
+	rows, err := r.db.Query(`
+		SELECT r.id, r.is_active, r.shares, r.amount,
+		       COALESCE(j.shares, 0), COALESCE(j.amount, 0)
+		FROM recharges r
+		LEFT JOIN (
+			SELECT account, SUM(shares) AS shares, SUM(amount) AS amount
+			FROM journal_postings
+			GROUP BY account
+		) j ON j.account = 'recharge:' || r.id
+		WHERE (r.is_active = 1 AND (r.shares != COALESCE(j.shares, 0) OR r.amount != COALESCE(j.amount, 0)))
+		   OR (r.is_active = 0 AND (COALESCE(j.shares, 0) != 0 OR COALESCE(j.amount, 0) != 0))
+		ORDER BY r.id`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var mismatches []*model.LedgerMismatch
+	for rows.Next() {
+		m := &model.LedgerMismatch{}
+		err := rows.Scan(&m.RechargeID, &m.IsActive, &m.RecordedShares, &m.RecordedAmount, &m.JournalShares, &m.JournalAmount)
+		if err != nil {
+			return nil, nil, err
+		}
+		mismatches = append(mismatches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	entryRows, err := r.db.Query(`
+		SELECT entry_id
+		FROM journal_postings
+		GROUP BY entry_id
+		HAVING SUM(shares) != 0 OR SUM(amount) != 0
+		ORDER BY entry_id`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer entryRows.Close()
+
+	var unbalanced []int
+	for entryRows.Next() {
+		var id int
+		if err := entryRows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		unbalanced = append(unbalanced, id)
+	}
+
+	return mismatches, unbalanced, entryRows.Err()
+}
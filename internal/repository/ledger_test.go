@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"testing"
+	"time"
+)
+
+func units(f float64) money.Decimal {
+	return money.FromFloat(f)
+}
+
+// subscription 申购分录：份额和本金从资金池划入充值记录
+func subscription(account string, amount, shares float64) *model.JournalEntry {
+	return &model.JournalEntry{
+		EntryType:      model.JournalSubscription,
+		AdminAccountID: 1,
+		Currency:       "USDT",
+		Postings: []*model.JournalPosting{
+			{Account: account, Shares: units(shares), Amount: units(amount)},
+			{Account: PoolAccount(1, "USDT"), Shares: units(-shares), Amount: units(-amount)},
+		},
+	}
+}
+
+// subscribe 开立一笔新充值并记申购分录，返回充值ID
+func subscribe(t *testing.T, r *Repository, userID int, amount, shares float64) int {
+	t.Helper()
+	id, err := r.PostJournal(subscription(NewRechargeAccount, amount, shares),
+		&model.Recharge{UserID: userID, AdminAccountID: 1, Currency: "USDT", RechargeAt: time.Now()})
+	if err != nil {
+		t.Fatalf("PostJournal: %v", err)
+	}
+	return int(id)
+}
+
+func count(t *testing.T, r *Repository, table string) int {
+	t.Helper()
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPostJournalRejectsUnbalanced(t *testing.T) {
+	r := newTestRepository(t)
+	pool := PoolAccount(1, "USDT")
+	newRecharge := &model.Recharge{UserID: 1, AdminAccountID: 1, Currency: "USDT"}
+
+	tests := []struct {
+		name     string
+		postings []*model.JournalPosting
+		recharge *model.Recharge
+	}{
+		{
+			name:     "只有一行",
+			postings: []*model.JournalPosting{{Account: NewRechargeAccount, Shares: units(10), Amount: units(10)}},
+			recharge: newRecharge,
+		},
+		{
+			name: "份额不平",
+			postings: []*model.JournalPosting{
+				{Account: NewRechargeAccount, Shares: units(10), Amount: units(10)},
+				{Account: pool, Shares: units(-9), Amount: units(-10)},
+			},
+			recharge: newRecharge,
+		},
+		{
+			name: "本金差1个最小单位",
+			postings: []*model.JournalPosting{
+				{Account: NewRechargeAccount, Shares: units(10), Amount: units(10)},
+				{Account: pool, Shares: units(-10), Amount: units(-10).Add(money.FromUnits(1))},
+			},
+			recharge: newRecharge,
+		},
+		{
+			name: "引用新充值但没有开立",
+			postings: []*model.JournalPosting{
+				{Account: NewRechargeAccount, Shares: units(10), Amount: units(10)},
+				{Account: pool, Shares: units(-10), Amount: units(-10)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		entry := &model.JournalEntry{EntryType: model.JournalSubscription, AdminAccountID: 1, Currency: "USDT", Postings: tt.postings}
+		if _, err := r.PostJournal(entry, tt.recharge); err == nil {
+			t.Errorf("%s: PostJournal 应该失败", tt.name)
+		}
+	}
+
+	// 失败的分录整体回滚，连同开立的充值记录
+	for _, table := range []string{"journal_entries", "journal_postings", "recharges"} {
+		if n := count(t, r, table); n != 0 {
+			t.Errorf("%s 有 %d 行，应为0", table, n)
+		}
+	}
+}
+
+func TestPostJournalSyncsRecharge(t *testing.T) {
+	r := newTestRepository(t)
+
+	id := subscribe(t, r, 1, 1000, 800)
+	assertRecharge(t, r, id, true, 1000, 800)
+
+	// 更正本金：只改金额，份额不变
+	correction := &model.JournalEntry{
+		EntryType:      model.JournalCorrection,
+		AdminAccountID: 1,
+		Currency:       "USDT",
+		Postings: []*model.JournalPosting{
+			{Account: RechargeAccount(id), Amount: units(200)},
+			{Account: PoolAccount(1, "USDT"), Amount: units(-200)},
+		},
+	}
+	if _, err := r.PostJournal(correction, nil); err != nil {
+		t.Fatalf("PostJournal: %v", err)
+	}
+	assertRecharge(t, r, id, true, 1200, 800)
+
+	// 部分撤资：原记录停用并保留停用时的金额，剩余份额和本金转入新记录
+	err := r.RecordPartialWithdrawal(id, 1,
+		units(700), units(900), units(200), 28.57,
+		units(500), units(600), units(300),
+		10, time.Now(), 1, "USDT")
+	if err != nil {
+		t.Fatalf("RecordPartialWithdrawal: %v", err)
+	}
+	assertRecharge(t, r, id, false, 1200, 800)
+	assertRecharge(t, r, id+1, true, 500, 300)
+
+	shares, amount, err := r.GetLedgerBalance(RechargeAccount(id))
+	if err != nil || !shares.IsZero() || !amount.IsZero() {
+		t.Errorf("原充值分录余额 = %s/%s, %v, 应为0", shares, amount, err)
+	}
+	shares, amount, _ = r.GetLedgerBalance(PoolAccount(1, "USDT"))
+	if shares != units(-300) || amount != units(-500) {
+		t.Errorf("资金池余额 = %s/%s, want -300/-500", shares, amount)
+	}
+
+	mismatches, unbalanced, err := r.VerifyLedger()
+	if err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v, 应该一致", mismatches, unbalanced, err)
+	}
+}
+
+func TestVerifyLedgerReportsDrift(t *testing.T) {
+	r := newTestRepository(t)
+	drifted := subscribe(t, r, 1, 1000, 1000)
+	closed := subscribe(t, r, 1, 500, 500)
+	clean := subscribe(t, r, 1, 300, 300)
+
+	// 绕过分录直接修改充值记录
+	if _, err := r.db.Exec("UPDATE recharges SET amount = amount + ? WHERE id = ?", money.FromUnits(1), drifted); err != nil {
+		t.Fatal(err)
+	}
+	// 停用时没有记注销分录
+	if _, err := r.db.Exec("UPDATE recharges SET is_active = 0 WHERE id = ?", closed); err != nil {
+		t.Fatal(err)
+	}
+	// 在已有分录上追加一行，使其借贷不平
+	entries, err := r.GetJournalByAccount(RechargeAccount(clean))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("GetJournalByAccount = %v, %v", entries, err)
+	}
+	_, err = r.db.Exec("INSERT INTO journal_postings (entry_id, account, shares, amount) VALUES (?, ?, 0, ?)",
+		entries[0].ID, PoolAccount(1, "USDT"), units(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, unbalanced, err := r.VerifyLedger()
+	if err != nil {
+		t.Fatalf("VerifyLedger: %v", err)
+	}
+	if len(mismatches) != 2 {
+		t.Fatalf("mismatches = %d 条, want 2", len(mismatches))
+	}
+
+	m := mismatches[0]
+	if m.RechargeID != drifted || !m.IsActive || m.RecordedAmount != units(1000).Add(money.FromUnits(1)) || m.JournalAmount != units(1000) {
+		t.Errorf("金额偏差 = %+v", m)
+	}
+	m = mismatches[1]
+	if m.RechargeID != closed || m.IsActive || m.JournalShares != units(500) {
+		t.Errorf("停用未注销 = %+v", m)
+	}
+
+	if len(unbalanced) != 1 || unbalanced[0] != entries[0].ID {
+		t.Errorf("unbalanced = %v, want [%d]", unbalanced, entries[0].ID)
+	}
+}
+
+func assertRecharge(t *testing.T, r *Repository, id int, active bool, amount, shares float64) {
+	t.Helper()
+	recharge, err := r.GetRechargeByID(id)
+	if err != nil || recharge == nil {
+		t.Fatalf("GetRechargeByID(%d) = %v, %v", id, recharge, err)
+	}
+	if recharge.IsActive != active || recharge.Amount != units(amount) || recharge.Shares != units(shares) {
+		t.Errorf("充值%d = active:%v %s/%s, want active:%v %v/%v",
+			id, recharge.IsActive, recharge.Amount, recharge.Shares, active, amount, shares)
+	}
+}
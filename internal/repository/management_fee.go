@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccrueManagementFee 计提管理费（同一事务）：增发份额给管理人账户并写入计提记录
+// 同一资金池同一天已有记录时违反唯一约束，整笔回滚。
+func (r *Repository) AccrueManagementFee(accrual *model.ManagementFeeAccrual) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entry := &model.JournalEntry{
+		EntryType:      model.JournalManagementFee,
+		AdminAccountID: accrual.AdminAccountID,
+		Currency:       accrual.Currency,
+		Memo:           fmt.Sprintf("%s 管理费 (%d天)", accrual.AccrualDate, accrual.Days),
+		Postings: []*model.JournalPosting{
+			{Account: PoolAccount(accrual.AdminAccountID, accrual.Currency), Shares: accrual.FeeShares.Neg(), Amount: accrual.FeeAmount.Neg()},
+			{Account: ManagerAccount(accrual.AdminAccountID, accrual.Currency), Shares: accrual.FeeShares, Amount: accrual.FeeAmount},
+		},
+	}
+	if err := postJournalTx(tx, entry, 0); err != nil {
+		return err
+	}
+	accrual.JournalEntryID = entry.ID
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO management_fee_accruals
+		(admin_account_id, currency, accrual_date, days, aum, rate, fee_amount, fee_shares,
+		 nav_before, nav_after, journal_entry_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		accrual.AdminAccountID, accrual.Currency, accrual.AccrualDate, accrual.Days, accrual.AUM, accrual.Rate,
+		accrual.FeeAmount, accrual.FeeShares, accrual.NAVBefore, accrual.NAVAfter, accrual.JournalEntryID, now.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存管理费计提记录失败: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	accrual.ID = int(id)
+	accrual.CreatedAt = time.Unix(now.Unix(), 0)
+
+	return tx.Commit()
+}
+
+// GetLastManagementFeeAccrual 某资金池最近一次管理费计提，没有时返回 nil
+func (r *Repository) GetLastManagementFeeAccrual(adminAccountID int, currency string) (*model.ManagementFeeAccrual, error) {
+	accruals, err := r.QueryManagementFeeAccruals(model.ManagementFeeFilter{
+		AdminAccountID: adminAccountID,
+		Currency:       currency,
+		Limit:          1,
+	})
+	if err != nil || len(accruals) == 0 {
+		return nil, err
+	}
+	return accruals[0], nil
+}
+
+// QueryManagementFeeAccruals 按条件查询管理费计提记录，最新的在前
+func (r *Repository) QueryManagementFeeAccruals(filter model.ManagementFeeFilter) ([]*model.ManagementFeeAccrual, error) {
+	var conds []string
+	var args []interface{}
+	if filter.AdminAccountID != 0 {
+		conds = append(conds, "admin_account_id = ?")
+		args = append(args, filter.AdminAccountID)
+	}
+	if filter.Currency != "" {
+		conds = append(conds, "currency = ?")
+		args = append(args, filter.Currency)
+	}
+	if filter.From != "" {
+		conds = append(conds, "accrual_date >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		conds = append(conds, "accrual_date <= ?")
+		args = append(args, filter.To)
+	}
+
+	query := `
+		SELECT id, admin_account_id, currency, accrual_date, days, aum, rate, fee_amount, fee_shares,
+		       nav_before, nav_after, journal_entry_id, created_at
+		FROM management_fee_accruals`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY accrual_date DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accruals []*model.ManagementFeeAccrual
+	for rows.Next() {
+		a := &model.ManagementFeeAccrual{}
+		var createdAt int64
+		err := rows.Scan(
+			&a.ID, &a.AdminAccountID, &a.Currency, &a.AccrualDate, &a.Days, &a.AUM, &a.Rate, &a.FeeAmount, &a.FeeShares,
+			&a.NAVBefore, &a.NAVAfter, &a.JournalEntryID, &createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		a.CreatedAt = time.Unix(createdAt, 0)
+		accruals = append(accruals, a)
+	}
+	return accruals, rows.Err()
+}
+
+// SumManagementFees 各Admin账户累计计提的管理费（所有币种之和）
+func (r *Repository) SumManagementFees() (map[int]money.Decimal, error) {
+	rows, err := r.db.Query(`
+		SELECT admin_account_id, SUM(fee_amount)
+		FROM management_fee_accruals
+		GROUP BY admin_account_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int]money.Decimal)
+	for rows.Next() {
+		var accountID int
+		var total money.Decimal
+		if err := rows.Scan(&accountID, &total); err != nil {
+			return nil, err
+		}
+		totals[accountID] = total
+	}
+	return totals, rows.Err()
+}
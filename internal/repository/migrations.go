@@ -0,0 +1,1024 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// migration 一次数据库结构变更
+// 版本号只增不减；已发布的迁移不要修改，需要调整时追加新的迁移。
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations 按版本号顺序排列的全部迁移
+var migrations = []migration{
+	{1, "基础表结构", migrateBaseSchema},
+	{2, "用户表增加API用户字段，phone允许为空", migrateUserAPIColumns},
+	{3, "份额字段：admin_accounts.passphrase/total_shares，recharges.shares", migrateShareColumns},
+	{4, "撤资记录、充值里程碑、月度快照表", migrateWithdrawalTables},
+	{5, "每日净值表 nav_history", migrateNAVHistory},
+	{6, "金额、份额、净值列改为 INTEGER 定点存储（1e-8 单位）", migrateMoneyToUnits},
+	{7, "复式记账分录表 journal_entries/journal_postings，按现有充值记录生成期初分录", migrateJournal},
+	{8, "登录会话表 sessions", migrateSessions},
+	{9, "users 增加 role 列，现有管理员设为超级管理员", migrateUserRoles},
+	{10, "两步验证：users 增加 TOTP 列，恢复码表 recovery_codes", migrateTwoFactor},
+	{11, "管理操作审计表 audit_events", migrateAuditEvents},
+	{12, "投资人撤资申请表 withdrawal_requests", migrateWithdrawalRequests},
+	{13, "业绩报酬：费率表 fee_rates、持仓高水位 recharges.high_water_mark、报酬明细 performance_fees", migratePerformanceFees},
+	{14, "管理费每日计提表 management_fee_accruals", migrateManagementFees},
+	{15, "业绩比较基准 benchmarks 和每日参考价格 benchmark_prices", migrateBenchmarks},
+	{16, "多链钱包地址表 wallet_addresses，迁入现有的以太坊钱包地址", migrateWalletAddresses},
+	{17, "盘中净值表 nav_strikes，nav_history 只保存每日收盘净值", migrateNAVStrikes},
+	{18, "两步验证失败计数：users 增加 totp_failed_attempts/totp_locked_until", migrateTwoFactorLockout},
+	{19, "withdrawal_requests 每笔充值最多一个未完成的申请（部分唯一索引）", migrateOpenWithdrawalRequestIndex},
+}
+
+// LatestSchemaVersion 当前程序支持的最高数据库版本
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion 当前数据库版本（未初始化为0）
+func (r *Repository) SchemaVersion() (int, error) {
+	var version int
+	err := r.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// Migrate 把数据库升级到最新版本
+// 新库和旧库（没有schema_version表）都会从头依次执行；数据库版本高于程序时拒绝启动。
+func (r *Repository) Migrate() error {
+	_, err := r.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建schema_version表失败: %v", err)
+	}
+
+	current, err := r.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("读取数据库版本失败: %v", err)
+	}
+
+	latest := LatestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("数据库版本(%d)高于程序支持的版本(%d)，请升级程序后再启动", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := r.applyMigration(m); err != nil {
+			return fmt.Errorf("执行迁移 %d (%s) 失败: %v", m.version, m.description, err)
+		}
+		fmt.Printf("✓ 数据库迁移 %d: %s\n", m.version, m.description)
+	}
+
+	return nil
+}
+
+// applyMigration 在事务中执行单个迁移并记录版本
+func (r *Repository) applyMigration(m migration) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO schema_version (version, description) VALUES (?, ?)",
+		m.version, m.description,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ==================== 迁移辅助函数 ====================
+
+// columnInfo PRAGMA table_info 的一行
+type columnInfo struct {
+	name    string
+	notNull bool
+}
+
+// tableColumns 获取表的所有列（表不存在时返回空）
+func tableColumns(tx *sql.Tx, table string) (map[string]columnInfo, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]columnInfo)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[strings.ToLower(name)] = columnInfo{name: name, notNull: notNull == 1}
+	}
+	return columns, rows.Err()
+}
+
+// tableColumnNames 按定义顺序返回表的列名
+func tableColumnNames(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// rebuildTable 按新的建表语句重建表并复制数据
+// createSQL 必须创建 <table>_new；两表同名的列会被复制，converters 中的列按给定表达式转换。
+func rebuildTable(tx *sql.Tx, table, createSQL string, converters map[string]string) error {
+	if _, err := tx.Exec(createSQL); err != nil {
+		return err
+	}
+
+	oldColumns, err := tableColumnNames(tx, table)
+	if err != nil {
+		return err
+	}
+	newColumns, err := tableColumns(tx, table+"_new")
+	if err != nil {
+		return err
+	}
+
+	var targets, sources []string
+	for _, name := range oldColumns {
+		if _, ok := newColumns[strings.ToLower(name)]; !ok {
+			continue
+		}
+		targets = append(targets, name)
+		if expr, ok := converters[strings.ToLower(name)]; ok {
+			sources = append(sources, expr)
+		} else {
+			sources = append(sources, name)
+		}
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s_new (%s) SELECT %s FROM %s",
+		table, strings.Join(targets, ", "), strings.Join(sources, ", "), table))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", table)); err != nil {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s_new RENAME TO %s", table, table))
+	return err
+}
+
+// addColumnIfMissing 列不存在时追加（兼容手工改过结构的旧库）
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	columns, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	if _, ok := columns[strings.ToLower(column)]; ok {
+		return nil
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// ==================== 迁移 ====================
+
+// migrateBaseSchema v1: 原 InitDB 创建的五张表
+func migrateBaseSchema(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		phone TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		is_admin BOOLEAN DEFAULT 0,
+		is_active BOOLEAN DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS admin_accounts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_type TEXT UNIQUE NOT NULL,
+		api_key TEXT,
+		api_secret TEXT,
+		wallet_address TEXT,
+		passphrase TEXT,
+		current_balance REAL DEFAULT 0,
+		total_shares REAL DEFAULT 0,
+		is_active BOOLEAN DEFAULT 1,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS admin_account_balances (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		admin_account_id INTEGER NOT NULL,
+		record_date DATE NOT NULL,
+		balance REAL NOT NULL,
+		daily_change REAL DEFAULT 0,
+		daily_change_rate REAL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id),
+		UNIQUE(admin_account_id, record_date)
+	);
+
+	CREATE TABLE IF NOT EXISTS recharges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		admin_account_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		currency TEXT NOT NULL,
+		recharge_at TIMESTAMP NOT NULL,
+		base_balance REAL NOT NULL,
+		shares REAL NOT NULL DEFAULT 0,
+		is_active BOOLEAN DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS recharge_daily_profits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recharge_id INTEGER NOT NULL,
+		record_date DATE NOT NULL,
+		admin_account_balance REAL NOT NULL,
+		profit REAL NOT NULL,
+		profit_rate REAL NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (recharge_id) REFERENCES recharges(id),
+		UNIQUE(recharge_id, record_date)
+	);
+	`)
+	return err
+}
+
+// migrateUserAPIColumns v2: API用户相关字段
+// API用户只有username没有phone，旧库的 phone NOT NULL 约束需要重建表去掉。
+func migrateUserAPIColumns(tx *sql.Tx) error {
+	columns := []struct{ name, definition string }{
+		{"is_active", "BOOLEAN DEFAULT 1"},
+		{"username", "TEXT"},
+		{"is_api_user", "BOOLEAN DEFAULT 0"},
+		{"api_admin_account_id", "INTEGER DEFAULT 0"},
+		{"initial_balance", "REAL DEFAULT 0"},
+		{"api_type", "TEXT"},
+		{"api_key", "TEXT"},
+		{"api_secret", "TEXT"},
+		{"api_passphrase", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(tx, "users", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	existing, err := tableColumns(tx, "users")
+	if err != nil {
+		return err
+	}
+
+	if existing["phone"].notNull {
+		_, err = tx.Exec(`
+		CREATE TABLE users_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			phone TEXT UNIQUE,
+			username TEXT,
+			password_hash TEXT NOT NULL,
+			is_admin BOOLEAN DEFAULT 0,
+			is_active BOOLEAN DEFAULT 1,
+			is_api_user BOOLEAN DEFAULT 0,
+			api_admin_account_id INTEGER DEFAULT 0,
+			initial_balance REAL DEFAULT 0,
+			api_type TEXT,
+			api_key TEXT,
+			api_secret TEXT,
+			api_passphrase TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT INTO users_new (id, phone, username, password_hash, is_admin, is_active, is_api_user,
+		                       api_admin_account_id, initial_balance, api_type, api_key, api_secret,
+		                       api_passphrase, created_at)
+		SELECT id, phone, username, password_hash, is_admin, is_active, is_api_user,
+		       api_admin_account_id, initial_balance, api_type, api_key, api_secret,
+		       api_passphrase, created_at
+		FROM users;
+
+		DROP TABLE users;
+		ALTER TABLE users_new RENAME TO users;
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username)")
+	return err
+}
+
+// migrateShareColumns v3: 份额模型字段（早期数据库建表时还没有这些列）
+func migrateShareColumns(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "admin_accounts", "passphrase", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(tx, "admin_accounts", "total_shares", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	return addColumnIfMissing(tx, "recharges", "shares", "REAL NOT NULL DEFAULT 0")
+}
+
+// migrateWithdrawalTables v4: 代码中已经在读写、但之前从未创建的表
+func migrateWithdrawalTables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS withdrawals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recharge_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		original_amount REAL NOT NULL,
+		withdrawn_amount REAL NOT NULL,
+		final_profit REAL NOT NULL,
+		final_profit_rate REAL NOT NULL,
+		days_held INTEGER NOT NULL DEFAULT 0,
+		withdrawal_type TEXT NOT NULL DEFAULT 'full',  -- full / partial
+		remaining_amount REAL NOT NULL DEFAULT 0,
+		withdrawn_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_withdrawals_user ON withdrawals(user_id);
+
+	CREATE TABLE IF NOT EXISTS recharge_milestones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recharge_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		milestone_type TEXT NOT NULL,
+		milestone_date DATE NOT NULL,
+		days_held INTEGER NOT NULL DEFAULT 0,
+		amount REAL NOT NULL,
+		current_value REAL NOT NULL,
+		profit REAL NOT NULL,
+		profit_rate REAL NOT NULL,
+		net_value REAL NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(recharge_id, milestone_type)
+	);
+
+	-- recharge_id 为负数时表示API用户的伪充值（-user_id），因此不加外键
+	CREATE TABLE IF NOT EXISTS recharge_monthly_snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recharge_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		snapshot_date DATE NOT NULL,
+		period_number INTEGER NOT NULL,
+		days_in_period INTEGER NOT NULL DEFAULT 30,
+		amount REAL NOT NULL,
+		start_value REAL NOT NULL,
+		end_value REAL NOT NULL,
+		period_profit REAL NOT NULL,
+		period_profit_rate REAL NOT NULL,
+		net_value REAL NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(recharge_id, period_number)
+	);
+	`)
+	return err
+}
+
+// migrateNAVHistory v5: 按账户、币种、日期记录净值
+// record_date 用 TEXT 而不是 DATE，避免驱动把它解析成 time.Time
+func migrateNAVHistory(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS nav_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		record_date TEXT NOT NULL,
+		balance REAL NOT NULL,
+		total_shares REAL NOT NULL,
+		nav REAL NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id),
+		UNIQUE(admin_account_id, currency, record_date)
+	);
+	`)
+	return err
+}
+
+// toUnits 把 REAL 金额列转换为 1e-8 单位整数的SQL表达式
+func toUnits(columns ...string) map[string]string {
+	converters := make(map[string]string, len(columns))
+	for _, c := range columns {
+		converters[c] = fmt.Sprintf("CAST(ROUND(%s * 100000000) AS INTEGER)", c)
+	}
+	return converters
+}
+
+// migrateMoneyToUnits v6: 金额、份额、余额、净值改为 INTEGER 定点存储
+// 与 money.Decimal 一致，1.0 存为 100000000。百分比类的比率仍为 REAL，只用于展示。
+// SQLite 不能修改列类型，因此逐表重建。
+func migrateMoneyToUnits(tx *sql.Tx) error {
+	tables := []struct {
+		name       string
+		createSQL  string
+		converters map[string]string
+	}{
+		{"users", `
+		CREATE TABLE users_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			phone TEXT UNIQUE,
+			username TEXT,
+			password_hash TEXT NOT NULL,
+			is_admin BOOLEAN DEFAULT 0,
+			is_active BOOLEAN DEFAULT 1,
+			is_api_user BOOLEAN DEFAULT 0,
+			api_admin_account_id INTEGER DEFAULT 0,
+			initial_balance INTEGER DEFAULT 0,
+			api_type TEXT,
+			api_key TEXT,
+			api_secret TEXT,
+			api_passphrase TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`, toUnits("initial_balance")},
+
+		{"admin_accounts", `
+		CREATE TABLE admin_accounts_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			account_type TEXT UNIQUE NOT NULL,
+			api_key TEXT,
+			api_secret TEXT,
+			wallet_address TEXT,
+			passphrase TEXT,
+			current_balance INTEGER DEFAULT 0,
+			total_shares INTEGER DEFAULT 0,
+			is_active BOOLEAN DEFAULT 1,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`, toUnits("current_balance", "total_shares")},
+
+		{"admin_account_balances", `
+		CREATE TABLE admin_account_balances_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			admin_account_id INTEGER NOT NULL,
+			record_date DATE NOT NULL,
+			balance INTEGER NOT NULL,
+			daily_change INTEGER DEFAULT 0,
+			daily_change_rate REAL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id),
+			UNIQUE(admin_account_id, record_date)
+		)`, toUnits("balance", "daily_change")},
+
+		{"recharges", `
+		CREATE TABLE recharges_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			admin_account_id INTEGER NOT NULL,
+			amount INTEGER NOT NULL,
+			currency TEXT NOT NULL,
+			recharge_at TIMESTAMP NOT NULL,
+			base_balance INTEGER NOT NULL DEFAULT 0,
+			shares INTEGER NOT NULL DEFAULT 0,
+			is_active BOOLEAN DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id)
+		)`, toUnits("amount", "base_balance", "shares")},
+
+		{"recharge_daily_profits", `
+		CREATE TABLE recharge_daily_profits_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recharge_id INTEGER NOT NULL,
+			record_date DATE NOT NULL,
+			admin_account_balance INTEGER NOT NULL,
+			profit INTEGER NOT NULL,
+			profit_rate REAL NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (recharge_id) REFERENCES recharges(id),
+			UNIQUE(recharge_id, record_date)
+		)`, toUnits("admin_account_balance", "profit")},
+
+		{"withdrawals", `
+		CREATE TABLE withdrawals_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recharge_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			original_amount INTEGER NOT NULL,
+			withdrawn_amount INTEGER NOT NULL,
+			final_profit INTEGER NOT NULL,
+			final_profit_rate REAL NOT NULL,
+			days_held INTEGER NOT NULL DEFAULT 0,
+			withdrawal_type TEXT NOT NULL DEFAULT 'full',
+			remaining_amount INTEGER NOT NULL DEFAULT 0,
+			withdrawn_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`, toUnits("original_amount", "withdrawn_amount", "final_profit", "remaining_amount")},
+
+		{"recharge_milestones", `
+		CREATE TABLE recharge_milestones_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recharge_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			milestone_type TEXT NOT NULL,
+			milestone_date DATE NOT NULL,
+			days_held INTEGER NOT NULL DEFAULT 0,
+			amount INTEGER NOT NULL,
+			current_value INTEGER NOT NULL,
+			profit INTEGER NOT NULL,
+			profit_rate REAL NOT NULL,
+			net_value INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(recharge_id, milestone_type)
+		)`, toUnits("amount", "current_value", "profit", "net_value")},
+
+		{"recharge_monthly_snapshots", `
+		CREATE TABLE recharge_monthly_snapshots_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recharge_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			snapshot_date DATE NOT NULL,
+			period_number INTEGER NOT NULL,
+			days_in_period INTEGER NOT NULL DEFAULT 30,
+			amount INTEGER NOT NULL,
+			start_value INTEGER NOT NULL,
+			end_value INTEGER NOT NULL,
+			period_profit INTEGER NOT NULL,
+			period_profit_rate REAL NOT NULL,
+			net_value INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(recharge_id, period_number)
+		)`, toUnits("amount", "start_value", "end_value", "period_profit", "net_value")},
+
+		{"nav_history", `
+		CREATE TABLE nav_history_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			admin_account_id INTEGER NOT NULL,
+			currency TEXT NOT NULL,
+			record_date TEXT NOT NULL,
+			balance INTEGER NOT NULL,
+			total_shares INTEGER NOT NULL,
+			nav INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id),
+			UNIQUE(admin_account_id, currency, record_date)
+		)`, toUnits("balance", "total_shares", "nav")},
+	}
+
+	for _, t := range tables {
+		if err := rebuildTable(tx, t.name, t.createSQL, t.converters); err != nil {
+			return fmt.Errorf("重建 %s 失败: %v", t.name, err)
+		}
+	}
+
+	_, err := tx.Exec(`
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
+	CREATE INDEX IF NOT EXISTS idx_withdrawals_user ON withdrawals(user_id);
+	`)
+	return err
+}
+
+// migrateJournal v7: 只追加的复式记账分录
+// 每条分录下的份额、本金两列分别借贷平衡；触发器禁止修改和删除已记账的分录。
+// 现有的活跃充值记录按资金池各生成一条期初分录，作为之后所有变动的起点。
+func migrateJournal(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS journal_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_type TEXT NOT NULL,
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		memo TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS journal_postings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id INTEGER NOT NULL,
+		account TEXT NOT NULL,
+		shares INTEGER NOT NULL DEFAULT 0,
+		amount INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (entry_id) REFERENCES journal_entries(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_journal_postings_account ON journal_postings(account);
+	CREATE INDEX IF NOT EXISTS idx_journal_postings_entry ON journal_postings(entry_id);
+
+	CREATE TRIGGER IF NOT EXISTS journal_entries_no_update BEFORE UPDATE ON journal_entries
+	BEGIN SELECT RAISE(ABORT, 'journal_entries 只允许追加'); END;
+	CREATE TRIGGER IF NOT EXISTS journal_entries_no_delete BEFORE DELETE ON journal_entries
+	BEGIN SELECT RAISE(ABORT, 'journal_entries 只允许追加'); END;
+	CREATE TRIGGER IF NOT EXISTS journal_postings_no_update BEFORE UPDATE ON journal_postings
+	BEGIN SELECT RAISE(ABORT, 'journal_postings 只允许追加'); END;
+	CREATE TRIGGER IF NOT EXISTS journal_postings_no_delete BEFORE DELETE ON journal_postings
+	BEGIN SELECT RAISE(ABORT, 'journal_postings 只允许追加'); END;
+	`)
+	if err != nil {
+		return err
+	}
+
+	type pool struct {
+		adminAccountID int
+		currency       string
+	}
+	var pools []pool
+	rows, err := tx.Query(`
+		SELECT DISTINCT admin_account_id, currency
+		FROM recharges
+		WHERE is_active = 1
+		ORDER BY admin_account_id, currency`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var p pool
+		if err := rows.Scan(&p.adminAccountID, &p.currency); err != nil {
+			rows.Close()
+			return err
+		}
+		pools = append(pools, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range pools {
+		result, err := tx.Exec(`
+			INSERT INTO journal_entries (entry_type, admin_account_id, currency, memo)
+			VALUES ('opening', ?, ?, '升级时按充值记录生成的期初余额')`,
+			p.adminAccountID, p.currency,
+		)
+		if err != nil {
+			return err
+		}
+		entryID, _ := result.LastInsertId()
+
+		// 每笔活跃充值记一行，资金池账户记相反数使分录平衡
+		_, err = tx.Exec(`
+			INSERT INTO journal_postings (entry_id, account, shares, amount)
+			SELECT ?, 'recharge:' || id, shares, amount
+			FROM recharges
+			WHERE is_active = 1 AND admin_account_id = ? AND currency = ?
+			ORDER BY id`,
+			entryID, p.adminAccountID, p.currency,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO journal_postings (entry_id, account, shares, amount)
+			SELECT ?, ?, -COALESCE(SUM(shares), 0), -COALESCE(SUM(amount), 0)
+			FROM journal_postings
+			WHERE entry_id = ?`,
+			entryID, PoolAccount(p.adminAccountID, p.currency), entryID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateSessions v8: 登录会话
+// 只保存令牌的 SHA-256；过期和吊销时间用 Unix 秒，方便直接在 SQL 中比较。
+func migrateSessions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at INTEGER NOT NULL,
+		revoked_at INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+	`)
+	return err
+}
+
+// migrateUserRoles v9: 角色
+// is_admin 保留，作为"能进管理后台"的标记，与 role 同步。
+func migrateUserRoles(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "users", "role", "TEXT NOT NULL DEFAULT 'investor'"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE users SET role = 'super_admin' WHERE is_admin = 1")
+	return err
+}
+
+// migrateTwoFactor v10: TOTP 两步验证
+// totp_secret 在启用前就会写入（绑定中），totp_enabled=1 才生效；
+// totp_last_step 记录最近一次通过的时间步，防止验证码重放。恢复码只存哈希。
+func migrateTwoFactor(tx *sql.Tx) error {
+	columns := []struct{ name, definition string }{
+		{"totp_secret", "TEXT NOT NULL DEFAULT ''"},
+		{"totp_enabled", "INTEGER NOT NULL DEFAULT 0"},
+		{"totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(tx, "users", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		code_hash TEXT NOT NULL,
+		used_at INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id);
+	`)
+	return err
+}
+
+// migrateAuditEvents v11: 管理操作审计
+// 不加外键，被操作的记录删除后审计仍然保留；与分录表一样用触发器保证只允许追加。
+func migrateAuditEvents(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER NOT NULL,
+		actor_name TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id TEXT NOT NULL DEFAULT '',
+		before_json TEXT NOT NULL DEFAULT '',
+		after_json TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events(created_at);
+
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events 只允许追加'); END;
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events 只允许追加'); END;
+	`)
+	return err
+}
+
+// migrateWithdrawalRequests v12: 投资人撤资申请
+// 每个状态一个时间列（Unix 秒，0 表示未到达该状态）；结算时锁定的净值、份额、金额写入 settled_* 列。
+func migrateWithdrawalRequests(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS withdrawal_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		recharge_id INTEGER NOT NULL,
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		principal INTEGER NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		note TEXT NOT NULL DEFAULT '',
+		reject_reason TEXT NOT NULL DEFAULT '',
+		reviewed_by INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		approved_at INTEGER NOT NULL DEFAULT 0,
+		settled_at INTEGER NOT NULL DEFAULT 0,
+		rejected_at INTEGER NOT NULL DEFAULT 0,
+		cancelled_at INTEGER NOT NULL DEFAULT 0,
+		settled_nav_date TEXT NOT NULL DEFAULT '',
+		settled_nav INTEGER NOT NULL DEFAULT 0,
+		settled_shares INTEGER NOT NULL DEFAULT 0,
+		settled_amount INTEGER NOT NULL DEFAULT 0,
+		settled_profit INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_withdrawal_requests_user ON withdrawal_requests(user_id);
+	CREATE INDEX IF NOT EXISTS idx_withdrawal_requests_status ON withdrawal_requests(status, admin_account_id, currency);
+	CREATE INDEX IF NOT EXISTS idx_withdrawal_requests_recharge ON withdrawal_requests(recharge_id);
+	`)
+	return err
+}
+
+// migratePerformanceFees v13: 业绩报酬
+// fee_rates 按 (费用类型, Admin账户, 用户) 保存费率，user_id=0 为账户默认；费率与金额一样按 1e-8 单位存储。
+// high_water_mark 为持仓上次计提后的净值，0 表示尚未计提（以成本净值 本金/份额 为准）。
+// fee_crystallizations 记录每个资金池已完成的月度计提，避免同一个月重复执行。
+func migratePerformanceFees(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "recharges", "high_water_mark", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS fee_rates (
+		fee_type TEXT NOT NULL,
+		admin_account_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL DEFAULT 0,
+		rate INTEGER NOT NULL,
+		updated_by INTEGER NOT NULL DEFAULT 0,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (fee_type, admin_account_id, user_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS performance_fees (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recharge_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		event TEXT NOT NULL,
+		period TEXT NOT NULL DEFAULT '',
+		nav_date TEXT NOT NULL,
+		nav INTEGER NOT NULL,
+		high_water_mark INTEGER NOT NULL,
+		shares INTEGER NOT NULL,
+		gain INTEGER NOT NULL,
+		rate INTEGER NOT NULL,
+		fee_amount INTEGER NOT NULL,
+		fee_shares INTEGER NOT NULL,
+		journal_entry_id INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		FOREIGN KEY (recharge_id) REFERENCES recharges(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_performance_fees_user ON performance_fees(user_id);
+	CREATE INDEX IF NOT EXISTS idx_performance_fees_recharge ON performance_fees(recharge_id);
+	CREATE INDEX IF NOT EXISTS idx_performance_fees_account ON performance_fees(admin_account_id, currency);
+
+	CREATE TABLE IF NOT EXISTS fee_crystallizations (
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		period TEXT NOT NULL,
+		nav_date TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (admin_account_id, currency, period)
+	);
+	`)
+	return err
+}
+
+// migrateManagementFees v14: 管理费每日计提
+// 费率沿用 fee_rates（fee_type='management'）；每个资金池每天最多一条计提记录。
+func migrateManagementFees(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS management_fee_accruals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		accrual_date TEXT NOT NULL,
+		days INTEGER NOT NULL,
+		aum INTEGER NOT NULL,
+		rate INTEGER NOT NULL,
+		fee_amount INTEGER NOT NULL,
+		fee_shares INTEGER NOT NULL,
+		nav_before INTEGER NOT NULL,
+		nav_after INTEGER NOT NULL,
+		journal_entry_id INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		UNIQUE(admin_account_id, currency, accrual_date)
+	);
+	`)
+	return err
+}
+
+// migrateBenchmarks v15: 业绩比较基准
+// 默认启用 BTC、ETH 两个买入持有基准；价格按基准代码和日期各一条。
+func migrateBenchmarks(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS benchmarks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		symbol TEXT NOT NULL UNIQUE,
+		name TEXT NOT NULL,
+		source_symbol TEXT NOT NULL,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS benchmark_prices (
+		symbol TEXT NOT NULL,
+		price_date TEXT NOT NULL,
+		price INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (symbol, price_date)
+	);
+
+	INSERT OR IGNORE INTO benchmarks (symbol, name, source_symbol, enabled, updated_at)
+	VALUES ('BTC', '比特币', 'BTCUSDT', 1, strftime('%s', 'now')),
+	       ('ETH', '以太坊', 'ETHUSDT', 1, strftime('%s', 'now'));
+	`)
+	return err
+}
+
+func migrateWalletAddresses(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS wallet_addresses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		admin_account_id INTEGER NOT NULL,
+		chain TEXT NOT NULL,
+		address TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		UNIQUE (admin_account_id, chain, address),
+		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id)
+	);
+
+	INSERT OR IGNORE INTO wallet_addresses (admin_account_id, chain, address, created_at)
+	SELECT id, 'ethereum', wallet_address, strftime('%s', 'now')
+	FROM admin_accounts
+	WHERE account_type = 'Wallet' AND COALESCE(wallet_address, '') != '';
+	`)
+	return err
+}
+
+// migrateNAVStrikes v17: 申购、赎回时的盘中净值单独保存，不再覆盖当天的收盘净值
+func migrateNAVStrikes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS nav_strikes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		admin_account_id INTEGER NOT NULL,
+		currency TEXT NOT NULL,
+		event TEXT NOT NULL,
+		balance INTEGER NOT NULL,
+		total_shares INTEGER NOT NULL,
+		nav INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_nav_strikes_account ON nav_strikes(admin_account_id, currency, created_at);
+	`)
+	return err
+}
+
+// migrateTwoFactorLockout v18: 两步验证连续失败次数和锁定截止时间（unix秒）
+func migrateTwoFactorLockout(tx *sql.Tx) error {
+	columns := []struct{ name, definition string }{
+		{"totp_failed_attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"totp_locked_until", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(tx, "users", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateOpenWithdrawalRequestIndex v19: 同一笔充值同时只能有一个待审核或已批准的申请
+// 已有的重复申请只保留最早的一个，其余改为已拒绝并记录原因，否则无法建立唯一索引。
+func migrateOpenWithdrawalRequestIndex(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	UPDATE withdrawal_requests
+	SET status = 'rejected', reject_reason = '重复的撤资申请', rejected_at = strftime('%s', 'now')
+	WHERE status IN ('pending', 'approved')
+	  AND id NOT IN (
+		SELECT MIN(id) FROM withdrawal_requests
+		WHERE status IN ('pending', 'approved')
+		GROUP BY recharge_id
+	  );
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_withdrawal_requests_open
+	ON withdrawal_requests(recharge_id) WHERE status IN ('pending', 'approved');
+	`)
+	return err
+}
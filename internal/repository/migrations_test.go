@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"crypto-final/internal/money"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// memoryDSN 每个测试独立的内存数据库
+func memoryDSN(t *testing.T) string {
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	return fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
+}
+
+// newTestRepository 每个测试一个独立的内存数据库（含默认管理员和Admin账户）
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	repo, err := NewRepository(memoryDSN(t), "admin-password")
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// openRawRepository 不执行迁移，直接打开数据库
+func openRawRepository(t *testing.T) *Repository {
+	t.Helper()
+	db, err := sql.Open("sqlite", memoryDSN(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Repository{db: db}
+}
+
+func columnsOf(t *testing.T, r *Repository, table string) map[string]columnInfo {
+	t.Helper()
+	tx, err := r.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	columns, err := tableColumns(tx, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return columns
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	r := newTestRepository(t)
+
+	version, err := r.SchemaVersion()
+	if err != nil || version != LatestSchemaVersion() {
+		t.Fatalf("SchemaVersion = %d, %v, want %d", version, err, LatestSchemaVersion())
+	}
+	for _, table := range []string{"users", "admin_accounts", "recharges", "withdrawals", "recharge_milestones", "recharge_monthly_snapshots"} {
+		if len(columnsOf(t, r, table)) == 0 {
+			t.Errorf("缺少表 %s", table)
+		}
+	}
+
+	// 新库上可以直接创建API用户（没有手机号）
+	if _, err := r.CreateAPIUser("trader", "hash", 0, 0); err != nil {
+		t.Errorf("CreateAPIUser: %v", err)
+	}
+
+	// 再次执行是空操作
+	if err := r.Migrate(); err != nil {
+		t.Fatalf("重复 Migrate: %v", err)
+	}
+	var applied int
+	r.db.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&applied)
+	if applied != LatestSchemaVersion() {
+		t.Errorf("schema_version 有 %d 行, want %d", applied, LatestSchemaVersion())
+	}
+}
+
+// TestMigrateLegacyDatabase 没有 schema_version 的旧库：补齐列、去掉 phone 的 NOT NULL，数据保留
+func TestMigrateLegacyDatabase(t *testing.T) {
+	r := openRawRepository(t)
+	_, err := r.db.Exec(`
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		phone TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		is_admin BOOLEAN DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE admin_accounts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_type TEXT UNIQUE NOT NULL,
+		api_key TEXT,
+		api_secret TEXT,
+		wallet_address TEXT,
+		current_balance REAL DEFAULT 0,
+		is_active BOOLEAN DEFAULT 1,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE recharges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		admin_account_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		currency TEXT NOT NULL,
+		recharge_at TIMESTAMP NOT NULL,
+		base_balance REAL NOT NULL,
+		is_active BOOLEAN DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO users (id, phone, password_hash, is_admin) VALUES (1, 'admin', 'h1', 1), (2, '13800000000', 'h2', 0);
+	INSERT INTO admin_accounts (id, account_type, api_key, current_balance) VALUES (1, 'Binance', 'k', 1234.5);
+	INSERT INTO recharges (user_id, admin_account_id, amount, currency, recharge_at, base_balance)
+	VALUES (2, 1, 500, 'USDT', '2024-01-01 00:00:00', 1000);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	users := columnsOf(t, r, "users")
+	if users["phone"].notNull {
+		t.Error("phone 仍然是 NOT NULL")
+	}
+	for _, column := range []string{"username", "is_api_user", "api_secret", "api_passphrase"} {
+		if _, ok := users[column]; !ok {
+			t.Errorf("users 缺少列 %s", column)
+		}
+	}
+	if _, ok := columnsOf(t, r, "admin_accounts")["total_shares"]; !ok {
+		t.Error("admin_accounts 缺少 total_shares")
+	}
+	if _, ok := columnsOf(t, r, "recharges")["shares"]; !ok {
+		t.Error("recharges 缺少 shares")
+	}
+
+	user, err := r.GetUserByPhone("13800000000")
+	if err != nil || user == nil || user.ID != 2 || !user.IsActive {
+		t.Errorf("迁移后用户 = %+v, %v", user, err)
+	}
+	account, err := r.GetAdminAccountByID(1)
+	if err != nil || account == nil || account.CurrentBalance != money.MustParse("1234.5") || account.APIKey != "k" {
+		t.Errorf("迁移后Admin账户 = %+v, %v", account, err)
+	}
+	if recharges, err := r.GetAllActiveRecharges(); err != nil || len(recharges) != 1 || recharges[0].Amount != money.FromFloat(500) {
+		t.Errorf("迁移后充值 = %v, %v", recharges, err)
+	}
+}
+
+func TestMigrateRejectsNewerDatabase(t *testing.T) {
+	r := newTestRepository(t)
+	if _, err := r.db.Exec("INSERT INTO schema_version (version, description) VALUES (?, 'future')", LatestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Migrate(); err == nil || !strings.Contains(err.Error(), "高于程序支持的版本") {
+		t.Errorf("Migrate error = %v, want 拒绝更高版本", err)
+	}
+}
+
+// TestMigrationFailureRollsBack 迁移失败时整体回滚，不记录版本
+func TestMigrationFailureRollsBack(t *testing.T) {
+	r := newTestRepository(t)
+	failing := migration{LatestSchemaVersion() + 1, "失败的迁移", func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE half_done (id INTEGER)"); err != nil {
+			return err
+		}
+		return fmt.Errorf("模拟失败")
+	}}
+	if err := r.applyMigration(failing); err == nil {
+		t.Fatal("applyMigration 应该失败")
+	}
+	if version, _ := r.SchemaVersion(); version != LatestSchemaVersion() {
+		t.Errorf("SchemaVersion = %d, 失败的迁移不应记录", version)
+	}
+	if len(columnsOf(t, r, "half_done")) != 0 {
+		t.Error("失败迁移创建的表没有回滚")
+	}
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"database/sql"
+	"time"
+)
+
+// SaveNAV 保存某账户某币种当天的收盘净值（同一天重复保存时覆盖，只由每日检查调用）
+func (r *Repository) SaveNAV(adminAccountID int, currency, date string, balance, totalShares, nav money.Decimal) error {
+	_, err := r.db.Exec(`
+		INSERT INTO nav_history (admin_account_id, currency, record_date, balance, total_shares, nav)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(admin_account_id, currency, record_date)
+		DO UPDATE SET balance = excluded.balance, total_shares = excluded.total_shares,
+		              nav = excluded.nav, created_at = CURRENT_TIMESTAMP`,
+		adminAccountID, currency, date, balance, totalShares, nav,
+	)
+	return err
+}
+
+// SaveNAVStrike 追加一条盘中净值
+func (r *Repository) SaveNAVStrike(strike *model.NAVStrike) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO nav_strikes (admin_account_id, currency, event, balance, total_shares, nav, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		strike.AdminAccountID, strike.Currency, strike.Event, strike.Balance, strike.TotalShares, strike.NAV, now.Unix(),
+	)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	strike.ID = int(id)
+	strike.CreatedAt = time.Unix(now.Unix(), 0)
+	return nil
+}
+
+// GetNAVStrikes 获取某账户某币种的盘中净值（按时间升序），from/to 为 YYYY-MM-DD（本地时间），空字符串表示不限
+func (r *Repository) GetNAVStrikes(adminAccountID int, currency, from, to string) ([]*model.NAVStrike, error) {
+	query := `
+		SELECT id, admin_account_id, currency, event, balance, total_shares, nav, created_at
+		FROM nav_strikes
+		WHERE admin_account_id = ? AND currency = ?`
+	args := []interface{}{adminAccountID, currency}
+
+	if from != "" {
+		start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND created_at >= ?"
+		args = append(args, start.Unix())
+	}
+	if to != "" {
+		end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND created_at < ?"
+		args = append(args, end.AddDate(0, 0, 1).Unix())
+	}
+	query += " ORDER BY created_at ASC, id ASC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	strikes := []*model.NAVStrike{}
+	for rows.Next() {
+		n := &model.NAVStrike{}
+		var createdAt int64
+		if err := rows.Scan(&n.ID, &n.AdminAccountID, &n.Currency, &n.Event, &n.Balance, &n.TotalShares, &n.NAV, &createdAt); err != nil {
+			return nil, err
+		}
+		n.CreatedAt = time.Unix(createdAt, 0)
+		strikes = append(strikes, n)
+	}
+	return strikes, rows.Err()
+}
+
+// GetLatestNAV 获取某账户某币种最近一次记录的净值（没有记录时返回nil）
+func (r *Repository) GetLatestNAV(adminAccountID int, currency string) (*model.NAVRecord, error) {
+	n := &model.NAVRecord{}
+	err := r.db.QueryRow(`
+		SELECT id, admin_account_id, currency, record_date, balance, total_shares, nav, created_at
+		FROM nav_history
+		WHERE admin_account_id = ? AND currency = ?
+		ORDER BY record_date DESC
+		LIMIT 1`,
+		adminAccountID, currency,
+	).Scan(&n.ID, &n.AdminAccountID, &n.Currency, &n.RecordDate, &n.Balance, &n.TotalShares, &n.NAV, &n.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GetNAVHistory 获取某账户某币种的净值序列（按日期升序）
+// from/to 为 YYYY-MM-DD，空字符串表示不限
+func (r *Repository) GetNAVHistory(adminAccountID int, currency, from, to string) ([]*model.NAVRecord, error) {
+	query := `
+		SELECT id, admin_account_id, currency, record_date, balance, total_shares, nav, created_at
+		FROM nav_history
+		WHERE admin_account_id = ? AND currency = ?`
+	args := []interface{}{adminAccountID, currency}
+
+	if from != "" {
+		query += " AND record_date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND record_date <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY record_date ASC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*model.NAVRecord{}
+	for rows.Next() {
+		n := &model.NAVRecord{}
+		if err := rows.Scan(&n.ID, &n.AdminAccountID, &n.Currency, &n.RecordDate, &n.Balance, &n.TotalShares, &n.NAV, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, n)
+	}
+	return history, rows.Err()
+}
+
+// GetAccountNAVHistory 获取某账户所有币种的净值序列（按日期、币种升序）
+func (r *Repository) GetAccountNAVHistory(adminAccountID int) ([]*model.NAVRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT id, admin_account_id, currency, record_date, balance, total_shares, nav, created_at
+		FROM nav_history
+		WHERE admin_account_id = ?
+		ORDER BY record_date ASC, currency ASC`,
+		adminAccountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*model.NAVRecord{}
+	for rows.Next() {
+		n := &model.NAVRecord{}
+		if err := rows.Scan(&n.ID, &n.AdminAccountID, &n.Currency, &n.RecordDate, &n.Balance, &n.TotalShares, &n.NAV, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, n)
+	}
+	return history, rows.Err()
+}
+
+// GetActiveCurrencies 获取某账户有活跃份额的币种
+func (r *Repository) GetActiveCurrencies(adminAccountID int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT currency
+		FROM recharges
+		WHERE admin_account_id = ? AND is_active = 1
+		ORDER BY currency`,
+		adminAccountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var currencies []string
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, currency)
+	}
+	return currencies, rows.Err()
+}
@@ -2,10 +2,11 @@ package repository
 
 import (
 	"crypto-final/internal/model"
-	"crypto/sha256"
+	"crypto-final/internal/money"
+	"crypto-final/internal/password"
 	"database/sql"
-	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -20,115 +21,84 @@ type Record struct {
 }
 
 func NewRepository(dbPath string, adminPassword string) (*Repository, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	repo, err := openDB(dbPath)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := repo.InitDB(adminPassword); err != nil {
 		return nil, err
 	}
 
-	repo := &Repository{db: db}
-	if err := repo.InitDB(adminPassword); err != nil {
+	return repo, nil
+}
+
+// OpenRepository 打开数据库并执行迁移，不写入默认数据（供命令行工具使用）
+func OpenRepository(dbPath string) (*Repository, error) {
+	repo, err := openDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Migrate(); err != nil {
+		repo.Close()
 		return nil, err
 	}
 
 	return repo, nil
 }
 
-func (r *Repository) InitDB(adminPassword string) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		phone TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		is_admin BOOLEAN DEFAULT 0,
-		is_active BOOLEAN DEFAULT 1,  -- 新增
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS admin_accounts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		account_type TEXT UNIQUE NOT NULL,
-		api_key TEXT,
-		api_secret TEXT,
-		wallet_address TEXT,
-		passphrase TEXT,           -- ← 新增
-		current_balance REAL DEFAULT 0,
-		total_shares REAL DEFAULT 0,  -- 新增：总份额数
-		is_active BOOLEAN DEFAULT 1,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS admin_account_balances (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		admin_account_id INTEGER NOT NULL,
-		record_date DATE NOT NULL,
-		balance REAL NOT NULL,
-		daily_change REAL DEFAULT 0,
-		daily_change_rate REAL DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id),
-		UNIQUE(admin_account_id, record_date)
-	);
-
-	CREATE TABLE IF NOT EXISTS recharges (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		admin_account_id INTEGER NOT NULL,
-		amount REAL NOT NULL,
-		currency TEXT NOT NULL,
-		recharge_at TIMESTAMP NOT NULL,
-		base_balance REAL NOT NULL,
-		shares REAL NOT NULL DEFAULT 0,  -- 新增：用户持有的份额数
-		is_active BOOLEAN DEFAULT 1,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id),
-		FOREIGN KEY (admin_account_id) REFERENCES admin_accounts(id)
-	);
-
-	CREATE TABLE IF NOT EXISTS recharge_daily_profits (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		recharge_id INTEGER NOT NULL,
-		record_date DATE NOT NULL,
-		admin_account_balance REAL NOT NULL,
-		profit REAL NOT NULL,
-		profit_rate REAL NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (recharge_id) REFERENCES recharges(id),
-		UNIQUE(recharge_id, record_date)
-	);
-	`
-
-	_, err := r.db.Exec(schema)
+func openDB(dbPath string) (*Repository, error) {
+	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Repository{db: db}, nil
+}
+
+// InitDB 执行数据库迁移并写入默认数据
+func (r *Repository) InitDB(adminPassword string) error {
+	if err := r.Migrate(); err != nil {
 		return err
 	}
 
 	// 创建默认管理员（使用环境变量密码）
-	passwordHash := hashPassword(adminPassword)
+	passwordHash, err := password.Hash(adminPassword)
+	if err != nil {
+		return err
+	}
 	defaultAdmin := `
-	INSERT OR IGNORE INTO users (id, phone, password_hash, is_admin)
-	VALUES (1, 'admin', ?, 1);
+	INSERT OR IGNORE INTO users (id, phone, password_hash, is_admin, role)
+	VALUES (1, 'admin', ?, 1, 'super_admin');
 	`
 	_, err = r.db.Exec(defaultAdmin, passwordHash)
 
-	// 初始化3个Admin账户
+	// 初始化Admin账户（Bybit、Bitget、Gate.io为后加的场所，配置密钥前不参与每日检查）
 	accounts := `
 	INSERT OR IGNORE INTO admin_accounts (id, account_type) VALUES (1, 'Binance');
 	INSERT OR IGNORE INTO admin_accounts (id, account_type) VALUES (2, 'OKX');
 	INSERT OR IGNORE INTO admin_accounts (id, account_type) VALUES (3, 'Wallet');
+	INSERT OR IGNORE INTO admin_accounts (id, account_type, is_active) VALUES (4, 'Bybit', 0);
+	INSERT OR IGNORE INTO admin_accounts (id, account_type, is_active) VALUES (5, 'Bitget', 0);
+	INSERT OR IGNORE INTO admin_accounts (id, account_type, is_active) VALUES (6, 'Gate', 0);
 	`
 	_, _ = r.db.Exec(accounts)
 
 	return err
 }
 
-// hashPassword 计算密码哈希
-func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+// UpdateUserPassword 更新用户密码哈希
+func (r *Repository) UpdateUserPassword(userID int, passwordHash string) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET password_hash = ? WHERE id = ?",
+		passwordHash, userID,
+	)
+	return err
 }
 
 // User operations
@@ -151,6 +121,8 @@ func (r *Repository) GetUserByPhone(phone string) (*model.User, error) {
 		       COALESCE(username, ''), 
 		       password_hash, 
 		       is_admin, 
+		       role,
+		       totp_enabled,
 		       COALESCE(is_active, 1),
 		       COALESCE(is_api_user, 0),
 		       COALESCE(api_admin_account_id, 0),
@@ -169,6 +141,8 @@ func (r *Repository) GetUserByPhone(phone string) (*model.User, error) {
 		&user.Username,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Role,
+		&user.TOTPEnabled,
 		&user.IsActive,
 		&user.IsAPIUser,
 		&user.APIAdminAccountID,
@@ -276,7 +250,7 @@ func (r *Repository) GetAllDashboardUsers() ([]*model.User, error) {
 }
 
 // UpdateUserAPIKeys 更新用户API密钥
-func (r *Repository) UpdateUserAPIKeys(userID int, apiType, apiKey, apiSecret, passphrase string, initialBalance float64) error {
+func (r *Repository) UpdateUserAPIKeys(userID int, apiType, apiKey, apiSecret, passphrase string, initialBalance money.Decimal) error {
 	_, err := r.db.Exec(`
 		UPDATE users 
 		SET api_type = ?, 
@@ -317,6 +291,9 @@ func (r *Repository) GetAdminAccountByID(id int) (*model.AdminAccount, error) {
 	acc.WalletAddress = walletAddress.String
 	acc.Passphrase = passphrase.String
 
+	if err := r.loadChainAddresses(acc); err != nil {
+		return nil, err
+	}
 	return acc, nil
 }
 func (r *Repository) GetAdminAccountByType(accountType string) (*model.AdminAccount, error) {
@@ -345,6 +322,9 @@ func (r *Repository) GetAdminAccountByType(accountType string) (*model.AdminAcco
 	acc.WalletAddress = walletAddress.String
 	acc.Passphrase = passphrase.String
 
+	if err := r.loadChainAddresses(acc); err != nil {
+		return nil, err
+	}
 	return acc, nil
 }
 
@@ -379,6 +359,16 @@ func (r *Repository) GetAllAdminAccounts() ([]*model.AdminAccount, error) {
 
 		accounts = append(accounts, acc)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, acc := range accounts {
+		if err := r.loadChainAddresses(acc); err != nil {
+			return nil, err
+		}
+	}
 	return accounts, nil
 }
 
@@ -398,6 +388,7 @@ func (r *Repository) GetAllUsersBasic() ([]*model.User, error) {
 		SELECT id, 
 		       COALESCE(phone, '') as phone, 
 		       COALESCE(username, '') as username, 
+		       role,
 		       is_active, 
 		       is_api_user, 
 		       created_at
@@ -417,6 +408,7 @@ func (r *Repository) GetAllUsersBasic() ([]*model.User, error) {
 			&user.ID,
 			&user.Phone,
 			&user.Username,
+			&user.Role,
 			&user.IsActive,
 			&user.IsAPIUser,
 			&user.CreatedAt,
@@ -429,7 +421,7 @@ func (r *Repository) GetAllUsersBasic() ([]*model.User, error) {
 
 	return users, nil
 }
-func (r *Repository) UpdateAdminAccountBalance(id int, balance float64) error {
+func (r *Repository) UpdateAdminAccountBalance(id int, balance money.Decimal) error {
 	_, err := r.db.Exec(
 		"UPDATE admin_accounts SET current_balance=?, updated_at=CURRENT_TIMESTAMP WHERE id=?",
 		balance, id,
@@ -454,7 +446,7 @@ func (r *Repository) UpdateRechargeActive(rechargeID int, isActive bool) error {
 }
 
 // AdminAccountBalance operations
-func (r *Repository) SaveAdminAccountBalance(accountID int, date string, balance, change, changeRate float64) error {
+func (r *Repository) SaveAdminAccountBalance(accountID int, date string, balance, change money.Decimal, changeRate float64) error {
 	_, err := r.db.Exec(
 		`INSERT INTO admin_account_balances (admin_account_id, record_date, balance, daily_change, daily_change_rate)
 		 VALUES (?, ?, ?, ?, ?)
@@ -466,8 +458,8 @@ func (r *Repository) SaveAdminAccountBalance(accountID int, date string, balance
 	return err
 }
 
-func (r *Repository) GetLatestAdminAccountBalance(accountID int) (float64, error) {
-	var balance float64
+func (r *Repository) GetLatestAdminAccountBalance(accountID int) (money.Decimal, error) {
+	var balance money.Decimal
 	err := r.db.QueryRow(
 		`SELECT balance FROM admin_account_balances 
 		 WHERE admin_account_id=? ORDER BY record_date DESC LIMIT 1`,
@@ -480,8 +472,34 @@ func (r *Repository) GetLatestAdminAccountBalance(accountID int) (float64, error
 	return balance, err
 }
 
+// GetAdminAccountBalanceHistory 获取某账户的每日余额记录（按日期升序）
+// record_date 列为 DATE，驱动会解析成 time.Time，这里转成 YYYY-MM-DD 字符串
+func (r *Repository) GetAdminAccountBalanceHistory(accountID int) ([]*model.AdminAccountBalance, error) {
+	rows, err := r.db.Query(`
+		SELECT id, admin_account_id, strftime('%Y-%m-%d', record_date), balance, daily_change, daily_change_rate
+		FROM admin_account_balances
+		WHERE admin_account_id = ?
+		ORDER BY record_date ASC`,
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []*model.AdminAccountBalance
+	for rows.Next() {
+		b := &model.AdminAccountBalance{}
+		if err := rows.Scan(&b.ID, &b.AdminAccountID, &b.RecordDate, &b.Balance, &b.DailyChange, &b.DailyChangeRate); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
 // GetRechargeStatistics 获取充值统计（按账户和币种）
-func (r *Repository) GetRechargeStatistics() (map[int]map[string]float64, error) {
+func (r *Repository) GetRechargeStatistics() (map[int]map[string]money.Decimal, error) {
 	rows, err := r.db.Query(`
 		SELECT admin_account_id, currency, SUM(amount) as total
 		FROM recharges
@@ -494,19 +512,19 @@ func (r *Repository) GetRechargeStatistics() (map[int]map[string]float64, error)
 	defer rows.Close()
 
 	// 结构: map[accountID]map[currency]total
-	stats := make(map[int]map[string]float64)
+	stats := make(map[int]map[string]money.Decimal)
 
 	for rows.Next() {
 		var accountID int
 		var currency string
-		var total float64
+		var total money.Decimal
 
 		if err := rows.Scan(&accountID, &currency, &total); err != nil {
 			return nil, err
 		}
 
 		if stats[accountID] == nil {
-			stats[accountID] = make(map[string]float64)
+			stats[accountID] = make(map[string]money.Decimal)
 		}
 		stats[accountID][currency] = total
 	}
@@ -514,8 +532,8 @@ func (r *Repository) GetRechargeStatistics() (map[int]map[string]float64, error)
 	return stats, nil
 }
 
-func (r *Repository) GetAdminAccountBalanceByDate(accountID int, date string) (float64, error) {
-	var balance float64
+func (r *Repository) GetAdminAccountBalanceByDate(accountID int, date string) (money.Decimal, error) {
+	var balance money.Decimal
 	err := r.db.QueryRow(
 		"SELECT balance FROM admin_account_balances WHERE admin_account_id=? AND record_date=?",
 		accountID, date,
@@ -527,8 +545,9 @@ func (r *Repository) GetAdminAccountBalanceByDate(accountID int, date string) (f
 	return balance, err
 }
 
-func (r *Repository) GetTodayAdminAccountChange(accountID int, today string) (float64, float64, error) {
-	var change, changeRate float64
+func (r *Repository) GetTodayAdminAccountChange(accountID int, today string) (money.Decimal, float64, error) {
+	var change money.Decimal
+	var changeRate float64
 	err := r.db.QueryRow(
 		"SELECT daily_change, daily_change_rate FROM admin_account_balances WHERE admin_account_id=? AND record_date=?",
 		accountID, today,
@@ -540,20 +559,6 @@ func (r *Repository) GetTodayAdminAccountChange(accountID int, today string) (fl
 	return change, changeRate, err
 }
 
-// Recharge operations
-func (r *Repository) CreateRecharge(recharge *model.Recharge) (int64, error) {
-	result, err := r.db.Exec(
-		`INSERT INTO recharges (user_id, admin_account_id, amount, currency, recharge_at, base_balance, is_active)
-		 VALUES (?, ?, ?, ?, ?, ?, 1)`,
-		recharge.UserID, recharge.AdminAccountID, recharge.Amount,
-		recharge.Currency, recharge.RechargeAt, recharge.BaseBalance,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
-}
-
 // CreateAdminAccount 创建新的Admin账户
 func (r *Repository) CreateAdminAccount(accountType, apiKey, apiSecret, passphrase string) (int, error) {
 	result, err := r.db.Exec(
@@ -576,7 +581,7 @@ func (r *Repository) DeleteAdminAccount(accountID int) error {
 }
 
 // CreateAPIUser 创建API用户（使用username）
-func (r *Repository) CreateAPIUser(username, passwordHash string, adminAccountID int, initialBalance float64) (int64, error) {
+func (r *Repository) CreateAPIUser(username, passwordHash string, adminAccountID int, initialBalance money.Decimal) (int64, error) {
 	result, err := r.db.Exec(
 		`INSERT INTO users (username, password_hash, is_admin, is_active, is_api_user, api_admin_account_id, initial_balance)
 		 VALUES (?, ?, 0, 1, 1, ?, ?)`,
@@ -597,6 +602,8 @@ func (r *Repository) GetUserByUsername(username string) (*model.User, error) {
 		       COALESCE(username, ''), 
 		       password_hash, 
 		       is_admin, 
+		       role,
+		       totp_enabled,
 		       COALESCE(is_active, 1),
 		       COALESCE(is_api_user, 0),
 		       COALESCE(api_admin_account_id, 0),
@@ -615,6 +622,8 @@ func (r *Repository) GetUserByUsername(username string) (*model.User, error) {
 		&user.Username,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Role,
+		&user.TOTPEnabled,
 		&user.IsActive,
 		&user.IsAPIUser,
 		&user.APIAdminAccountID,
@@ -668,8 +677,8 @@ func (r *Repository) GetSystemRecharge(adminAccountID int, currency string) (*mo
 }
 
 // GetSystemSharesByCurrency 获取系统账户某币种的总份额
-func (r *Repository) GetSystemSharesByCurrency(adminAccountID int, currency string) (float64, error) {
-	var shares float64
+func (r *Repository) GetSystemSharesByCurrency(adminAccountID int, currency string) (money.Decimal, error) {
+	var shares money.Decimal
 	err := r.db.QueryRow(`
 		SELECT COALESCE(SUM(shares), 0)
 		FROM recharges
@@ -684,8 +693,8 @@ func (r *Repository) GetSystemSharesByCurrency(adminAccountID int, currency stri
 }
 
 // GetUserSharesByCurrency 获取用户在某账户某币种的总份额
-func (r *Repository) GetUserSharesByCurrency(userID, adminAccountID int, currency string) (float64, error) {
-	var shares float64
+func (r *Repository) GetUserSharesByCurrency(userID, adminAccountID int, currency string) (money.Decimal, error) {
+	var shares money.Decimal
 	err := r.db.QueryRow(`
 		SELECT COALESCE(SUM(shares), 0)
 		FROM recharges
@@ -699,53 +708,32 @@ func (r *Repository) GetUserSharesByCurrency(userID, adminAccountID int, currenc
 	return shares, err
 }
 
-// GetTotalSharesByCurrency 获取某个账户某个币种的总份额
-func (r *Repository) GetTotalSharesByCurrency(adminAccountID int, currency string) (float64, error) {
-	var totalShares float64
+// GetTotalSharesByCurrency 获取某个账户某个币种的总份额（由分录汇总：资金池科目余额的相反数）
+func (r *Repository) GetTotalSharesByCurrency(adminAccountID int, currency string) (money.Decimal, error) {
+	var totalShares money.Decimal
 	err := r.db.QueryRow(`
-		SELECT COALESCE(SUM(shares), 0)
-		FROM recharges
-		WHERE admin_account_id = ? 
-		  AND currency = ? 
-		  AND is_active = 1`,
-		adminAccountID, currency,
+		SELECT COALESCE(-SUM(shares), 0)
+		FROM journal_postings
+		WHERE account = ?`,
+		PoolAccount(adminAccountID, currency),
 	).Scan(&totalShares)
 
 	return totalShares, err
 }
 
-// GetAllSharesByAccount 获取某账户所有币种的总份额
-func (r *Repository) GetAllSharesByAccount(adminAccountID int) (float64, error) {
-	var shares float64
+// GetAllSharesByAccount 获取某账户所有币种的总份额（由分录汇总）
+func (r *Repository) GetAllSharesByAccount(adminAccountID int) (money.Decimal, error) {
+	var shares money.Decimal
 	err := r.db.QueryRow(`
-		SELECT COALESCE(SUM(shares), 0)
-		FROM recharges
-		WHERE admin_account_id = ?
-		  AND is_active = 1`,
-		adminAccountID,
+		SELECT COALESCE(-SUM(shares), 0)
+		FROM journal_postings
+		WHERE account LIKE ?`,
+		PoolAccount(adminAccountID, "%"),
 	).Scan(&shares)
 
 	return shares, err
 }
 
-// UpdateRechargeShares 更新充值记录的份额
-func (r *Repository) UpdateRechargeShares(rechargeID int, shares float64) error {
-	_, err := r.db.Exec(
-		"UPDATE recharges SET shares = ? WHERE id = ?",
-		shares, rechargeID,
-	)
-	return err
-}
-
-// UpdateRechargeAmountAndShares 更新充值记录的金额和份额
-func (r *Repository) UpdateRechargeAmountAndShares(rechargeID int, amount, shares float64) error {
-	_, err := r.db.Exec(
-		"UPDATE recharges SET amount = ?, shares = ? WHERE id = ?",
-		amount, shares, rechargeID,
-	)
-	return err
-}
-
 func (r *Repository) GetRechargesByUserID(userID int) ([]*model.Recharge, error) {
 	rows, err := r.db.Query(
 		`SELECT id, user_id, admin_account_id, amount, currency, recharge_at, 
@@ -834,15 +822,34 @@ func (r *Repository) GetRechargeByID(rechargeID int) (*model.Recharge, error) {
 	return recharge, err
 }
 
-// UpdateUserStatus 更新用户状态
+// UpdateUserStatus 更新用户状态（超级管理员不能被停用）
 func (r *Repository) UpdateUserStatus(userID int, isActive bool) error {
 	_, err := r.db.Exec(
-		"UPDATE users SET is_active = ? WHERE id = ? AND is_admin = 0",
+		"UPDATE users SET is_active = ? WHERE id = ? AND role != 'super_admin'",
 		isActive, userID,
 	)
 	return err
 }
 
+// UpdateUserRole 更新用户角色，is_admin 随角色同步
+func (r *Repository) UpdateUserRole(userID int, role string, isAdmin bool) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET role = ?, is_admin = ? WHERE id = ?",
+		role, isAdmin, userID,
+	)
+	return err
+}
+
+// CountActiveUsersByRole 统计某角色的活跃用户数
+func (r *Repository) CountActiveUsersByRole(role string) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM users WHERE role = ? AND COALESCE(is_active, 1) = 1",
+		role,
+	).Scan(&count)
+	return count, err
+}
+
 // GetUserByID 获取用户信息（含is_active）
 func (r *Repository) GetUserByID(userID int) (*model.User, error) {
 	user := &model.User{}
@@ -852,6 +859,8 @@ func (r *Repository) GetUserByID(userID int) (*model.User, error) {
 		       COALESCE(username, ''),
 		       password_hash, 
 		       is_admin, 
+		       role,
+		       totp_enabled,
 		       COALESCE(is_active, 1), 
 		       COALESCE(is_api_user, 0), 
 		       COALESCE(api_admin_account_id, 0),
@@ -870,6 +879,8 @@ func (r *Repository) GetUserByID(userID int) (*model.User, error) {
 		&user.Username, // 新增
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Role,
+		&user.TOTPEnabled,
 		&user.IsActive,
 		&user.IsAPIUser,
 		&user.APIAdminAccountID,
@@ -886,29 +897,6 @@ func (r *Repository) GetUserByID(userID int) (*model.User, error) {
 	return user, err
 }
 
-// DeleteRecharge 删除充值记录
-func (r *Repository) DeleteRecharge(rechargeID int) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// 删除充值的每日盈亏记录
-	_, err = tx.Exec("DELETE FROM recharge_daily_profits WHERE recharge_id = ?", rechargeID)
-	if err != nil {
-		return err
-	}
-
-	// 删除充值记录
-	_, err = tx.Exec("DELETE FROM recharges WHERE id = ?", rechargeID)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
-}
-
 // UpdateRechargeStatus 更新充值状态（软删除）
 func (r *Repository) UpdateRechargeStatus(rechargeID int, isActive bool) error {
 	_, err := r.db.Exec(
@@ -919,7 +907,7 @@ func (r *Repository) UpdateRechargeStatus(rechargeID int, isActive bool) error {
 }
 
 // RechargeDailyProfit operations
-func (r *Repository) SaveRechargeDailyProfit(rechargeID int, date string, accountBalance, profit, profitRate float64) error {
+func (r *Repository) SaveRechargeDailyProfit(rechargeID int, date string, accountBalance, profit money.Decimal, profitRate float64) error {
 	_, err := r.db.Exec(
 		`INSERT INTO recharge_daily_profits (recharge_id, record_date, admin_account_balance, profit, profit_rate)
 		 VALUES (?, ?, ?, ?, ?)
@@ -975,7 +963,7 @@ func (r *Repository) Close() error {
 }
 
 // UpdateAdminAccountShares 更新Admin账户总份额
-func (r *Repository) UpdateAdminAccountShares(accountID int, totalShares float64) error {
+func (r *Repository) UpdateAdminAccountShares(accountID int, totalShares money.Decimal) error {
 	_, err := r.db.Exec(
 		"UPDATE admin_accounts SET total_shares = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
 		totalShares, accountID,
@@ -983,21 +971,8 @@ func (r *Repository) UpdateAdminAccountShares(accountID int, totalShares float64
 	return err
 }
 
-// CreateRechargeWithShares 创建充值记录（含份额）
-func (r *Repository) CreateRechargeWithShares(userID, adminAccountID int, amount float64, currency string, baseBalance, shares float64) (int64, error) {
-	result, err := r.db.Exec(
-		`INSERT INTO recharges (user_id, admin_account_id, amount, currency, base_balance, shares, recharge_at, is_active)
-         VALUES (?, ?, ?, ?, ?, ?, datetime('now'), 1)`,
-		userID, adminAccountID, amount, currency, baseBalance, shares,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
-}
-
 // UpdateUserInitialBalance 更新用户初始余额
-func (r *Repository) UpdateUserInitialBalance(userID int, initialBalance float64) error {
+func (r *Repository) UpdateUserInitialBalance(userID int, initialBalance money.Decimal) error {
 	_, err := r.db.Exec(`
 		UPDATE users 
 		SET initial_balance = ? 
@@ -1008,8 +983,8 @@ func (r *Repository) UpdateUserInitialBalance(userID int, initialBalance float64
 }
 
 // GetTotalRechargeAmountByCurrency 获取某个账户某个币种的总充值金额（不包括系统充值）
-func (r *Repository) GetTotalRechargeAmountByCurrency(adminAccountID int, currency string) (float64, error) {
-	var totalAmount float64
+func (r *Repository) GetTotalRechargeAmountByCurrency(adminAccountID int, currency string) (money.Decimal, error) {
+	var totalAmount money.Decimal
 	err := r.db.QueryRow(`
 		SELECT COALESCE(SUM(amount), 0)
 		FROM recharges
@@ -1023,7 +998,7 @@ func (r *Repository) GetTotalRechargeAmountByCurrency(adminAccountID int, curren
 }
 
 // SaveMilestone 保存充值里程碑快照
-func (r *Repository) SaveMilestone(rechargeID, userID int, milestoneType string, daysHeld int, amount, currentValue, profit, profitRate, netValue float64) error {
+func (r *Repository) SaveMilestone(rechargeID, userID int, milestoneType string, daysHeld int, amount, currentValue, profit money.Decimal, profitRate float64, netValue money.Decimal) error {
 	milestoneDate := time.Now().Format("2006-01-02")
 
 	_, err := r.db.Exec(`
@@ -1039,7 +1014,8 @@ func (r *Repository) SaveMilestone(rechargeID, userID int, milestoneType string,
 func (r *Repository) GetMilestone(rechargeID int, milestoneType string) (map[string]interface{}, error) {
 	var milestone map[string]interface{}
 	var daysHeld int
-	var amount, currentValue, profit, profitRate, netValue float64
+	var amount, currentValue, profit, netValue money.Decimal
+	var profitRate float64
 	var milestoneDate string
 
 	err := r.db.QueryRow(`
@@ -1066,15 +1042,23 @@ func (r *Repository) GetMilestone(rechargeID int, milestoneType string) (map[str
 }
 
 // RecordWithdrawal 记录全部撤资
-func (r *Repository) RecordWithdrawal(rechargeID, userID int, originalAmount, withdrawnAmount, finalProfit, finalProfitRate float64, daysHeld int) error {
+func (r *Repository) RecordWithdrawal(rechargeID, userID int, originalAmount, withdrawnAmount, finalProfit money.Decimal, finalProfitRate float64, daysHeld int) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	
+
+	if err := recordWithdrawalTx(tx, rechargeID, userID, originalAmount, withdrawnAmount, finalProfit, finalProfitRate, daysHeld); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordWithdrawalTx 全部撤资：写撤资记录、停用充值、注销全部份额
+func recordWithdrawalTx(tx *sql.Tx, rechargeID, userID int, originalAmount, withdrawnAmount, finalProfit money.Decimal, finalProfitRate float64, daysHeld int) error {
 	// 1. 记录撤资
-	_, err = tx.Exec(`
+	_, err := tx.Exec(`
 		INSERT INTO withdrawals 
 		(recharge_id, user_id, original_amount, withdrawn_amount, final_profit, final_profit_rate, days_held, withdrawal_type, remaining_amount)
 		VALUES (?, ?, ?, ?, ?, ?, ?, 'full', 0)
@@ -1095,14 +1079,26 @@ func (r *Repository) RecordWithdrawal(rechargeID, userID int, originalAmount, wi
 		return err
 	}
 	
-	return tx.Commit()
+	// 3. 记账：注销全部份额
+	return closeRechargeTx(tx, rechargeID, 0, 0, 0, fmt.Sprintf("用户%d 全部撤资", userID))
+}
+
+// recordRedemptionTx 按定价结果记录全部或部分撤资
+func recordRedemptionTx(tx *sql.Tx, rd *model.Redemption) error {
+	if rd.Full {
+		return recordWithdrawalTx(tx, rd.RechargeID, rd.UserID, rd.Principal, rd.Amount, rd.Profit, rd.ProfitRate, rd.DaysHeld)
+	}
+	return recordPartialWithdrawalTx(tx, rd.RechargeID, rd.UserID,
+		rd.Principal, rd.Amount, rd.Profit, rd.ProfitRate,
+		rd.RemainingPrincipal, rd.RemainingValue, rd.RemainingShares,
+		rd.DaysHeld, rd.RechargeAt, rd.AdminAccountID, rd.Currency)
 }
 
 // RecordPartialWithdrawal 记录部分撤资
 func (r *Repository) RecordPartialWithdrawal(
 	originalRechargeID, userID int,
-	withdrawPrincipal, withdrawAmount, withdrawProfit, withdrawProfitRate float64,
-	remainingPrincipal, remainingValue float64,
+	withdrawPrincipal, withdrawAmount, withdrawProfit money.Decimal, withdrawProfitRate float64,
+	remainingPrincipal, remainingValue, remainingShares money.Decimal,
 	daysHeld int,
 	originalRechargeAt time.Time,
 	adminAccountID int,
@@ -1113,9 +1109,30 @@ func (r *Repository) RecordPartialWithdrawal(
 		return err
 	}
 	defer tx.Rollback()
-	
+
+	err = recordPartialWithdrawalTx(tx, originalRechargeID, userID,
+		withdrawPrincipal, withdrawAmount, withdrawProfit, withdrawProfitRate,
+		remainingPrincipal, remainingValue, remainingShares,
+		daysHeld, originalRechargeAt, adminAccountID, currency)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordPartialWithdrawalTx 部分撤资：停用原充值，剩余部分转入新充值记录，写撤资记录
+func recordPartialWithdrawalTx(
+	tx *sql.Tx,
+	originalRechargeID, userID int,
+	withdrawPrincipal, withdrawAmount, withdrawProfit money.Decimal, withdrawProfitRate float64,
+	remainingPrincipal, remainingValue, remainingShares money.Decimal,
+	daysHeld int,
+	originalRechargeAt time.Time,
+	adminAccountID int,
+	currency string,
+) error {
 	// 1. 停用原充值记录
-	_, err = tx.Exec(`
+	_, err := tx.Exec(`
 		UPDATE recharges 
 		SET is_active = 0 
 		WHERE id = ?
@@ -1125,20 +1142,31 @@ func (r *Repository) RecordPartialWithdrawal(
 		return err
 	}
 	
-	// 2. 创建新的充值记录（剩余部分，继续持有）
-	result, err := tx.Exec(`
-		INSERT INTO recharges 
-		(user_id, admin_account_id, amount, currency, recharge_at, base_balance, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, 1)
-	`, userID, adminAccountID, remainingPrincipal, currency, originalRechargeAt, 0)
-	
+	// 2. 创建新的充值记录（剩余部分，继续持有），记账：注销原记录，剩余份额和本金转入新记录
+	newRechargeID, err := insertRechargeTx(tx, userID, adminAccountID, currency, originalRechargeAt)
 	if err != nil {
 		return err
 	}
 	
-	newRechargeID, _ := result.LastInsertId()
-	
-	// 3. 复制原充值的月度快照到新充值记录
+	err = closeRechargeTx(tx, originalRechargeID, newRechargeID, remainingShares, remainingPrincipal,
+		fmt.Sprintf("用户%d 部分撤资，剩余转入充值%d", userID, newRechargeID))
+	if err != nil {
+		return err
+	}
+
+	// 剩余部分沿用原持仓的业绩报酬高水位
+	_, err = tx.Exec(`
+		UPDATE recharges
+		SET high_water_mark = (SELECT high_water_mark FROM recharges WHERE id = ?)
+		WHERE id = ?`,
+		originalRechargeID, newRechargeID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// 3. 复制原充值的月度快照到新充值记录（按保留本金占原本金的比例缩放）
+	keepRatio := money.Ratio(remainingPrincipal, remainingPrincipal.Add(withdrawPrincipal))
 	_, err = tx.Exec(`
 		INSERT INTO recharge_monthly_snapshots 
 		(recharge_id, user_id, snapshot_date, period_number, days_in_period, amount, start_value, end_value, period_profit, period_profit_rate, net_value, created_at)
@@ -1149,15 +1177,15 @@ func (r *Repository) RecordPartialWithdrawal(
 			period_number,
 			days_in_period,
 			? as amount,
-			start_value * ?,
-			end_value * ?,
-			period_profit * ?,
+			CAST(ROUND(start_value * ?) AS INTEGER),
+			CAST(ROUND(end_value * ?) AS INTEGER),
+			CAST(ROUND(period_profit * ?) AS INTEGER),
 			period_profit_rate,
 			net_value,
 			created_at
 		FROM recharge_monthly_snapshots
 		WHERE recharge_id = ?
-	`, newRechargeID, remainingPrincipal, remainingPrincipal/withdrawPrincipal, remainingPrincipal/withdrawPrincipal, remainingPrincipal/withdrawPrincipal, originalRechargeID)
+	`, newRechargeID, remainingPrincipal, keepRatio, keepRatio, keepRatio, originalRechargeID)
 	
 	if err != nil {
 		// 快照复制失败不影响撤资，只记录日志
@@ -1171,25 +1199,51 @@ func (r *Repository) RecordPartialWithdrawal(
 		VALUES (?, ?, ?, ?, ?, ?, ?, 'partial', ?)
 	`, originalRechargeID, userID, withdrawPrincipal, withdrawAmount, withdrawProfit, withdrawProfitRate, daysHeld, remainingPrincipal)
 	
-	if err != nil {
-		return err
-	}
-	
-	return tx.Commit()
+	return err
 }
 
 // GetWithdrawals 获取用户的撤资记录
 func (r *Repository) GetWithdrawals(userID int) ([]*model.Withdrawal, error) {
+	return r.QueryWithdrawals(model.WithdrawalFilter{UserID: userID})
+}
+
+// withdrawalFilterSQL 撤资记录查询条件（w 为 withdrawals，r 为 recharges）
+func withdrawalFilterSQL(filter model.WithdrawalFilter) (string, []interface{}) {
+	conds := []string{"1 = 1"}
+	var args []interface{}
+	if filter.UserID != 0 {
+		conds = append(conds, "w.user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.AdminAccountID != 0 {
+		conds = append(conds, "r.admin_account_id = ?")
+		args = append(args, filter.AdminAccountID)
+	}
+	if filter.From != "" {
+		conds = append(conds, "date(w.withdrawn_at) >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		conds = append(conds, "date(w.withdrawn_at) <= ?")
+		args = append(args, filter.To)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// QueryWithdrawals 按条件查询撤资记录，最新的在前
+func (r *Repository) QueryWithdrawals(filter model.WithdrawalFilter) ([]*model.Withdrawal, error) {
+	where, args := withdrawalFilterSQL(filter)
 	rows, err := r.db.Query(`
-		SELECT w.id, w.recharge_id, w.original_amount, w.withdrawn_amount, 
-		       w.final_profit, w.final_profit_rate, w.days_held, w.withdrawn_at,
-		       r.currency, r.recharge_at
+		SELECT w.id, w.recharge_id, w.user_id, COALESCE(r.admin_account_id, 0), w.withdrawal_type,
+		       w.original_amount, w.withdrawn_amount, w.final_profit, w.final_profit_rate,
+		       w.remaining_amount, w.days_held, w.withdrawn_at,
+		       COALESCE(r.currency, ''), r.recharge_at
 		FROM withdrawals w
 		LEFT JOIN recharges r ON w.recharge_id = r.id
-		WHERE w.user_id = ?
-		ORDER BY w.withdrawn_at DESC
-	`, userID)
-
+		WHERE `+where+`
+		ORDER BY w.withdrawn_at DESC, w.id DESC`,
+		args...,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -1198,29 +1252,50 @@ func (r *Repository) GetWithdrawals(userID int) ([]*model.Withdrawal, error) {
 	var withdrawals []*model.Withdrawal
 	for rows.Next() {
 		var w model.Withdrawal
+		var rechargeAt sql.NullTime
 		err := rows.Scan(
 			&w.ID,
 			&w.RechargeID,
+			&w.UserID,
+			&w.AdminAccountID,
+			&w.WithdrawalType,
 			&w.OriginalAmount,
 			&w.WithdrawnAmount,
 			&w.FinalProfit,
 			&w.FinalProfitRate,
+			&w.RemainingAmount,
 			&w.DaysHeld,
 			&w.WithdrawnAt,
 			&w.Currency,
-			&w.RechargeAt,
+			&rechargeAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		w.RechargeAt = rechargeAt.Time
 		withdrawals = append(withdrawals, &w)
 	}
 
-	return withdrawals, nil
+	return withdrawals, rows.Err()
+}
+
+// SumWithdrawals 按条件汇总撤资记录
+func (r *Repository) SumWithdrawals(filter model.WithdrawalFilter) (*model.WithdrawalTotals, error) {
+	where, args := withdrawalFilterSQL(filter)
+	totals := &model.WithdrawalTotals{}
+	err := r.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(w.original_amount), 0),
+		       COALESCE(SUM(w.withdrawn_amount), 0), COALESCE(SUM(w.final_profit), 0)
+		FROM withdrawals w
+		LEFT JOIN recharges r ON w.recharge_id = r.id
+		WHERE `+where,
+		args...,
+	).Scan(&totals.Count, &totals.Principal, &totals.Withdrawn, &totals.RealizedProfit)
+	return totals, err
 }
 
 // SaveMonthlySnapshot 保存月度快照
-func (r *Repository) SaveMonthlySnapshot(rechargeID, userID, periodNumber, daysInPeriod int, amount, startValue, endValue, periodProfit, periodProfitRate, netValue float64) error {
+func (r *Repository) SaveMonthlySnapshot(rechargeID, userID, periodNumber, daysInPeriod int, amount, startValue, endValue, periodProfit money.Decimal, periodProfitRate float64, netValue money.Decimal) error {
 	snapshotDate := time.Now().Format("2006-01-02")
 	
 	_, err := r.db.Exec(`
@@ -1237,7 +1312,8 @@ func (r *Repository) GetMonthlySnapshot(rechargeID int, periodNumber int) (map[s
 	var snapshot map[string]interface{}
 	var snapshotDate string
 	var daysInPeriod int
-	var amount, startValue, endValue, periodProfit, periodProfitRate, netValue float64
+	var amount, startValue, endValue, periodProfit, netValue money.Decimal
+	var periodProfitRate float64
 	
 	err := r.db.QueryRow(`
 		SELECT snapshot_date, days_in_period, amount, start_value, end_value, period_profit, period_profit_rate, net_value
@@ -1295,7 +1371,8 @@ func (r *Repository) GetRecentSnapshots(rechargeID int, count int) ([]map[string
 	for rows.Next() {
 		var periodNumber int
 		var snapshotDate string
-		var periodProfit, periodProfitRate, startValue, endValue float64
+		var periodProfit, startValue, endValue money.Decimal
+		var periodProfitRate float64
 		
 		err := rows.Scan(&periodNumber, &snapshotDate, &periodProfit, &periodProfitRate, &startValue, &endValue)
 		if err != nil {
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"crypto-final/internal/vault"
+	"fmt"
+)
+
+// secretColumns 存放交易所密钥和 TOTP 密钥的列（加密存储）
+var secretColumns = []struct{ table, column string }{
+	{"admin_accounts", "api_secret"},
+	{"admin_accounts", "passphrase"},
+	{"users", "api_secret"},
+	{"users", "api_passphrase"},
+	{"users", "totp_secret"},
+}
+
+// RewriteSecrets 在一个事务中用 rewrite 重写所有密钥列的非空值，返回改动的数量
+// context 为该值的存储位置（vault.Context），用于启动时加密旧的明文密钥，以及轮换主密钥；任何一个值失败都整体回滚。
+func (r *Repository) RewriteSecrets(rewrite func(value, context string) (string, error)) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	type row struct {
+		id    int
+		value string
+	}
+
+	changed := 0
+	for _, c := range secretColumns {
+		rows, err := tx.Query(fmt.Sprintf(
+			"SELECT id, %s FROM %s WHERE %s IS NOT NULL AND %s != ''",
+			c.column, c.table, c.column, c.column,
+		))
+		if err != nil {
+			return 0, err
+		}
+		var values []row
+		for rows.Next() {
+			var v row
+			if err := rows.Scan(&v.id, &v.value); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			values = append(values, v)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for _, v := range values {
+			newValue, err := rewrite(v.value, vault.Context(c.table, c.column, v.id))
+			if err != nil {
+				return 0, fmt.Errorf("%s.%s (id=%d): %v", c.table, c.column, v.id, err)
+			}
+			if newValue == v.value {
+				continue
+			}
+			_, err = tx.Exec(
+				fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", c.table, c.column),
+				newValue, v.id,
+			)
+			if err != nil {
+				return 0, err
+			}
+			changed++
+		}
+	}
+
+	return changed, tx.Commit()
+}
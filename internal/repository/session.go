@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"database/sql"
+	"time"
+)
+
+// CreateSession 保存会话
+func (r *Repository) CreateSession(userID int, tokenHash string, expiresAt time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO sessions (user_id, token_hash, expires_at)
+		VALUES (?, ?, ?)`,
+		userID, tokenHash, expiresAt.Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetSessionByTokenHash 按令牌哈希获取会话，不存在时返回 nil
+func (r *Repository) GetSessionByTokenHash(tokenHash string) (*model.Session, error) {
+	session := &model.Session{}
+	var expiresAt, revokedAt int64
+	err := r.db.QueryRow(`
+		SELECT id, user_id, expires_at, revoked_at, created_at
+		FROM sessions
+		WHERE token_hash = ?`,
+		tokenHash,
+	).Scan(&session.ID, &session.UserID, &expiresAt, &revokedAt, &session.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session.ExpiresAt = time.Unix(expiresAt, 0)
+	session.Revoked = revokedAt != 0
+	return session, nil
+}
+
+// RevokeSession 吊销单个会话
+func (r *Repository) RevokeSession(sessionID int) error {
+	_, err := r.db.Exec(`
+		UPDATE sessions
+		SET revoked_at = ?
+		WHERE id = ? AND revoked_at = 0`,
+		time.Now().Unix(), sessionID,
+	)
+	return err
+}
+
+// RevokeUserSessions 吊销用户的所有会话
+func (r *Repository) RevokeUserSessions(userID int) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions
+		SET revoked_at = ?
+		WHERE user_id = ? AND revoked_at = 0`,
+		time.Now().Unix(), userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpiredSessions 清理过期会话
+func (r *Repository) DeleteExpiredSessions() error {
+	_, err := r.db.Exec("DELETE FROM sessions WHERE expires_at < ?", time.Now().Unix())
+	return err
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetTOTPState 获取用户的 TOTP 密钥（密文）、是否启用、最近通过的时间步
+func (r *Repository) GetTOTPState(userID int) (string, bool, int64, error) {
+	var secret string
+	var enabled bool
+	var lastStep int64
+	err := r.db.QueryRow(
+		"SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = ?",
+		userID,
+	).Scan(&secret, &enabled, &lastStep)
+	return secret, enabled, lastStep, err
+}
+
+// SetPendingTOTPSecret 写入待绑定的密钥密文（已启用两步验证时不覆盖）
+func (r *Repository) SetPendingTOTPSecret(userID int, secret string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE id = ? AND totp_enabled = 0",
+		secret, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// EnableTOTP 启用两步验证，并在同一事务中写入恢复码
+func (r *Repository) EnableTOTP(userID int, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE users SET totp_enabled = 1, totp_last_step = ? WHERE id = ?",
+		step, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodesTx(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetSecondFactorLockout 两步验证锁定截止时间（unix秒，0 表示未锁定过）
+func (r *Repository) GetSecondFactorLockout(userID int) (int64, error) {
+	var lockedUntil int64
+	err := r.db.QueryRow(
+		"SELECT totp_locked_until FROM users WHERE id = ?",
+		userID,
+	).Scan(&lockedUntil)
+	return lockedUntil, err
+}
+
+// RecordSecondFactorFailure 连续失败次数加一，达到 maxFailures 时锁定到 lockUntil 并重新计数
+// 返回锁定截止时间（未锁定时为之前的值）。
+func (r *Repository) RecordSecondFactorFailure(userID, maxFailures int, lockUntil int64) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET
+			totp_locked_until = CASE WHEN totp_failed_attempts + 1 >= ? THEN ? ELSE totp_locked_until END,
+			totp_failed_attempts = CASE WHEN totp_failed_attempts + 1 >= ? THEN 0 ELSE totp_failed_attempts + 1 END
+		WHERE id = ?`,
+		maxFailures, lockUntil, maxFailures, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var lockedUntil int64
+	if err := tx.QueryRow("SELECT totp_locked_until FROM users WHERE id = ?", userID).Scan(&lockedUntil); err != nil {
+		return 0, err
+	}
+	return lockedUntil, tx.Commit()
+}
+
+// ResetSecondFactorFailures 验证通过后清零连续失败次数
+func (r *Repository) ResetSecondFactorFailures(userID int) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET totp_failed_attempts = 0 WHERE id = ? AND totp_failed_attempts != 0",
+		userID,
+	)
+	return err
+}
+
+// DisableTOTP 关闭两步验证，清除密钥和恢复码
+func (r *Repository) DisableTOTP(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE users SET totp_secret = '', totp_enabled = 0, totp_last_step = 0, totp_failed_attempts = 0 WHERE id = ?",
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AdvanceTOTPStep 记录通过的时间步，只有比上次大才更新
+// 返回 false 表示该时间步已经用过（验证码重放）。
+func (r *Repository) AdvanceTOTPStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码，旧的全部作废
+func (r *Repository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodesTx(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodesTx(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)",
+			userID, hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode 使用一个恢复码，每个只能用一次
+func (r *Repository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE recovery_codes
+		SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at = 0`,
+		time.Now().Unix(), userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// CountUnusedRecoveryCodes 剩余可用的恢复码数量
+func (r *Repository) CountUnusedRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at = 0",
+		userID,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"database/sql"
+	"time"
+)
+
+// GetChainAddresses 获取某个Wallet账户绑定的全部链上地址
+func (r *Repository) GetChainAddresses(adminAccountID int) ([]model.ChainAddress, error) {
+	rows, err := r.db.Query(`
+		SELECT id, admin_account_id, chain, address, label, created_at
+		FROM wallet_addresses
+		WHERE admin_account_id = ?
+		ORDER BY id ASC`,
+		adminAccountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []model.ChainAddress{}
+	for rows.Next() {
+		var a model.ChainAddress
+		var createdAt int64
+		if err := rows.Scan(&a.ID, &a.AdminAccountID, &a.Chain, &a.Address, &a.Label, &createdAt); err != nil {
+			return nil, err
+		}
+		a.CreatedAt = time.Unix(createdAt, 0)
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// GetChainAddress 按ID获取链上地址，不存在时返回 nil
+func (r *Repository) GetChainAddress(id int) (*model.ChainAddress, error) {
+	a := &model.ChainAddress{}
+	var createdAt int64
+	err := r.db.QueryRow(`
+		SELECT id, admin_account_id, chain, address, label, created_at
+		FROM wallet_addresses WHERE id = ?`,
+		id,
+	).Scan(&a.ID, &a.AdminAccountID, &a.Chain, &a.Address, &a.Label, &createdAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.CreatedAt = time.Unix(createdAt, 0)
+	return a, nil
+}
+
+// AddChainAddress 绑定链上地址；同一账户同一条链上已存在该地址时只更新备注
+func (r *Repository) AddChainAddress(a *model.ChainAddress) error {
+	now := time.Now().Unix()
+	_, err := r.db.Exec(`
+		INSERT INTO wallet_addresses (admin_account_id, chain, address, label, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(admin_account_id, chain, address)
+		DO UPDATE SET label = excluded.label`,
+		a.AdminAccountID, a.Chain, a.Address, a.Label, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	var createdAt int64
+	err = r.db.QueryRow(`
+		SELECT id, created_at FROM wallet_addresses
+		WHERE admin_account_id = ? AND chain = ? AND address = ?`,
+		a.AdminAccountID, a.Chain, a.Address,
+	).Scan(&a.ID, &createdAt)
+	if err != nil {
+		return err
+	}
+	a.CreatedAt = time.Unix(createdAt, 0)
+	return nil
+}
+
+// DeleteChainAddress 解绑链上地址
+func (r *Repository) DeleteChainAddress(id int) error {
+	_, err := r.db.Exec("DELETE FROM wallet_addresses WHERE id = ?", id)
+	return err
+}
+
+// loadChainAddresses 为Wallet账户填充绑定的链上地址
+func (r *Repository) loadChainAddresses(acc *model.AdminAccount) error {
+	if acc.AccountType != "Wallet" {
+		return nil
+	}
+	addresses, err := r.GetChainAddresses(acc.ID)
+	if err != nil {
+		return err
+	}
+	acc.ChainAddresses = addresses
+	return nil
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrWithdrawalStateChanged 撤资申请已不在预期状态（被并发处理或已撤回）
+var ErrWithdrawalStateChanged = errors.New("撤资申请状态已变化，请刷新后重试")
+
+// ErrOpenWithdrawalRequestExists 该充值已有待审核或已批准的申请（idx_withdrawal_requests_open）
+var ErrOpenWithdrawalRequestExists = errors.New("该充值已有未完成的撤资申请")
+
+// withdrawalStatusColumns 各状态对应的时间列
+var withdrawalStatusColumns = map[string]string{
+	model.WithdrawalApproved:  "approved_at",
+	model.WithdrawalSettled:   "settled_at",
+	model.WithdrawalRejected:  "rejected_at",
+	model.WithdrawalCancelled: "cancelled_at",
+}
+
+const withdrawalRequestColumns = `
+	id, user_id, recharge_id, admin_account_id, currency, principal, status, note, reject_reason, reviewed_by,
+	created_at, approved_at, settled_at, rejected_at, cancelled_at,
+	settled_nav_date, settled_nav, settled_shares, settled_amount, settled_profit`
+
+// CreateWithdrawalRequest 保存待审核的撤资申请
+// 同一笔充值已有未完成的申请时返回 ErrOpenWithdrawalRequestExists。
+func (r *Repository) CreateWithdrawalRequest(req *model.WithdrawalRequest) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO withdrawal_requests (user_id, recharge_id, admin_account_id, currency, principal, status, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.UserID, req.RechargeID, req.AdminAccountID, req.Currency, req.Principal, model.WithdrawalPending, req.Note, now.Unix(),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrOpenWithdrawalRequestExists
+		}
+		return err
+	}
+
+	id, _ := result.LastInsertId()
+	req.ID = int(id)
+	req.Status = model.WithdrawalPending
+	req.CreatedAt = time.Unix(now.Unix(), 0)
+	return nil
+}
+
+// GetWithdrawalRequest 获取撤资申请，不存在时返回 nil
+func (r *Repository) GetWithdrawalRequest(id int) (*model.WithdrawalRequest, error) {
+	row := r.db.QueryRow("SELECT "+withdrawalRequestColumns+" FROM withdrawal_requests WHERE id = ?", id)
+	req, err := scanWithdrawalRequest(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return req, err
+}
+
+// ListWithdrawalRequests 查询撤资申请，userID 为0不限用户，status 为空不限状态，最新的在前
+func (r *Repository) ListWithdrawalRequests(userID int, status string) ([]*model.WithdrawalRequest, error) {
+	var conds []string
+	var args []interface{}
+	if userID != 0 {
+		conds = append(conds, "user_id = ?")
+		args = append(args, userID)
+	}
+	if status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, status)
+	}
+
+	query := "SELECT " + withdrawalRequestColumns + " FROM withdrawal_requests"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC"
+
+	return r.queryWithdrawalRequests(query, args...)
+}
+
+// GetApprovedWithdrawalRequests 某账户某币种已批准、等待结算的申请，按批准顺序
+func (r *Repository) GetApprovedWithdrawalRequests(adminAccountID int, currency string) ([]*model.WithdrawalRequest, error) {
+	return r.queryWithdrawalRequests(
+		"SELECT "+withdrawalRequestColumns+` FROM withdrawal_requests
+		WHERE status = ? AND admin_account_id = ? AND currency = ?
+		ORDER BY approved_at, id`,
+		model.WithdrawalApproved, adminAccountID, currency,
+	)
+}
+
+// CountOpenWithdrawalRequests 某笔充值尚未结束（待审核或已批准）的申请数
+func (r *Repository) CountOpenWithdrawalRequests(rechargeID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM withdrawal_requests WHERE recharge_id = ? AND status IN (?, ?)",
+		rechargeID, model.WithdrawalPending, model.WithdrawalApproved,
+	).Scan(&count)
+	return count, err
+}
+
+// TransitionWithdrawalRequest 把申请从 from 中的某个状态改为 to，并记录该状态的时间
+// 申请已不在 from 中时返回 ErrWithdrawalStateChanged。
+func (r *Repository) TransitionWithdrawalRequest(id int, from []string, to string, reviewedBy int, rejectReason string) error {
+	column, ok := withdrawalStatusColumns[to]
+	if !ok || to == model.WithdrawalSettled {
+		return fmt.Errorf("不支持的状态变更: %s", to)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(from)), ", ")
+	args := []interface{}{to, time.Now().Unix(), rejectReason}
+	setReviewer := ""
+	if reviewedBy != 0 {
+		setReviewer = ", reviewed_by = ?"
+		args = append(args, reviewedBy)
+	}
+	args = append(args, id)
+	for _, s := range from {
+		args = append(args, s)
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE withdrawal_requests
+		SET status = ?, `+column+` = ?, reject_reason = ?`+setReviewer+`
+		WHERE id = ? AND status IN (`+placeholders+`)`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWithdrawalStateChanged
+	}
+	return nil
+}
+
+// SettleWithdrawalRequest 按定价结果完成撤资，并把已批准的申请标记为已结算（同一事务）
+// fee 为撤资前计提的业绩报酬，nil 表示不收取。
+func (r *Repository) SettleWithdrawalRequest(id int, fee *model.PerformanceFee, rd *model.Redemption) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE withdrawal_requests
+		SET status = ?, settled_at = ?, settled_nav_date = ?, settled_nav = ?,
+		    settled_shares = ?, settled_amount = ?, settled_profit = ?
+		WHERE id = ? AND status = ?`,
+		model.WithdrawalSettled, time.Now().Unix(), rd.NAVDate, rd.NAV,
+		rd.Shares, rd.Amount, rd.Profit,
+		id, model.WithdrawalApproved,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWithdrawalStateChanged
+	}
+
+	if fee != nil {
+		if err := chargePerformanceFeeTx(tx, fee); err != nil {
+			return err
+		}
+	}
+	if err := recordRedemptionTx(tx, rd); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) queryWithdrawalRequests(query string, args ...interface{}) ([]*model.WithdrawalRequest, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*model.WithdrawalRequest
+	for rows.Next() {
+		req, err := scanWithdrawalRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// scanWithdrawalRequest 按 withdrawalRequestColumns 的顺序读取一行
+func scanWithdrawalRequest(row interface{ Scan(...interface{}) error }) (*model.WithdrawalRequest, error) {
+	req := &model.WithdrawalRequest{}
+	var createdAt, approvedAt, settledAt, rejectedAt, cancelledAt int64
+	err := row.Scan(
+		&req.ID, &req.UserID, &req.RechargeID, &req.AdminAccountID, &req.Currency, &req.Principal,
+		&req.Status, &req.Note, &req.RejectReason, &req.ReviewedBy,
+		&createdAt, &approvedAt, &settledAt, &rejectedAt, &cancelledAt,
+		&req.SettledNAVDate, &req.SettledNAV, &req.SettledShares, &req.SettledAmount, &req.SettledProfit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.CreatedAt = time.Unix(createdAt, 0)
+	req.ApprovedAt = unixTimePtr(approvedAt)
+	req.SettledAt = unixTimePtr(settledAt)
+	req.RejectedAt = unixTimePtr(rejectedAt)
+	req.CancelledAt = unixTimePtr(cancelledAt)
+	return req, nil
+}
+
+// unixTimePtr 0 表示没有时间
+func unixTimePtr(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0)
+	return &t
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"crypto-final/internal/model"
+	"errors"
+	"testing"
+)
+
+func newWithdrawalRequest(rechargeID int) *model.WithdrawalRequest {
+	return &model.WithdrawalRequest{UserID: 2, RechargeID: rechargeID, AdminAccountID: 1, Currency: "USDT", Principal: units(100)}
+}
+
+// TestOneOpenWithdrawalRequestPerRecharge 唯一索引保证同一笔充值只有一个未完成的申请
+func TestOneOpenWithdrawalRequestPerRecharge(t *testing.T) {
+	r := newTestRepository(t)
+
+	first := newWithdrawalRequest(5)
+	if err := r.CreateWithdrawalRequest(first); err != nil {
+		t.Fatalf("CreateWithdrawalRequest: %v", err)
+	}
+	if err := r.CreateWithdrawalRequest(newWithdrawalRequest(5)); !errors.Is(err, ErrOpenWithdrawalRequestExists) {
+		t.Fatalf("重复的待审核申请 err = %v, want ErrOpenWithdrawalRequestExists", err)
+	}
+
+	// 已批准的申请同样占用
+	if err := r.TransitionWithdrawalRequest(first.ID, []string{model.WithdrawalPending}, model.WithdrawalApproved, 1, ""); err != nil {
+		t.Fatalf("批准: %v", err)
+	}
+	if err := r.CreateWithdrawalRequest(newWithdrawalRequest(5)); !errors.Is(err, ErrOpenWithdrawalRequestExists) {
+		t.Fatalf("已批准后再提交 err = %v, want ErrOpenWithdrawalRequestExists", err)
+	}
+
+	// 其他充值不受影响；结束（撤回）后可以重新提交
+	if err := r.CreateWithdrawalRequest(newWithdrawalRequest(6)); err != nil {
+		t.Fatalf("其他充值: %v", err)
+	}
+	if err := r.TransitionWithdrawalRequest(first.ID, []string{model.WithdrawalApproved}, model.WithdrawalCancelled, 0, ""); err != nil {
+		t.Fatalf("撤回: %v", err)
+	}
+	if err := r.CreateWithdrawalRequest(newWithdrawalRequest(5)); err != nil {
+		t.Fatalf("撤回后重新提交: %v", err)
+	}
+}
+
+// TestMigrateOpenWithdrawalRequestIndex 建索引前已有的重复申请只保留最早的一个
+func TestMigrateOpenWithdrawalRequestIndex(t *testing.T) {
+	r := newTestRepository(t)
+	if _, err := r.db.Exec("DROP INDEX idx_withdrawal_requests_open"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := r.CreateWithdrawalRequest(newWithdrawalRequest(5)); err != nil {
+			t.Fatalf("CreateWithdrawalRequest: %v", err)
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateOpenWithdrawalRequestIndex(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("migrateOpenWithdrawalRequestIndex: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := r.ListWithdrawalRequests(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := 0
+	for _, req := range requests {
+		switch req.Status {
+		case model.WithdrawalPending:
+			open++
+			if req.ID != 1 {
+				t.Errorf("保留了申请 #%d, want 最早的 #1", req.ID)
+			}
+		case model.WithdrawalRejected:
+			if req.RejectReason == "" {
+				t.Errorf("申请 #%d 被拒绝但没有原因", req.ID)
+			}
+		}
+	}
+	if open != 1 {
+		t.Errorf("未完成的申请 %d 个, want 1", open)
+	}
+	if err := r.CreateWithdrawalRequest(newWithdrawalRequest(5)); !errors.Is(err, ErrOpenWithdrawalRequestExists) {
+		t.Errorf("迁移后 err = %v, want ErrOpenWithdrawalRequestExists", err)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// RecordAudit 记录一条管理操作，before/after 为操作前后的快照（nil 表示不适用）
+func (s *Service) RecordAudit(actor *model.User, action, targetType, targetID string, before, after interface{}, ip string) error {
+	event := &model.AuditEvent{
+		ActorID:    actor.ID,
+		ActorName:  actor.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         ip,
+	}
+	if event.ActorName == "" {
+		event.ActorName = actor.Phone
+	}
+
+	var err error
+	if event.Before, err = auditSnapshot(before); err != nil {
+		return fmt.Errorf("序列化操作前快照失败: %v", err)
+	}
+	if event.After, err = auditSnapshot(after); err != nil {
+		return fmt.Errorf("序列化操作后快照失败: %v", err)
+	}
+
+	if err := s.repo.InsertAuditEvent(event); err != nil {
+		return fmt.Errorf("写入审计记录失败: %v", err)
+	}
+	return nil
+}
+
+// auditSnapshot 序列化快照，nil（包括空指针）记为空
+func auditSnapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetAuditEvents 按条件查询审计记录，默认每页50条，最多500条
+func (s *Service) GetAuditEvents(filter model.AuditFilter) ([]*model.AuditEvent, int, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.QueryAuditEvents(filter)
+}
+
+// GetRecharge 获取充值记录，不存在时返回 nil
+func (s *Service) GetRecharge(rechargeID int) (*model.Recharge, error) {
+	return s.repo.GetRechargeByID(rechargeID)
+}
+
+// AccountConfigSnapshot Admin账户配置的审计快照
+// 不记录密钥本身：API Key 只保留首尾，Secret/Passphrase 只记录是否已设置。
+func (s *Service) AccountConfigSnapshot(accountType string) (map[string]interface{}, error) {
+	account, err := s.repo.GetAdminAccountByType(accountType)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, nil
+	}
+	snapshot := map[string]interface{}{
+		"account_type":   account.AccountType,
+		"api_key":        maskAPIKey(account.APIKey),
+		"api_secret_set": account.APISecret != "",
+		"passphrase_set": account.Passphrase != "",
+		"wallet_address": account.WalletAddress,
+	}
+	if len(account.ChainAddresses) > 0 {
+		snapshot["chain_addresses"] = account.ChainAddresses
+	}
+	return snapshot, nil
+}
+
+// maskAPIKey 只保留 API Key 的前4位和后4位
+func maskAPIKey(key string) string {
+	if len(key) <= 8 {
+		if key == "" {
+			return ""
+		}
+		return "****"
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"testing"
+)
+
+// TestRecordAudit 记录操作人和前后快照，空指针快照记为空；没有用户名时用手机号
+func TestRecordAudit(t *testing.T) {
+	s := newTestService(t, nil)
+	actor := &model.User{ID: 7, Phone: "13800000000"}
+
+	var missing *model.Recharge
+	after := map[string]interface{}{"amount": 100}
+	if err := s.RecordAudit(actor, "recharge.create", "recharge", "5", missing, after, "10.0.0.1"); err != nil {
+		t.Fatalf("RecordAudit: %v", err)
+	}
+
+	events, total, err := s.GetAuditEvents(model.AuditFilter{})
+	if err != nil || total != 1 || len(events) != 1 {
+		t.Fatalf("GetAuditEvents = %v, %d, %v", events, total, err)
+	}
+	e := events[0]
+	if e.ActorID != 7 || e.ActorName != "13800000000" || e.Action != "recharge.create" || e.TargetID != "5" || e.IP != "10.0.0.1" {
+		t.Errorf("审计记录 = %+v", e)
+	}
+	if len(e.Before) != 0 || string(e.After) != `{"amount":100}` {
+		t.Errorf("快照 = %q → %q, want 空 → {\"amount\":100}", e.Before, e.After)
+	}
+
+	if err := s.RecordAudit(actor, "bad", "x", "1", nil, make(chan int), ""); err == nil {
+		t.Error("无法序列化的快照应该返回错误")
+	}
+}
+
+// TestAccountConfigSnapshotMasksSecrets 审计快照不包含密钥明文
+func TestAccountConfigSnapshotMasksSecrets(t *testing.T) {
+	s := newTestService(t, nil)
+	if err := s.repo.UpdateAdminAccountConfig("Binance", "binance-api-key-1234", "sealed-secret", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := s.AccountConfigSnapshot("Binance")
+	if err != nil {
+		t.Fatalf("AccountConfigSnapshot: %v", err)
+	}
+	if snapshot["api_key"] != "bina****1234" || snapshot["api_secret_set"] != true || snapshot["passphrase_set"] != false {
+		t.Errorf("Binance 快照 = %v", snapshot)
+	}
+	for _, v := range snapshot {
+		if v == "sealed-secret" {
+			t.Error("快照包含 Secret")
+		}
+	}
+
+	if missing, err := s.AccountConfigSnapshot("Kraken"); err != nil || missing != nil {
+		t.Errorf("不存在的账户 = %v, %v, want nil", missing, err)
+	}
+
+	for key, want := range map[string]string{"": "", "short": "****", "12345678": "****", "123456789": "1234****6789"} {
+		if got := maskAPIKey(key); got != want {
+			t.Errorf("maskAPIKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+// TestGetAuditEventsLimit 默认每页50条，最多500条
+func TestGetAuditEventsLimit(t *testing.T) {
+	s := newTestService(t, nil)
+	actor := &model.User{ID: 1, Username: "admin"}
+	for i := 0; i < defaultAuditLimit+1; i++ {
+		if err := s.RecordAudit(actor, "user.create", "user", "1", nil, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if events, total, _ := s.GetAuditEvents(model.AuditFilter{}); len(events) != defaultAuditLimit || total != defaultAuditLimit+1 {
+		t.Errorf("默认分页 %d 条 (共%d), want %d", len(events), total, defaultAuditLimit)
+	}
+	if events, _, _ := s.GetAuditEvents(model.AuditFilter{Limit: maxAuditLimit + 1, Offset: -1}); len(events) != defaultAuditLimit+1 {
+		t.Errorf("超过上限时 %d 条, want 全部 %d 条", len(events), defaultAuditLimit+1)
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// benchmarkPeriods 基准比较的区间，与Dashboard汇总的月/季/年化一致，另有成立以来
+var benchmarkPeriods = []struct {
+	name string
+	days int
+}{
+	{model.BenchmarkPeriod30d, 30},
+	{model.BenchmarkPeriod90d, 90},
+	{model.BenchmarkPeriod365d, 365},
+}
+
+// benchmarkBackfillDays 还没有任何净值、余额记录时首次补录的天数
+const benchmarkBackfillDays = 365
+
+// SetPriceSource 替换基准行情来源（离线测试或接入其他行情）
+func (s *Service) SetPriceSource(source PriceSource) {
+	s.priceSource = source
+}
+
+// GetBenchmarks 全部基准及最近记录的价格
+func (s *Service) GetBenchmarks() ([]*model.Benchmark, error) {
+	return s.repo.GetBenchmarks(false)
+}
+
+// GetBenchmark 按代码获取基准，不存在时返回 nil
+func (s *Service) GetBenchmark(symbol string) (*model.Benchmark, error) {
+	return s.repo.GetBenchmark(strings.ToUpper(strings.TrimSpace(symbol)))
+}
+
+// SetBenchmark 新增或修改基准；更换行情交易对时清空已记录的价格，启用的基准立即补录
+func (s *Service) SetBenchmark(symbol, name, sourceSymbol string, enabled bool) (*model.Benchmark, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	sourceSymbol = strings.ToUpper(strings.TrimSpace(sourceSymbol))
+	if symbol == "" || sourceSymbol == "" {
+		return nil, errors.New("基准代码和行情交易对不能为空")
+	}
+	before, err := s.repo.GetBenchmark(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不填名称时沿用原来的名称
+	name = strings.TrimSpace(name)
+	if name == "" && before != nil {
+		name = before.Name
+	}
+	if name == "" {
+		name = symbol
+	}
+
+	benchmark := &model.Benchmark{
+		Symbol:       symbol,
+		Name:         name,
+		SourceSymbol: sourceSymbol,
+		Enabled:      enabled,
+	}
+	if err := s.repo.SetBenchmark(benchmark); err != nil {
+		return nil, fmt.Errorf("保存基准失败: %v", err)
+	}
+	if before != nil && before.SourceSymbol != sourceSymbol {
+		if err := s.repo.DeleteBenchmarkPrices(symbol); err != nil {
+			return nil, fmt.Errorf("清空基准价格失败: %v", err)
+		}
+	}
+	fmt.Printf("✓ 基准已设置: %s (%s) 启用=%v\n", symbol, sourceSymbol, enabled)
+
+	if enabled {
+		if err := s.recordBenchmarkPrices(benchmark); err != nil {
+			fmt.Printf("⚠️  记录基准%s价格失败: %v\n", symbol, err)
+		}
+	}
+	return benchmark, nil
+}
+
+// RecordBenchmarkPrices 把所有启用的基准的每日价格补录到今天（每日余额检查后执行）
+func (s *Service) RecordBenchmarkPrices() {
+	benchmarks, err := s.repo.GetBenchmarks(true)
+	if err != nil {
+		fmt.Printf("⚠️  读取基准失败: %v\n", err)
+		return
+	}
+	for _, b := range benchmarks {
+		if err := s.recordBenchmarkPrices(b); err != nil {
+			fmt.Printf("⚠️  记录基准%s价格失败: %v\n", b.Symbol, err)
+		}
+	}
+}
+
+// recordBenchmarkPrices 从最近一次记录的次日补到今天；从未记录时从最早的净值/余额记录日开始
+func (s *Service) recordBenchmarkPrices(b *model.Benchmark) error {
+	if s.priceSource == nil {
+		return errors.New("未配置行情来源")
+	}
+
+	today := time.Now()
+	from := today.AddDate(0, 0, -benchmarkBackfillDays)
+	start := b.LatestPriceDate
+	if start == "" {
+		earliest, err := s.repo.GetEarliestRecordDate()
+		if err != nil {
+			return err
+		}
+		start = earliest
+	}
+	if start != "" {
+		t, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			return fmt.Errorf("日期格式错误: %s", start)
+		}
+		from = t
+		if b.LatestPriceDate != "" {
+			from = t.AddDate(0, 0, 1)
+		}
+	}
+	if from.Format("2006-01-02") > today.Format("2006-01-02") {
+		return nil
+	}
+
+	prices, err := s.priceSource.DailyPrices(b.SourceSymbol, from, today)
+	if err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+
+	records := make([]*model.BenchmarkPrice, 0, len(prices))
+	for date, price := range prices {
+		records = append(records, &model.BenchmarkPrice{Symbol: b.Symbol, PriceDate: date, Price: price})
+	}
+	if err := s.repo.SaveBenchmarkPrices(records); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 基准%s: 记录 %d 天价格\n", b.Symbol, len(records))
+	return nil
+}
+
+// GetAccountBenchmarkReports 各Admin账户相对各基准的表现
+func (s *Service) GetAccountBenchmarkReports() ([]*model.BenchmarkReport, error) {
+	benchmarks, prices, err := s.loadBenchmarkPrices()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.GetAllAdminAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*model.BenchmarkReport
+	for _, account := range accounts {
+		series, err := s.accountReturnSeries(account)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", account.AccountType, err)
+		}
+		report := benchmarkReport(series, benchmarks, prices)
+		report.Scope = model.RiskScopeAccount
+		report.ID = account.ID
+		report.Name = account.AccountType
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// GetUserBenchmarkReport 某用户持仓组合相对各基准的表现
+func (s *Service) GetUserBenchmarkReport(userID int) (*model.BenchmarkReport, error) {
+	benchmarks, prices, err := s.loadBenchmarkPrices()
+	if err != nil {
+		return nil, err
+	}
+	user, series, err := s.userReturnSeries(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := benchmarkReport(series, benchmarks, prices)
+	report.Scope = model.RiskScopeUser
+	report.ID = user.ID
+	report.Name = user.Phone
+	return report, nil
+}
+
+// loadBenchmarkPrices 启用的基准及其全部价格
+func (s *Service) loadBenchmarkPrices() ([]*model.Benchmark, map[string]priceHistory, error) {
+	benchmarks, err := s.repo.GetBenchmarks(true)
+	if err != nil {
+		return nil, nil, err
+	}
+	prices := make(map[string]priceHistory)
+	for _, b := range benchmarks {
+		history, err := s.repo.GetBenchmarkPrices(b.Symbol)
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取基准%s价格失败: %v", b.Symbol, err)
+		}
+		prices[b.Symbol] = history
+	}
+	return benchmarks, prices, nil
+}
+
+// priceHistory 按日期升序的基准价格
+type priceHistory []*model.BenchmarkPrice
+
+// on 某天（含）之前最近一次记录的价格
+func (h priceHistory) on(date string) (money.Decimal, bool) {
+	i := sort.Search(len(h), func(i int) bool { return h[i].PriceDate > date })
+	if i == 0 {
+		return 0, false
+	}
+	return h[i-1].Price, true
+}
+
+// benchmarkReport 组合在各区间相对各基准的表现；序列没有覆盖整个区间、或缺少基准价格时跳过该项
+func benchmarkReport(series *returnSeries, benchmarks []*model.Benchmark, prices map[string]priceHistory) *model.BenchmarkReport {
+	report := &model.BenchmarkReport{
+		Source:      series.source,
+		Comparisons: []*model.BenchmarkComparison{},
+	}
+	for _, b := range benchmarks {
+		for _, period := range benchmarkPeriods {
+			window, covered := series.window(period.days)
+			if !covered {
+				continue
+			}
+			if c := compareBenchmark(window, prices[b.Symbol]); c != nil {
+				c.Benchmark = b.Symbol
+				c.Period = period.name
+				report.Comparisons = append(report.Comparisons, c)
+			}
+		}
+		if c := compareBenchmark(series, prices[b.Symbol]); c != nil {
+			c.Benchmark = b.Symbol
+			c.Period = model.BenchmarkPeriodInception
+			report.Comparisons = append(report.Comparisons, c)
+		}
+	}
+	return report
+}
+
+// compareBenchmark 组合和买入持有基准在同一区间的表现
+// 每个日收益率样本对应的基准收益按样本的起止日期取价；
+// 贝塔 = cov(组合, 基准) / var(基准)，跟踪误差 = 日超额收益的标准差 × √365。
+func compareBenchmark(series *returnSeries, prices priceHistory) *model.BenchmarkComparison {
+	n := len(series.daily)
+	if n == 0 {
+		return nil
+	}
+	startPrice, ok := prices.on(series.base)
+	if !ok || startPrice.Sign() <= 0 {
+		return nil
+	}
+
+	c := &model.BenchmarkComparison{
+		StartDate: series.base,
+		EndDate:   series.daily[n-1].Date,
+		Days:      n,
+	}
+
+	growth := 1.0
+	portfolio := make([]float64, 0, n)
+	benchmark := make([]float64, 0, n)
+	prev := startPrice
+	for _, d := range series.daily {
+		growth *= 1 + d.Return
+		price, _ := prices.on(d.Date)
+		portfolio = append(portfolio, d.Return)
+		benchmark = append(benchmark, money.Ratio(price, prev)-1)
+		prev = price
+	}
+	c.PortfolioReturn = (growth - 1) * 100
+	c.BenchmarkReturn = (money.Ratio(prev, startPrice) - 1) * 100
+	c.ExcessReturn = c.PortfolioReturn - c.BenchmarkReturn
+	if n < 2 {
+		return c
+	}
+
+	meanP, meanB := mean(portfolio), mean(benchmark)
+	cov, varB := 0.0, 0.0
+	excess := make([]float64, n)
+	for i := range portfolio {
+		cov += (portfolio[i] - meanP) * (benchmark[i] - meanB)
+		varB += (benchmark[i] - meanB) * (benchmark[i] - meanB)
+		excess[i] = portfolio[i] - benchmark[i]
+	}
+	if varB > 0 {
+		beta := cov / varB
+		c.Beta = &beta
+	}
+
+	meanE := mean(excess)
+	variance := 0.0
+	for _, e := range excess {
+		variance += (e - meanE) * (e - meanE)
+	}
+	trackingError := math.Sqrt(variance/float64(n-1)) * math.Sqrt(365) * 100
+	c.TrackingError = &trackingError
+	return c
+}
+
+// mean 算术平均
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"math"
+	"testing"
+	"time"
+)
+
+func testPrices(points ...string) priceHistory {
+	var h priceHistory
+	for i := 0; i+1 < len(points); i += 2 {
+		h = append(h, &model.BenchmarkPrice{Symbol: "BTC", PriceDate: points[i], Price: money.MustParse(points[i+1])})
+	}
+	return h
+}
+
+// TestPriceHistoryOn 取当天或之前最近一次的价格
+func TestPriceHistoryOn(t *testing.T) {
+	h := testPrices("2024-01-01", "100", "2024-01-03", "120")
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2024-01-01", "100"},
+		{"2024-01-02", "100"}, // 缺一天沿用前一天
+		{"2024-01-03", "120"},
+		{"2024-02-01", "120"},
+	}
+	for _, tt := range tests {
+		if got, ok := h.on(tt.date); !ok || got != money.MustParse(tt.want) {
+			t.Errorf("on(%s) = %s, %v, want %s", tt.date, got, ok, tt.want)
+		}
+	}
+	if _, ok := h.on("2023-12-31"); ok {
+		t.Error("最早价格之前应该取不到")
+	}
+}
+
+// TestCompareBenchmarkBetaAndTrackingError 组合日收益正好是基准的2倍：贝塔为2，日超额收益等于基准日收益
+func TestCompareBenchmarkBetaAndTrackingError(t *testing.T) {
+	series := &returnSeries{
+		base: "2024-01-01",
+		daily: []dailyReturn{
+			{Date: "2024-01-02", Return: 0.20},
+			{Date: "2024-01-03", Return: -0.10},
+			{Date: "2024-01-04", Return: 0.04},
+		},
+	}
+	// 基准 +10%、-5%、+2%
+	prices := testPrices("2024-01-01", "100", "2024-01-02", "110", "2024-01-03", "104.5", "2024-01-04", "106.59")
+
+	c := compareBenchmark(series, prices)
+	if c == nil {
+		t.Fatal("compareBenchmark = nil")
+	}
+	if c.StartDate != "2024-01-01" || c.EndDate != "2024-01-04" || c.Days != 3 {
+		t.Errorf("区间 = %s ~ %s (%d天)", c.StartDate, c.EndDate, c.Days)
+	}
+	wantPortfolio := (1.2*0.9*1.04 - 1) * 100
+	if math.Abs(c.PortfolioReturn-wantPortfolio) > 1e-9 || math.Abs(c.BenchmarkReturn-6.59) > 1e-9 ||
+		math.Abs(c.ExcessReturn-(wantPortfolio-6.59)) > 1e-9 {
+		t.Errorf("收益 组合 %.4f 基准 %.4f 超额 %.4f", c.PortfolioReturn, c.BenchmarkReturn, c.ExcessReturn)
+	}
+	if c.Beta == nil || math.Abs(*c.Beta-2) > 1e-9 {
+		t.Errorf("贝塔 = %v, want 2", c.Beta)
+	}
+	benchmark := []float64{0.10, -0.05, 0.02}
+	m := mean(benchmark)
+	variance := 0.0
+	for _, b := range benchmark {
+		variance += (b - m) * (b - m)
+	}
+	wantTE := math.Sqrt(variance/2) * math.Sqrt(365) * 100
+	if c.TrackingError == nil || math.Abs(*c.TrackingError-wantTE) > 1e-9 {
+		t.Errorf("跟踪误差 = %v, want %.6f", c.TrackingError, wantTE)
+	}
+
+	// 基准没有波动：没有贝塔
+	flat := testPrices("2024-01-01", "100")
+	if c := compareBenchmark(series, flat); c == nil || c.Beta != nil || c.BenchmarkReturn != 0 {
+		t.Errorf("基准价格不变 = %+v, want 没有贝塔", c)
+	}
+	// 起点之前没有基准价格：跳过
+	if c := compareBenchmark(series, testPrices("2024-01-02", "110")); c != nil {
+		t.Errorf("缺少起点价格 = %+v, want nil", c)
+	}
+}
+
+// TestBenchmarkReportSkipsUncoveredPeriods 序列没有覆盖的区间不比较，成立以来总是比较
+func TestBenchmarkReportSkipsUncoveredPeriods(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := &returnSeries{base: start.Format("2006-01-02"), source: model.RiskSourceNAV}
+	prices := testPrices(start.Format("2006-01-02"), "100")
+	for i := 1; i <= 40; i++ {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		series.daily = append(series.daily, dailyReturn{Date: date, Return: 0.001})
+		prices = append(prices, &model.BenchmarkPrice{Symbol: "BTC", PriceDate: date, Price: money.FromInt(int64(100 + i))})
+	}
+
+	report := benchmarkReport(series, []*model.Benchmark{{Symbol: "BTC"}}, map[string]priceHistory{"BTC": prices})
+	var periods []string
+	for _, c := range report.Comparisons {
+		periods = append(periods, c.Period)
+	}
+	if report.Source != model.RiskSourceNAV || len(periods) != 2 ||
+		periods[0] != model.BenchmarkPeriod30d || periods[1] != model.BenchmarkPeriodInception {
+		t.Errorf("比较区间 = %v, want [30d inception]", periods)
+	}
+	if len(report.Comparisons) > 0 && report.Comparisons[0].Days != 30 {
+		t.Errorf("30天区间样本 %d 个, want 30", report.Comparisons[0].Days)
+	}
+}
+
+// TestRecordBenchmarkPrices 价格日期 D 取前一天日K线的收盘价，再次记录只补新的日期
+func TestRecordBenchmarkPrices(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	s := newTestService(t, ws)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	mock.SetDailyClose("BTCUSDT", today.AddDate(0, 0, -3), 40000)
+	mock.SetDailyClose("BTCUSDT", today.AddDate(0, 0, -2), 42000)
+
+	if _, err := s.SetBenchmark("btc", "Bitcoin", "btcusdt", true); err != nil {
+		t.Fatalf("SetBenchmark: %v", err)
+	}
+	prices, err := s.repo.GetBenchmarkPrices("BTC")
+	if err != nil || len(prices) != 2 {
+		t.Fatalf("基准价格 = %v, %v", prices, err)
+	}
+	if prices[0].PriceDate != today.AddDate(0, 0, -2).Format("2006-01-02") || prices[0].Price != money.FromInt(40000) {
+		t.Errorf("第一天 = %s $%s, want 前一天的收盘价 40000", prices[0].PriceDate, prices[0].Price)
+	}
+
+	mock.SetDailyClose("BTCUSDT", today.AddDate(0, 0, -1), 43000)
+	s.RecordBenchmarkPrices()
+	prices, _ = s.repo.GetBenchmarkPrices("BTC")
+	if len(prices) != 3 || prices[2].Price != money.FromInt(43000) || prices[2].PriceDate != today.Format("2006-01-02") {
+		t.Errorf("补录后 = %d 条, 最后 %+v", len(prices), prices[len(prices)-1])
+	}
+}
@@ -0,0 +1,668 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binanceAdapter Binance交易所适配器（现货 + U本位 + 币本位合约）
+type binanceAdapter struct {
+	httpClient     *http.Client
+	spotURL        string // 现货 api.binance.com
+	futuresURL     string // U本位合约 fapi.binance.com
+	coinFuturesURL string // 币本位合约 dapi.binance.com
+}
+
+// ==================== Binance API ====================
+
+func (a *binanceAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置Binance API Key")
+	}
+
+	totalBalance := money.Zero
+
+	// 1. 获取现货账户余额
+	spotBalance, err := a.getSpotBalance(account)
+	if err != nil {
+		fmt.Printf("  ⚠️  获取Binance现货余额失败: %v\n", err)
+	} else {
+		totalBalance += spotBalance
+		if spotBalance > 0 {
+			fmt.Printf("  Binance 现货账户: $%.2f\n", spotBalance)
+		}
+	}
+
+	// 2. 获取USDⓈ-M永续合约余额（智能合约）
+	futuresBalance, err := a.getFuturesBalance(account)
+	if err != nil {
+		fmt.Printf("  ⚠️  获取Binance USDⓈ-M合约失败: %v\n", err)
+	} else {
+		totalBalance += futuresBalance
+		if futuresBalance > 0 {
+			fmt.Printf("  Binance USDⓈ-M合约: $%.2f\n", futuresBalance)
+		}
+	}
+
+	// 3. 获取币本位合约余额
+	coinFuturesBalance, err := a.getCoinFuturesBalance(account)
+	if err != nil {
+		fmt.Printf("  ⚠️  获取Binance币本位合约失败: %v\n", err)
+	} else {
+		totalBalance += coinFuturesBalance
+		if coinFuturesBalance > 0 {
+			fmt.Printf("  Binance 币本位合约: $%.2f\n", coinFuturesBalance)
+		}
+	}
+
+	fmt.Printf("  ✓ Binance 总余额: $%.2f\n", totalBalance)
+	return totalBalance, nil
+}
+
+// getSpotBalance 获取现货账户余额
+func (a *binanceAdapter) getSpotBalance(account *model.AdminAccount) (money.Decimal, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.spotURL + "/api/v3/account?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Balances []struct {
+			Asset  string `json:"asset"`
+			Free   string `json:"free"`
+			Locked string `json:"locked"`
+		} `json:"balances"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	totalBalance := money.Zero
+	for _, balance := range result.Balances {
+		if balance.Asset == "USDC" || balance.Asset == "USDT" {
+			free := parseAmount(balance.Free)
+			locked := parseAmount(balance.Locked)
+			assetTotal := free + locked
+
+			if assetTotal > 0 {
+				totalBalance += assetTotal
+				fmt.Printf("    现货 %s: %.2f (可用: %.2f, 锁定: %.2f)\n",
+					balance.Asset, assetTotal, free, locked)
+			}
+		}
+	}
+
+	return totalBalance, nil
+}
+
+// getFuturesBalance 获取USDT永续合约账户余额
+func (a *binanceAdapter) getFuturesBalance(account *model.AdminAccount) (money.Decimal, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.futuresURL + "/fapi/v2/balance?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Asset              string `json:"asset"`
+		Balance            string `json:"balance"`
+		CrossWalletBalance string `json:"crossWalletBalance"`
+		CrossUnPnl         string `json:"crossUnPnl"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	totalBalance := money.Zero
+	// 同时统计USDT和USDC
+	for _, balance := range result {
+		if balance.Asset == "USDC" || balance.Asset == "USDT" {
+			// 钱包余额
+			walletBalance := parseAmount(balance.CrossWalletBalance)
+			// 未实现盈亏
+			unrealizedPnl := parseAmount(balance.CrossUnPnl)
+			// 总权益 = 钱包余额 + 未实现盈亏
+			equity := walletBalance + unrealizedPnl
+
+			if equity > 0 || walletBalance > 0 || unrealizedPnl != 0 {
+				totalBalance += equity
+				fmt.Printf("    合约 %s: %.2f (钱包: %.2f, 未实现: %.2f)\n",
+					balance.Asset, equity, walletBalance, unrealizedPnl)
+			}
+		}
+	}
+
+	return totalBalance, nil
+}
+
+// getCoinFuturesBalance 获取币本位永续合约账户余额
+func (a *binanceAdapter) getCoinFuturesBalance(account *model.AdminAccount) (money.Decimal, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.coinFuturesURL + "/dapi/v1/balance?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.StatusCode != 200 {
+		// 如果没有币本位合约权限，返回0而不是错误
+		if resp.StatusCode == 400 || resp.StatusCode == 401 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Asset              string `json:"asset"`
+		Balance            string `json:"balance"`
+		CrossWalletBalance string `json:"crossWalletBalance"`
+		CrossUnPnl         string `json:"crossUnPnl"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	// 统计USDT和USDC（币本位合约也可能有稳定币）
+	totalBalance := money.Zero
+	for _, balance := range result {
+		if balance.Asset == "USDT" || balance.Asset == "USDC" {
+			walletBalance := parseAmount(balance.CrossWalletBalance)
+			unrealizedPnl := parseAmount(balance.CrossUnPnl)
+			equity := walletBalance + unrealizedPnl
+
+			if equity > 0 || walletBalance > 0 || unrealizedPnl != 0 {
+				totalBalance += equity
+				fmt.Printf("    币本位合约 %s: %.2f (钱包: %.2f, 未实现: %.2f)\n",
+					balance.Asset, equity, walletBalance, unrealizedPnl)
+			}
+		}
+	}
+
+	return totalBalance, nil
+}
+
+// sign 生成Binance签名
+func (a *binanceAdapter) sign(queryString, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(queryString))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// GetPositions 获取Binance持仓
+func (a *binanceAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.futuresURL + "/fapi/v2/positionRisk?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Symbol           string `json:"symbol"`
+		PositionAmt      string `json:"positionAmt"`
+		EntryPrice       string `json:"entryPrice"`
+		MarkPrice        string `json:"markPrice"`
+		UnRealizedProfit string `json:"unRealizedProfit"`
+		Leverage         string `json:"leverage"`
+		MarginType       string `json:"marginType"`
+		PositionSide     string `json:"positionSide"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	positions := []model.Position{}
+	count := 0
+
+	for _, pos := range result {
+		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
+
+		// 跳过空仓
+		if posAmt == 0 {
+			continue
+		}
+
+		if count >= limit {
+			break
+		}
+
+		entryPrice, _ := strconv.ParseFloat(pos.EntryPrice, 64)
+		markPrice, _ := strconv.ParseFloat(pos.MarkPrice, 64)
+		unrealizedPnl, _ := strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		leverage, _ := strconv.Atoi(pos.Leverage)
+
+		side := "LONG"
+		if posAmt < 0 {
+			side = "SHORT"
+			posAmt = -posAmt
+		}
+
+		pnlRate := 0.0
+		if entryPrice > 0 {
+			pnlRate = (unrealizedPnl / (posAmt * entryPrice)) * 100
+		}
+
+		positions = append(positions, model.Position{
+			Symbol:            pos.Symbol,
+			Side:              side,
+			Size:              posAmt,
+			EntryPrice:        entryPrice,
+			MarkPrice:         markPrice,
+			UnrealizedPnl:     unrealizedPnl,
+			UnrealizedPnlRate: pnlRate,
+			Leverage:          leverage,
+			MarginType:        pos.MarginType,
+		})
+
+		count++
+	}
+
+	return positions, nil
+}
+
+// GetOrders 获取Binance当前委托
+func (a *binanceAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.futuresURL + "/fapi/v1/openOrders?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		OrderID     int64  `json:"orderId"`
+		Symbol      string `json:"symbol"`
+		Side        string `json:"side"`
+		Type        string `json:"type"`
+		Price       string `json:"price"`
+		OrigQty     string `json:"origQty"`
+		ExecutedQty string `json:"executedQty"`
+		Status      string `json:"status"`
+		Time        int64  `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	orders := []model.Order{}
+	count := 0
+
+	for _, ord := range result {
+		if count >= limit {
+			break
+		}
+
+		price, _ := strconv.ParseFloat(ord.Price, 64)
+		origQty, _ := strconv.ParseFloat(ord.OrigQty, 64)
+		executedQty, _ := strconv.ParseFloat(ord.ExecutedQty, 64)
+
+		orders = append(orders, model.Order{
+			OrderID:     fmt.Sprintf("%d", ord.OrderID),
+			Symbol:      ord.Symbol,
+			Side:        ord.Side,
+			Type:        ord.Type,
+			Price:       price,
+			OrigQty:     origQty,
+			ExecutedQty: executedQty,
+			Status:      ord.Status,
+			Time:        time.Unix(ord.Time/1000, 0).Format("2006-01-02 15:04:05"),
+		})
+
+		count++
+	}
+
+	return orders, nil
+}
+
+// GetHistoryTrades 获取Binance历史成交
+func (a *binanceAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s&limit=%d", timestamp, limit)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.futuresURL + "/fapi/v1/userTrades?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Symbol      string `json:"symbol"`
+		Side        string `json:"side"`
+		Price       string `json:"price"`
+		Qty         string `json:"qty"`
+		RealizedPnl string `json:"realizedPnl"`
+		Commission  string `json:"commission"`
+		Time        int64  `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	trades := []model.HistoryTrade{}
+
+	for _, trade := range result {
+		price, _ := strconv.ParseFloat(trade.Price, 64)
+		qty, _ := strconv.ParseFloat(trade.Qty, 64)
+		pnl, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+		commission, _ := strconv.ParseFloat(trade.Commission, 64)
+
+		tradeTime := time.Unix(trade.Time/1000, 0).Format("2006-01-02 15:04:05")
+
+		trades = append(trades, model.HistoryTrade{
+			Symbol:      trade.Symbol,
+			Side:        trade.Side,
+			OpenTime:    tradeTime,
+			CloseTime:   tradeTime,
+			OpenPrice:   price,
+			ClosePrice:  price,
+			Quantity:    qty,
+			RealizedPnl: pnl,
+			Commission:  commission,
+		})
+	}
+
+	return trades, nil
+}
+
+// GetBalanceByAsset 获取Binance指定币种余额
+func (a *binanceAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置Binance API Key")
+	}
+
+	totalBalance := money.Zero
+
+	// 1. 获取现货账户余额（暂时跳过，因为只统计USDⓈ-M合约）
+	// spotBalance, _ := ws.getBinanceSpotBalanceByAsset(account, currency)
+	// totalBalance += spotBalance
+
+	// 2. 获取USDⓈ-M永续合约余额（U本位合约）
+	futuresBalance, err := a.getFuturesBalanceByAsset(account, currency)
+	if err != nil {
+		fmt.Printf("  ⚠️  获取Binance USDⓈ-M %s余额失败: %v\n", currency, err)
+	} else {
+		totalBalance += futuresBalance
+	}
+
+	fmt.Printf("  ✓ Binance %s 余额: $%.2f\n", currency, totalBalance)
+	return totalBalance, nil
+}
+
+// getFuturesBalanceByAsset 获取U本位合约指定币种余额
+func (a *binanceAdapter) getFuturesBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+	url := a.futuresURL + "/fapi/v2/balance?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var balances []struct {
+		Asset              string `json:"asset"`
+		Balance            string `json:"balance"`
+		CrossWalletBalance string `json:"crossWalletBalance"` // 全仓钱包余额
+		CrossUnPnl         string `json:"crossUnPnl"`         // 未实现盈亏
+		AvailableBalance   string `json:"availableBalance"`
+	}
+
+	if err := json.Unmarshal(body, &balances); err != nil {
+		// 检查是否是错误对象
+		var apiErr struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err2 := json.Unmarshal(body, &apiErr); err2 == nil {
+			return 0, fmt.Errorf("Binance API error [%d]: %s", apiErr.Code, apiErr.Msg)
+		}
+		return 0, err
+	}
+
+	// 只统计指定币种（USDT或USDC）
+	for _, b := range balances {
+		if b.Asset == currency {
+			// 🔥 修复：使用 crossWalletBalance + crossUnPnl
+			crossWallet := parseAmount(b.CrossWalletBalance)
+			crossUnPnl := parseAmount(b.CrossUnPnl)
+
+			// 总权益 = 全仓钱包余额 + 未实现盈亏
+			totalBalance := crossWallet + crossUnPnl
+
+			fmt.Printf("  ✓ Binance %s: 钱包余额=$%.2f, 未实现盈亏=$%.2f, 总权益=$%.2f\n",
+				currency, crossWallet, crossUnPnl, totalBalance)
+
+			return totalBalance, nil
+		}
+	}
+
+	fmt.Printf("  ⚠️  未找到币种 %s\n", currency)
+	return 0, nil
+}
+
+// CheckKeyPermissions 查询API密钥权限 GET /sapi/v1/account/apiRestrictions
+func (a *binanceAdapter) CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return nil, fmt.Errorf("未配置Binance API Key")
+	}
+
+	timestamp := fmt.Sprintf("%d", time.Now().UnixNano()/1000000)
+	queryString := fmt.Sprintf("timestamp=%s", timestamp)
+	signature := a.sign(queryString, account.APISecret)
+
+	url := a.spotURL + "/sapi/v1/account/apiRestrictions?" + queryString + "&signature=" + signature
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-MBX-APIKEY", account.APIKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("无法连接Binance: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		var apiErr struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Msg != "" {
+			return nil, fmt.Errorf("Binance API密钥无效 [%d]: %s", apiErr.Code, apiErr.Msg)
+		}
+		return nil, fmt.Errorf("Binance API密钥无效 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	enabled := func(key string) bool {
+		v, _ := result[key].(bool)
+		return v
+	}
+
+	perms := &model.KeyPermissions{Read: enabled("enableReading")}
+	// 交易类权限：现货杠杆、合约、期权、统一账户
+	for _, key := range []string{"enableSpotAndMarginTrading", "enableMargin", "enableFutures", "enableVanillaOptions", "enablePortfolioMarginTrading"} {
+		if enabled(key) {
+			perms.Trade = true
+		}
+	}
+	// 资金转出类权限：提现、向其他账户内部转账
+	for _, key := range []string{"enableWithdrawals", "enableInternalTransfer"} {
+		if enabled(key) {
+			perms.Withdraw = true
+		}
+	}
+	for key := range result {
+		if strings.HasPrefix(key, "enable") || strings.HasPrefix(key, "permits") {
+			if enabled(key) {
+				perms.Raw = append(perms.Raw, key)
+			}
+		}
+	}
+	sort.Strings(perms.Raw)
+
+	return perms, nil
+}
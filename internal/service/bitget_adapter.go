@@ -0,0 +1,399 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bitgetAdapter Bitget交易所适配器（V2：现货 + USDT/USDC本位合约）
+type bitgetAdapter struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// bitgetProductTypes 统计的合约产品线及其保证金币种
+var bitgetProductTypes = []struct {
+	marginCoin  string
+	productType string
+}{
+	{"USDT", "USDT-FUTURES"},
+	{"USDC", "USDC-FUTURES"},
+}
+
+// ==================== Bitget API ====================
+
+// GetBalance 获取Bitget现货 + 合约账户的 USDC+USDT 权益
+func (a *bitgetAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	if err := a.checkConfig(account); err != nil {
+		return 0, err
+	}
+
+	totalBalance := money.Zero
+	for _, currency := range []string{"USDT", "USDC"} {
+		balance, err := a.GetBalanceByAsset(account, currency)
+		if err != nil {
+			fmt.Printf("  ⚠️  获取Bitget账户失败: %v\n", err)
+			return 0, err
+		}
+		totalBalance += balance
+	}
+
+	fmt.Printf("  ✓ Bitget 总资产: $%.2f\n", totalBalance)
+	return totalBalance, nil
+}
+
+// GetBalanceByAsset 获取Bitget指定币种余额：现货可用+冻结，加上以该币种为保证金的合约账户权益
+func (a *bitgetAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	if err := a.checkConfig(account); err != nil {
+		return 0, err
+	}
+
+	// 1. 现货账户
+	var spot []struct {
+		Coin      string `json:"coin"`
+		Available string `json:"available"` // 可用
+		Frozen    string `json:"frozen"`    // 挂单冻结
+		Locked    string `json:"locked"`    // 锁仓
+	}
+	if err := a.get(account, "/api/v2/spot/account/assets", "coin="+currency, &spot); err != nil {
+		return 0, fmt.Errorf("现货账户: %v", err)
+	}
+
+	spotBalance := money.Zero
+	for _, asset := range spot {
+		if asset.Coin == currency {
+			spotBalance += parseAmount(asset.Available) + parseAmount(asset.Frozen) + parseAmount(asset.Locked)
+		}
+	}
+
+	// 2. 合约账户（只有USDT、USDC有对应的产品线）
+	futuresBalance := money.Zero
+	unrealized := money.Zero
+	for _, pt := range bitgetProductTypes {
+		if pt.marginCoin != currency {
+			continue
+		}
+		var futures []struct {
+			MarginCoin    string `json:"marginCoin"`
+			Available     string `json:"available"`     // 可用
+			Locked        string `json:"locked"`        // 冻结
+			AccountEquity string `json:"accountEquity"` // 账户权益（含未实现盈亏）
+			UnrealizedPL  string `json:"unrealizedPL"`  // 未实现盈亏
+		}
+		if err := a.get(account, "/api/v2/mix/account/accounts", "productType="+pt.productType, &futures); err != nil {
+			return 0, fmt.Errorf("%s合约账户: %v", pt.productType, err)
+		}
+		for _, acc := range futures {
+			if acc.MarginCoin == currency {
+				futuresBalance += parseAmount(acc.AccountEquity)
+				unrealized += parseAmount(acc.UnrealizedPL)
+			}
+		}
+	}
+
+	totalBalance := spotBalance + futuresBalance
+	if totalBalance > 0 {
+		fmt.Printf("  Bitget %s: 总权益=$%.2f (现货=$%.2f, 合约=$%.2f, 未实现=$%.2f)\n",
+			currency, totalBalance, spotBalance, futuresBalance, unrealized)
+	}
+	return totalBalance, nil
+}
+
+// GetPositions 获取Bitget USDT/USDC本位合约持仓
+func (a *bitgetAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	positions := []model.Position{}
+
+	for _, pt := range bitgetProductTypes {
+		var result []struct {
+			Symbol       string `json:"symbol"`       // 交易对
+			HoldSide     string `json:"holdSide"`     // 持仓方向 long/short
+			Total        string `json:"total"`        // 持仓数量
+			OpenPriceAvg string `json:"openPriceAvg"` // 开仓均价
+			MarkPrice    string `json:"markPrice"`    // 标记价格
+			UnrealizedPL string `json:"unrealizedPL"` // 未实现盈亏
+			Leverage     string `json:"leverage"`     // 杠杆倍数
+			MarginMode   string `json:"marginMode"`   // crossed 全仓 / isolated 逐仓
+		}
+		query := "productType=" + pt.productType + "&marginCoin=" + pt.marginCoin
+		if err := a.get(account, "/api/v2/mix/position/all-position", query, &result); err != nil {
+			return nil, err
+		}
+
+		for _, pos := range result {
+			size, _ := strconv.ParseFloat(pos.Total, 64)
+
+			// 跳过空仓
+			if size == 0 {
+				continue
+			}
+
+			if len(positions) >= limit {
+				return positions, nil
+			}
+
+			openPrice, _ := strconv.ParseFloat(pos.OpenPriceAvg, 64)
+			markPrice, _ := strconv.ParseFloat(pos.MarkPrice, 64)
+			upl, _ := strconv.ParseFloat(pos.UnrealizedPL, 64)
+			leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+
+			side := "LONG"
+			if pos.HoldSide == "short" {
+				side = "SHORT"
+			}
+
+			marginType := "cross"
+			if pos.MarginMode == "isolated" {
+				marginType = "isolated"
+			}
+
+			pnlRate := 0.0
+			if openPrice > 0 {
+				pnlRate = (upl / (size * openPrice)) * 100
+			}
+
+			positions = append(positions, model.Position{
+				Symbol:            pos.Symbol,
+				Side:              side,
+				Size:              size,
+				EntryPrice:        openPrice,
+				MarkPrice:         markPrice,
+				UnrealizedPnl:     upl,
+				UnrealizedPnlRate: pnlRate,
+				Leverage:          int(leverage),
+				MarginType:        marginType,
+			})
+		}
+	}
+
+	return positions, nil
+}
+
+// GetOrders 获取Bitget合约当前委托
+func (a *bitgetAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	orders := []model.Order{}
+
+	for _, pt := range bitgetProductTypes {
+		var result struct {
+			EntrustedList []struct {
+				OrderID    string `json:"orderId"`    // 订单ID
+				Symbol     string `json:"symbol"`     // 交易对
+				Side       string `json:"side"`       // 订单方向 buy/sell
+				OrderType  string `json:"orderType"`  // 订单类型 limit/market
+				Price      string `json:"price"`      // 委托价格
+				Size       string `json:"size"`       // 委托数量
+				BaseVolume string `json:"baseVolume"` // 已成交数量
+				Status     string `json:"status"`     // 订单状态
+				CTime      string `json:"cTime"`      // 创建时间（毫秒）
+			} `json:"entrustedList"`
+		}
+		if err := a.get(account, "/api/v2/mix/order/orders-pending", "productType="+pt.productType, &result); err != nil {
+			return nil, err
+		}
+
+		for _, ord := range result.EntrustedList {
+			if len(orders) >= limit {
+				return orders, nil
+			}
+
+			price, _ := strconv.ParseFloat(ord.Price, 64)
+			size, _ := strconv.ParseFloat(ord.Size, 64)
+			filled, _ := strconv.ParseFloat(ord.BaseVolume, 64)
+
+			// 转换时间戳
+			cTime, _ := strconv.ParseInt(ord.CTime, 10, 64)
+			orderTime := time.Unix(cTime/1000, 0).Format("2006-01-02 15:04:05")
+
+			orders = append(orders, model.Order{
+				OrderID:     ord.OrderID,
+				Symbol:      ord.Symbol,
+				Side:        strings.ToUpper(ord.Side),
+				Type:        strings.ToUpper(ord.OrderType),
+				Price:       price,
+				OrigQty:     size,
+				ExecutedQty: filled,
+				Status:      ord.Status,
+				Time:        orderTime,
+			})
+		}
+	}
+
+	return orders, nil
+}
+
+// GetHistoryTrades 获取Bitget合约历史仓位（已平仓）
+func (a *bitgetAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	trades := []model.HistoryTrade{}
+
+	for _, pt := range bitgetProductTypes {
+		var result struct {
+			List []struct {
+				Symbol        string `json:"symbol"`        // 交易对
+				HoldSide      string `json:"holdSide"`      // 持仓方向 long/short
+				OpenAvgPrice  string `json:"openAvgPrice"`  // 开仓均价
+				CloseAvgPrice string `json:"closeAvgPrice"` // 平仓均价
+				CloseTotalPos string `json:"closeTotalPos"` // 累计平仓数量
+				Pnl           string `json:"pnl"`           // 已实现盈亏（不含手续费）
+				OpenFee       string `json:"openFee"`       // 开仓手续费（负数）
+				CloseFee      string `json:"closeFee"`      // 平仓手续费（负数）
+				CTime         string `json:"cTime"`         // 开仓时间（毫秒）
+				UTime         string `json:"uTime"`         // 平仓时间（毫秒）
+			} `json:"list"`
+		}
+		query := fmt.Sprintf("productType=%s&limit=%d", pt.productType, limit)
+		if err := a.get(account, "/api/v2/mix/position/history-position", query, &result); err != nil {
+			return nil, err
+		}
+
+		for _, trade := range result.List {
+			if len(trades) >= limit {
+				return trades, nil
+			}
+
+			openPrice, _ := strconv.ParseFloat(trade.OpenAvgPrice, 64)
+			closePrice, _ := strconv.ParseFloat(trade.CloseAvgPrice, 64)
+			qty, _ := strconv.ParseFloat(trade.CloseTotalPos, 64)
+			pnl, _ := strconv.ParseFloat(trade.Pnl, 64)
+			openFee, _ := strconv.ParseFloat(trade.OpenFee, 64)
+			closeFee, _ := strconv.ParseFloat(trade.CloseFee, 64)
+
+			// 转换时间戳
+			cTime, _ := strconv.ParseInt(trade.CTime, 10, 64)
+			uTime, _ := strconv.ParseInt(trade.UTime, 10, 64)
+
+			openTime := time.Unix(cTime/1000, 0).Format("2006-01-02 15:04:05")
+			closeTime := time.Unix(uTime/1000, 0).Format("2006-01-02 15:04:05")
+
+			trades = append(trades, model.HistoryTrade{
+				Symbol:      trade.Symbol,
+				Side:        strings.ToUpper(trade.HoldSide),
+				OpenTime:    openTime,
+				CloseTime:   closeTime,
+				OpenPrice:   openPrice,
+				ClosePrice:  closePrice,
+				Quantity:    qty,
+				RealizedPnl: pnl,
+				Commission:  -(openFee + closeFee),
+			})
+		}
+	}
+
+	return trades, nil
+}
+
+// CheckKeyPermissions 查询API密钥权限 GET /api/v2/spot/account/info（authorities 字段）
+func (a *bitgetAdapter) CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error) {
+	if err := a.checkConfig(account); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		UserID      string   `json:"userId"`
+		Authorities []string `json:"authorities"` // 已开启的权限，如 readonly、trade、transfer、withdraw
+	}
+	if err := a.get(account, "/api/v2/spot/account/info", "", &result); err != nil {
+		return nil, fmt.Errorf("Bitget API密钥无效: %v", err)
+	}
+
+	// 能查询账户信息即有读取权限
+	perms := &model.KeyPermissions{Read: true}
+	for _, p := range result.Authorities {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		perms.Raw = append(perms.Raw, p)
+		switch lower := strings.ToLower(p); {
+		case strings.Contains(lower, "trade"):
+			perms.Trade = true
+		case strings.Contains(lower, "withdraw"), strings.Contains(lower, "transfer"):
+			perms.Withdraw = true
+		}
+	}
+
+	return perms, nil
+}
+
+// checkConfig Bitget需要 API Key、Secret 和 Passphrase
+func (a *bitgetAdapter) checkConfig(account *model.AdminAccount) error {
+	if account.APIKey == "" || account.APISecret == "" {
+		return fmt.Errorf("未配置Bitget API Key")
+	}
+	if account.Passphrase == "" {
+		return fmt.Errorf("未配置Bitget Passphrase")
+	}
+	return nil
+}
+
+// get 发送签名的GET请求，校验 code 后把 data 解析到 out
+// 签名内容为 timestamp + method + requestPath(含?查询参数) + body，HMAC-SHA256 后 Base64
+func (a *bitgetAdapter) get(account *model.AdminAccount, path, query string, out interface{}) error {
+	timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	method := "GET"
+	requestPath := path
+	if query != "" {
+		requestPath += "?" + query
+	}
+	signature := a.sign(timestamp+method+requestPath, account.APISecret)
+
+	req, err := http.NewRequest(method, a.baseURL+requestPath, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("ACCESS-KEY", account.APIKey)
+	req.Header.Set("ACCESS-SIGN", signature)
+	req.Header.Set("ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("locale", "zh-CN")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	if resp.StatusCode != 200 || result.Code != "00000" {
+		return fmt.Errorf("Bitget API错误 [%s]: %s", result.Code, result.Msg)
+	}
+
+	if len(result.Data) == 0 || string(result.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// sign 生成Bitget签名
+func (a *bitgetAdapter) sign(message, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bybitAdapter Bybit交易所适配器（V5 统一交易账户）
+type bybitAdapter struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// bybitRecvWindow 请求的时间戳容忍窗口（毫秒）
+const bybitRecvWindow = "5000"
+
+// bybitSettleCoins 统计的U本位合约结算币种
+var bybitSettleCoins = []string{"USDT", "USDC"}
+
+// ==================== Bybit API ====================
+
+// GetBalance 获取Bybit统一账户 USDC+USDT 权益
+func (a *bybitAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置Bybit API Key")
+	}
+
+	coins, err := a.getWalletCoins(account, "")
+	if err != nil {
+		fmt.Printf("  ⚠️  获取Bybit账户失败: %v\n", err)
+		return 0, err
+	}
+
+	totalBalance := money.Zero
+	for _, coin := range coins {
+		if coin.Coin != "USDC" && coin.Coin != "USDT" {
+			continue
+		}
+		equity := parseAmount(coin.Equity)
+		if equity > 0 {
+			totalBalance += equity
+			fmt.Printf("  Bybit %s: 总权益=$%.2f (钱包=$%.2f, 冻结=$%.2f, 未实现=$%.2f)\n",
+				coin.Coin, equity, parseAmount(coin.WalletBalance), parseAmount(coin.Locked), parseAmount(coin.UnrealisedPnl))
+		}
+	}
+
+	fmt.Printf("  ✓ Bybit 总资产: $%.2f\n", totalBalance)
+	return totalBalance, nil
+}
+
+// GetBalanceByAsset 获取Bybit指定币种权益（含未实现盈亏）
+func (a *bybitAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置Bybit API")
+	}
+
+	coins, err := a.getWalletCoins(account, currency)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, coin := range coins {
+		if coin.Coin == currency {
+			equity := parseAmount(coin.Equity)
+			fmt.Printf("  ✓ Bybit %s: 总权益=$%.2f (钱包=$%.2f)\n", currency, equity, parseAmount(coin.WalletBalance))
+			return equity, nil
+		}
+	}
+
+	fmt.Printf("  ⚠️  Bybit %s: 未找到余额\n", currency)
+	return 0, nil
+}
+
+// bybitCoin 统一账户中单个币种的余额
+type bybitCoin struct {
+	Coin          string `json:"coin"`
+	Equity        string `json:"equity"`        // 币种权益（含未实现盈亏）
+	WalletBalance string `json:"walletBalance"` // 钱包余额
+	Locked        string `json:"locked"`        // 现货挂单冻结
+	UnrealisedPnl string `json:"unrealisedPnl"` // 未实现盈亏
+}
+
+// getWalletCoins 查询统一账户各币种余额，coin 为空时返回全部币种
+func (a *bybitAdapter) getWalletCoins(account *model.AdminAccount, coin string) ([]bybitCoin, error) {
+	query := "accountType=UNIFIED"
+	if coin != "" {
+		query += "&coin=" + coin
+	}
+
+	var result struct {
+		List []struct {
+			AccountType string      `json:"accountType"`
+			TotalEquity string      `json:"totalEquity"` // 美元总权益
+			Coin        []bybitCoin `json:"coin"`
+		} `json:"list"`
+	}
+	if err := a.get(account, "/v5/account/wallet-balance", query, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.List) == 0 {
+		return nil, nil
+	}
+	return result.List[0].Coin, nil
+}
+
+// GetPositions 获取Bybit U本位合约持仓
+func (a *bybitAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	positions := []model.Position{}
+
+	for _, settleCoin := range bybitSettleCoins {
+		var result struct {
+			List []struct {
+				Symbol        string `json:"symbol"`        // 交易对
+				Side          string `json:"side"`          // 持仓方向 Buy/Sell，空仓为空
+				Size          string `json:"size"`          // 持仓数量
+				AvgPrice      string `json:"avgPrice"`      // 开仓均价
+				MarkPrice     string `json:"markPrice"`     // 标记价格
+				UnrealisedPnl string `json:"unrealisedPnl"` // 未实现盈亏
+				Leverage      string `json:"leverage"`      // 杠杆倍数
+				TradeMode     int    `json:"tradeMode"`     // 0 全仓 / 1 逐仓
+			} `json:"list"`
+		}
+		query := "category=linear&limit=200&settleCoin=" + settleCoin
+		if err := a.get(account, "/v5/position/list", query, &result); err != nil {
+			return nil, err
+		}
+
+		for _, pos := range result.List {
+			size, _ := strconv.ParseFloat(pos.Size, 64)
+
+			// 跳过空仓
+			if size == 0 {
+				continue
+			}
+
+			if len(positions) >= limit {
+				return positions, nil
+			}
+
+			avgPrice, _ := strconv.ParseFloat(pos.AvgPrice, 64)
+			markPrice, _ := strconv.ParseFloat(pos.MarkPrice, 64)
+			upl, _ := strconv.ParseFloat(pos.UnrealisedPnl, 64)
+			leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+
+			side := "LONG"
+			if pos.Side == "Sell" {
+				side = "SHORT"
+			}
+
+			marginType := "cross"
+			if pos.TradeMode == 1 {
+				marginType = "isolated"
+			}
+
+			pnlRate := 0.0
+			if avgPrice > 0 {
+				pnlRate = (upl / (size * avgPrice)) * 100
+			}
+
+			positions = append(positions, model.Position{
+				Symbol:            pos.Symbol,
+				Side:              side,
+				Size:              size,
+				EntryPrice:        avgPrice,
+				MarkPrice:         markPrice,
+				UnrealizedPnl:     upl,
+				UnrealizedPnlRate: pnlRate,
+				Leverage:          int(leverage),
+				MarginType:        marginType,
+			})
+		}
+	}
+
+	return positions, nil
+}
+
+// GetOrders 获取Bybit U本位合约当前委托
+func (a *bybitAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	orders := []model.Order{}
+
+	for _, settleCoin := range bybitSettleCoins {
+		var result struct {
+			List []struct {
+				OrderID     string `json:"orderId"`     // 订单ID
+				Symbol      string `json:"symbol"`      // 交易对
+				Side        string `json:"side"`        // 订单方向 Buy/Sell
+				OrderType   string `json:"orderType"`   // 订单类型 Limit/Market
+				Price       string `json:"price"`       // 委托价格
+				Qty         string `json:"qty"`         // 委托数量
+				CumExecQty  string `json:"cumExecQty"`  // 已成交数量
+				OrderStatus string `json:"orderStatus"` // 订单状态
+				CreatedTime string `json:"createdTime"` // 创建时间（毫秒）
+			} `json:"list"`
+		}
+		query := "category=linear&limit=50&settleCoin=" + settleCoin
+		if err := a.get(account, "/v5/order/realtime", query, &result); err != nil {
+			return nil, err
+		}
+
+		for _, ord := range result.List {
+			if len(orders) >= limit {
+				return orders, nil
+			}
+
+			price, _ := strconv.ParseFloat(ord.Price, 64)
+			qty, _ := strconv.ParseFloat(ord.Qty, 64)
+			execQty, _ := strconv.ParseFloat(ord.CumExecQty, 64)
+
+			// 转换时间戳
+			createdTime, _ := strconv.ParseInt(ord.CreatedTime, 10, 64)
+			orderTime := time.Unix(createdTime/1000, 0).Format("2006-01-02 15:04:05")
+
+			orders = append(orders, model.Order{
+				OrderID:     ord.OrderID,
+				Symbol:      ord.Symbol,
+				Side:        strings.ToUpper(ord.Side),
+				Type:        strings.ToUpper(ord.OrderType),
+				Price:       price,
+				OrigQty:     qty,
+				ExecutedQty: execQty,
+				Status:      ord.OrderStatus,
+				Time:        orderTime,
+			})
+		}
+	}
+
+	return orders, nil
+}
+
+// GetHistoryTrades 获取Bybit平仓盈亏记录（最近7天）
+func (a *bybitAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	var result struct {
+		List []struct {
+			Symbol        string `json:"symbol"`        // 交易对
+			Side          string `json:"side"`          // 平仓订单方向
+			ClosedSize    string `json:"closedSize"`    // 平仓数量
+			AvgEntryPrice string `json:"avgEntryPrice"` // 开仓均价
+			AvgExitPrice  string `json:"avgExitPrice"`  // 平仓均价
+			ClosedPnl     string `json:"closedPnl"`     // 已实现盈亏（已扣手续费）
+			OpenFee       string `json:"openFee"`       // 开仓手续费
+			CloseFee      string `json:"closeFee"`      // 平仓手续费
+			CreatedTime   string `json:"createdTime"`   // 创建时间（毫秒）
+			UpdatedTime   string `json:"updatedTime"`   // 平仓时间（毫秒）
+		} `json:"list"`
+	}
+	query := fmt.Sprintf("category=linear&limit=%d", limit)
+	if err := a.get(account, "/v5/position/closed-pnl", query, &result); err != nil {
+		return nil, err
+	}
+
+	trades := []model.HistoryTrade{}
+
+	for _, trade := range result.List {
+		qty, _ := strconv.ParseFloat(trade.ClosedSize, 64)
+		entryPrice, _ := strconv.ParseFloat(trade.AvgEntryPrice, 64)
+		exitPrice, _ := strconv.ParseFloat(trade.AvgExitPrice, 64)
+		pnl, _ := strconv.ParseFloat(trade.ClosedPnl, 64)
+		openFee, _ := strconv.ParseFloat(trade.OpenFee, 64)
+		closeFee, _ := strconv.ParseFloat(trade.CloseFee, 64)
+
+		// 转换时间戳
+		createdTime, _ := strconv.ParseInt(trade.CreatedTime, 10, 64)
+		updatedTime, _ := strconv.ParseInt(trade.UpdatedTime, 10, 64)
+
+		openTime := time.Unix(createdTime/1000, 0).Format("2006-01-02 15:04:05")
+		closeTime := time.Unix(updatedTime/1000, 0).Format("2006-01-02 15:04:05")
+
+		trades = append(trades, model.HistoryTrade{
+			Symbol:      trade.Symbol,
+			Side:        strings.ToUpper(trade.Side),
+			OpenTime:    openTime,
+			CloseTime:   closeTime,
+			OpenPrice:   entryPrice,
+			ClosePrice:  exitPrice,
+			Quantity:    qty,
+			RealizedPnl: pnl,
+			Commission:  openFee + closeFee,
+		})
+	}
+
+	return trades, nil
+}
+
+// CheckKeyPermissions 查询API密钥权限 GET /v5/user/query-api
+// readOnly=1 为只读密钥；读写密钥开启了任意交易类权限即视为可交易，开启钱包权限（提现/划转）视为可提现。
+func (a *bybitAdapter) CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return nil, fmt.Errorf("未配置完整的Bybit API Key/Secret")
+	}
+
+	var result struct {
+		ReadOnly    int                 `json:"readOnly"`    // 0 读写 / 1 只读
+		Permissions map[string][]string `json:"permissions"` // 分组 -> 已开启的权限项
+	}
+	if err := a.get(account, "/v5/user/query-api", "", &result); err != nil {
+		return nil, fmt.Errorf("Bybit API密钥无效: %v", err)
+	}
+
+	perms := &model.KeyPermissions{Read: true}
+	if result.ReadOnly == 1 {
+		perms.Raw = append(perms.Raw, "ReadOnly")
+	}
+	groups := make([]string, 0, len(result.Permissions))
+	for group := range result.Permissions {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		items := result.Permissions[group]
+		for _, item := range items {
+			perms.Raw = append(perms.Raw, group+":"+item)
+		}
+		if len(items) == 0 || result.ReadOnly == 1 {
+			continue
+		}
+		switch group {
+		case "ContractTrade", "Spot", "Options", "Derivatives", "CopyTrading", "BlockTrade", "Exchange":
+			perms.Trade = true
+		case "Wallet":
+			perms.Withdraw = true
+		}
+	}
+
+	return perms, nil
+}
+
+// get 发送签名的GET请求，校验 retCode 后把 result 解析到 out
+// 签名内容为 timestamp + apiKey + recvWindow + queryString，HMAC-SHA256 十六进制
+func (a *bybitAdapter) get(account *model.AdminAccount, path, query string, out interface{}) error {
+	timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	signature := a.sign(timestamp+account.APIKey+bybitRecvWindow+query, account.APISecret)
+
+	url := a.baseURL + path
+	if query != "" {
+		url += "?" + query
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-BAPI-API-KEY", account.APIKey)
+	req.Header.Set("X-BAPI-SIGN", signature)
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return err
+	}
+
+	if result.RetCode != 0 {
+		return fmt.Errorf("Bybit API错误 [%d]: %s", result.RetCode, result.RetMsg)
+	}
+
+	return json.Unmarshal(result.Result, out)
+}
+
+// sign 生成Bybit签名
+func (a *bybitAdapter) sign(message, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"crypto-final/internal/mockexchange"
+	"crypto-final/internal/money"
+	"testing"
+	"time"
+)
+
+// TestBybitBalances 统一账户只统计 USDT+USDC 权益（含未实现盈亏）
+func TestBybitBalances(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	mock.SetBybitCredentials("bybit-key", "bybit-secret")
+	mock.SetBybitBalance("USDT", mockexchange.Balance{Wallet: 1000, UnrealizedPnl: -40, Locked: 100})
+	mock.SetBybitBalance("USDC", mockexchange.Balance{Wallet: 500})
+	mock.SetBybitBalance("BTC", mockexchange.Balance{Wallet: 2})
+
+	account := sealedAccount(ws, "Bybit", "bybit-key", "bybit-secret", "")
+	if balance, err := ws.GetBalance(account); err != nil || balance != money.FromFloat(1460) {
+		t.Errorf("Bybit = %v, %v, want 1460（USDT+USDC）", balance, err)
+	}
+	if balance, err := ws.GetBalanceByAsset(account, "USDT"); err != nil || balance != money.FromFloat(960) {
+		t.Errorf("Bybit USDT = %v, %v, want 960", balance, err)
+	}
+	if balance, err := ws.GetBalanceByAsset(account, "DAI"); err != nil || !balance.IsZero() {
+		t.Errorf("Bybit DAI = %v, %v, want 0", balance, err)
+	}
+
+	wrong := sealedAccount(ws, "Bybit", "bybit-key", "not-the-secret", "")
+	if _, err := ws.GetBalance(wrong); err == nil {
+		t.Error("错误的签名应该被拒绝")
+	}
+}
+
+// TestBybitTradingData 持仓、委托和平仓盈亏映射为统一的模型，两种结算币种都统计
+func TestBybitTradingData(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	mock.SetBybitCredentials("bybit-key", "bybit-secret")
+	mock.AddBybitPosition(mockexchange.Position{Symbol: "BTCUSDT", Amount: -0.5, EntryPrice: 40000, MarkPrice: 39000, UnrealizedPnl: 500, Leverage: 10, MarginType: "isolated"})
+	mock.AddBybitPosition(mockexchange.Position{Symbol: "ETHUSDC", Amount: 2, EntryPrice: 2000, MarkPrice: 2100, UnrealizedPnl: 200, Leverage: 5, MarginType: "cross"})
+	mock.AddBybitOrder(mockexchange.Order{ID: 42, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 38000, Quantity: 0.1, Status: "New", Time: time.Now()})
+	mock.AddBybitTrade(mockexchange.Trade{Symbol: "ETHUSDT", Side: "SELL", Price: 2000, AvgPrice: 2200, Quantity: 1, RealizedPnl: 199, Commission: 1, OpenTime: time.Now().Add(-time.Hour), CloseTime: time.Now()})
+	account := sealedAccount(ws, "Bybit", "bybit-key", "bybit-secret", "")
+
+	positions, err := ws.GetPositions(account, 10)
+	if err != nil || len(positions) != 2 {
+		t.Fatalf("GetPositions = %+v, %v", positions, err)
+	}
+	short := positions[0]
+	if short.Side != "SHORT" || short.Size != 0.5 || short.MarginType != "isolated" || short.Leverage != 10 || short.UnrealizedPnlRate != 2.5 {
+		t.Errorf("BTCUSDT 持仓 = %+v, want 空仓 0.5 逐仓 10倍 收益率 2.5%%", short)
+	}
+	if long := positions[1]; long.Symbol != "ETHUSDC" || long.Side != "LONG" || long.MarginType != "cross" {
+		t.Errorf("ETHUSDC 持仓 = %+v, want 全仓多仓", long)
+	}
+	if limited, _ := ws.GetPositions(account, 1); len(limited) != 1 {
+		t.Errorf("limit=1 返回 %d 条持仓", len(limited))
+	}
+
+	orders, err := ws.GetOrders(account, 10)
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("GetOrders = %+v, %v", orders, err)
+	}
+	if o := orders[0]; o.OrderID != "42" || o.Side != "BUY" || o.Type != "LIMIT" || o.Price != 38000 || o.OrigQty != 0.1 {
+		t.Errorf("委托 = %+v", o)
+	}
+
+	trades, err := ws.GetHistoryTrades(account, 10)
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("GetHistoryTrades = %+v, %v", trades, err)
+	}
+	if tr := trades[0]; tr.Side != "SELL" || tr.OpenPrice != 2000 || tr.ClosePrice != 2200 || tr.RealizedPnl != 199 || tr.Commission != 1 {
+		t.Errorf("平仓记录 = %+v", tr)
+	}
+}
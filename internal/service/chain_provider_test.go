@@ -0,0 +1,169 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testTronAddress   = "TLa2f6VPqDgRE67v1736s7bJ8Ray5wYjU7"
+	testSolanaAddress = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+)
+
+func chainTokens(t *testing.T, name string) []model.ChainToken {
+	t.Helper()
+	chain, ok := chainByName(name)
+	if !ok {
+		t.Fatalf("没有注册 %s", name)
+	}
+	return chain.Tokens
+}
+
+// TestTronGridProvider 一次请求读取全部TRC20余额，合约地址按原样匹配；未激活的地址余额为0
+func TestTronGridProvider(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	tokens := chainTokens(t, "tron")
+	mock.SetChainTokenBalance("tron", tokens[0].Contract, testTronAddress, rawUnits(1500, 6))
+
+	provider := ws.chainProviders["tron"]
+	balances, err := provider.TokenBalances(testTronAddress, tokens)
+	if err != nil {
+		t.Fatalf("TokenBalances: %v", err)
+	}
+	if balances["USDT"] != money.FromFloat(1500) || !balances["USDC"].IsZero() {
+		t.Errorf("balances = %v, want USDT 1500 / USDC 0", balances)
+	}
+
+	balances, err = provider.TokenBalances("TXYZopYRdj2D9XRtbG411XZZ3kM5VkAeBf", tokens)
+	if err != nil || !balances["USDT"].IsZero() || !balances["USDC"].IsZero() {
+		t.Errorf("未激活地址 = %v, %v, want 全部为0", balances, err)
+	}
+}
+
+// TestTronGridProviderError 接口返回失败时报错，不按0处理
+func TestTronGridProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "rate limited"})
+	}))
+	t.Cleanup(srv.Close)
+
+	provider := &tronGridProvider{httpClient: srv.Client(), baseURL: srv.URL}
+	if balances, err := provider.TokenBalances(testTronAddress, chainTokens(t, "tron")); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("TokenBalances = %v, %v, want 报错", balances, err)
+	}
+}
+
+// solanaStub 按Mint返回固定的代币账户余额（最小单位），同一个Mint可以有多个账户
+func solanaStub(t *testing.T, accounts map[string][]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&call)
+		var filter struct {
+			Mint string `json:"mint"`
+		}
+		json.Unmarshal(call.Params[1], &filter)
+
+		value := []interface{}{}
+		for _, amount := range accounts[filter.Mint] {
+			value = append(value, map[string]interface{}{
+				"account": map[string]interface{}{"data": map[string]interface{}{"parsed": map[string]interface{}{
+					"info": map[string]interface{}{"tokenAmount": map[string]interface{}{"amount": amount, "decimals": 6}},
+				}}},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": call.ID, "result": map[string]interface{}{"value": value}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestSolanaProvider 同一个Mint的多个代币账户余额合计，没有代币账户时为0
+func TestSolanaProvider(t *testing.T) {
+	tokens := chainTokens(t, "solana")
+	srv := solanaStub(t, map[string][]string{tokens[1].Contract: {"1000000", "2500000"}})
+
+	provider := &solanaRPCProvider{httpClient: srv.Client(), rpcURL: srv.URL}
+	balances, err := provider.TokenBalances(testSolanaAddress, tokens)
+	if err != nil {
+		t.Fatalf("TokenBalances: %v", err)
+	}
+	if balances["USDC"] != money.FromFloat(3.5) || !balances["USDT"].IsZero() {
+		t.Errorf("balances = %v, want USDC 3.5 / USDT 0", balances)
+	}
+
+	bad := solanaStub(t, map[string][]string{tokens[0].Contract: {"not-a-number"}})
+	provider = &solanaRPCProvider{httpClient: bad.Client(), rpcURL: bad.URL}
+	if _, err := provider.TokenBalances(testSolanaAddress, tokens); err == nil || !strings.Contains(err.Error(), "无法解析") {
+		t.Errorf("err = %v, want 无法解析的余额", err)
+	}
+}
+
+// TestMultiChainWalletBalance Wallet账户汇总各链全部地址的余额，任意一个地址查询失败都整体报错
+func TestMultiChainWalletBalance(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	tron, solana, bsc := chainTokens(t, "tron"), chainTokens(t, "solana"), chainTokens(t, "bsc")
+	mock.SetChainTokenBalance("tron", tron[0].Contract, testTronAddress, rawUnits(1000, 6))
+	mock.SetChainTokenBalance("solana", solana[1].Contract, testSolanaAddress, rawUnits(200, 6))
+	mock.SetChainTokenBalance("bsc", bsc[0].Contract, testWalletA, rawUnits(30, 18))
+
+	wallet := &model.AdminAccount{ID: 3, AccountType: "Wallet", ChainAddresses: []model.ChainAddress{
+		{Chain: "tron", Address: testTronAddress},
+		{Chain: "solana", Address: testSolanaAddress},
+		{Chain: "bsc", Address: testWalletA},
+	}}
+	if balance, err := ws.GetBalance(wallet); err != nil || balance != money.FromFloat(1230) {
+		t.Errorf("Wallet = %v, %v, want 1230", balance, err)
+	}
+	if balance, err := ws.GetBalanceByAsset(wallet, "USDC"); err != nil || balance != money.FromFloat(200) {
+		t.Errorf("Wallet USDC = %v, %v, want 200", balance, err)
+	}
+
+	wallet.ChainAddresses = append(wallet.ChainAddresses, model.ChainAddress{Chain: "dogecoin", Address: "D000"})
+	if balance, err := ws.GetBalance(wallet); err == nil {
+		t.Errorf("不支持的链应该报错，得到 %v", balance)
+	}
+}
+
+// TestAddChainAddressFormat 按链校验地址格式，EVM地址统一小写
+func TestAddChainAddressFormat(t *testing.T) {
+	s := newTestService(t, NewWalletService())
+	wallet, _ := s.repo.GetAdminAccountByType("Wallet")
+
+	tests := []struct {
+		chain   string
+		address string
+		want    string // 空表示格式不正确
+	}{
+		{"tron", testTronAddress, testTronAddress},
+		{"tron", "0x" + strings.Repeat("a", 40), ""},
+		{"solana", testSolanaAddress, testSolanaAddress},
+		{"solana", "0OIl" + testSolanaAddress[4:], ""},
+		{"BSC", "0x" + strings.ToUpper(testWalletA[2:]), testWalletA},
+		{"polygon", testTronAddress, ""},
+	}
+	for _, tt := range tests {
+		addr, err := s.AddChainAddress(wallet.ID, tt.chain, tt.address, "")
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s %s 应该被拒绝", tt.chain, tt.address)
+			}
+			continue
+		}
+		if err != nil || addr.Address != tt.want {
+			t.Errorf("%s %s = %v, %v, want %s", tt.chain, tt.address, addr, err, tt.want)
+		}
+	}
+
+	if _, err := s.AddChainAddress(wallet.ID, "dogecoin", "D000", ""); err == nil {
+		t.Error("不支持的链应该被拒绝")
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ChainBalanceProvider 链上余额查询
+// 每种节点接口（EVM JSON-RPC、TronGrid、Solana RPC……）实现这个接口，并按链标识注册到 WalletService。
+type ChainBalanceProvider interface {
+	// TokenBalances 查询地址持有的各代币余额（已按精度换算），key 为代币符号
+	TokenBalances(address string, tokens []model.ChainToken) (map[string]money.Decimal, error)
+}
+
+// BlockPinnedProvider 可选接口：支持按区块高度查询的链（EVM JSON-RPC）实现它
+// 每日快照先取各链的最新区块，再让全部钱包地址在同一区块读取余额。
+type BlockPinnedProvider interface {
+	ChainBalanceProvider
+	// LatestBlock 当前最新区块高度
+	LatestBlock() (uint64, error)
+	// TokenBalancesAt 查询指定区块高度时地址持有的各代币余额
+	TokenBalancesAt(address string, tokens []model.ChainToken, block uint64) (map[string]money.Decimal, error)
+}
+
+// 链类型：决定地址格式和余额查询方式
+const (
+	chainKindEVM    = "evm"
+	chainKindTron   = "tron"
+	chainKindSolana = "solana"
+)
+
+// chainEthereum 以太坊主网；配置了 ETHEREUM_RPC_URL 时直接查询节点，否则通过Etherscan（旧版Wallet账户的默认链）
+const chainEthereum = "ethereum"
+
+// chainRegistry 支持的链及各链上统计的稳定币合约（USDT、USDC）
+var chainRegistry = []model.Chain{
+	{
+		Name: chainEthereum, DisplayName: "Ethereum (ERC20)", Kind: chainKindEVM,
+		Tokens: []model.ChainToken{
+			{Symbol: "USDT", Contract: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Decimals: 6},
+			{Symbol: "USDC", Contract: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Decimals: 6},
+		},
+	},
+	{
+		Name: "tron", DisplayName: "Tron (TRC20)", Kind: chainKindTron,
+		Tokens: []model.ChainToken{
+			{Symbol: "USDT", Contract: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", Decimals: 6},
+			{Symbol: "USDC", Contract: "TEkxiTehnzSmSe2XqrBj4w32RUN966rdz8", Decimals: 6},
+		},
+	},
+	{
+		Name: "bsc", DisplayName: "BNB Smart Chain (BEP20)", Kind: chainKindEVM,
+		Tokens: []model.ChainToken{
+			// BSC上的锚定币是18位小数
+			{Symbol: "USDT", Contract: "0x55d398326f99059fF775485246999027B3197955", Decimals: 18},
+			{Symbol: "USDC", Contract: "0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d", Decimals: 18},
+		},
+	},
+	{
+		Name: "arbitrum", DisplayName: "Arbitrum One", Kind: chainKindEVM,
+		Tokens: []model.ChainToken{
+			{Symbol: "USDT", Contract: "0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9", Decimals: 6},
+			{Symbol: "USDC", Contract: "0xaf88d065e77c8cC2239327C5EDb3A432268e5831", Decimals: 6}, // 原生USDC
+		},
+	},
+	{
+		Name: "polygon", DisplayName: "Polygon PoS", Kind: chainKindEVM,
+		Tokens: []model.ChainToken{
+			{Symbol: "USDT", Contract: "0xc2132D05D31c914a87C6611C10748AEb04B58e8F", Decimals: 6},
+			{Symbol: "USDC", Contract: "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359", Decimals: 6}, // 原生USDC
+		},
+	},
+	{
+		Name: "solana", DisplayName: "Solana (SPL)", Kind: chainKindSolana,
+		Tokens: []model.ChainToken{
+			{Symbol: "USDT", Contract: "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB", Decimals: 6},
+			{Symbol: "USDC", Contract: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", Decimals: 6},
+		},
+	},
+}
+
+// SupportedChains 支持的链列表
+func SupportedChains() []model.Chain {
+	return chainRegistry
+}
+
+// chainByName 按链标识查找
+func chainByName(name string) (model.Chain, bool) {
+	for _, c := range chainRegistry {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return model.Chain{}, false
+}
+
+// 各类链的地址格式
+var (
+	evmAddressPattern    = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	tronAddressPattern   = regexp.MustCompile(`^T[1-9A-HJ-NP-Za-km-z]{33}$`)
+	solanaAddressPattern = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
+)
+
+// normalizeChainAddress 校验地址格式；EVM地址统一转成小写，避免同一地址重复绑定
+func normalizeChainAddress(chain model.Chain, address string) (string, bool) {
+	address = strings.TrimSpace(address)
+	switch chain.Kind {
+	case chainKindEVM:
+		return strings.ToLower(address), evmAddressPattern.MatchString(address)
+	case chainKindTron:
+		return address, tronAddressPattern.MatchString(address)
+	case chainKindSolana:
+		return address, solanaAddressPattern.MatchString(address)
+	}
+	return address, false
+}
+
+// RegisterChainProvider 注册（或替换）某条链的余额查询
+func (ws *WalletService) RegisterChainProvider(chain string, provider ChainBalanceProvider) {
+	ws.chainProviders[chain] = provider
+}
+
+// PinChainBlocks 把账户各链地址的余额查询固定到同一区块
+// blocks 在一次每日快照内共用：某条链第一次出现时取最新区块并记下，之后所有钱包都读这个区块。
+// 不支持按区块查询的链（Etherscan、TronGrid、Solana）仍读最新余额。
+func (ws *WalletService) PinChainBlocks(account *model.AdminAccount, blocks map[string]uint64) error {
+	for _, addr := range walletChainAddresses(account) {
+		provider, ok := ws.chainProviders[addr.Chain].(BlockPinnedProvider)
+		if !ok {
+			continue
+		}
+		block, ok := blocks[addr.Chain]
+		if !ok {
+			latest, err := provider.LatestBlock()
+			if err != nil {
+				return fmt.Errorf("获取 %s 最新区块失败: %v", addr.Chain, err)
+			}
+			block = latest
+			blocks[addr.Chain] = block
+		}
+		if account.PinnedBlocks == nil {
+			account.PinnedBlocks = make(map[string]uint64)
+		}
+		account.PinnedBlocks[addr.Chain] = block
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"crypto-final/internal/mockexchange"
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/vault"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMockExchangeWallet 所有场所都指向 httptest 上的模拟交易所
+func newMockExchangeWallet(t *testing.T) (*WalletService, *mockexchange.Server) {
+	t.Helper()
+	mock := mockexchange.New()
+	mock.SetBinanceCredentials("binance-key", "binance-secret")
+	mock.SetOKXCredentials("okx-key", "okx-secret", "okx-pass")
+	mock.SetEtherscanAPIKey("etherscan-key")
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+
+	ws := NewWalletServiceWithEndpoints(SingleHostEndpoints(srv.URL))
+	keyring, err := vault.NewKeyring(bytes.Repeat([]byte{7}, vault.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.SetKeyring(keyring)
+	return ws, mock
+}
+
+// sealedAccount 按保存时的方式加密密钥的账户
+func sealedAccount(ws *WalletService, accountType, key, secret, passphrase string) *model.AdminAccount {
+	account := &model.AdminAccount{ID: 1, AccountType: accountType, APIKey: key}
+	secretCtx, passphraseCtx := secretContexts(account)
+	account.APISecret, _ = ws.SealSecret(secret, secretCtx)
+	account.Passphrase, _ = ws.SealSecret(passphrase, passphraseCtx)
+	return account
+}
+
+// TestMockExchangeBalances 真实的适配器通过签名接口读取模拟交易所设置的余额
+func TestMockExchangeBalances(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	mock.SetBinanceFuturesBalance("USDT", mockexchange.Balance{Wallet: 1000, UnrealizedPnl: 50})
+	mock.SetOKXBalance("USDT", mockexchange.Balance{Wallet: 700})
+	mock.SetOKXBalance("USDC", mockexchange.Balance{Wallet: 300})
+	mock.SetTokenBalance("0xdAC17F958D2ee523a2206206994597C13D831ec7", "0x1111111111111111111111111111111111111111", big.NewInt(2500000000))
+
+	binance := sealedAccount(ws, "Binance", "binance-key", "binance-secret", "")
+	if balance, err := ws.GetBalanceByAsset(binance, "USDT"); err != nil || balance != money.FromFloat(1050) {
+		t.Errorf("Binance USDT = %v, %v, want 1050（钱包+未实现盈亏）", balance, err)
+	}
+
+	okx := sealedAccount(ws, "OKX", "okx-key", "okx-secret", "okx-pass")
+	if balance, err := ws.GetBalance(okx); err != nil || balance != money.FromFloat(1000) {
+		t.Errorf("OKX = %v, %v, want 1000（USDT+USDC）", balance, err)
+	}
+
+	wallet := sealedAccount(ws, "Wallet", "", "etherscan-key", "")
+	wallet.WalletAddress = "0x1111111111111111111111111111111111111111"
+	if balance, err := ws.GetBalance(wallet); err != nil || balance != money.FromFloat(2500) {
+		t.Errorf("Wallet = %v, %v, want 2500", balance, err)
+	}
+}
+
+// TestMockExchangeRejectsWrongSignature 模拟交易所按真实规则校验签名，错误的 Secret 或 Passphrase 被拒绝
+func TestMockExchangeRejectsWrongSignature(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	mock.SetBinanceFuturesBalance("USDT", mockexchange.Balance{Wallet: 1000})
+	mock.SetOKXBalance("USDT", mockexchange.Balance{Wallet: 700})
+
+	binance := ws.adapters["Binance"].(*binanceAdapter)
+	good := &model.AdminAccount{AccountType: "Binance", APIKey: "binance-key", APISecret: "binance-secret"}
+	if _, err := binance.getFuturesBalanceByAsset(good, "USDT"); err != nil {
+		t.Errorf("Binance 正确的签名: %v", err)
+	}
+	wrong := &model.AdminAccount{AccountType: "Binance", APIKey: "binance-key", APISecret: "not-the-secret"}
+	if _, err := binance.getFuturesBalanceByAsset(wrong, "USDT"); err == nil {
+		t.Error("Binance 错误的签名应该被拒绝")
+	}
+
+	tests := []struct {
+		name    string
+		account *model.AdminAccount
+	}{
+		{"OKX Secret", sealedAccount(ws, "OKX", "okx-key", "not-the-secret", "okx-pass")},
+		{"OKX Passphrase", sealedAccount(ws, "OKX", "okx-key", "okx-secret", "wrong-pass")},
+		{"OKX Key", sealedAccount(ws, "OKX", "other-key", "okx-secret", "okx-pass")},
+	}
+	for _, tt := range tests {
+		if balance, err := ws.GetBalanceByAsset(tt.account, "USDT"); err == nil {
+			t.Errorf("%s: 错误的签名应该被拒绝，得到余额 %v", tt.name, balance)
+		}
+	}
+}
+
+// TestEndToEndDailyCheck 每日检查通过签名接口读取模拟交易所的余额；凭证失效的账户不更新
+func TestEndToEndDailyCheck(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	s := newTestService(t, ws)
+	mock.SetBinanceFuturesBalance("USDT", mockexchange.Balance{Wallet: 1000, UnrealizedPnl: 50})
+	mock.SetOKXBalance("USDT", mockexchange.Balance{Wallet: 700})
+	mock.SetOKXBalance("USDC", mockexchange.Balance{Wallet: 300})
+	mock.SetTokenBalance("0xdAC17F958D2ee523a2206206994597C13D831ec7", "0x1111111111111111111111111111111111111111", big.NewInt(2500000000))
+
+	s.ConfigAdminAccount("Binance", "binance-key", "binance-secret", "", "")
+	s.ConfigAdminAccount("OKX", "okx-key", "okx-secret", "", "okx-pass")
+	s.ConfigAdminAccount("Wallet", "", "etherscan-key", "0x1111111111111111111111111111111111111111", "")
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+
+	want := map[string]money.Decimal{"Binance": money.FromFloat(1050), "OKX": money.FromFloat(1000), "Wallet": money.FromFloat(2500)}
+	for accountType, balance := range want {
+		account, _ := s.repo.GetAdminAccountByType(accountType)
+		if account.CurrentBalance != balance {
+			t.Errorf("%s 余额 = %s, want %s", accountType, account.CurrentBalance, balance)
+		}
+	}
+
+	// OKX 换了 Passphrase：签名不再通过，保留上次的余额
+	mock.SetOKXCredentials("okx-key", "okx-secret", "rotated-pass")
+	mock.SetOKXBalance("USDT", mockexchange.Balance{Wallet: 100})
+	mock.SetBinanceFuturesBalance("USDT", mockexchange.Balance{Wallet: 1200})
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+	if account, _ := s.repo.GetAdminAccountByType("OKX"); account.CurrentBalance != money.FromFloat(1000) {
+		t.Errorf("签名失败的 OKX 余额 = %s, want 保留 1000", account.CurrentBalance)
+	}
+	if account, _ := s.repo.GetAdminAccountByType("Binance"); account.CurrentBalance != money.FromFloat(1200) {
+		t.Errorf("Binance 余额 = %s, want 1200", account.CurrentBalance)
+	}
+}
+
+// TestEndToEndDailyCheckAndWithdrawal 入金、申购、每日检查、撤资申请结算全流程，余额都来自模拟交易所的签名接口
+func TestEndToEndDailyCheckAndWithdrawal(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	s := newTestService(t, ws)
+	if err := s.ConfigAdminAccount("Binance", "binance-key", "binance-secret", "", ""); err != nil {
+		t.Fatalf("ConfigAdminAccount: %v", err)
+	}
+	binance, _ := s.repo.GetAdminAccountByType("Binance")
+	setUSDT := func(v float64) {
+		mock.SetBinanceFuturesBalance("USDT", mockexchange.Balance{Wallet: v})
+	}
+
+	// 管理员入金 1000，投资人申购 500（净值 1.0）
+	if err := s.AdminDepositToExchange(binance.ID, money.FromFloat(1000), "USDT"); err != nil {
+		t.Fatalf("AdminDepositToExchange: %v", err)
+	}
+	setUSDT(1000)
+	userID, err := s.AdminCreateUser("13800000000")
+	if err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+	rechargeID, err := s.AdminRecharge(int(userID), binance.ID, money.FromFloat(500), "USDT")
+	if err != nil {
+		t.Fatalf("AdminRecharge: %v", err)
+	}
+
+	// 投资人的份额从系统账户划转，总份额仍是 1000；余额上涨20%
+	setUSDT(1200)
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+	history, _ := s.repo.GetNAVHistory(binance.ID, "USDT", "", "")
+	if len(history) != 1 || history[0].NAV != money.MustParse("1.2") {
+		t.Fatalf("净值记录 = %+v, want 1.2", history)
+	}
+
+	// 撤资申请：提交 → 批准 → 下一次每日检查按当时净值成交
+	req, err := s.SubmitWithdrawalRequest(int(userID), int(rechargeID), money.FromFloat(250), "")
+	if err != nil {
+		t.Fatalf("SubmitWithdrawalRequest: %v", err)
+	}
+	if err := s.ApproveWithdrawalRequest(1, req.ID); err != nil {
+		t.Fatalf("ApproveWithdrawalRequest: %v", err)
+	}
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+
+	settled, _ := s.GetWithdrawalRequest(req.ID)
+	if settled.Status != model.WithdrawalSettled {
+		t.Fatalf("申请状态 = %s, want settled", settled.Status)
+	}
+	if settled.SettledShares != money.FromFloat(250) || settled.SettledAmount != money.FromFloat(300) {
+		t.Errorf("成交 = %s 份 / $%s, want 250 / 300", settled.SettledShares, settled.SettledAmount)
+	}
+
+	recharges, _ := s.repo.GetRechargesByUserID(int(userID))
+	var remaining *model.Recharge
+	for _, r := range recharges {
+		if r.IsActive {
+			remaining = r
+		}
+	}
+	if remaining == nil || remaining.Amount != money.FromFloat(250) || remaining.Shares != money.FromFloat(250) {
+		t.Fatalf("剩余持仓 = %+v, want 250/250", remaining)
+	}
+
+	// 已批准但充值在结算前被全部撤出的申请：结算时改为已拒绝并记录原因
+	setUSDT(900) // 付出 300 后剩 750 份，净值仍为 1.2
+	stuck, err := s.SubmitWithdrawalRequest(int(userID), remaining.ID, money.FromFloat(100), "")
+	if err != nil {
+		t.Fatalf("SubmitWithdrawalRequest: %v", err)
+	}
+	if err := s.ApproveWithdrawalRequest(1, stuck.ID); err != nil {
+		t.Fatalf("ApproveWithdrawalRequest: %v", err)
+	}
+	if err := s.WithdrawRecharge(remaining.ID, int(userID)); err != nil {
+		t.Fatalf("WithdrawRecharge: %v", err)
+	}
+	setUSDT(600)
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+	rejected, _ := s.GetWithdrawalRequest(stuck.ID)
+	if rejected.Status != model.WithdrawalRejected || !strings.Contains(rejected.RejectReason, "已停用") {
+		t.Errorf("申请 = %s (%q), want rejected 并记录原因", rejected.Status, rejected.RejectReason)
+	}
+
+	mismatches, unbalanced, err := s.VerifyLedger()
+	if err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v", mismatches, unbalanced, err)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import "strings"
+
+// ExchangeEndpoints 各场所的API根地址
+// 默认指向正式环境；离线测试时可以全部指向本地模拟服务器（cmd/mockexchange）。
+type ExchangeEndpoints struct {
+	BinanceSpot        string // 现货 https://api.binance.com
+	BinanceFutures     string // U本位合约 https://fapi.binance.com
+	BinanceCoinFutures string // 币本位合约 https://dapi.binance.com
+	OKX                string // https://www.okx.com
+	Bybit              string // https://api.bybit.com
+	Bitget             string // https://api.bitget.com
+	Gate               string // https://api.gateio.ws
+	Etherscan          string // https://api.etherscan.io
+
+	// 链上余额（Wallet账户）
+	EthereumRPC string // 以太坊 JSON-RPC，为空时通过Etherscan查询
+	TronGrid    string // TronGrid REST https://api.trongrid.io
+	BSCRPC      string // BNB Smart Chain JSON-RPC
+	ArbitrumRPC string // Arbitrum One JSON-RPC
+	PolygonRPC  string // Polygon PoS JSON-RPC
+	SolanaRPC   string // Solana JSON-RPC
+}
+
+// DefaultExchangeEndpoints 正式环境地址
+func DefaultExchangeEndpoints() ExchangeEndpoints {
+	return ExchangeEndpoints{
+		BinanceSpot:        "https://api.binance.com",
+		BinanceFutures:     "https://fapi.binance.com",
+		BinanceCoinFutures: "https://dapi.binance.com",
+		OKX:                "https://www.okx.com",
+		Bybit:              "https://api.bybit.com",
+		Bitget:             "https://api.bitget.com",
+		Gate:               "https://api.gateio.ws",
+		Etherscan:          "https://api.etherscan.io",
+		TronGrid:           "https://api.trongrid.io",
+		BSCRPC:             "https://bsc-dataseed.bnbchain.org",
+		ArbitrumRPC:        "https://arb1.arbitrum.io/rpc",
+		PolygonRPC:         "https://polygon-rpc.com",
+		SolanaRPC:          "https://api.mainnet-beta.solana.com",
+	}
+}
+
+// SingleHostEndpoints 所有场所都指向同一个地址（用于本地模拟服务器）
+// JSON-RPC 没有路径区分，各链的节点用 /rpc/<链标识> 区分；以太坊仍走Etherscan接口，
+// 需要测试节点查询时另外设置 EthereumRPC（模拟服务器同样提供 /rpc/ethereum）。
+func SingleHostEndpoints(baseURL string) ExchangeEndpoints {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return ExchangeEndpoints{
+		BinanceSpot:        baseURL,
+		BinanceFutures:     baseURL,
+		BinanceCoinFutures: baseURL,
+		OKX:                baseURL,
+		Bybit:              baseURL,
+		Bitget:             baseURL,
+		Gate:               baseURL,
+		Etherscan:          baseURL,
+		TronGrid:           baseURL,
+		BSCRPC:             baseURL + "/rpc/bsc",
+		ArbitrumRPC:        baseURL + "/rpc/arbitrum",
+		PolygonRPC:         baseURL + "/rpc/polygon",
+		SolanaRPC:          baseURL + "/rpc/solana",
+	}
+}
+
+// normalize 去掉末尾的斜杠，空值回退到正式环境地址
+func (e ExchangeEndpoints) normalize() ExchangeEndpoints {
+	def := DefaultExchangeEndpoints()
+	pick := func(v, fallback string) string {
+		v = strings.TrimRight(strings.TrimSpace(v), "/")
+		if v == "" {
+			return fallback
+		}
+		return v
+	}
+	return ExchangeEndpoints{
+		BinanceSpot:        pick(e.BinanceSpot, def.BinanceSpot),
+		BinanceFutures:     pick(e.BinanceFutures, def.BinanceFutures),
+		BinanceCoinFutures: pick(e.BinanceCoinFutures, def.BinanceCoinFutures),
+		OKX:                pick(e.OKX, def.OKX),
+		Bybit:              pick(e.Bybit, def.Bybit),
+		Bitget:             pick(e.Bitget, def.Bitget),
+		Gate:               pick(e.Gate, def.Gate),
+		Etherscan:          pick(e.Etherscan, def.Etherscan),
+		EthereumRPC:        pick(e.EthereumRPC, ""), // 可选，没有默认节点
+		TronGrid:           pick(e.TronGrid, def.TronGrid),
+		BSCRPC:             pick(e.BSCRPC, def.BSCRPC),
+		ArbitrumRPC:        pick(e.ArbitrumRPC, def.ArbitrumRPC),
+		PolygonRPC:         pick(e.PolygonRPC, def.PolygonRPC),
+		SolanaRPC:          pick(e.SolanaRPC, def.SolanaRPC),
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// erc20BalanceOfSelector balanceOf(address) 的函数选择器
+const erc20BalanceOfSelector = "0x70a08231"
+
+// evmRPCProvider 通过 EVM JSON-RPC 节点的 eth_call 调用 balanceOf 查询ERC20/BEP20余额
+// 一个地址的全部代币放在一次批量请求里查询；实现 BlockPinnedProvider，每日快照可以固定区块读取。
+type evmRPCProvider struct {
+	httpClient *http.Client
+	rpcURL     string
+}
+
+// TokenBalances 实现 ChainBalanceProvider，读取最新区块
+func (p *evmRPCProvider) TokenBalances(address string, tokens []model.ChainToken) (map[string]money.Decimal, error) {
+	return p.tokenBalances(address, tokens, "latest")
+}
+
+// TokenBalancesAt 实现 BlockPinnedProvider，读取指定区块高度的余额
+func (p *evmRPCProvider) TokenBalancesAt(address string, tokens []model.ChainToken, block uint64) (map[string]money.Decimal, error) {
+	return p.tokenBalances(address, tokens, fmt.Sprintf("0x%x", block))
+}
+
+// LatestBlock 实现 BlockPinnedProvider，eth_blockNumber
+func (p *evmRPCProvider) LatestBlock() (uint64, error) {
+	var result string
+	if err := jsonRPCCall(p.httpClient, p.rpcURL, "eth_blockNumber", []interface{}{}, &result); err != nil {
+		return 0, err
+	}
+	block, err := parseHexUint(result)
+	if err != nil {
+		return 0, err
+	}
+	if !block.IsUint64() {
+		return 0, fmt.Errorf("无效的区块高度: %s", result)
+	}
+	return block.Uint64(), nil
+}
+
+// tokenBalances 把各代币的 balanceOf 合并成一次批量 eth_call，blockTag 为 "latest" 或十六进制区块高度
+func (p *evmRPCProvider) tokenBalances(address string, tokens []model.ChainToken, blockTag string) (map[string]money.Decimal, error) {
+	data, err := balanceOfCallData(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return map[string]money.Decimal{}, nil
+	}
+
+	reqs := make([]jsonRPCRequest, len(tokens))
+	for i, token := range tokens {
+		call := map[string]string{"to": token.Contract, "data": data}
+		reqs[i] = jsonRPCRequest{Method: "eth_call", Params: []interface{}{call, blockTag}}
+	}
+	results, err := jsonRPCBatch(p.httpClient, p.rpcURL, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]money.Decimal, len(tokens))
+	for i, token := range tokens {
+		var result string
+		if err := json.Unmarshal(results[i], &result); err != nil {
+			return nil, fmt.Errorf("%s: 解析返回值失败: %v", token.Symbol, err)
+		}
+		raw, err := parseHexUint(result)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", token.Symbol, err)
+		}
+		balance, err := money.FromRawChecked(raw, token.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("%s: 余额 %s 超出范围", token.Symbol, raw)
+		}
+		balances[token.Symbol] = balance
+	}
+	return balances, nil
+}
+
+// balanceOfCallData 拼出 balanceOf(address) 的调用数据：选择器 + 左补零到32字节的地址
+func balanceOfCallData(address string) (string, error) {
+	if !evmAddressPattern.MatchString(address) {
+		return "", fmt.Errorf("无效的EVM地址: %s", address)
+	}
+	return erc20BalanceOfSelector + strings.Repeat("0", 24) + strings.ToLower(address[2:]), nil
+}
+
+// parseHexUint 解析节点返回的十六进制整数（"0x" 表示0）
+func parseHexUint(s string) (*big.Int, error) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if hex == "" {
+		return new(big.Int), nil
+	}
+	v, ok := new(big.Int).SetString(hex, 16)
+	if !ok {
+		return nil, fmt.Errorf("无法解析的返回值: %s", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// rpcStub 最小的 EVM JSON-RPC 节点：eth_blockNumber 和 ERC20 balanceOf 的 eth_call
+// 余额按区块记录，查询某个区块时取不晚于它的最近一次设置。
+type rpcStub struct {
+	mu       sync.Mutex
+	block    uint64
+	balances map[string]map[uint64]*big.Int // 合约:持有地址（小写）→ 区块 → 原始余额
+	failing  map[string]bool                // 这些合约的 eth_call 返回错误
+	missing  map[string]bool                // 这些合约的 eth_call 不返回结果
+	noBatch  bool                           // 模拟不支持批量请求的节点
+	reverse  bool                           // 批量响应倒序返回
+
+	requests         int      // HTTP请求数
+	blockNumberCalls int      // eth_blockNumber 调用次数
+	blockTags        []string // 每次 eth_call 的区块参数
+}
+
+type rpcStubCall struct {
+	ID     int               `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func newRPCStub(t *testing.T) (*rpcStub, *httptest.Server) {
+	stub := &rpcStub{balances: map[string]map[uint64]*big.Int{}, failing: map[string]bool{}, missing: map[string]bool{}}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return stub, srv
+}
+
+func balanceKey(contract, holder string) string {
+	return strings.ToLower(contract) + ":" + strings.ToLower(holder)
+}
+
+// setBalance 从当前区块起 holder 持有 raw 个最小单位的代币
+func (s *rpcStub) setBalance(contract, holder string, raw *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := balanceKey(contract, holder)
+	if s.balances[key] == nil {
+		s.balances[key] = map[uint64]*big.Int{}
+	}
+	s.balances[key][s.block] = raw
+}
+
+func (s *rpcStub) mine() {
+	s.mu.Lock()
+	s.block++
+	s.mu.Unlock()
+}
+
+func (s *rpcStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	body, _ := io.ReadAll(r.Body)
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var call rpcStubCall
+		json.Unmarshal(body, &call)
+		json.NewEncoder(w).Encode(s.handle(call))
+		return
+	}
+	if s.noBatch {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": nil, "error": map[string]interface{}{"code": -32600, "message": "batch requests are not supported"},
+		})
+		return
+	}
+
+	var calls []rpcStubCall
+	json.Unmarshal(body, &calls)
+	responses := []map[string]interface{}{}
+	for _, call := range calls {
+		if resp := s.handle(call); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if s.reverse {
+		for i, j := 0, len(responses)-1; i < j; i, j = i+1, j-1 {
+			responses[i], responses[j] = responses[j], responses[i]
+		}
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (s *rpcStub) handle(call rpcStubCall) map[string]interface{} {
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": call.ID}
+	switch call.Method {
+	case "eth_blockNumber":
+		s.blockNumberCalls++
+		resp["result"] = fmt.Sprintf("0x%x", s.block)
+	case "eth_call":
+		var tx struct{ To, Data string }
+		var tag string
+		json.Unmarshal(call.Params[0], &tx)
+		json.Unmarshal(call.Params[1], &tag)
+		s.blockTags = append(s.blockTags, tag)
+
+		contract := strings.ToLower(tx.To)
+		if s.missing[contract] {
+			return nil
+		}
+		if s.failing[contract] {
+			resp["error"] = map[string]interface{}{"code": 3, "message": "execution reverted"}
+			return resp
+		}
+		block := s.block
+		if tag != "latest" {
+			fmt.Sscanf(tag, "0x%x", &block)
+		}
+		holder := "0x" + tx.Data[len(tx.Data)-40:]
+		raw := new(big.Int)
+		var at uint64
+		for b, v := range s.balances[balanceKey(contract, holder)] {
+			if b <= block && b >= at {
+				raw, at = v, b
+			}
+		}
+		resp["result"] = fmt.Sprintf("0x%064x", raw)
+	default:
+		resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+	return resp
+}
+
+// rawUnits 把 v 个代币换算成 decimals 位精度的原始数量
+func rawUnits(v int64, decimals int) *big.Int {
+	return new(big.Int).Mul(big.NewInt(v), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+}
+
+const (
+	testWalletA = "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	testWalletB = "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	testWalletC = "0xcccccccccccccccccccccccccccccccccccccccc"
+)
+
+func bscTokens(t *testing.T) (usdt, usdc model.ChainToken) {
+	t.Helper()
+	chain, ok := chainByName("bsc")
+	if !ok {
+		t.Fatal("没有注册 bsc")
+	}
+	return chain.Tokens[0], chain.Tokens[1]
+}
+
+// TestEVMTokenBalancesBatched 一个地址的全部代币合并成一次批量 eth_call，响应按 id 对应
+func TestEVMTokenBalancesBatched(t *testing.T) {
+	stub, srv := newRPCStub(t)
+	stub.reverse = true
+	usdt, usdc := bscTokens(t)
+	stub.setBalance(usdt.Contract, testWalletA, rawUnits(1234, usdt.Decimals))
+	stub.setBalance(usdc.Contract, testWalletA, new(big.Int).Div(rawUnits(5, usdc.Decimals), big.NewInt(2)))
+
+	provider := &evmRPCProvider{httpClient: srv.Client(), rpcURL: srv.URL}
+	// 大写地址也按小写拼进调用数据
+	balances, err := provider.TokenBalances("0x"+strings.ToUpper(testWalletA[2:]), []model.ChainToken{usdt, usdc})
+	if err != nil {
+		t.Fatalf("TokenBalances: %v", err)
+	}
+	if balances["USDT"] != money.FromFloat(1234) || balances["USDC"] != money.FromFloat(2.5) {
+		t.Errorf("balances = %v, want USDT 1234 / USDC 2.5", balances)
+	}
+	if stub.requests != 1 {
+		t.Errorf("HTTP请求 %d 次, want 1", stub.requests)
+	}
+	if len(stub.blockTags) != 2 || stub.blockTags[0] != "latest" || stub.blockTags[1] != "latest" {
+		t.Errorf("blockTags = %v, want 两次 latest", stub.blockTags)
+	}
+
+	// 没有代币时不发请求
+	if balances, err := provider.TokenBalances(testWalletA, nil); err != nil || len(balances) != 0 {
+		t.Errorf("TokenBalances(nil) = %v, %v", balances, err)
+	}
+	if stub.requests != 1 {
+		t.Errorf("没有代币时不应请求节点")
+	}
+}
+
+// TestEVMTokenBalancesPartialFailure 批量里任意一个调用失败都整体报错，不返回少算的余额
+func TestEVMTokenBalancesPartialFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(stub *rpcStub, usdc model.ChainToken)
+		want  string
+	}{
+		{"单个调用返回错误", func(stub *rpcStub, usdc model.ChainToken) { stub.failing[strings.ToLower(usdc.Contract)] = true }, "eth_call 失败 [3]: execution reverted"},
+		{"单个调用缺少响应", func(stub *rpcStub, usdc model.ChainToken) { stub.missing[strings.ToLower(usdc.Contract)] = true }, "eth_call 没有返回结果"},
+		{"节点不支持批量请求", func(stub *rpcStub, usdc model.ChainToken) { stub.noBatch = true }, "批量请求失败 [-32600]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, srv := newRPCStub(t)
+			usdt, usdc := bscTokens(t)
+			stub.setBalance(usdt.Contract, testWalletA, rawUnits(100, usdt.Decimals))
+			stub.setBalance(usdc.Contract, testWalletA, rawUnits(100, usdc.Decimals))
+			tt.setup(stub, usdc)
+
+			provider := &evmRPCProvider{httpClient: srv.Client(), rpcURL: srv.URL}
+			balances, err := provider.TokenBalances(testWalletA, []model.ChainToken{usdt, usdc})
+			if err == nil {
+				t.Fatalf("TokenBalances = %v, 应该报错", balances)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want 包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestPinChainBlocks 一次快照内所有钱包在同一区块读取，期间出块不影响结果
+func TestPinChainBlocks(t *testing.T) {
+	stub, srv := newRPCStub(t)
+	ws := NewWalletServiceWithEndpoints(SingleHostEndpoints(srv.URL))
+	usdt, usdc := bscTokens(t)
+
+	stub.block = 100
+	stub.setBalance(usdt.Contract, testWalletA, rawUnits(10, usdt.Decimals))
+	stub.setBalance(usdt.Contract, testWalletB, rawUnits(20, usdt.Decimals))
+	stub.setBalance(usdc.Contract, testWalletC, rawUnits(30, usdc.Decimals))
+
+	wallet := func(id int, addresses ...string) *model.AdminAccount {
+		account := &model.AdminAccount{ID: id, AccountType: "Wallet"}
+		for _, addr := range addresses {
+			account.ChainAddresses = append(account.ChainAddresses, model.ChainAddress{AdminAccountID: id, Chain: "bsc", Address: addr})
+		}
+		return account
+	}
+	first, second := wallet(1, testWalletA, testWalletB), wallet(2, testWalletC)
+
+	blocks := map[string]uint64{}
+	if err := ws.PinChainBlocks(first, blocks); err != nil {
+		t.Fatalf("PinChainBlocks: %v", err)
+	}
+	// 两个账户之间出了新块：A 又收到 1000 USDT，C 的 USDC 转走了
+	stub.mine()
+	stub.setBalance(usdt.Contract, testWalletA, rawUnits(1010, usdt.Decimals))
+	stub.setBalance(usdc.Contract, testWalletC, new(big.Int))
+	if err := ws.PinChainBlocks(second, blocks); err != nil {
+		t.Fatalf("PinChainBlocks: %v", err)
+	}
+
+	if stub.blockNumberCalls != 1 {
+		t.Errorf("eth_blockNumber 调用 %d 次, want 1", stub.blockNumberCalls)
+	}
+	if first.PinnedBlocks["bsc"] != 100 || second.PinnedBlocks["bsc"] != 100 {
+		t.Fatalf("PinnedBlocks = %v / %v, want 都是 100", first.PinnedBlocks, second.PinnedBlocks)
+	}
+
+	if got, err := ws.GetBalance(first); err != nil || got != money.FromFloat(30) {
+		t.Errorf("第一个账户 = %v, %v, want 30（区块100）", got, err)
+	}
+	if got, err := ws.GetBalance(second); err != nil || got != money.FromFloat(30) {
+		t.Errorf("第二个账户 = %v, %v, want 30（区块100）", got, err)
+	}
+	for _, tag := range stub.blockTags {
+		if tag != "0x64" {
+			t.Fatalf("blockTags = %v, want 都是 0x64", stub.blockTags)
+		}
+	}
+
+	// 不固定区块时读最新余额
+	if got, err := ws.GetBalance(wallet(1, testWalletA, testWalletB)); err != nil || got != money.FromFloat(1030) {
+		t.Errorf("最新余额 = %v, %v, want 1030", got, err)
+	}
+}
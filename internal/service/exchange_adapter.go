@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+)
+
+// ExchangeAdapter 交易所/钱包适配器
+// 每个场所（Binance、OKX、链上钱包……）实现这个接口，并按 AccountType 注册到 WalletService。
+// 新增场所时只需要在单独的文件中实现接口，然后调用 RegisterAdapter 注册即可。
+type ExchangeAdapter interface {
+	// GetBalance 获取账户USDC+USDT总余额
+	GetBalance(account *model.AdminAccount) (money.Decimal, error)
+	// GetBalanceByAsset 获取账户指定币种余额
+	GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error)
+	// GetPositions 获取持仓列表
+	GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error)
+	// GetOrders 获取当前委托
+	GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error)
+	// GetHistoryTrades 获取历史成交
+	GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error)
+}
+
+// KeyPermissionChecker 可选接口：能查询API密钥权限的场所实现它
+// 保存密钥前据此校验凭证并拒绝开启了交易/提现权限的密钥；链上钱包等没有权限概念的场所不实现。
+type KeyPermissionChecker interface {
+	CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error)
+}
+
+// RegisterAdapter 注册（或替换）某个账户类型的适配器
+func (ws *WalletService) RegisterAdapter(accountType string, adapter ExchangeAdapter) {
+	ws.adapters[accountType] = adapter
+}
+
+// HasAdapter 是否已注册该账户类型
+func (ws *WalletService) HasAdapter(accountType string) bool {
+	_, ok := ws.adapters[accountType]
+	return ok
+}
+
+// adapterFor 根据账户类型查找适配器
+func (ws *WalletService) adapterFor(accountType string) (ExchangeAdapter, bool) {
+	adapter, ok := ws.adapters[accountType]
+	return adapter, ok
+}
+
+// parseAmount 解析交易所返回的金额字符串，无法解析时按0处理
+// 余额直接解析成定点小数，不经过 float64。
+func parseAmount(s string) money.Decimal {
+	if s == "" {
+		return money.Zero
+	}
+	d, err := money.Parse(s)
+	if err != nil {
+		return money.Zero
+	}
+	return d
+}
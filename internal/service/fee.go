@@ -0,0 +1,295 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// validFeeType 是否是已定义的费用类型
+func validFeeType(feeType string) bool {
+	return feeType == model.FeePerformance || feeType == model.FeeManagement
+}
+
+// SetFeeRate 设置费率，userID 为0时设置Admin账户的默认费率
+func (s *Service) SetFeeRate(actorID int, feeType string, adminAccountID, userID int, rate money.Decimal) (*model.FeeRate, error) {
+	if !validFeeType(feeType) {
+		return nil, fmt.Errorf("不支持的费用类型: %s", feeType)
+	}
+	if feeType == model.FeeManagement && userID != 0 {
+		return nil, errors.New("管理费只能按Admin账户设置")
+	}
+	if rate.Sign() < 0 || rate.Cmp(money.One) >= 0 {
+		return nil, errors.New("费率必须在0到100%之间（0.2 表示 20%）")
+	}
+	if err := s.checkFeeTarget(adminAccountID, userID); err != nil {
+		return nil, err
+	}
+
+	feeRate := &model.FeeRate{
+		FeeType:        feeType,
+		AdminAccountID: adminAccountID,
+		UserID:         userID,
+		Rate:           rate,
+		UpdatedBy:      actorID,
+	}
+	if err := s.repo.SetFeeRate(feeRate); err != nil {
+		return nil, fmt.Errorf("保存费率失败: %v", err)
+	}
+
+	fmt.Printf("✓ 费率已设置: %s 账户%d 用户%d → %.2f%%\n", feeType, adminAccountID, userID, rate.Float64()*100)
+	return feeRate, nil
+}
+
+// DeleteFeeRate 删除费率设置；删除用户设置后该用户按账户默认费率计算
+func (s *Service) DeleteFeeRate(feeType string, adminAccountID, userID int) error {
+	if !validFeeType(feeType) {
+		return fmt.Errorf("不支持的费用类型: %s", feeType)
+	}
+	found, err := s.repo.DeleteFeeRate(feeType, adminAccountID, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("费率设置不存在")
+	}
+	return nil
+}
+
+// GetFeeRate 获取某条费率设置，不存在时返回 nil
+func (s *Service) GetFeeRate(feeType string, adminAccountID, userID int) (*model.FeeRate, error) {
+	return s.repo.GetFeeRate(feeType, adminAccountID, userID)
+}
+
+// GetFeeRates 全部费率设置，feeType 为空不限类型
+func (s *Service) GetFeeRates(feeType string) ([]*model.FeeRate, error) {
+	return s.repo.GetFeeRates(feeType)
+}
+
+// GetManagerHoldings 各资金池管理人账户持有的份额，按最近净值估值
+func (s *Service) GetManagerHoldings() ([]*model.ManagerHolding, error) {
+	holdings, err := s.repo.GetManagerHoldings()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range holdings {
+		nav, err := s.repo.GetLatestNAV(h.AdminAccountID, h.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if nav != nil {
+			if h.Value, err = money.MarkValueChecked(h.Shares, nav.NAV, h.Currency); err != nil {
+				return nil, fmt.Errorf("管理人份额估值失败: %v", err)
+			}
+		}
+	}
+	return holdings, nil
+}
+
+// GetPerformanceFees 按条件查询业绩报酬明细及汇总
+func (s *Service) GetPerformanceFees(filter model.PerformanceFeeFilter) ([]*model.PerformanceFee, *model.PerformanceFeeTotals, error) {
+	fees, err := s.repo.QueryPerformanceFees(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	totals, err := s.repo.SumPerformanceFees(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fees, totals, nil
+}
+
+// checkFeeTarget 校验费率设置的账户和用户
+func (s *Service) checkFeeTarget(adminAccountID, userID int) error {
+	account, err := s.repo.GetAdminAccountByID(adminAccountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return errors.New("Admin账户不存在")
+	}
+	if userID == 0 {
+		return nil
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	return nil
+}
+
+// feeRate 某用户在某账户适用的费率：用户设置优先，其次账户默认，都没有为0
+func (s *Service) feeRate(feeType string, adminAccountID, userID int) (money.Decimal, error) {
+	rate, err := s.repo.GetFeeRate(feeType, adminAccountID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if rate == nil && userID != 0 {
+		rate, err = s.repo.GetFeeRate(feeType, adminAccountID, 0)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if rate == nil {
+		return 0, nil
+	}
+	return rate.Rate, nil
+}
+
+// performanceFee 按给定净值计算一笔持仓应收的业绩报酬，不保存
+// 高水位为上次计提时的净值，从未计提过时取成本净值（本金/份额）；净值不超过高水位时不收取，返回 nil。
+// 报酬 = 份额 × (净值 - 高水位) × 费率，按净值折算成份额划转给管理人，本金不变。
+func (s *Service) performanceFee(recharge *model.Recharge, nav *model.NAVRecord, event, period string) (*model.PerformanceFee, error) {
+	if recharge.UserID == 0 || recharge.Shares.Sign() <= 0 || recharge.Amount.Sign() <= 0 {
+		return nil, nil
+	}
+
+	rate, err := s.feeRate(model.FeePerformance, recharge.AdminAccountID, recharge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("读取业绩报酬费率失败: %v", err)
+	}
+	if rate.Sign() <= 0 {
+		return nil, nil
+	}
+
+	hwm, err := s.repo.GetHighWaterMark(recharge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("读取高水位失败: %v", err)
+	}
+	if hwm.IsZero() {
+		if hwm, err = money.NAVChecked(recharge.Amount, recharge.Shares); err != nil {
+			return nil, fmt.Errorf("计算成本净值失败: %v", err)
+		}
+	}
+	if nav.NAV.Cmp(hwm) <= 0 {
+		return nil, nil
+	}
+
+	places := money.CurrencyPlaces(recharge.Currency)
+	gain, err := money.RedeemValueChecked(recharge.Shares, nav.NAV.Sub(hwm), recharge.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("计算超额收益失败: %v", err)
+	}
+	feeAmount, err := gain.MulRatioChecked(rate, money.One, money.RoundDown)
+	if err != nil {
+		return nil, fmt.Errorf("计算业绩报酬失败: %v", err)
+	}
+	feeAmount = feeAmount.Round(places, money.RoundDown)
+	feeShares, err := money.IssueSharesChecked(feeAmount, nav.NAV)
+	if err != nil {
+		return nil, fmt.Errorf("计算业绩报酬份额失败: %v", err)
+	}
+	if feeShares.Sign() <= 0 {
+		return nil, nil
+	}
+
+	return &model.PerformanceFee{
+		RechargeID:     recharge.ID,
+		UserID:         recharge.UserID,
+		AdminAccountID: recharge.AdminAccountID,
+		Currency:       recharge.Currency,
+		Event:          event,
+		Period:         period,
+		NAVDate:        nav.RecordDate,
+		NAV:            nav.NAV,
+		HighWaterMark:  hwm,
+		Shares:         recharge.Shares,
+		Gain:           gain,
+		Rate:           rate,
+		FeeAmount:      feeAmount,
+		FeeShares:      feeShares,
+	}, nil
+}
+
+// priceRedemptionWithFee 先按成交净值计算整笔持仓的业绩报酬，再按扣除报酬后的份额为撤资定价
+// 两者都不保存，由调用方在同一个事务中写入（RedeemWithFee / SettleWithdrawalRequest）；报酬为 nil 表示不收取。
+func (s *Service) priceRedemptionWithFee(recharge *model.Recharge, principal money.Decimal, nav *model.NAVRecord) (*model.PerformanceFee, *model.Redemption, error) {
+	fee, err := s.performanceFee(recharge, nav, model.FeeEventWithdrawal, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if fee != nil {
+		afterFee := *recharge
+		afterFee.Shares = recharge.Shares.Sub(fee.FeeShares)
+		recharge = &afterFee
+	}
+
+	rd, err := priceRedemption(recharge, principal, nav)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fee, rd, nil
+}
+
+// logPerformanceFee 打印已保存的业绩报酬
+func logPerformanceFee(fee *model.PerformanceFee) {
+	fmt.Printf("💰 充值%d 业绩报酬(%s): 净值 $%.4f > 高水位 $%.4f, 收益$%.2f × %.2f%% = $%.2f (%.4f份)\n",
+		fee.RechargeID, fee.Event, fee.NAV, fee.HighWaterMark, fee.Gain, fee.Rate.Float64()*100, fee.FeeAmount, fee.FeeShares)
+}
+
+// crystallizeMonthlyFees 每月第一次净值结算时，按这次的净值为资金池内所有持仓计提上个月的业绩报酬
+// 每笔持仓单独提交，本期已经计提过的持仓跳过；全部成功后才记录本期完成，有失败的下次净值结算时重试。
+func (s *Service) crystallizeMonthlyFees(account *model.AdminAccount, currency string, nav *model.NAVRecord) {
+	now := time.Now()
+	period := now.AddDate(0, 0, -now.Day()).Format("2006-01")
+
+	done, err := s.repo.HasFeeCrystallization(account.ID, currency, period)
+	if err != nil {
+		fmt.Printf("⚠️  读取%s %s业绩报酬计提记录失败: %v\n", account.AccountType, currency, err)
+		return
+	}
+	if done {
+		return
+	}
+
+	recharges, err := s.repo.GetAllActiveRecharges()
+	if err != nil {
+		fmt.Printf("⚠️  获取充值记录失败: %v\n", err)
+		return
+	}
+	alreadyCharged, err := s.repo.GetChargedRecharges(account.ID, currency, model.FeeEventMonthly, period)
+	if err != nil {
+		fmt.Printf("⚠️  读取%s %s本期已计提的持仓失败: %v\n", account.AccountType, currency, err)
+		return
+	}
+
+	charged, failed := 0, 0
+	total := money.Zero
+	for _, recharge := range recharges {
+		if recharge.AdminAccountID != account.ID || recharge.Currency != currency || alreadyCharged[recharge.ID] {
+			continue
+		}
+		fee, err := s.performanceFee(recharge, nav, model.FeeEventMonthly, period)
+		if err == nil && fee != nil {
+			err = s.repo.ChargePerformanceFee(fee)
+		}
+		if err != nil {
+			fmt.Printf("❌ 充值%d 计提业绩报酬失败: %v\n", recharge.ID, err)
+			failed++
+			continue
+		}
+		if fee != nil {
+			logPerformanceFee(fee)
+			charged++
+			total = total.Add(fee.FeeAmount)
+		}
+	}
+
+	if charged > 0 {
+		fmt.Printf("✓ %s %s %s 业绩报酬: %d 笔, 合计 $%.2f\n", account.AccountType, currency, period, charged, total)
+	}
+	if failed > 0 {
+		fmt.Printf("⚠️  %s %s %s 有 %d 笔持仓计提失败，下次净值结算时重试\n", account.AccountType, currency, period, failed)
+		return
+	}
+	if err := s.repo.RecordFeeCrystallization(account.ID, currency, period, nav.RecordDate); err != nil {
+		fmt.Printf("⚠️  保存%s %s业绩报酬计提记录失败: %v\n", account.AccountType, currency, err)
+	}
+}
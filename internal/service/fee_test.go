@@ -0,0 +1,215 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"errors"
+	"testing"
+)
+
+func navAt(v string) *model.NAVRecord {
+	return &model.NAVRecord{NAV: money.MustParse(v), RecordDate: "2024-02-01"}
+}
+
+// TestPerformanceFeeHighWaterMark 只对超过高水位的收益收取报酬，从未计提过时以成本净值为高水位
+func TestPerformanceFeeHighWaterMark(t *testing.T) {
+	tests := []struct {
+		name       string
+		nav        string
+		wantAmount string // 空表示不收取
+		wantShares string
+	}{
+		{"净值等于成本", "1", "", ""},
+		{"净值低于成本", "0.8", "", ""},
+		{"首次计提按成本净值", "1.2", "20", "16.66666666"}, // 500 × (1.2-1.0) × 20%
+		{"收益更高", "1.5", "50", "33.33333333"},      // 500 × 0.5 × 20%
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPool(t)
+			if _, err := p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.MustParse("0.2")); err != nil {
+				t.Fatalf("SetFeeRate: %v", err)
+			}
+
+			fee, err := p.s.performanceFee(p.recharge(t), navAt(tt.nav), model.FeeEventMonthly, "2024-01")
+			if err != nil {
+				t.Fatalf("performanceFee: %v", err)
+			}
+			if tt.wantAmount == "" {
+				if fee != nil {
+					t.Fatalf("不应收取，得到 $%s", fee.FeeAmount)
+				}
+				return
+			}
+			if fee == nil {
+				t.Fatalf("应收取 $%s", tt.wantAmount)
+			}
+			if fee.HighWaterMark != money.One {
+				t.Errorf("高水位 = %s, want 成本净值 1", fee.HighWaterMark)
+			}
+			if fee.FeeAmount != money.MustParse(tt.wantAmount) || fee.FeeShares != money.MustParse(tt.wantShares) {
+				t.Errorf("报酬 = $%s (%s份), want $%s (%s份)", fee.FeeAmount, fee.FeeShares, tt.wantAmount, tt.wantShares)
+			}
+		})
+	}
+}
+
+// TestPerformanceFeeRaisesHighWaterMark 计提后高水位提高到计提时的净值，同一净值不再重复收取
+func TestPerformanceFeeRaisesHighWaterMark(t *testing.T) {
+	p := newTestPool(t)
+	p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.MustParse("0.2"))
+
+	fee, err := p.s.performanceFee(p.recharge(t), navAt("1.2"), model.FeeEventMonthly, "2024-01")
+	if err != nil || fee == nil {
+		t.Fatalf("performanceFee = %v, %v", fee, err)
+	}
+	if err := p.s.repo.ChargePerformanceFee(fee); err != nil {
+		t.Fatalf("ChargePerformanceFee: %v", err)
+	}
+
+	if hwm, _ := p.s.repo.GetHighWaterMark(p.rechargeID); hwm != money.MustParse("1.2") {
+		t.Errorf("高水位 = %s, want 1.2", hwm)
+	}
+	recharge := p.recharge(t)
+	if recharge.Shares != money.FromFloat(500).Sub(fee.FeeShares) || recharge.Amount != money.FromFloat(500) {
+		t.Errorf("计提后持仓 = %s份 / 本金%s, want 份额减少、本金不变", recharge.Shares, recharge.Amount)
+	}
+
+	if again, err := p.s.performanceFee(recharge, navAt("1.2"), model.FeeEventMonthly, "2024-02"); err != nil || again != nil {
+		t.Errorf("净值未超过新高水位 = %v, %v, want 不收取", again, err)
+	}
+	next, err := p.s.performanceFee(recharge, navAt("1.3"), model.FeeEventMonthly, "2024-02")
+	if err != nil || next == nil {
+		t.Fatalf("performanceFee(1.3) = %v, %v", next, err)
+	}
+	wantGain := money.RedeemValue(recharge.Shares, money.MustParse("0.1"), "USDT")
+	if next.HighWaterMark != money.MustParse("1.2") || next.Gain != wantGain {
+		t.Errorf("第二次计提 高水位 %s 收益 %s, want 1.2 / %s", next.HighWaterMark, next.Gain, wantGain)
+	}
+
+	if mismatches, unbalanced, err := p.s.VerifyLedger(); err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v", mismatches, unbalanced, err)
+	}
+}
+
+// TestFeeRateUserOverride 用户费率优先于账户默认费率，删除后回到默认
+func TestFeeRateUserOverride(t *testing.T) {
+	p := newTestPool(t)
+	other, _ := p.s.AdminCreateUser("13900000000")
+
+	rate := func(userID int) money.Decimal {
+		t.Helper()
+		r, err := p.s.feeRate(model.FeePerformance, p.account.ID, userID)
+		if err != nil {
+			t.Fatalf("feeRate: %v", err)
+		}
+		return r
+	}
+
+	if r := rate(p.userID); !r.IsZero() {
+		t.Errorf("没有设置时费率 = %s, want 0", r)
+	}
+	p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.MustParse("0.2"))
+	p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, p.userID, money.MustParse("0.1"))
+
+	tests := []struct {
+		name   string
+		userID int
+		want   string
+	}{
+		{"用户设置", p.userID, "0.1"},
+		{"账户默认", int(other), "0.2"},
+	}
+	for _, tt := range tests {
+		if r := rate(tt.userID); r != money.MustParse(tt.want) {
+			t.Errorf("%s: 费率 = %s, want %s", tt.name, r, tt.want)
+		}
+	}
+
+	if err := p.s.DeleteFeeRate(model.FeePerformance, p.account.ID, p.userID); err != nil {
+		t.Fatalf("DeleteFeeRate: %v", err)
+	}
+	if r := rate(p.userID); r != money.MustParse("0.2") {
+		t.Errorf("删除用户设置后费率 = %s, want 账户默认 0.2", r)
+	}
+
+	if _, err := p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.One); err == nil {
+		t.Error("100% 的费率应该被拒绝")
+	}
+	if _, err := p.s.SetFeeRate(1, model.FeeManagement, p.account.ID, p.userID, money.MustParse("0.02")); err == nil {
+		t.Error("管理费不能按用户设置")
+	}
+}
+
+// TestWithdrawChargesFeeAndRedeemsAtomically 撤资前计提的报酬和撤资一起保存，按扣除报酬后的份额定价
+func TestWithdrawChargesFeeAndRedeemsAtomically(t *testing.T) {
+	p := newTestPool(t)
+	p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.MustParse("0.2"))
+	p.setBalance("1200") // 净值 1.2
+
+	if err := p.s.WithdrawRecharge(p.rechargeID, p.userID); err != nil {
+		t.Fatalf("WithdrawRecharge: %v", err)
+	}
+
+	fees, totals, err := p.s.GetPerformanceFees(model.PerformanceFeeFilter{RechargeID: p.rechargeID})
+	if err != nil || len(fees) != 1 {
+		t.Fatalf("业绩报酬 = %v, %v", fees, err)
+	}
+	if fees[0].Event != model.FeeEventWithdrawal || totals.FeeAmount != money.FromFloat(20) {
+		t.Errorf("报酬 = %s $%s, want withdrawal $20", fees[0].Event, totals.FeeAmount)
+	}
+	withdrawals, _ := p.s.GetWithdrawals(p.userID)
+	if len(withdrawals) != 1 {
+		t.Fatalf("撤资记录 %d 条, want 1", len(withdrawals))
+	}
+	// (500 - 16.66666666) × 1.2 = 580.000000008，向下取整
+	if withdrawals[0].WithdrawnAmount != money.FromFloat(580) || withdrawals[0].FinalProfit != money.FromFloat(80) {
+		t.Errorf("撤资 = $%s (盈亏 $%s), want 580 / 80", withdrawals[0].WithdrawnAmount, withdrawals[0].FinalProfit)
+	}
+	if mismatches, unbalanced, err := p.s.VerifyLedger(); err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v", mismatches, unbalanced, err)
+	}
+}
+
+// TestSettlementFailureRollsBackFee 撤资结算失败时，同一事务中的业绩报酬一起回滚
+func TestSettlementFailureRollsBackFee(t *testing.T) {
+	p := newTestPool(t)
+	p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.MustParse("0.2"))
+
+	fee, rd, err := p.s.priceRedemptionWithFee(p.recharge(t), money.FromFloat(250), navAt("1.2"))
+	if err != nil || fee == nil {
+		t.Fatalf("priceRedemptionWithFee = %v, %v", fee, err)
+	}
+	if want := money.FromFloat(500).Sub(fee.FeeShares).MulRatio(money.FromFloat(250), money.FromFloat(500), money.RoundDown); rd.Shares != want {
+		t.Errorf("赎回份额 = %s, want 按扣除报酬后的份额 %s", rd.Shares, want)
+	}
+
+	// 申请不存在（或已被处理）：整个事务回滚
+	if err := p.s.repo.SettleWithdrawalRequest(999, fee, rd); !errors.Is(err, ErrWithdrawalStateChanged) {
+		t.Fatalf("SettleWithdrawalRequest err = %v, want ErrWithdrawalStateChanged", err)
+	}
+	fees, _, _ := p.s.GetPerformanceFees(model.PerformanceFeeFilter{RechargeID: p.rechargeID})
+	if len(fees) != 0 {
+		t.Errorf("结算失败后仍有 %d 条业绩报酬", len(fees))
+	}
+	if hwm, _ := p.s.repo.GetHighWaterMark(p.rechargeID); !hwm.IsZero() {
+		t.Errorf("结算失败后高水位 = %s, want 未变", hwm)
+	}
+	if recharge := p.recharge(t); recharge.Shares != money.FromFloat(500) || !recharge.IsActive {
+		t.Errorf("结算失败后持仓 = %+v, want 不变", recharge)
+	}
+}
+
+// TestMonthlyFeesRecordedOnce 月度计提完成后记录本期，同一期间不再重复收取
+func TestMonthlyFeesRecordedOnce(t *testing.T) {
+	p := newTestPool(t)
+	p.s.SetFeeRate(1, model.FeePerformance, p.account.ID, 0, money.MustParse("0.2"))
+
+	p.s.crystallizeMonthlyFees(p.account, "USDT", navAt("1.2"))
+	p.s.crystallizeMonthlyFees(p.account, "USDT", navAt("1.5"))
+
+	fees, totals, _ := p.s.GetPerformanceFees(model.PerformanceFeeFilter{AdminAccountID: p.account.ID})
+	if len(fees) != 1 || totals.FeeAmount != money.FromFloat(20) {
+		t.Errorf("月度报酬 %d 笔 $%s, want 1 笔 $20", len(fees), totals.FeeAmount)
+	}
+}
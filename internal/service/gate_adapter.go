@@ -0,0 +1,352 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// gateAdapter Gate.io交易所适配器（API v4：现货 + USDT本位永续合约）
+// Gate.io没有查询API密钥权限的接口，保存密钥时只能通过读取账户校验凭证。
+type gateAdapter struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// gateSettle 统计的合约结算币种（Gate.io的U本位永续只有USDT结算）
+const gateSettle = "usdt"
+
+// ==================== Gate.io API ====================
+
+// GetBalance 获取Gate.io现货 + 合约账户的 USDC+USDT 权益
+func (a *gateAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置Gate.io API Key")
+	}
+
+	totalBalance := money.Zero
+	for _, currency := range []string{"USDT", "USDC"} {
+		balance, err := a.GetBalanceByAsset(account, currency)
+		if err != nil {
+			fmt.Printf("  ⚠️  获取Gate.io账户失败: %v\n", err)
+			return 0, err
+		}
+		totalBalance += balance
+	}
+
+	fmt.Printf("  ✓ Gate.io 总资产: $%.2f\n", totalBalance)
+	return totalBalance, nil
+}
+
+// GetBalanceByAsset 获取Gate.io指定币种余额：现货可用+冻结，USDT另加永续合约账户权益
+func (a *gateAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置Gate.io API")
+	}
+
+	// 1. 现货账户
+	var spot []struct {
+		Currency  string `json:"currency"`
+		Available string `json:"available"` // 可用
+		Locked    string `json:"locked"`    // 冻结
+	}
+	if err := a.get(account, "/api/v4/spot/accounts", "currency="+currency, &spot); err != nil {
+		return 0, fmt.Errorf("现货账户: %v", err)
+	}
+
+	spotBalance := money.Zero
+	for _, asset := range spot {
+		if asset.Currency == currency {
+			spotBalance += parseAmount(asset.Available) + parseAmount(asset.Locked)
+		}
+	}
+
+	// 2. 永续合约账户（total 为不含未实现盈亏的余额）
+	futuresBalance := money.Zero
+	unrealized := money.Zero
+	if currency == "USDT" {
+		var futures struct {
+			Total         string `json:"total"`          // 账户余额
+			UnrealisedPnl string `json:"unrealised_pnl"` // 未实现盈亏
+			Available     string `json:"available"`      // 可用
+			Currency      string `json:"currency"`
+		}
+		if err := a.get(account, "/api/v4/futures/"+gateSettle+"/accounts", "", &futures); err != nil {
+			return 0, fmt.Errorf("永续合约账户: %v", err)
+		}
+		unrealized = parseAmount(futures.UnrealisedPnl)
+		futuresBalance = parseAmount(futures.Total) + unrealized
+	}
+
+	totalBalance := spotBalance + futuresBalance
+	if totalBalance > 0 {
+		fmt.Printf("  Gate.io %s: 总权益=$%.2f (现货=$%.2f, 合约=$%.2f, 未实现=$%.2f)\n",
+			currency, totalBalance, spotBalance, futuresBalance, unrealized)
+	}
+	return totalBalance, nil
+}
+
+// GetPositions 获取Gate.io USDT永续合约持仓（数量为合约张数）
+func (a *gateAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	var result []struct {
+		Contract           string `json:"contract"`             // 合约
+		Size               int64  `json:"size"`                 // 持仓张数，负数为空头
+		EntryPrice         string `json:"entry_price"`          // 开仓均价
+		MarkPrice          string `json:"mark_price"`           // 标记价格
+		Value              string `json:"value"`                // 按标记价格计算的仓位价值
+		UnrealisedPnl      string `json:"unrealised_pnl"`       // 未实现盈亏
+		Leverage           string `json:"leverage"`             // 杠杆倍数，0 表示全仓
+		CrossLeverageLimit string `json:"cross_leverage_limit"` // 全仓杠杆倍数
+	}
+	if err := a.get(account, "/api/v4/futures/"+gateSettle+"/positions", "holding=true", &result); err != nil {
+		return nil, err
+	}
+
+	positions := []model.Position{}
+	count := 0
+
+	for _, pos := range result {
+		// 跳过空仓
+		if pos.Size == 0 {
+			continue
+		}
+
+		if count >= limit {
+			break
+		}
+
+		entryPrice, _ := strconv.ParseFloat(pos.EntryPrice, 64)
+		markPrice, _ := strconv.ParseFloat(pos.MarkPrice, 64)
+		value, _ := strconv.ParseFloat(pos.Value, 64)
+		upl, _ := strconv.ParseFloat(pos.UnrealisedPnl, 64)
+		leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+
+		marginType := "isolated"
+		if leverage == 0 {
+			marginType = "cross"
+			leverage, _ = strconv.ParseFloat(pos.CrossLeverageLimit, 64)
+		}
+
+		side := "LONG"
+		size := float64(pos.Size)
+		if size < 0 {
+			side = "SHORT"
+			size = -size
+		}
+
+		// 张数不是币数，盈亏率按仓位价值计算
+		pnlRate := 0.0
+		if value > 0 {
+			pnlRate = (upl / value) * 100
+		}
+
+		positions = append(positions, model.Position{
+			Symbol:            pos.Contract,
+			Side:              side,
+			Size:              size,
+			EntryPrice:        entryPrice,
+			MarkPrice:         markPrice,
+			UnrealizedPnl:     upl,
+			UnrealizedPnlRate: pnlRate,
+			Leverage:          int(leverage),
+			MarginType:        marginType,
+		})
+
+		count++
+	}
+
+	return positions, nil
+}
+
+// GetOrders 获取Gate.io USDT永续合约当前委托（数量为合约张数）
+func (a *gateAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	var result []struct {
+		ID         int64   `json:"id"`          // 订单ID
+		Contract   string  `json:"contract"`    // 合约
+		Size       int64   `json:"size"`        // 委托张数，负数为卖出
+		Left       int64   `json:"left"`        // 未成交张数
+		Price      string  `json:"price"`       // 委托价格，市价单为0
+		Tif        string  `json:"tif"`         // gtc/ioc/poc/fok
+		Status     string  `json:"status"`      // 订单状态
+		CreateTime float64 `json:"create_time"` // 创建时间（秒）
+	}
+	query := fmt.Sprintf("status=open&limit=%d", limit)
+	if err := a.get(account, "/api/v4/futures/"+gateSettle+"/orders", query, &result); err != nil {
+		return nil, err
+	}
+
+	orders := []model.Order{}
+	count := 0
+
+	for _, ord := range result {
+		if count >= limit {
+			break
+		}
+
+		price, _ := strconv.ParseFloat(ord.Price, 64)
+
+		side := "BUY"
+		size, left := ord.Size, ord.Left
+		if size < 0 {
+			side = "SELL"
+			size, left = -size, -left
+		}
+
+		orderType := "LIMIT"
+		if price == 0 && ord.Tif == "ioc" {
+			orderType = "MARKET"
+		}
+
+		orderTime := time.Unix(int64(ord.CreateTime), 0).Format("2006-01-02 15:04:05")
+
+		orders = append(orders, model.Order{
+			OrderID:     strconv.FormatInt(ord.ID, 10),
+			Symbol:      ord.Contract,
+			Side:        side,
+			Type:        orderType,
+			Price:       price,
+			OrigQty:     float64(size),
+			ExecutedQty: float64(size - left),
+			Status:      ord.Status,
+			Time:        orderTime,
+		})
+
+		count++
+	}
+
+	return orders, nil
+}
+
+// GetHistoryTrades 获取Gate.io USDT永续合约平仓记录（数量为合约张数）
+func (a *gateAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	var result []struct {
+		Time          float64 `json:"time"`            // 平仓时间（秒）
+		FirstOpenTime int64   `json:"first_open_time"` // 开仓时间（秒）
+		Contract      string  `json:"contract"`        // 合约
+		Side          string  `json:"side"`            // 持仓方向 long/short
+		PnlPnl        string  `json:"pnl_pnl"`         // 平仓盈亏（不含手续费、资金费）
+		PnlFee        string  `json:"pnl_fee"`         // 手续费（负数）
+		AccumSize     string  `json:"accum_size"`      // 累计平仓张数
+		LongPrice     string  `json:"long_price"`      // 多头成交均价
+		ShortPrice    string  `json:"short_price"`     // 空头成交均价
+	}
+	query := fmt.Sprintf("limit=%d", limit)
+	if err := a.get(account, "/api/v4/futures/"+gateSettle+"/position_close", query, &result); err != nil {
+		return nil, err
+	}
+
+	trades := []model.HistoryTrade{}
+
+	for _, trade := range result {
+		pnl, _ := strconv.ParseFloat(trade.PnlPnl, 64)
+		fee, _ := strconv.ParseFloat(trade.PnlFee, 64)
+		qty, _ := strconv.ParseFloat(trade.AccumSize, 64)
+		longPrice, _ := strconv.ParseFloat(trade.LongPrice, 64)
+		shortPrice, _ := strconv.ParseFloat(trade.ShortPrice, 64)
+
+		// 多头按买入价开仓、卖出价平仓，空头相反
+		side, openPrice, closePrice := "LONG", longPrice, shortPrice
+		if trade.Side == "short" {
+			side, openPrice, closePrice = "SHORT", shortPrice, longPrice
+		}
+
+		trades = append(trades, model.HistoryTrade{
+			Symbol:      trade.Contract,
+			Side:        side,
+			OpenTime:    time.Unix(trade.FirstOpenTime, 0).Format("2006-01-02 15:04:05"),
+			CloseTime:   time.Unix(int64(trade.Time), 0).Format("2006-01-02 15:04:05"),
+			OpenPrice:   openPrice,
+			ClosePrice:  closePrice,
+			Quantity:    qty,
+			RealizedPnl: pnl,
+			Commission:  -fee,
+		})
+	}
+
+	return trades, nil
+}
+
+// CheckKeyPermissions 先读取现货账户校验凭证，再返回 ErrKeyPermissionsUnverifiable
+// Gate.io API v4 没有查询当前密钥权限的接口，无法确认密钥没有开启交易/提现，按只读校验的要求拒绝。
+func (a *gateAdapter) CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return nil, fmt.Errorf("未配置Gate.io API Key")
+	}
+
+	var spot []struct {
+		Currency string `json:"currency"`
+	}
+	if err := a.get(account, "/api/v4/spot/accounts", "currency=USDT", &spot); err != nil {
+		return nil, fmt.Errorf("Gate.io API密钥无效: %v", err)
+	}
+
+	return nil, fmt.Errorf("Gate.io %w", ErrKeyPermissionsUnverifiable)
+}
+
+// get 发送签名的GET请求，把响应解析到 out
+// 签名内容为 method\npath\nquery\nhex(sha512(body))\ntimestamp，HMAC-SHA512 十六进制
+func (a *gateAdapter) get(account *model.AdminAccount, path, query string, out interface{}) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	method := "GET"
+	bodyHash := sha512.Sum512(nil)
+	message := method + "\n" + path + "\n" + query + "\n" + hex.EncodeToString(bodyHash[:]) + "\n" + timestamp
+	signature := a.sign(message, account.APISecret)
+
+	url := a.baseURL + path
+	if query != "" {
+		url += "?" + query
+	}
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("KEY", account.APIKey)
+	req.Header.Set("SIGN", signature)
+	req.Header.Set("Timestamp", timestamp)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		var apiErr struct {
+			Label   string `json:"label"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Label != "" {
+			return fmt.Errorf("Gate.io API错误 [%s]: %s", apiErr.Label, apiErr.Message)
+		}
+		return fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	return json.Unmarshal(respBody, out)
+}
+
+// sign 生成Gate.io签名
+func (a *gateAdapter) sign(message, secret string) string {
+	h := hmac.New(sha512.New, []byte(secret))
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
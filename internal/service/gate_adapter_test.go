@@ -0,0 +1,38 @@
+package service
+
+import (
+	"crypto-final/internal/mockexchange"
+	"crypto-final/internal/money"
+	"testing"
+)
+
+// TestGateBalances 现货可用+冻结，USDT另加永续合约账户权益（含未实现盈亏）
+func TestGateBalances(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	mock.SetGateCredentials("gate-key", "gate-secret")
+	mock.SetGateSpotBalance("USDT", mockexchange.Balance{Wallet: 300, Locked: 50})
+	mock.SetGateSpotBalance("USDC", mockexchange.Balance{Wallet: 200})
+	mock.SetGateFuturesBalance(mockexchange.Balance{Wallet: 1000, UnrealizedPnl: -25})
+
+	account := sealedAccount(ws, "Gate", "gate-key", "gate-secret", "")
+	tests := []struct {
+		currency string
+		want     float64
+	}{
+		{"USDT", 1275},
+		{"USDC", 200},
+	}
+	for _, tt := range tests {
+		if balance, err := ws.GetBalanceByAsset(account, tt.currency); err != nil || balance != money.FromFloat(tt.want) {
+			t.Errorf("Gate %s = %v, %v, want %v", tt.currency, balance, err, tt.want)
+		}
+	}
+	if balance, err := ws.GetBalance(account); err != nil || balance != money.FromFloat(1475) {
+		t.Errorf("Gate = %v, %v, want 1475", balance, err)
+	}
+
+	wrong := sealedAccount(ws, "Gate", "gate-key", "not-the-secret", "")
+	if _, err := ws.GetBalance(wrong); err == nil {
+		t.Error("错误的签名应该被拒绝")
+	}
+}
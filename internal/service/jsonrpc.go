@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// jsonRPCRequest JSON-RPC 2.0 请求
+type jsonRPCRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// jsonRPCResponse JSON-RPC 2.0 响应
+type jsonRPCResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// jsonRPCCall 调用一次 JSON-RPC 方法，把 result 解析到 out
+func jsonRPCCall(client *http.Client, url, method string, params []interface{}, out interface{}) error {
+	body, err := json.Marshal(jsonRPCRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("节点返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result jsonRPCResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析JSON失败: %v", err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("%s 失败 [%d]: %s", method, result.Error.Code, result.Error.Message)
+	}
+	return json.Unmarshal(result.Result, out)
+}
+
+// jsonRPCBatch 把多个请求放在一次HTTP请求里发送，按请求顺序返回各自的 result
+// 任意一个请求出错都整体报错；不支持批量请求的节点会返回单个错误对象。
+func jsonRPCBatch(client *http.Client, url string, reqs []jsonRPCRequest) ([]json.RawMessage, error) {
+	for i := range reqs {
+		reqs[i].JSONRPC = "2.0"
+		reqs[i].ID = i + 1
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("节点返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(string(respBody)), "[") {
+		var single jsonRPCResponse
+		if err := json.Unmarshal(respBody, &single); err == nil && single.Error != nil {
+			return nil, fmt.Errorf("批量请求失败 [%d]: %s", single.Error.Code, single.Error.Message)
+		}
+		return nil, fmt.Errorf("节点不支持批量请求: %s", string(respBody))
+	}
+
+	var responses []jsonRPCResponse
+	if err := json.Unmarshal(respBody, &responses); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+
+	// 响应顺序不保证与请求一致，按 id 对应
+	results := make([]json.RawMessage, len(reqs))
+	for _, r := range responses {
+		if r.ID < 1 || r.ID > len(reqs) {
+			continue
+		}
+		if r.Error != nil {
+			return nil, fmt.Errorf("%s 失败 [%d]: %s", reqs[r.ID-1].Method, r.Error.Code, r.Error.Message)
+		}
+		results[r.ID-1] = r.Result
+	}
+	for i, r := range results {
+		if r == nil {
+			return nil, fmt.Errorf("%s 没有返回结果", reqs[i].Method)
+		}
+	}
+	return results, nil
+}
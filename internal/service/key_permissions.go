@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyPermissionsUnverifiable 场所不提供查询密钥权限的接口，无法确认密钥是只读的
+var ErrKeyPermissionsUnverifiable = errors.New("不提供查询API密钥权限的接口，无法确认密钥是只读的，不能保存")
+
+// ValidateReadOnlyKey 保存密钥前向场所校验凭证和权限
+// 系统只读取余额和持仓，开启了交易或提现权限的密钥一律拒绝；
+// 场所没有实现 KeyPermissionChecker 时（如链上钱包）跳过；无法查询权限的交易所返回 ErrKeyPermissionsUnverifiable。
+func (ws *WalletService) ValidateReadOnlyKey(account *model.AdminAccount) error {
+	adapter, ok := ws.adapterFor(account.AccountType)
+	if !ok {
+		return fmt.Errorf("不支持的账户类型: %s", account.AccountType)
+	}
+	checker, ok := adapter.(KeyPermissionChecker)
+	if !ok {
+		return nil
+	}
+
+	signing, err := ws.signingAccount(account)
+	if err != nil {
+		return err
+	}
+	perms, err := checker.CheckKeyPermissions(signing)
+	if err != nil {
+		return err
+	}
+
+	if !perms.Read {
+		return fmt.Errorf("%s API密钥没有读取权限", account.AccountType)
+	}
+
+	var extra []string
+	if perms.Trade {
+		extra = append(extra, "交易")
+	}
+	if perms.Withdraw {
+		extra = append(extra, "提现/转账")
+	}
+	if len(extra) > 0 {
+		return fmt.Errorf("%s API密钥开启了%s权限（%s），本系统只需要只读权限，请在交易所后台关闭后重新提交",
+			account.AccountType, strings.Join(extra, "、"), strings.Join(perms.Raw, ","))
+	}
+
+	fmt.Printf("  ✓ %s API密钥权限校验通过（只读）\n", account.AccountType)
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"crypto-final/internal/mockexchange"
+	"crypto-final/internal/model"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestValidateReadOnlyKey 按各交易所返回的权限字段判断密钥是否只读
+func TestValidateReadOnlyKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		accountType string
+		key         string
+		secret      string
+		passphrase  string
+		perms       mockexchange.Permissions
+		want        string // 空表示通过
+	}{
+		{"Binance 只读", "Binance", "binance-key", "binance-secret", "", mockexchange.Permissions{}, ""},
+		{"Binance 交易", "Binance", "binance-key", "binance-secret", "", mockexchange.Permissions{Trade: true}, "交易"},
+		{"Binance 提现", "Binance", "binance-key", "binance-secret", "", mockexchange.Permissions{Withdraw: true}, "提现"},
+		{"Binance 错误的 Secret", "Binance", "binance-key", "not-the-secret", "", mockexchange.Permissions{}, "无效"},
+		{"OKX 只读", "OKX", "okx-key", "okx-secret", "okx-pass", mockexchange.Permissions{}, ""},
+		{"OKX 交易", "OKX", "okx-key", "okx-secret", "okx-pass", mockexchange.Permissions{Trade: true}, "交易"},
+		{"OKX 提现", "OKX", "okx-key", "okx-secret", "okx-pass", mockexchange.Permissions{Withdraw: true}, "提现"},
+		{"OKX 错误的 Passphrase", "OKX", "okx-key", "okx-secret", "wrong-pass", mockexchange.Permissions{}, "无效"},
+		{"Bybit 只读", "Bybit", "bybit-key", "bybit-secret", "", mockexchange.Permissions{}, ""},
+		{"Bybit 交易", "Bybit", "bybit-key", "bybit-secret", "", mockexchange.Permissions{Trade: true}, "交易"},
+		{"Bybit 提现", "Bybit", "bybit-key", "bybit-secret", "", mockexchange.Permissions{Withdraw: true}, "提现"},
+		{"Bybit 错误的 Secret", "Bybit", "bybit-key", "not-the-secret", "", mockexchange.Permissions{}, "无效"},
+		{"Bitget 只读", "Bitget", "bitget-key", "bitget-secret", "bitget-pass", mockexchange.Permissions{}, ""},
+		{"Bitget 交易", "Bitget", "bitget-key", "bitget-secret", "bitget-pass", mockexchange.Permissions{Trade: true}, "交易"},
+		{"Bitget 提现", "Bitget", "bitget-key", "bitget-secret", "bitget-pass", mockexchange.Permissions{Withdraw: true}, "提现"},
+		{"Bitget 错误的 Passphrase", "Bitget", "bitget-key", "bitget-secret", "wrong", mockexchange.Permissions{}, "无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, mock := newMockExchangeWallet(t)
+			mock.SetBybitCredentials("bybit-key", "bybit-secret")
+			mock.SetBitgetCredentials("bitget-key", "bitget-secret", "bitget-pass")
+			mock.SetBinancePermissions(tt.perms)
+			mock.SetOKXPermissions(tt.perms)
+			mock.SetBybitPermissions(tt.perms)
+			mock.SetBitgetPermissions(tt.perms)
+
+			err := ws.ValidateReadOnlyKey(sealedAccount(ws, tt.accountType, tt.key, tt.secret, tt.passphrase))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("ValidateReadOnlyKey: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want 包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestGateKeyPermissionsUnverifiable Gate.io 无法查询密钥权限：凭证有效也拒绝保存，凭证无效时报凭证错误
+func TestGateKeyPermissionsUnverifiable(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	mock.SetGateCredentials("gate-key", "gate-secret")
+
+	err := ws.ValidateReadOnlyKey(sealedAccount(ws, "Gate", "gate-key", "gate-secret", ""))
+	if !errors.Is(err, ErrKeyPermissionsUnverifiable) {
+		t.Errorf("有效凭证 err = %v, want ErrKeyPermissionsUnverifiable", err)
+	}
+
+	err = ws.ValidateReadOnlyKey(sealedAccount(ws, "Gate", "gate-key", "not-the-secret", ""))
+	if err == nil || errors.Is(err, ErrKeyPermissionsUnverifiable) || !strings.Contains(err.Error(), "无效") {
+		t.Errorf("错误的 Secret err = %v, want 凭证无效", err)
+	}
+}
+
+// fakePermissionAdapter 同时实现 KeyPermissionChecker
+type fakePermissionAdapter struct {
+	*fakeAdapter
+	perms model.KeyPermissions
+}
+
+func (f *fakePermissionAdapter) CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error) {
+	return &f.perms, nil
+}
+
+// TestSaveUserAPIKeysRejectsTradingKey API用户保存带交易权限的密钥被拒绝，只读密钥可以保存
+func TestSaveUserAPIKeysRejectsTradingKey(t *testing.T) {
+	adapter := &fakePermissionAdapter{
+		fakeAdapter: newFakeAdapter(100, 0),
+		perms:       model.KeyPermissions{Read: true, Trade: true, Raw: []string{"read_only", "trade"}},
+	}
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"OKX": adapter}))
+
+	userID, _ := s.CreateAPIUser("trader", "trader-password")
+	err := s.SaveUserAPIKeys(int(userID), "OKX", "key", "secret", "pass")
+	if err == nil || !strings.Contains(err.Error(), "交易") {
+		t.Fatalf("SaveUserAPIKeys err = %v, want 拒绝交易权限", err)
+	}
+	if user, _ := s.repo.GetUserByID(int(userID)); user.APIKey != "" {
+		t.Errorf("校验失败时不应保存，得到 %q", user.APIKey)
+	}
+
+	adapter.perms = model.KeyPermissions{Read: true}
+	if err := s.SaveUserAPIKeys(int(userID), "OKX", "key", "secret", "pass"); err != nil {
+		t.Fatalf("只读密钥 SaveUserAPIKeys: %v", err)
+	}
+}
+
+// TestConfigAdminAccountRejectsInvalidKey 配置Admin账户时向交易所校验，凭证错误或有交易权限时不保存
+func TestConfigAdminAccountRejectsInvalidKey(t *testing.T) {
+	ws, mock := newMockExchangeWallet(t)
+	s := newTestService(t, ws)
+
+	if err := s.ConfigAdminAccount("Binance", "binance-key", "not-the-secret", "", ""); err == nil {
+		t.Error("ConfigAdminAccount 应该拒绝错误的 Secret")
+	}
+	mock.SetBinancePermissions(mockexchange.Permissions{Trade: true})
+	if err := s.ConfigAdminAccount("Binance", "binance-key", "binance-secret", "", ""); err == nil || !strings.Contains(err.Error(), "交易") {
+		t.Errorf("交易权限 err = %v, want 拒绝", err)
+	}
+	if saved, _ := s.repo.GetAdminAccountByType("Binance"); saved.APIKey != "" {
+		t.Errorf("校验失败时不应保存，得到 %q", saved.APIKey)
+	}
+
+	mock.SetBinancePermissions(mockexchange.Permissions{})
+	if err := s.ConfigAdminAccount("Binance", "binance-key", "binance-secret", "", ""); err != nil {
+		t.Fatalf("只读密钥 ConfigAdminAccount: %v", err)
+	}
+	if saved, _ := s.repo.GetAdminAccountByType("Binance"); saved.APIKey != "binance-key" {
+		t.Errorf("保存的 APIKey = %q", saved.APIKey)
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/repository"
+	"errors"
+	"fmt"
+)
+
+// GetRechargeJournal 获取某笔充值的全部分录，按记账顺序
+func (s *Service) GetRechargeJournal(rechargeID int) ([]*model.JournalEntry, error) {
+	recharge, err := s.repo.GetRechargeByID(rechargeID)
+	if err != nil {
+		return nil, err
+	}
+	if recharge == nil {
+		return nil, errors.New("充值记录不存在")
+	}
+	return s.repo.GetJournalByAccount(repository.RechargeAccount(rechargeID))
+}
+
+// VerifyLedger 核对充值记录与分录是否一致
+// 活跃充值的金额、份额应等于分录汇总；已停用充值的分录余额应为0；每笔分录借贷平衡。
+func (s *Service) VerifyLedger() ([]*model.LedgerMismatch, []int, error) {
+	mismatches, unbalanced, err := s.repo.VerifyLedger()
+	if err != nil {
+		return nil, nil, fmt.Errorf("核对分录失败: %v", err)
+	}
+
+	if len(mismatches) > 0 || len(unbalanced) > 0 {
+		fmt.Printf("⚠️  分录核对: %d 笔充值不一致, %d 笔分录不平衡\n", len(mismatches), len(unbalanced))
+	}
+	return mismatches, unbalanced, nil
+}
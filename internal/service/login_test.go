@@ -0,0 +1,47 @@
+package service
+
+import (
+	"crypto-final/internal/password"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestLoginUpgradesLegacyHash 旧版无盐 SHA-256 哈希在登录成功后升级为 bcrypt，错误密码不升级
+func TestLoginUpgradesLegacyHash(t *testing.T) {
+	s := newTestService(t, nil)
+	id, err := s.AdminCreateUser("13800000000")
+	if err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+	sum := sha256.Sum256([]byte("abc123456"))
+	legacy := hex.EncodeToString(sum[:])
+	if err := s.repo.UpdateUserPassword(int(id), legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Login("13800000000", "wrong"); err == nil {
+		t.Fatal("错误的密码登录成功")
+	}
+	if user, _ := s.repo.GetUserByID(int(id)); user.PasswordHash != legacy {
+		t.Errorf("密码错误时哈希被修改为 %s", user.PasswordHash)
+	}
+
+	user, err := s.Login("13800000000", "abc123456")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	stored, _ := s.repo.GetUserByID(int(id))
+	if !strings.HasPrefix(stored.PasswordHash, "$2") || user.PasswordHash != stored.PasswordHash {
+		t.Fatalf("登录后哈希 = %s, want 升级为 bcrypt", stored.PasswordHash)
+	}
+	if ok, rehash := password.Verify(stored.PasswordHash, "abc123456"); !ok || rehash {
+		t.Errorf("升级后的哈希 Verify = %v, %v", ok, rehash)
+	}
+
+	// 升级后仍可用同一密码登录
+	if _, err := s.Login("13800000000", "abc123456"); err != nil {
+		t.Errorf("升级后登录: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/repository"
+	"fmt"
+	"time"
+)
+
+// recentAccrualDays 充值统计中每个账户展示的最近计提记录数
+const recentAccrualDays = 7
+
+// accrueManagementFee 按日计提某资金池的管理费，增发份额给管理人账户
+// 管理费 = AUM × 年费率 × 天数 / 365，AUM 不含管理人自己持有的份额；天数为距上次计提的天数，
+// 费率在上次计提后修改过时只计1天，不按新费率追溯。同一天只计提一次。
+// 返回计提后的净值（已写入 nav_history）；不需要计提或计提失败时原样返回 nav。
+func (s *Service) accrueManagementFee(account *model.AdminAccount, currency string, nav *model.NAVRecord) *model.NAVRecord {
+	rate, err := s.repo.GetFeeRate(model.FeeManagement, account.ID, 0)
+	if err != nil {
+		fmt.Printf("⚠️  读取%s管理费费率失败: %v\n", account.AccountType, err)
+		return nav
+	}
+	if rate == nil || rate.Rate.Sign() <= 0 || nav.TotalShares.Sign() <= 0 || nav.Balance.Sign() <= 0 {
+		return nav
+	}
+
+	last, err := s.repo.GetLastManagementFeeAccrual(account.ID, currency)
+	if err != nil {
+		fmt.Printf("⚠️  读取%s %s管理费计提记录失败: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+	days := 1
+	if last != nil {
+		if last.AccrualDate >= nav.RecordDate {
+			return nav
+		}
+		if !rate.UpdatedAt.After(last.CreatedAt) {
+			days = daysBetween(last.AccrualDate, nav.RecordDate)
+		}
+	}
+
+	managerShares, _, err := s.repo.GetLedgerBalance(repository.ManagerAccount(account.ID, currency))
+	if err != nil {
+		fmt.Printf("⚠️  读取%s %s管理人份额失败: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+
+	aum, err := money.MarkValueChecked(nav.TotalShares.Sub(managerShares), nav.NAV, currency)
+	if err != nil {
+		fmt.Printf("⚠️  %s %s 管理费AUM计算失败: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+	fee, err := aum.MulRatioChecked(rate.Rate, money.One, money.RoundDown)
+	if err == nil {
+		fee, err = fee.MulRatioChecked(money.FromInt(int64(days)), money.FromInt(365), money.RoundDown)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  %s %s 管理费计算失败: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+	fee = fee.Round(money.CurrencyPlaces(currency), money.RoundDown)
+	if fee.Sign() <= 0 || fee.Cmp(nav.Balance) >= 0 {
+		return nav
+	}
+
+	// 增发 m 份后 m × 新净值 = 管理费：m = 管理费 × 总份额 / (余额 - 管理费)
+	feeShares, err := nav.TotalShares.MulRatioChecked(fee, nav.Balance.Sub(fee), money.RoundDown)
+	if err != nil {
+		fmt.Printf("⚠️  %s %s 管理费份额计算失败: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+	feeShares = feeShares.Round(money.SharePlaces, money.RoundDown)
+	if feeShares.Sign() <= 0 {
+		return nav
+	}
+
+	after := &model.NAVRecord{
+		AdminAccountID: nav.AdminAccountID,
+		Currency:       nav.Currency,
+		RecordDate:     nav.RecordDate,
+		Balance:        nav.Balance,
+		TotalShares:    nav.TotalShares.Add(feeShares),
+	}
+	if after.NAV, err = money.NAVChecked(after.Balance, after.TotalShares); err != nil || after.NAV.Sign() <= 0 {
+		fmt.Printf("⚠️  %s %s 管理费计提后净值异常: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+
+	accrual := &model.ManagementFeeAccrual{
+		AdminAccountID: account.ID,
+		Currency:       currency,
+		AccrualDate:    nav.RecordDate,
+		Days:           days,
+		AUM:            aum,
+		Rate:           rate.Rate,
+		FeeAmount:      fee,
+		FeeShares:      feeShares,
+		NAVBefore:      nav.NAV,
+		NAVAfter:       after.NAV,
+	}
+	if err := s.repo.AccrueManagementFee(accrual); err != nil {
+		fmt.Printf("❌ %s %s 管理费计提失败: %v\n", account.AccountType, currency, err)
+		return nav
+	}
+
+	fmt.Printf("💰 %s %s 管理费: AUM $%.2f × %.2f%% × %d/365 = $%.2f (增发%.4f份), 净值 $%.4f → $%.4f\n",
+		account.AccountType, currency, aum, rate.Rate.Float64()*100, days, fee, feeShares, nav.NAV, after.NAV)
+
+	if err := s.repo.SaveNAV(after.AdminAccountID, after.Currency, after.RecordDate, after.Balance, after.TotalShares, after.NAV); err != nil {
+		fmt.Printf("⚠️  保存计提后净值失败: %v\n", err)
+	}
+	if _, err := s.refreshAccountShares(account.ID); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	return after
+}
+
+// GetManagementFeeAccruals 按条件查询管理费计提记录
+func (s *Service) GetManagementFeeAccruals(filter model.ManagementFeeFilter) ([]*model.ManagementFeeAccrual, error) {
+	return s.repo.QueryManagementFeeAccruals(filter)
+}
+
+// daysBetween 两个 YYYY-MM-DD 日期之间的天数，至少为1
+func daysBetween(from, to string) int {
+	start, err1 := time.Parse("2006-01-02", from)
+	end, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		return 1
+	}
+	days := int(end.Sub(start).Hours() / 24)
+	if days < 1 {
+		return 1
+	}
+	return days
+}
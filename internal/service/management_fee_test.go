@@ -0,0 +1,93 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/repository"
+	"testing"
+)
+
+// poolNAV 资金池在某天的净值记录
+func (p *testPool) poolNAV(date, balance, totalShares string) *model.NAVRecord {
+	b, shares := money.MustParse(balance), money.MustParse(totalShares)
+	return &model.NAVRecord{
+		AdminAccountID: p.account.ID,
+		Currency:       "USDT",
+		RecordDate:     date,
+		Balance:        b,
+		TotalShares:    shares,
+		NAV:            money.NAV(b, shares),
+	}
+}
+
+// TestManagementFeeAccrual 增发 m = 总份额 × 管理费 / (余额 - 管理费) 份，计提后净值 = (余额 - 管理费) / 原总份额
+func TestManagementFeeAccrual(t *testing.T) {
+	p := newTestPool(t)
+	if _, err := p.s.SetFeeRate(1, model.FeeManagement, p.account.ID, 0, money.MustParse("0.0365")); err != nil {
+		t.Fatalf("SetFeeRate: %v", err)
+	}
+
+	nav := p.poolNAV("2024-02-01", "1000", "1000")
+	after := p.s.accrueManagementFee(p.account, "USDT", nav)
+
+	accruals, err := p.s.GetManagementFeeAccruals(model.ManagementFeeFilter{AdminAccountID: p.account.ID})
+	if err != nil || len(accruals) != 1 {
+		t.Fatalf("计提记录 = %v, %v", accruals, err)
+	}
+	a := accruals[0]
+	// 1000 × 3.65% / 365 = 0.1；1000 × 0.1 / 999.9 = 0.10001000(1...)
+	if a.AUM != money.FromInt(1000) || a.FeeAmount != money.MustParse("0.1") || a.FeeShares != money.MustParse("0.10001") {
+		t.Errorf("计提 AUM $%s 管理费 $%s 增发 %s份, want 1000 / 0.1 / 0.10001", a.AUM, a.FeeAmount, a.FeeShares)
+	}
+	wantNAV := money.NAV(nav.Balance.Sub(a.FeeAmount), nav.TotalShares)
+	if after.NAV != wantNAV || a.NAVAfter != wantNAV || wantNAV != money.MustParse("0.9999") {
+		t.Errorf("计提后净值 = %s (记录 %s), want (余额-管理费)/总份额 = %s", after.NAV, a.NAVAfter, wantNAV)
+	}
+	if after.TotalShares != nav.TotalShares.Add(a.FeeShares) || after.Balance != nav.Balance {
+		t.Errorf("计提后 总份额 %s 余额 %s, want 增发后份额、余额不变", after.TotalShares, after.Balance)
+	}
+
+	// 管理人份额按新净值估值正好等于管理费
+	managerShares, _, _ := p.s.repo.GetLedgerBalance(repository.ManagerAccount(p.account.ID, "USDT"))
+	if managerShares != a.FeeShares || money.MarkValue(managerShares, after.NAV, "USDT") != a.FeeAmount {
+		t.Errorf("管理人持有 %s份, want %s份、价值 $%s", managerShares, a.FeeShares, a.FeeAmount)
+	}
+
+	// 同一天不重复计提
+	if again := p.s.accrueManagementFee(p.account, "USDT", nav); again != nav {
+		t.Errorf("同一天再次计提 = %+v, want 原样返回", again)
+	}
+	if mismatches, unbalanced, err := p.s.VerifyLedger(); err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v", mismatches, unbalanced, err)
+	}
+}
+
+// TestManagementFeeExcludesManagerShares AUM 不含管理人持有的份额，按距上次计提的天数收取
+func TestManagementFeeExcludesManagerShares(t *testing.T) {
+	p := newTestPool(t)
+	p.s.SetFeeRate(1, model.FeeManagement, p.account.ID, 0, money.MustParse("0.0365"))
+	first := p.s.accrueManagementFee(p.account, "USDT", p.poolNAV("2024-02-01", "1000", "1000"))
+
+	p.s.accrueManagementFee(p.account, "USDT", p.poolNAV("2024-02-03", "1000", first.TotalShares.String()))
+
+	last, err := p.s.repo.GetLastManagementFeeAccrual(p.account.ID, "USDT")
+	if err != nil || last == nil || last.AccrualDate != "2024-02-03" {
+		t.Fatalf("第二次计提 = %+v, %v", last, err)
+	}
+	// AUM = 1000份 × 0.9999，不含管理人的 0.10001 份；管理费 = 999.9 × 3.65% × 2 / 365
+	if last.Days != 2 || last.AUM != money.MustParse("999.9") || last.FeeAmount != money.MustParse("0.19998") {
+		t.Errorf("第二次计提 %d天 AUM $%s 管理费 $%s, want 2天 / 999.9 / 0.19998", last.Days, last.AUM, last.FeeAmount)
+	}
+}
+
+// TestManagementFeeWithoutRate 没有设置管理费费率时不计提
+func TestManagementFeeWithoutRate(t *testing.T) {
+	p := newTestPool(t)
+	nav := p.poolNAV("2024-02-01", "1000", "1000")
+	if after := p.s.accrueManagementFee(p.account, "USDT", nav); after != nav {
+		t.Errorf("没有费率时 = %+v, want 原样返回", after)
+	}
+	if accruals, _ := p.s.GetManagementFeeAccruals(model.ManagementFeeFilter{}); len(accruals) != 0 {
+		t.Errorf("计提记录 %d 条, want 0", len(accruals))
+	}
+}
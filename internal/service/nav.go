@@ -0,0 +1,158 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"fmt"
+	"time"
+)
+
+// computeNAV 按当前余额计算某账户某币种的净值（不保存）
+// 该币种还没有任何份额时按面值1.0入池。
+func (s *Service) computeNAV(account *model.AdminAccount, currency string) (*model.NAVRecord, error) {
+	totalShares, err := s.repo.GetTotalSharesByCurrency(account.ID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("获取总份额失败: %v", err)
+	}
+
+	record := &model.NAVRecord{
+		AdminAccountID: account.ID,
+		Currency:       currency,
+		RecordDate:     time.Now().Format("2006-01-02"),
+		TotalShares:    totalShares,
+		NAV:            money.One,
+	}
+
+	if totalShares.Sign() > 0 {
+		balance, err := s.walletService.GetBalanceByAsset(account, currency)
+		if err != nil {
+			return nil, fmt.Errorf("获取%s %s余额失败: %v", account.AccountType, currency, err)
+		}
+		if balance.Sign() <= 0 {
+			return nil, fmt.Errorf("%s %s 有 %.4f 份额但余额为 %.2f，无法计算净值", account.AccountType, currency, totalShares, balance)
+		}
+		nav, err := money.NAVChecked(balance, totalShares)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s 余额 %.2f / 份额 %.4f 无法计算净值: %v", account.AccountType, currency, balance, totalShares, err)
+		}
+		if nav.Sign() <= 0 {
+			return nil, fmt.Errorf("%s %s 净值为 %s（余额 %.2f / 份额 %.4f），拒绝记录", account.AccountType, currency, nav, balance, totalShares)
+		}
+		record.Balance = balance
+		record.NAV = nav
+	}
+	return record, nil
+}
+
+// strikeNAV 申购和赎回成交前按当前余额计算净值，写入盘中净值表 nav_strikes
+// 按这个价格成交，保证后加入的用户不会分走之前的收益；不影响 nav_history 中的每日收盘净值。
+func (s *Service) strikeNAV(account *model.AdminAccount, currency, event string) (*model.NAVRecord, error) {
+	record, err := s.computeNAV(account, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.SaveNAVStrike(&model.NAVStrike{
+		AdminAccountID: record.AdminAccountID,
+		Currency:       record.Currency,
+		Event:          event,
+		Balance:        record.Balance,
+		TotalShares:    record.TotalShares,
+		NAV:            record.NAV,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存盘中净值失败: %v", err)
+	}
+	return record, nil
+}
+
+// closeDailyNAV 每日检查时记录当天的收盘净值（nav_history），盈亏、费用和撤资申请都按它计算
+func (s *Service) closeDailyNAV(account *model.AdminAccount, currency string) (*model.NAVRecord, error) {
+	record, err := s.computeNAV(account, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.SaveNAV(record.AdminAccountID, record.Currency, record.RecordDate, record.Balance, record.TotalShares, record.NAV)
+	if err != nil {
+		return nil, fmt.Errorf("保存净值失败: %v", err)
+	}
+	return record, nil
+}
+
+// latestNAV 读取某账户某币种最近的收盘净值，所有盈亏计算都以它为准
+// 还没有记录时（新币种或升级后第一次每日检查之前）按当前余额计算，不写入收盘净值。
+func (s *Service) latestNAV(account *model.AdminAccount, currency string) (*model.NAVRecord, error) {
+	record, err := s.repo.GetLatestNAV(account.ID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("读取净值失败: %v", err)
+	}
+	if record != nil {
+		return record, nil
+	}
+	return s.computeNAV(account, currency)
+}
+
+// GetNAVHistory 获取某账户某币种的净值序列
+func (s *Service) GetNAVHistory(adminAccountID int, currency, from, to string) ([]*model.NAVRecord, error) {
+	account, err := s.repo.GetAdminAccountByID(adminAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("Admin账户不存在: %d", adminAccountID)
+	}
+	return s.repo.GetNAVHistory(adminAccountID, currency, from, to)
+}
+
+// refreshAccountShares 重新汇总Admin账户总份额（所有币种之和）
+func (s *Service) refreshAccountShares(adminAccountID int) (money.Decimal, error) {
+	allShares, err := s.repo.GetAllSharesByAccount(adminAccountID)
+	if err != nil {
+		return 0, fmt.Errorf("获取总份额失败: %v", err)
+	}
+	if err := s.repo.UpdateAdminAccountShares(adminAccountID, allShares); err != nil {
+		return 0, fmt.Errorf("更新总份额失败: %v", err)
+	}
+	return allShares, nil
+}
+
+// rechargeValue 按份额估值：当前价值 = 持有份额 × 最近记录的净值（四舍五入到币种精度）
+func (s *Service) rechargeValue(account *model.AdminAccount, recharge *model.Recharge) (money.Decimal, *model.NAVRecord, error) {
+	if recharge.Shares.Sign() <= 0 {
+		return 0, nil, fmt.Errorf("份额数据异常(shares=%.4f)", recharge.Shares)
+	}
+
+	nav, err := s.latestNAV(account, recharge.Currency)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	value, err := money.MarkValueChecked(recharge.Shares, nav.NAV, recharge.Currency)
+	if err != nil {
+		return 0, nil, fmt.Errorf("估值失败: %v", err)
+	}
+	return value, nav, nil
+}
+
+// CanViewNAV 有账目查看权限的工作人员可以查看所有资金池，投资人只能查看自己持有的资金池
+func (s *Service) CanViewNAV(user *model.User, adminAccountID int, currency string) (bool, error) {
+	if user.HasPermission(model.PermViewLedger) {
+		return true, nil
+	}
+	recharges, err := s.repo.GetRechargesByUserID(user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range recharges {
+		if r.IsActive && r.AdminAccountID == adminAccountID && r.Currency == currency {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetNAVStrikes 获取某账户某币种的盘中净值（每笔申购、赎回的成交价格）
+func (s *Service) GetNAVStrikes(adminAccountID int, currency, from, to string) ([]*model.NAVStrike, error) {
+	return s.repo.GetNAVStrikes(adminAccountID, currency, from, to)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"strings"
+	"testing"
+)
+
+// TestSharesIssuedAtCurrentNAV 入金和申购都按成交时的净值计算份额，后加入的用户不分走之前的收益
+func TestSharesIssuedAtCurrentNAV(t *testing.T) {
+	p := newTestPool(t)
+	p.setBalance("1200") // 净值 1.2
+
+	// 管理员再入金 600：按到账前的净值 1.2 发行 500 份给系统账户
+	if err := p.s.AdminDepositToExchange(p.account.ID, money.FromFloat(600), "USDT"); err != nil {
+		t.Fatalf("AdminDepositToExchange: %v", err)
+	}
+	p.setBalance("1800")
+
+	other, _ := p.s.AdminCreateUser("13900000000")
+	rechargeID, err := p.s.AdminRecharge(int(other), p.account.ID, money.FromFloat(600), "USDT")
+	if err != nil {
+		t.Fatalf("AdminRecharge: %v", err)
+	}
+	joined, _ := p.s.repo.GetRechargeByID(int(rechargeID))
+	if joined.Shares != money.FromFloat(500) || joined.Amount != money.FromFloat(600) {
+		t.Errorf("新申购 = %s份 / $%s, want 500 / 600", joined.Shares, joined.Amount)
+	}
+
+	// 先加入的用户仍然按 1.2 估值
+	value, _, err := p.s.rechargeValue(p.account, p.recharge(t))
+	if err != nil || value != money.FromFloat(600) {
+		t.Errorf("原用户价值 = %s, %v, want 600", value, err)
+	}
+
+	strikes, err := p.s.GetNAVStrikes(p.account.ID, "USDT", "", "")
+	if err != nil {
+		t.Fatalf("GetNAVStrikes: %v", err)
+	}
+	events := map[string]money.Decimal{}
+	for _, s := range strikes {
+		events[s.Event] = s.NAV
+	}
+	if events[model.NAVEventDeposit] != money.MustParse("1.2") || events[model.NAVEventSubscription] != money.MustParse("1.2") {
+		t.Errorf("盘中净值 = %v, want 入金和申购都是 1.2", events)
+	}
+
+	// 系统账户只剩 500 份，按 1.2 只够 600
+	if _, err := p.s.AdminRecharge(int(other), p.account.ID, money.MustParse("600.01"), "USDT"); err == nil || !strings.Contains(err.Error(), "份额不足") {
+		t.Errorf("超过系统账户份额 err = %v, want 份额不足", err)
+	}
+	if mismatches, unbalanced, err := p.s.VerifyLedger(); err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v", mismatches, unbalanced, err)
+	}
+}
+
+// TestDailyNAVPerCurrency 每日检查按币种分别记录净值，充值盈亏按所在币种的净值计算
+func TestDailyNAVPerCurrency(t *testing.T) {
+	binance := newFakeAdapter(0, 0)
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"Binance": binance}))
+	s.SetPriceSource(nil)
+	if err := s.ConfigAdminAccount("Binance", "binance-key", "binance-secret", "", ""); err != nil {
+		t.Fatalf("ConfigAdminAccount: %v", err)
+	}
+	account, _ := s.repo.GetAdminAccountByType("Binance")
+
+	// 管理员入金 1000 USDT、500 USDC（净值 1.0），投资人申购 400 USDT
+	if err := s.AdminDepositToExchange(account.ID, money.FromFloat(1000), "USDT"); err != nil {
+		t.Fatalf("AdminDepositToExchange USDT: %v", err)
+	}
+	binance.balances["USDT"] = money.FromFloat(1000)
+	if err := s.AdminDepositToExchange(account.ID, money.FromFloat(500), "USDC"); err != nil {
+		t.Fatalf("AdminDepositToExchange USDC: %v", err)
+	}
+	binance.balances["USDC"] = money.FromFloat(500)
+
+	userID, _ := s.AdminCreateUser("13800000000")
+	rechargeID, err := s.AdminRecharge(int(userID), account.ID, money.FromFloat(400), "USDT")
+	if err != nil {
+		t.Fatalf("AdminRecharge: %v", err)
+	}
+
+	// USDT 池涨10%，USDC 池跌20%
+	binance.balances["USDT"] = money.FromFloat(1100)
+	binance.balances["USDC"] = money.FromFloat(400)
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+
+	for currency, want := range map[string]money.Decimal{"USDT": money.MustParse("1.1"), "USDC": money.MustParse("0.8")} {
+		history, err := s.GetNAVHistory(account.ID, currency, "", "")
+		if err != nil || len(history) != 1 {
+			t.Fatalf("%s 净值记录 = %v, %v", currency, history, err)
+		}
+		if history[0].NAV != want {
+			t.Errorf("%s 净值 = %s, want %s", currency, history[0].NAV, want)
+		}
+	}
+
+	profits, err := s.repo.GetRechargeProfitHistory(int(rechargeID))
+	if err != nil || len(profits) != 1 {
+		t.Fatalf("充值盈亏记录 = %v, %v", profits, err)
+	}
+	if profits[0].Profit != money.FromFloat(40) {
+		t.Errorf("充值盈亏 = %s, want 40（400 × 10%%）", profits[0].Profit)
+	}
+
+	// 投资人只能查看自己持有的资金池
+	user, _ := s.repo.GetUserByID(int(userID))
+	for currency, want := range map[string]bool{"USDT": true, "USDC": false} {
+		if ok, err := s.CanViewNAV(user, account.ID, currency); err != nil || ok != want {
+			t.Errorf("CanViewNAV(%s) = %v, %v, want %v", currency, ok, err, want)
+		}
+	}
+	admin, _ := s.repo.GetUserByID(1)
+	if ok, _ := s.CanViewNAV(admin, account.ID, "USDC"); !ok {
+		t.Error("管理员应该能查看所有资金池")
+	}
+}
@@ -0,0 +1,594 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// okxAdapter OKX交易所适配器（统一交易账户）
+type okxAdapter struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// ==================== OKX API ====================
+
+// GetBalance 获取OKX USDC+USDT余额
+func (a *okxAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" {
+		return 0, fmt.Errorf("未配置OKX API Key")
+	}
+
+	passphrase := account.Passphrase
+	if passphrase == "" {
+		return 0, fmt.Errorf("未配置OKX Passphrase")
+	}
+
+	// 🔥 只查询交易账户（已包含所有持仓、未实现盈亏和资金）
+	balance, err := a.getTradingBalance(account)
+	if err != nil {
+		fmt.Printf("  ⚠️  获取OKX账户失败: %v\n", err)
+		return 0, err
+	}
+
+	fmt.Printf("  ✓ OKX 总资产: $%.2f\n", balance)
+	return balance, nil
+}
+
+// getTradingBalance 获取交易账户余额
+func (a *okxAdapter) getTradingBalance(account *model.AdminAccount) (money.Decimal, error) {
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	method := "GET"
+	requestPath := "/api/v5/account/balance"
+	body := ""
+
+	message := timestamp + method + requestPath + body
+	signature := a.sign(message, account.APISecret)
+
+	url := a.baseURL + requestPath
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("OK-ACCESS-KEY", account.APIKey)
+	req.Header.Set("OK-ACCESS-SIGN", signature)
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			TotalEq string `json:"totalEq"` // 美元总权益
+			Details []struct {
+				Ccy       string `json:"ccy"`
+				Eq        string `json:"eq"`        // 币种总权益
+				AvailEq   string `json:"availEq"`   // 可用权益
+				CashBal   string `json:"cashBal"`   // 现金余额
+				FrozenBal string `json:"frozenBal"` // 冻结余额
+				OrdFrozen string `json:"ordFrozen"` // 挂单冻结
+				Upl       string `json:"upl"`       // 未实现盈亏
+			} `json:"details"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return 0, err
+	}
+
+	if result.Code != "0" {
+		return 0, fmt.Errorf("API返回错误 [%s]: %s", result.Code, result.Msg)
+	}
+
+	totalBalance := money.Zero
+
+	if len(result.Data) > 0 {
+		for _, detail := range result.Data[0].Details {
+			if detail.Ccy == "USDC" || detail.Ccy == "USDT" {
+				eq := parseAmount(detail.Eq)
+				availEq := parseAmount(detail.AvailEq)
+				cashBal := parseAmount(detail.CashBal)
+				frozenBal := parseAmount(detail.FrozenBal)
+				ordFrozen := parseAmount(detail.OrdFrozen)
+				upl := parseAmount(detail.Upl)
+
+				if eq > 0 {
+					totalBalance += eq
+					fmt.Printf("  OKX %s: 总权益=$%.2f (可用=$%.2f, 现金=$%.2f, 冻结=$%.2f, 挂单=$%.2f, 未实现=$%.2f)\n",
+						detail.Ccy, eq, availEq, cashBal, frozenBal, ordFrozen, upl)
+				}
+			}
+		}
+	}
+
+	return totalBalance, nil
+}
+
+// sign 生成OKX签名
+func (a *okxAdapter) sign(message, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// GetPositions 获取OKX持仓
+func (a *okxAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	method := "GET"
+	requestPath := "/api/v5/account/positions"
+	body := ""
+
+	message := timestamp + method + requestPath + body
+	signature := a.sign(message, account.APISecret)
+
+	url := a.baseURL + requestPath
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("OK-ACCESS-KEY", account.APIKey)
+	req.Header.Set("OK-ACCESS-SIGN", signature)
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstID   string `json:"instId"`   // 产品ID
+			PosSide  string `json:"posSide"`  // 持仓方向
+			Pos      string `json:"pos"`      // 持仓数量
+			AvgPx    string `json:"avgPx"`    // 开仓均价
+			MarkPx   string `json:"markPx"`   // 标记价格
+			Upl      string `json:"upl"`      // 未实现盈亏
+			UplRatio string `json:"uplRatio"` // 未实现盈亏比率
+			Lever    string `json:"lever"`    // 杠杆倍数
+			MgnMode  string `json:"mgnMode"`  // 保证金模式
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Code != "0" {
+		return nil, fmt.Errorf("OKX API错误 [%s]: %s", result.Code, result.Msg)
+	}
+
+	positions := []model.Position{}
+	count := 0
+
+	for _, pos := range result.Data {
+		posSize, _ := strconv.ParseFloat(pos.Pos, 64)
+
+		// 跳过空仓
+		if posSize == 0 {
+			continue
+		}
+
+		if count >= limit {
+			break
+		}
+
+		avgPx, _ := strconv.ParseFloat(pos.AvgPx, 64)
+		markPx, _ := strconv.ParseFloat(pos.MarkPx, 64)
+		upl, _ := strconv.ParseFloat(pos.Upl, 64)
+		uplRatio, _ := strconv.ParseFloat(pos.UplRatio, 64)
+		lever, _ := strconv.Atoi(pos.Lever)
+
+		side := "LONG"
+		if pos.PosSide == "short" {
+			side = "SHORT"
+		}
+
+		marginType := "cross"
+		if pos.MgnMode == "isolated" {
+			marginType = "isolated"
+		}
+
+		positions = append(positions, model.Position{
+			Symbol:            pos.InstID,
+			Side:              side,
+			Size:              posSize,
+			EntryPrice:        avgPx,
+			MarkPrice:         markPx,
+			UnrealizedPnl:     upl,
+			UnrealizedPnlRate: uplRatio * 100, // 转换为百分比
+			Leverage:          lever,
+			MarginType:        marginType,
+		})
+
+		count++
+	}
+
+	return positions, nil
+}
+
+// GetOrders 获取OKX当前委托
+func (a *okxAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	method := "GET"
+	requestPath := "/api/v5/trade/orders-pending"
+	body := ""
+
+	message := timestamp + method + requestPath + body
+	signature := a.sign(message, account.APISecret)
+
+	url := a.baseURL + requestPath
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("OK-ACCESS-KEY", account.APIKey)
+	req.Header.Set("OK-ACCESS-SIGN", signature)
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			OrdID     string `json:"ordId"`     // 订单ID
+			InstID    string `json:"instId"`    // 产品ID
+			Side      string `json:"side"`      // 订单方向
+			OrdType   string `json:"ordType"`   // 订单类型
+			Px        string `json:"px"`        // 委托价格
+			Sz        string `json:"sz"`        // 委托数量
+			AccFillSz string `json:"accFillSz"` // 已成交数量
+			State     string `json:"state"`     // 订单状态
+			CTime     string `json:"cTime"`     // 创建时间
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Code != "0" {
+		return nil, fmt.Errorf("OKX API错误 [%s]: %s", result.Code, result.Msg)
+	}
+
+	orders := []model.Order{}
+	count := 0
+
+	for _, ord := range result.Data {
+		if count >= limit {
+			break
+		}
+
+		px, _ := strconv.ParseFloat(ord.Px, 64)
+		sz, _ := strconv.ParseFloat(ord.Sz, 64)
+		accFillSz, _ := strconv.ParseFloat(ord.AccFillSz, 64)
+
+		// 转换时间戳
+		cTimeInt, _ := strconv.ParseInt(ord.CTime, 10, 64)
+		orderTime := time.Unix(cTimeInt/1000, 0).Format("2006-01-02 15:04:05")
+
+		orders = append(orders, model.Order{
+			OrderID:     ord.OrdID,
+			Symbol:      ord.InstID,
+			Side:        strings.ToUpper(ord.Side),
+			Type:        strings.ToUpper(ord.OrdType),
+			Price:       px,
+			OrigQty:     sz,
+			ExecutedQty: accFillSz,
+			Status:      ord.State,
+			Time:        orderTime,
+		})
+
+		count++
+	}
+
+	return orders, nil
+}
+
+// GetHistoryTrades 获取OKX历史成交
+func (a *okxAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	method := "GET"
+	requestPath := fmt.Sprintf("/api/v5/trade/orders-history?limit=%d", limit)
+	body := ""
+
+	message := timestamp + method + requestPath + body
+	signature := a.sign(message, account.APISecret)
+
+	url := a.baseURL + requestPath
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("OK-ACCESS-KEY", account.APIKey)
+	req.Header.Set("OK-ACCESS-SIGN", signature)
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstID string `json:"instId"` // 产品ID
+			Side   string `json:"side"`   // 订单方向
+			Px     string `json:"px"`     // 委托价格
+			AvgPx  string `json:"avgPx"`  // 成交均价
+			Sz     string `json:"sz"`     // 委托数量
+			Pnl    string `json:"pnl"`    // 收益
+			Fee    string `json:"fee"`    // 手续费
+			CTime  string `json:"cTime"`  // 创建时间
+			UTime  string `json:"uTime"`  // 更新时间
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Code != "0" {
+		return nil, fmt.Errorf("OKX API错误 [%s]: %s", result.Code, result.Msg)
+	}
+
+	trades := []model.HistoryTrade{}
+
+	for _, trade := range result.Data {
+		px, _ := strconv.ParseFloat(trade.Px, 64)
+		avgPx, _ := strconv.ParseFloat(trade.AvgPx, 64)
+		sz, _ := strconv.ParseFloat(trade.Sz, 64)
+		pnl, _ := strconv.ParseFloat(trade.Pnl, 64)
+		fee, _ := strconv.ParseFloat(trade.Fee, 64)
+
+		// 转换时间戳
+		cTimeInt, _ := strconv.ParseInt(trade.CTime, 10, 64)
+		uTimeInt, _ := strconv.ParseInt(trade.UTime, 10, 64)
+
+		openTime := time.Unix(cTimeInt/1000, 0).Format("2006-01-02 15:04:05")
+		closeTime := time.Unix(uTimeInt/1000, 0).Format("2006-01-02 15:04:05")
+
+		trades = append(trades, model.HistoryTrade{
+			Symbol:      trade.InstID,
+			Side:        strings.ToUpper(trade.Side),
+			OpenTime:    openTime,
+			CloseTime:   closeTime,
+			OpenPrice:   px,
+			ClosePrice:  avgPx,
+			Quantity:    sz,
+			RealizedPnl: pnl,
+			Commission:  fee,
+		})
+	}
+
+	return trades, nil
+}
+
+// GetBalanceByAsset 获取OKX指定币种余额
+func (a *okxAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	if account.APIKey == "" || account.APISecret == "" || account.Passphrase == "" {
+		return 0, fmt.Errorf("未配置OKX API")
+	}
+
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	method := "GET"
+	requestPath := "/api/v5/account/balance"
+	prehash := timestamp + method + requestPath
+
+	signature := a.sign(prehash, account.APISecret)
+	url := a.baseURL + requestPath
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("OK-ACCESS-KEY", account.APIKey)
+	req.Header.Set("OK-ACCESS-SIGN", signature)
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			Details []struct {
+				Ccy       string `json:"ccy"`
+				CashBal   string `json:"cashBal"`   // 现金余额（含冻结）
+				AvailBal  string `json:"availBal"`  // 可用余额
+				FrozenBal string `json:"frozenBal"` // 冻结余额
+				Eq        string `json:"eq"`        // 总权益
+			} `json:"details"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	if result.Code != "0" {
+		return 0, fmt.Errorf("OKX API error: %s", result.Msg)
+	}
+
+	totalBalance := money.Zero
+	if len(result.Data) > 0 {
+		for _, detail := range result.Data[0].Details {
+			if detail.Ccy == currency {
+				// 🔥 修复：直接使用 eq (总权益) 或 cashBal
+				// eq 包含了未实现盈亏，cashBal 是现金余额
+				eq := parseAmount(detail.Eq)
+				cash := parseAmount(detail.CashBal)
+				avail := parseAmount(detail.AvailBal)
+
+				// 🔥 使用总权益（包含未实现盈亏）
+				totalBalance = eq
+
+				fmt.Printf("  ✓ OKX %s: 总权益=$%.2f (现金=$%.2f, 可用=$%.2f)\n",
+					currency, eq, cash, avail)
+
+				return totalBalance, nil
+			}
+		}
+	}
+
+	fmt.Printf("  ⚠️  OKX %s: 未找到余额\n", currency)
+	return 0, nil
+}
+
+// CheckKeyPermissions 查询API密钥权限 GET /api/v5/account/config（perm 字段：read_only,trade,withdraw）
+func (a *okxAdapter) CheckKeyPermissions(account *model.AdminAccount) (*model.KeyPermissions, error) {
+	if account.APIKey == "" || account.APISecret == "" || account.Passphrase == "" {
+		return nil, fmt.Errorf("未配置完整的OKX API Key/Secret/Passphrase")
+	}
+
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	method := "GET"
+	requestPath := "/api/v5/account/config"
+
+	signature := a.sign(timestamp+method+requestPath, account.APISecret)
+
+	req, err := http.NewRequest(method, a.baseURL+requestPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("OK-ACCESS-KEY", account.APIKey)
+	req.Header.Set("OK-ACCESS-SIGN", signature)
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", account.Passphrase)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("无法连接OKX: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			Perm string `json:"perm"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("OKX返回无法解析 [%d]: %s", resp.StatusCode, string(respBody))
+	}
+	if resp.StatusCode != 200 || result.Code != "0" {
+		return nil, fmt.Errorf("OKX API密钥无效 [%s]: %s", result.Code, result.Msg)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("OKX未返回账户配置")
+	}
+
+	perms := &model.KeyPermissions{}
+	for _, p := range strings.Split(result.Data[0].Perm, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		perms.Raw = append(perms.Raw, p)
+		switch p {
+		case "read_only":
+			perms.Read = true
+		case "trade":
+			perms.Trade = true
+		case "withdraw":
+			perms.Withdraw = true
+		}
+	}
+
+	return perms, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto-final/internal/money"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// PriceSource 基准行情来源
+// 默认使用 Binance 现货的公开日K线（不需要密钥），离线测试时指向 cmd/mockexchange，
+// 也可以用 Service.SetPriceSource 注入自定义实现。
+type PriceSource interface {
+	// DailyPrices 获取 [from, to] 之间每天UTC 0点（北京时间8:00）的价格，key 为 YYYY-MM-DD
+	DailyPrices(symbol string, from, to time.Time) (map[string]money.Decimal, error)
+}
+
+// binanceKlineLimit Binance K线接口单次最多返回的条数
+const binanceKlineLimit = 1000
+
+// binancePriceSource 按 Binance 现货日K线取价：某天UTC 0点的价格即前一天K线的收盘价
+type binancePriceSource struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// DailyPrices 实现 PriceSource，超过1000天时分页请求
+func (p *binancePriceSource) DailyPrices(symbol string, from, to time.Time) (map[string]money.Decimal, error) {
+	prices := make(map[string]money.Decimal)
+	day := 24 * time.Hour
+
+	// 价格日期 D 对应的是 D-1 那根K线
+	start := from.UTC().Truncate(day).Add(-day)
+	end := to.UTC().Truncate(day)
+	for start.Before(end) {
+		url := fmt.Sprintf("%s/api/v3/klines?symbol=%s&interval=1d&startTime=%d&endTime=%d&limit=%d",
+			p.baseURL, symbol, start.UnixMilli(), end.UnixMilli()-1, binanceKlineLimit)
+
+		resp, err := p.httpClient.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("API返回错误 [%d]: %s", resp.StatusCode, string(body))
+		}
+
+		// [开盘时间, 开, 高, 低, 收, 量, 收盘时间, ...]
+		var klines [][]interface{}
+		if err := json.Unmarshal(body, &klines); err != nil {
+			return nil, err
+		}
+		if len(klines) == 0 {
+			break
+		}
+
+		next := start
+		for _, k := range klines {
+			if len(k) < 7 {
+				continue
+			}
+			openTime, ok1 := k[0].(float64)
+			closeTime, ok2 := k[6].(float64)
+			closePrice, ok3 := k[4].(string)
+			if !ok1 || !ok2 || !ok3 {
+				continue
+			}
+			// 还没收盘的K线不算
+			closedAt := time.UnixMilli(int64(closeTime) + 1).UTC()
+			if closedAt.After(time.Now()) {
+				continue
+			}
+			price := parseAmount(closePrice)
+			if price.Sign() > 0 {
+				prices[closedAt.Format("2006-01-02")] = price
+			}
+			next = time.UnixMilli(int64(openTime)).UTC().Add(day)
+		}
+		if len(klines) < binanceKlineLimit || !next.After(start) {
+			break
+		}
+		start = next
+	}
+	return prices, nil
+}
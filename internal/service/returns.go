@@ -0,0 +1,430 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/repository"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// poolKey 资金池（Admin账户 + 币种）
+type poolKey struct {
+	AdminAccountID int
+	Currency       string
+}
+
+// holdingEvent 投资人某笔充值的一次持仓变动
+// Shares 为份额变动；In 为投入的本金（申购、更正），Out 为撤资到账金额。
+type holdingEvent struct {
+	RechargeID int
+	Pool       poolKey
+	At         time.Time
+	EntryType  string
+	Shares     money.Decimal
+	In         money.Decimal
+	Out        money.Decimal
+}
+
+// price 这次变动的成交净值：申购为本金/份额，撤资为到账金额/注销份额；其他变动没有成交价
+func (e *holdingEvent) price() (float64, bool) {
+	switch {
+	case (e.EntryType == model.JournalSubscription || e.EntryType == model.JournalOpening) && e.In.Sign() > 0 && e.Shares.Sign() > 0:
+		return money.Ratio(e.In, e.Shares), true
+	case e.EntryType == model.JournalRedemption && e.Out.Sign() > 0 && e.Shares.Sign() < 0:
+		return money.Ratio(e.Out, e.Shares.Neg()), true
+	}
+	return 0, false
+}
+
+// returnLedger 计算收益率用到的某用户全部持仓变动
+type returnLedger struct {
+	events   []*holdingEvent
+	previous map[int]int // 部分撤资后的新充值 → 原充值
+}
+
+// loadReturnLedger 从分录和撤资记录整理某用户的持仓变动
+// 申购、更正按记账时间计入投入，期初余额按充值时间计入；撤资记在原充值上：注销的份额和到账金额。
+// 被删除（冲销）的充值视为从未发生；没有分录的历史撤资（升级前）无法还原份额，不计入。
+func (s *Service) loadReturnLedger(userID int) (*returnLedger, error) {
+	recharges, err := s.repo.GetRechargesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals, err := s.repo.QueryWithdrawals(model.WithdrawalFilter{UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("读取撤资记录失败: %v", err)
+	}
+	payouts := make(map[int]money.Decimal)
+	for _, w := range withdrawals {
+		payouts[w.RechargeID] = payouts[w.RechargeID].Add(w.WithdrawnAmount)
+	}
+
+	ledger := &returnLedger{previous: make(map[int]int)}
+	for _, r := range recharges {
+		entries, err := s.repo.GetJournalByAccount(repository.RechargeAccount(r.ID))
+		if err != nil {
+			return nil, fmt.Errorf("读取充值%d分录失败: %v", r.ID, err)
+		}
+		if hasEntryType(entries, model.JournalReversal) {
+			continue
+		}
+
+		pool := poolKey{r.AdminAccountID, r.Currency}
+		account := repository.RechargeAccount(r.ID)
+		for _, e := range entries {
+			for _, p := range e.Postings {
+				if p.Account != account {
+					continue
+				}
+				event := &holdingEvent{RechargeID: r.ID, Pool: pool, At: e.CreatedAt, EntryType: e.EntryType, Shares: p.Shares}
+				switch e.EntryType {
+				case model.JournalOpening:
+					event.At = r.RechargeAt
+					event.In = p.Amount
+				case model.JournalSubscription, model.JournalCorrection:
+					event.In = p.Amount
+				case model.JournalRedemption:
+					// 部分撤资转入新充值的份额不算变动，原充值只记注销（回到资金池）的份额
+					if p.Shares.Sign() > 0 {
+						if prev, ok := redeemedRecharge(e, account); ok {
+							ledger.previous[r.ID] = prev
+						}
+						continue
+					}
+					event.Shares = redeemedShares(e, repository.PoolAccount(r.AdminAccountID, r.Currency))
+					event.Out = payouts[r.ID]
+				}
+				ledger.events = append(ledger.events, event)
+			}
+		}
+	}
+
+	sort.SliceStable(ledger.events, func(i, j int) bool {
+		return ledger.events[i].At.Before(ledger.events[j].At)
+	})
+	return ledger, nil
+}
+
+// chain 某笔充值及其部分撤资前的各笔原充值
+func (l *returnLedger) chain(rechargeID int) map[int]bool {
+	ids := map[int]bool{rechargeID: true}
+	for id, ok := l.previous[rechargeID]; ok && !ids[id]; id, ok = l.previous[id] {
+		ids[id] = true
+	}
+	return ids
+}
+
+// filter 只保留指定充值的持仓变动
+func (l *returnLedger) filter(ids map[int]bool) []*holdingEvent {
+	var events []*holdingEvent
+	for _, e := range l.events {
+		if ids[e.RechargeID] {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// navMark 某资金池某天最后一次结算的净值，按结算时间作为估值点
+type navMark struct {
+	At   time.Time
+	Pool poolKey
+	NAV  float64
+}
+
+// returnTrace 按持仓变动逐段计算的结果
+type returnTrace struct {
+	start  time.Time
+	growth float64       // 累计增长倍数
+	daily  []dailyReturn // 每天的收益率（当天各区间连乘），只含有估值的日子
+	flows  []cashFlow
+	value  float64 // 当前市值
+}
+
+// dailyReturn 某天的收益率（小数）
+type dailyReturn struct {
+	Date   string
+	Return float64
+}
+
+// traceReturns 按持仓变动逐段计算时间加权收益，events 需按时间排序
+// 在每次持仓变动和每条净值记录处切分区间，区间收益 = 期末市值(扣除本次投入、加回到账金额) / 期初市值；
+// 市值 = 持有份额 × 最近的成交净值或净值记录。业绩报酬扣减的份额不是现金流，计为亏损，即扣费后收益。
+func (s *Service) traceReturns(events []*holdingEvent, now time.Time) (*returnTrace, error) {
+	var marks []navMark
+	loaded := make(map[poolKey]bool)
+	for _, e := range events {
+		if loaded[e.Pool] {
+			continue
+		}
+		loaded[e.Pool] = true
+		history, err := s.repo.GetNAVHistory(e.Pool.AdminAccountID, e.Pool.Currency, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("读取净值序列失败: %v", err)
+		}
+		for _, n := range history {
+			marks = append(marks, navMark{At: n.CreatedAt, Pool: e.Pool, NAV: n.NAV.Float64()})
+		}
+	}
+	sort.SliceStable(marks, func(i, j int) bool { return marks[i].At.Before(marks[j].At) })
+
+	prices := make(map[poolKey]float64)
+	shares := make(map[poolKey]money.Decimal)
+	value := func() float64 {
+		total := 0.0
+		for pool, held := range shares {
+			total += held.Float64() * prices[pool]
+		}
+		return total
+	}
+
+	trace := &returnTrace{start: events[0].At, growth: 1}
+	// grow 记一段区间的收益，同一天的区间合并为当天收益
+	grow := func(t time.Time, factor float64) {
+		trace.growth *= factor
+		date := t.Local().Format("2006-01-02")
+		if n := len(trace.daily); n > 0 && trace.daily[n-1].Date == date {
+			trace.daily[n-1].Return = (1+trace.daily[n-1].Return)*factor - 1
+			return
+		}
+		trace.daily = append(trace.daily, dailyReturn{Date: date, Return: factor - 1})
+	}
+
+	base := 0.0
+	next := 0
+	// markBefore 处理 t 之前的净值记录，每条记录都是一个估值点
+	// 与持仓变动同一秒的记录放在变动之后：成交前结算的净值与成交价相同，之后的检查则应计入
+	markBefore := func(t time.Time) {
+		for ; next < len(marks) && marks[next].At.Before(t); next++ {
+			prices[marks[next].Pool] = marks[next].NAV
+			if base > 0 {
+				v := value()
+				grow(marks[next].At, v/base)
+				base = v
+			}
+		}
+	}
+
+	for _, e := range events {
+		markBefore(e.At)
+		if p, ok := e.price(); ok {
+			prices[e.Pool] = p
+		}
+		shares[e.Pool] = shares[e.Pool].Add(e.Shares)
+
+		flow := e.In.Float64() - e.Out.Float64()
+		after := value()
+		if base > 0 {
+			grow(e.At, (after-flow)/base)
+		}
+		base = after
+
+		if !e.In.IsZero() {
+			trace.flows = append(trace.flows, cashFlow{At: e.At, Amount: -e.In.Float64()})
+		}
+		if !e.Out.IsZero() {
+			trace.flows = append(trace.flows, cashFlow{At: e.At, Amount: e.Out.Float64()})
+		}
+	}
+	markBefore(now.Add(time.Second))
+
+	trace.value = base
+	return trace, nil
+}
+
+// computeReturns 按持仓变动计算收益率，events 需按时间排序
+// XIRR：投入记为负，撤资到账和当前市值记为正，求净现值为0的年化收益率。
+func (s *Service) computeReturns(events []*holdingEvent, now time.Time) (*model.ReturnMetrics, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	trace, err := s.traceReturns(events, now)
+	if err != nil {
+		return nil, err
+	}
+
+	days := int(now.Sub(trace.start).Hours() / 24)
+	metrics := &model.ReturnMetrics{
+		TWR:           (trace.growth - 1) * 100,
+		TWRAnnualized: compoundRate(trace.growth, days, 365),
+		Days:          days,
+		StartDate:     trace.start.Local().Format("2006-01-02"),
+	}
+
+	flows := trace.flows
+	if trace.value > 0 {
+		flows = append(flows, cashFlow{At: now, Amount: trace.value})
+	}
+	if days >= 1 {
+		if rate, ok := xirr(flows); ok {
+			rate *= 100
+			metrics.XIRR = &rate
+		}
+	}
+	return metrics, nil
+}
+
+// compoundRate 把 days 天的累计增长按复利折算为 period 天的收益率（百分比）
+func compoundRate(growth float64, days, period int) float64 {
+	if growth <= 0 || days < 1 {
+		return 0
+	}
+	return (math.Pow(growth, float64(period)/float64(days)) - 1) * 100
+}
+
+// cashFlow 投资人视角的一笔现金流：投入为负，取回为正
+type cashFlow struct {
+	At     time.Time
+	Amount float64
+}
+
+// xirr 求使现金流净现值为0的年化收益率（按365天/年）
+// 先用牛顿法，不收敛时在 (-100%, 1e6%) 区间二分；现金流没有正负两个方向时无解。
+func xirr(flows []cashFlow) (float64, bool) {
+	if len(flows) < 2 {
+		return 0, false
+	}
+	first := flows[0].At
+	hasIn, hasOut := false, false
+	for _, f := range flows {
+		if f.At.Before(first) {
+			first = f.At
+		}
+		hasIn = hasIn || f.Amount < 0
+		hasOut = hasOut || f.Amount > 0
+	}
+	if !hasIn || !hasOut {
+		return 0, false
+	}
+
+	years := make([]float64, len(flows))
+	for i, f := range flows {
+		years[i] = f.At.Sub(first).Hours() / 24 / 365
+	}
+	npv := func(rate float64) (value, derivative float64) {
+		for i, f := range flows {
+			d := math.Pow(1+rate, years[i])
+			value += f.Amount / d
+			derivative -= years[i] * f.Amount / (d * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.1
+	for i := 0; i < 100; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < 1e-7 {
+			return rate, true
+		}
+		if derivative == 0 || math.IsNaN(derivative) {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-10 {
+			return next, true
+		}
+		rate = next
+	}
+
+	low, high := -0.999999, 1e4
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if math.IsNaN(lowValue) || math.IsNaN(highValue) || lowValue*highValue > 0 {
+		return 0, false
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		value, _ := npv(mid)
+		if math.Abs(value) < 1e-7 || high-low < 1e-12 {
+			return mid, true
+		}
+		if value*lowValue > 0 {
+			low, lowValue = mid, value
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, true
+}
+
+// hasEntryType 分录中是否有某种类型
+func hasEntryType(entries []*model.JournalEntry, entryType string) bool {
+	for _, e := range entries {
+		if e.EntryType == entryType {
+			return true
+		}
+	}
+	return false
+}
+
+// redeemedShares 撤资分录中注销（回到资金池）的份额，返回负数
+func redeemedShares(entry *model.JournalEntry, poolAccount string) money.Decimal {
+	for _, p := range entry.Postings {
+		if p.Account == poolAccount {
+			return p.Shares.Neg()
+		}
+	}
+	return money.Zero
+}
+
+// redeemedRecharge 部分撤资分录中被注销的原充值（份额为负的另一笔充值账户）
+func redeemedRecharge(entry *model.JournalEntry, account string) (int, bool) {
+	for _, p := range entry.Postings {
+		if p.Account == account || p.Shares.Sign() >= 0 {
+			continue
+		}
+		if id, ok := repository.ParseRechargeAccount(p.Account); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// apiUserReturns API用户的收益率
+// API用户只有创建时投入的初始余额一笔已知现金流：TWR 为累计收益率，按持有天数复利年化；
+// XIRR 把当前余额视为期末取回，持有超过一天才计算。
+func apiUserReturns(user *model.User, currentBalance money.Decimal, holdDays int, now time.Time) *model.ReturnMetrics {
+	profitRate := 0.0
+	if user.InitialBalance > 0 {
+		profitRate = money.Ratio(currentBalance.Sub(user.InitialBalance), user.InitialBalance) * 100
+	}
+	returns := &model.ReturnMetrics{
+		TWR:           profitRate,
+		TWRAnnualized: compoundRate(1+profitRate/100, holdDays, 365),
+		Days:          holdDays,
+		StartDate:     user.CreatedAt.Format("2006-01-02"),
+	}
+	if user.InitialBalance > 0 && holdDays > 1 {
+		flows := []cashFlow{
+			{At: user.CreatedAt, Amount: -user.InitialBalance.Float64()},
+			{At: now, Amount: currentBalance.Float64()},
+		}
+		if rate, ok := xirr(flows); ok {
+			rate *= 100
+			returns.XIRR = &rate
+		}
+	}
+	return returns
+}
+
+// userReturns 某用户全部持仓（含已撤资部分）的收益率，计算失败时记录日志并返回 nil
+func (s *Service) userReturns(userID int) *model.ReturnMetrics {
+	ledger, err := s.loadReturnLedger(userID)
+	if err != nil {
+		fmt.Printf("⚠️  用户%d: 无法计算收益率: %v\n", userID, err)
+		return nil
+	}
+	returns, err := s.computeReturns(ledger.events, time.Now())
+	if err != nil {
+		fmt.Printf("⚠️  用户%d: 无法计算收益率: %v\n", userID, err)
+		return nil
+	}
+	return returns
+}
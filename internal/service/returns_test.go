@@ -0,0 +1,210 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"math"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []cashFlow
+		want  float64
+	}{
+		{
+			name: "一年后取回110%",
+			flows: []cashFlow{
+				{date("2023-01-01"), -1000},
+				{date("2024-01-01"), 1100},
+			},
+			want: 0.1, // 2023 年 365 天
+		},
+		{
+			// Excel XIRR 文档中的示例
+			name: "多笔现金流",
+			flows: []cashFlow{
+				{date("2008-01-01"), -10000},
+				{date("2008-03-01"), 2750},
+				{date("2008-10-30"), 4250},
+				{date("2009-02-15"), 3250},
+				{date("2009-04-01"), 2750},
+			},
+			want: 0.373362535,
+		},
+		{
+			name: "亏损一半",
+			flows: []cashFlow{
+				{date("2021-01-01"), -2000},
+				{date("2022-01-01"), 1000},
+			},
+			want: -0.5,
+		},
+		{
+			name: "中途追加投入，顺序无关",
+			flows: []cashFlow{
+				{date("2022-07-01"), -1000},
+				{date("2022-01-01"), -1000},
+				{date("2023-01-01"), 2200},
+			},
+			want: 0.1344,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := xirr(tt.flows)
+			if !ok {
+				t.Fatalf("xirr 无解")
+			}
+			if math.Abs(got-tt.want) > 1e-4 {
+				t.Errorf("xirr = %.6f, want %.6f", got, tt.want)
+			}
+
+			// 解使净现值为0
+			first := tt.flows[0].At
+			for _, f := range tt.flows {
+				if f.At.Before(first) {
+					first = f.At
+				}
+			}
+			npv := 0.0
+			for _, f := range tt.flows {
+				npv += f.Amount / math.Pow(1+got, f.At.Sub(first).Hours()/24/365)
+			}
+			if math.Abs(npv) > 1e-6 {
+				t.Errorf("npv(%.6f) = %g", got, npv)
+			}
+		})
+	}
+}
+
+func TestXIRRNoSolution(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []cashFlow
+	}{
+		{"没有现金流", nil},
+		{"只有一笔", []cashFlow{{date("2023-01-01"), -1000}}},
+		{"只有投入", []cashFlow{{date("2023-01-01"), -1000}, {date("2023-06-01"), -500}}},
+		{"只有取回", []cashFlow{{date("2023-01-01"), 1000}, {date("2023-06-01"), 500}}},
+	}
+	for _, tt := range tests {
+		if rate, ok := xirr(tt.flows); ok {
+			t.Errorf("%s: xirr = %v, 应该无解", tt.name, rate)
+		}
+	}
+}
+
+func TestCompoundRate(t *testing.T) {
+	if got := compoundRate(1.21, 730, 365); math.Abs(got-10) > 1e-9 {
+		t.Errorf("compoundRate(1.21, 730, 365) = %v, want 10", got)
+	}
+	if got := compoundRate(1.1, 0, 365); got != 0 {
+		t.Errorf("持有不满一天应为0，得到 %v", got)
+	}
+	if got := compoundRate(0, 30, 365); got != 0 {
+		t.Errorf("增长倍数为0应为0，得到 %v", got)
+	}
+}
+
+// TestComputeReturnsChainsTWR 多次申购、撤资按成交净值分段连乘，不受投入金额影响
+func TestComputeReturnsChainsTWR(t *testing.T) {
+	s := newTestService(t, nil)
+	pool := poolKey{AdminAccountID: 1, Currency: "USDT"}
+	d := money.FromFloat
+
+	start := date("2023-01-01")
+	events := []*holdingEvent{
+		// 净值 1.0 申购 1000 份
+		{RechargeID: 1, Pool: pool, At: start, EntryType: model.JournalSubscription, Shares: d(1000), In: d(1000)},
+		// 净值涨到 1.2 后再申购 1000 份：第一段 ×1.2
+		{RechargeID: 2, Pool: pool, At: start.AddDate(0, 6, 0), EntryType: model.JournalSubscription, Shares: d(1000), In: d(1200)},
+		// 净值 1.5 时撤出第一笔全部份额：第二段 ×1.25
+		{RechargeID: 1, Pool: pool, At: start.AddDate(1, 0, 0), EntryType: model.JournalRedemption, Shares: d(-1000), Out: d(1500)},
+	}
+	now := start.AddDate(1, 0, 0).Add(time.Hour)
+
+	metrics, err := s.computeReturns(events, now)
+	if err != nil {
+		t.Fatalf("computeReturns: %v", err)
+	}
+	if math.Abs(metrics.TWR-50) > 1e-6 {
+		t.Errorf("TWR = %.6f%%, want 50%%", metrics.TWR)
+	}
+	if metrics.Days != 365 {
+		t.Errorf("Days = %d, want 365", metrics.Days)
+	}
+	if metrics.XIRR == nil {
+		t.Fatal("XIRR 为空")
+	}
+
+	// 现金流：-1000、-1200、+1500，期末市值 1000 份 × 1.5
+	flows := []cashFlow{
+		{events[0].At, -1000},
+		{events[1].At, -1200},
+		{events[2].At, 1500},
+		{now, 1500},
+	}
+	want, _ := xirr(flows)
+	if math.Abs(*metrics.XIRR-want*100) > 1e-6 {
+		t.Errorf("XIRR = %.6f%%, want %.6f%%", *metrics.XIRR, want*100)
+	}
+}
+
+func TestComputeReturnsSingleFlow(t *testing.T) {
+	s := newTestService(t, nil)
+	pool := poolKey{AdminAccountID: 1, Currency: "USDT"}
+	start := date("2023-01-01")
+	events := []*holdingEvent{
+		{RechargeID: 1, Pool: pool, At: start, EntryType: model.JournalSubscription, Shares: money.FromFloat(100), In: money.FromFloat(100)},
+	}
+
+	// 不满一天：没有 XIRR
+	metrics, err := s.computeReturns(events, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("computeReturns: %v", err)
+	}
+	if metrics.XIRR != nil {
+		t.Errorf("不满一天 XIRR = %v, 应为空", *metrics.XIRR)
+	}
+	if metrics.TWR != 0 {
+		t.Errorf("TWR = %v, want 0", metrics.TWR)
+	}
+
+	// 没有持仓变动
+	if metrics, err := s.computeReturns(nil, start); err != nil || metrics != nil {
+		t.Errorf("computeReturns(nil) = %v, %v", metrics, err)
+	}
+}
+
+// TestAPIUserReturnsCompound API用户按持有天数复利年化，不再线性外推
+func TestAPIUserReturnsCompound(t *testing.T) {
+	now := date("2024-12-31")
+	user := &model.User{InitialBalance: money.FromFloat(1000), CreatedAt: now.AddDate(0, 0, -730)}
+
+	returns := apiUserReturns(user, money.FromFloat(1210), 730, now)
+	if math.Abs(returns.TWR-21) > 1e-9 {
+		t.Errorf("TWR = %v, want 21", returns.TWR)
+	}
+	if math.Abs(returns.TWRAnnualized-10) > 1e-9 {
+		t.Errorf("年化 = %v, want 10（线性外推会得到 10.5）", returns.TWRAnnualized)
+	}
+	if returns.XIRR == nil || math.Abs(*returns.XIRR-10) > 0.01 {
+		t.Errorf("XIRR = %v, want 约10", returns.XIRR)
+	}
+
+	// 持有不满一天不计算 XIRR
+	if short := apiUserReturns(user, money.FromFloat(1210), 1, now); short.XIRR != nil {
+		t.Errorf("持有1天 XIRR = %v, want nil", *short.XIRR)
+	}
+}
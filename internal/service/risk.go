@@ -0,0 +1,292 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// riskWindows 滚动最大回撤的窗口（天）
+var riskWindows = []int{30, 90, 365}
+
+// SetRiskFreeRate 设置计算夏普/索提诺比率的默认年化无风险利率（0.04 表示 4%）
+func (s *Service) SetRiskFreeRate(rate float64) {
+	if rate >= 0 && rate < 1 {
+		s.riskFreeRate = rate
+	}
+}
+
+// RiskFreeRate 默认的年化无风险利率
+func (s *Service) RiskFreeRate() float64 {
+	return s.riskFreeRate
+}
+
+// GetAccountRiskStats 各Admin账户的风险指标
+func (s *Service) GetAccountRiskStats(riskFreeRate float64) ([]*model.RiskStats, error) {
+	accounts, err := s.repo.GetAllAdminAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*model.RiskStats
+	for _, account := range accounts {
+		stats, err := s.accountRiskStats(account, riskFreeRate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", account.AccountType, err)
+		}
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+// accountRiskStats 某Admin账户的风险指标
+func (s *Service) accountRiskStats(account *model.AdminAccount, riskFreeRate float64) (*model.RiskStats, error) {
+	series, err := s.accountReturnSeries(account)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := riskStats(series, riskFreeRate)
+	stats.Scope = model.RiskScopeAccount
+	stats.ID = account.ID
+	stats.Name = account.AccountType
+	stats.Source = series.source
+	return stats, nil
+}
+
+// GetUserRiskStats 某用户持仓组合的风险指标，日收益率按持仓逐段计算（同时间加权收益率）
+func (s *Service) GetUserRiskStats(userID int, riskFreeRate float64) (*model.RiskStats, error) {
+	user, series, err := s.userReturnSeries(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := riskStats(series, riskFreeRate)
+	stats.Scope = model.RiskScopeUser
+	stats.ID = user.ID
+	stats.Name = user.Phone
+	stats.Source = series.source
+	return stats, nil
+}
+
+// returnSeries 日收益率序列，base 为第一天收益之前的起点日期
+type returnSeries struct {
+	daily  []dailyReturn
+	base   string
+	source string
+}
+
+// window 最近 days 天（相对序列最后一天）的子序列；covered 表示序列是否覆盖了整个窗口
+func (rs *returnSeries) window(days int) (*returnSeries, bool) {
+	n := len(rs.daily)
+	if n == 0 {
+		return rs, false
+	}
+	end, err := time.Parse("2006-01-02", rs.daily[n-1].Date)
+	if err != nil {
+		return rs, false
+	}
+
+	cutoff := end.AddDate(0, 0, -days).Format("2006-01-02")
+	sub := &returnSeries{base: rs.base, source: rs.source}
+	i := 0
+	for ; i < n && rs.daily[i].Date <= cutoff; i++ {
+		sub.base = rs.daily[i].Date
+	}
+	sub.daily = rs.daily[i:]
+	return sub, rs.base != "" && rs.base <= cutoff
+}
+
+// accountReturnSeries 某Admin账户的日收益率
+// 按各币种净值的日收益率、以前一天余额加权计算，充值和撤资不影响；
+// 没有净值记录的账户（升级前的历史数据）退回用余额日变化率，此时包含资金进出。
+func (s *Service) accountReturnSeries(account *model.AdminAccount) (*returnSeries, error) {
+	history, err := s.repo.GetAccountNAVHistory(account.ID)
+	if err != nil {
+		return nil, fmt.Errorf("读取净值序列失败: %v", err)
+	}
+	if len(history) > 0 {
+		return &returnSeries{
+			daily:  accountDailyReturns(history),
+			base:   history[0].RecordDate,
+			source: model.RiskSourceNAV,
+		}, nil
+	}
+
+	balances, err := s.repo.GetAdminAccountBalanceHistory(account.ID)
+	if err != nil {
+		return nil, fmt.Errorf("读取余额记录失败: %v", err)
+	}
+	series := &returnSeries{source: model.RiskSourceBalance}
+	for i, b := range balances {
+		if i == 0 {
+			series.base = b.RecordDate
+			continue
+		}
+		series.daily = append(series.daily, dailyReturn{Date: b.RecordDate, Return: b.DailyChangeRate / 100})
+	}
+	return series, nil
+}
+
+// userReturnSeries 某用户持仓组合的日收益率（与时间加权收益率相同的逐段收益，同一天的合并）
+func (s *Service) userReturnSeries(userID int) (*model.User, *returnSeries, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, errors.New("用户不存在")
+	}
+
+	ledger, err := s.loadReturnLedger(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	series := &returnSeries{source: model.RiskSourceHoldings}
+	if len(ledger.events) > 0 {
+		trace, err := s.traceReturns(ledger.events, time.Now())
+		if err != nil {
+			return nil, nil, err
+		}
+		series.daily = trace.daily
+		series.base = trace.start.Local().Format("2006-01-02")
+	}
+	return user, series, nil
+}
+
+// accountDailyReturns 账户每天的收益率：各币种净值相对上一条记录的变化，按上一条记录的余额加权
+func accountDailyReturns(history []*model.NAVRecord) []dailyReturn {
+	var daily []dailyReturn
+	prev := make(map[string]*model.NAVRecord)
+	for i := 0; i < len(history); {
+		date := history[i].RecordDate
+		weighted, weight := 0.0, 0.0
+		j := i
+		for ; j < len(history) && history[j].RecordDate == date; j++ {
+			n := history[j]
+			p, ok := prev[n.Currency]
+			if !ok || p.NAV.Sign() <= 0 || p.Balance.Sign() <= 0 {
+				continue
+			}
+			w := p.Balance.Float64()
+			weighted += w * (money.Ratio(n.NAV, p.NAV) - 1)
+			weight += w
+		}
+		for ; i < j; i++ {
+			prev[history[i].Currency] = history[i]
+		}
+		if weight > 0 {
+			daily = append(daily, dailyReturn{Date: date, Return: weighted / weight})
+		}
+	}
+	return daily
+}
+
+// riskStats 由日收益率序列计算风险指标
+// 夏普 = (日均收益 - 日无风险收益) / 日收益标准差 × √365；索提诺的分母只计低于日无风险收益的部分。
+func riskStats(series *returnSeries, riskFreeRate float64) *model.RiskStats {
+	daily := series.daily
+	stats := &model.RiskStats{
+		RiskFreeRate:     riskFreeRate * 100,
+		Days:             len(daily),
+		StartDate:        series.base,
+		EndDate:          series.base,
+		RollingDrawdowns: []*model.RollingDrawdown{},
+	}
+	n := len(daily)
+	if n == 0 {
+		return stats
+	}
+	stats.EndDate = daily[n-1].Date
+
+	dd := maxDrawdown(series)
+	stats.TotalReturn = (dd.wealth - 1) * 100
+	stats.MaxDrawdown = dd.max * 100
+	stats.MaxDrawdownPeak = dd.peakDate
+	stats.MaxDrawdownTrough = dd.troughDate
+	stats.CurrentDrawdown = dd.current * 100
+
+	for _, days := range riskWindows {
+		window, _ := series.window(days)
+		stats.RollingDrawdowns = append(stats.RollingDrawdowns, &model.RollingDrawdown{
+			WindowDays:  days,
+			MaxDrawdown: maxDrawdown(window).max * 100,
+		})
+	}
+
+	mean := 0.0
+	wins := 0
+	best, worst := daily[0], daily[0]
+	for _, d := range daily {
+		mean += d.Return
+		if d.Return > 0 {
+			wins++
+		}
+		if d.Return > best.Return {
+			best = d
+		}
+		if d.Return < worst.Return {
+			worst = d
+		}
+	}
+	mean /= float64(n)
+	stats.BestDay = &model.DailyReturn{Date: best.Date, Return: best.Return * 100}
+	stats.WorstDay = &model.DailyReturn{Date: worst.Date, Return: worst.Return * 100}
+	stats.WinRatio = float64(wins) / float64(n) * 100
+
+	dailyRiskFree := math.Pow(1+riskFreeRate, 1.0/365) - 1
+	variance, downside := 0.0, 0.0
+	for _, d := range daily {
+		variance += (d.Return - mean) * (d.Return - mean)
+		if excess := d.Return - dailyRiskFree; excess < 0 {
+			downside += excess * excess
+		}
+	}
+	if n < 2 {
+		return stats
+	}
+	std := math.Sqrt(variance / float64(n-1))
+	annualize := math.Sqrt(365)
+	stats.Volatility = std * annualize * 100
+	if std > 0 {
+		sharpe := (mean - dailyRiskFree) / std * annualize
+		stats.Sharpe = &sharpe
+	}
+	if downside > 0 {
+		sortino := (mean - dailyRiskFree) / math.Sqrt(downside/float64(n)) * annualize
+		stats.Sortino = &sortino
+	}
+	return stats
+}
+
+// drawdown 回撤计算结果（小数）
+type drawdown struct {
+	wealth     float64 // 累计增长倍数
+	max        float64 // 最大回撤（≤0）
+	current    float64 // 当前回撤
+	peakDate   string
+	troughDate string
+}
+
+// maxDrawdown 按日收益率连乘出的净值计算最大回撤
+func maxDrawdown(series *returnSeries) drawdown {
+	result := drawdown{wealth: 1}
+	peak, peakDate := 1.0, series.base
+	for _, d := range series.daily {
+		result.wealth *= 1 + d.Return
+		if result.wealth > peak {
+			peak, peakDate = result.wealth, d.Date
+		}
+		if current := result.wealth/peak - 1; current < result.max {
+			result.max = current
+			result.peakDate = peakDate
+			result.troughDate = d.Date
+		}
+	}
+	result.current = result.wealth/peak - 1
+	return result
+}
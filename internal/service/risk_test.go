@@ -0,0 +1,131 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"math"
+	"testing"
+)
+
+// testSeries 2024-01-01 起 +10%、-20%、+5%、+10%
+func testSeries() *returnSeries {
+	return &returnSeries{
+		base: "2024-01-01",
+		daily: []dailyReturn{
+			{Date: "2024-01-02", Return: 0.10},
+			{Date: "2024-01-03", Return: -0.20},
+			{Date: "2024-01-04", Return: 0.05},
+			{Date: "2024-01-05", Return: 0.10},
+		},
+	}
+}
+
+// TestMaxDrawdown 最大回撤从最高点算起，记录峰值和谷底日期
+func TestMaxDrawdown(t *testing.T) {
+	dd := maxDrawdown(testSeries())
+	// 净值 1.1 → 0.88 → 0.924 → 1.0164
+	if math.Abs(dd.max-(-0.2)) > 1e-9 || dd.peakDate != "2024-01-02" || dd.troughDate != "2024-01-03" {
+		t.Errorf("最大回撤 = %.4f (%s → %s), want -0.2 (2024-01-02 → 2024-01-03)", dd.max, dd.peakDate, dd.troughDate)
+	}
+	if math.Abs(dd.wealth-1.0164) > 1e-9 || math.Abs(dd.current-(1.0164/1.1-1)) > 1e-9 {
+		t.Errorf("累计 %.6f 当前回撤 %.6f", dd.wealth, dd.current)
+	}
+
+	rising := &returnSeries{base: "2024-01-01", daily: []dailyReturn{{Date: "2024-01-02", Return: 0.01}}}
+	if dd := maxDrawdown(rising); dd.max != 0 || dd.current != 0 {
+		t.Errorf("只涨不跌时回撤 = %v / %v, want 0", dd.max, dd.current)
+	}
+}
+
+// TestReturnSeriesWindow 窗口以最后一天为终点，起点不足时标记为未覆盖
+func TestReturnSeriesWindow(t *testing.T) {
+	series := testSeries()
+
+	window, covered := series.window(2)
+	if !covered || window.base != "2024-01-03" || len(window.daily) != 2 || window.daily[0].Date != "2024-01-04" {
+		t.Errorf("2天窗口 = base %s %v (covered %v)", window.base, window.daily, covered)
+	}
+	// 窗口内从 1.0 开始：+5%、+10% 没有回撤
+	if dd := maxDrawdown(window); dd.max != 0 {
+		t.Errorf("2天窗口最大回撤 = %v, want 0", dd.max)
+	}
+
+	window, covered = series.window(30)
+	if covered || len(window.daily) != 4 {
+		t.Errorf("30天窗口 covered = %v, %d 天, want 未覆盖、全部4天", covered, len(window.daily))
+	}
+}
+
+// TestRiskStats 年化波动率、夏普、索提诺按日收益率计算并乘 √365
+func TestRiskStats(t *testing.T) {
+	stats := riskStats(testSeries(), 0)
+
+	mean := 0.0125
+	std := math.Sqrt((0.0875*0.0875 + 0.2125*0.2125 + 0.0375*0.0375 + 0.0875*0.0875) / 3)
+	annualize := math.Sqrt(365)
+	if stats.Days != 4 || stats.StartDate != "2024-01-01" || stats.EndDate != "2024-01-05" {
+		t.Errorf("区间 = %d天 %s ~ %s", stats.Days, stats.StartDate, stats.EndDate)
+	}
+	if math.Abs(stats.TotalReturn-1.64) > 1e-9 || math.Abs(stats.MaxDrawdown-(-20)) > 1e-9 {
+		t.Errorf("累计收益 %.4f%% 最大回撤 %.4f%%, want 1.64 / -20", stats.TotalReturn, stats.MaxDrawdown)
+	}
+	if math.Abs(stats.Volatility-std*annualize*100) > 1e-9 {
+		t.Errorf("波动率 = %.6f, want %.6f", stats.Volatility, std*annualize*100)
+	}
+	if stats.Sharpe == nil || math.Abs(*stats.Sharpe-mean/std*annualize) > 1e-9 {
+		t.Errorf("夏普 = %v, want %.6f", stats.Sharpe, mean/std*annualize)
+	}
+	// 只有 -20% 一天低于无风险收益：下行偏差 = √(0.04/4)
+	if stats.Sortino == nil || math.Abs(*stats.Sortino-mean/0.1*annualize) > 1e-9 {
+		t.Errorf("索提诺 = %v, want %.6f", stats.Sortino, mean/0.1*annualize)
+	}
+	if stats.WinRatio != 75 || stats.BestDay.Date != "2024-01-02" || stats.WorstDay.Date != "2024-01-03" {
+		t.Errorf("胜率 %.0f%% 最好 %s 最差 %s", stats.WinRatio, stats.BestDay.Date, stats.WorstDay.Date)
+	}
+	if len(stats.RollingDrawdowns) != len(riskWindows) {
+		t.Errorf("滚动回撤 %d 个, want %d", len(stats.RollingDrawdowns), len(riskWindows))
+	}
+
+	// 无风险利率降低超额收益
+	if withRate := riskStats(testSeries(), 0.04); *withRate.Sharpe >= *stats.Sharpe {
+		t.Errorf("无风险利率4%%时夏普 %.4f 不低于 %.4f", *withRate.Sharpe, *stats.Sharpe)
+	}
+
+	// 样本不足时没有夏普；全是正收益时没有索提诺
+	single := riskStats(&returnSeries{base: "2024-01-01", daily: []dailyReturn{{Date: "2024-01-02", Return: 0.01}}}, 0)
+	if single.Sharpe != nil || single.Sortino != nil || single.Volatility != 0 {
+		t.Errorf("单日样本 = 夏普 %v 索提诺 %v 波动 %v", single.Sharpe, single.Sortino, single.Volatility)
+	}
+	empty := riskStats(&returnSeries{base: "2024-01-01"}, 0)
+	if empty.Days != 0 || empty.BestDay != nil || empty.EndDate != "2024-01-01" {
+		t.Errorf("空序列 = %+v", empty)
+	}
+}
+
+// TestAccountDailyReturns 各币种净值变化按前一天余额加权，只有一个币种有新记录时只算它
+func TestAccountDailyReturns(t *testing.T) {
+	nav := func(date, currency, balance, value string) *model.NAVRecord {
+		return &model.NAVRecord{RecordDate: date, Currency: currency, Balance: money.MustParse(balance), NAV: money.MustParse(value)}
+	}
+	history := []*model.NAVRecord{
+		nav("2024-01-01", "USDT", "1000", "1"),
+		nav("2024-01-01", "USDC", "3000", "1"),
+		nav("2024-01-02", "USDT", "1100", "1.1"),
+		nav("2024-01-02", "USDC", "2700", "0.9"),
+		nav("2024-01-03", "USDT", "1210", "1.21"),
+	}
+
+	daily := accountDailyReturns(history)
+	want := []dailyReturn{
+		{Date: "2024-01-02", Return: (1000*0.1 + 3000*-0.1) / 4000},
+		{Date: "2024-01-03", Return: 0.1},
+	}
+	if len(daily) != len(want) {
+		t.Fatalf("日收益 = %v, want %v", daily, want)
+	}
+	for i := range want {
+		if daily[i].Date != want[i].Date || math.Abs(daily[i].Return-want[i].Return) > 1e-9 {
+			t.Errorf("第%d天 = %v, want %v", i+1, daily[i], want[i])
+		}
+	}
+}
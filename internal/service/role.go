@@ -0,0 +1,49 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"errors"
+	"fmt"
+)
+
+// AssignRole 管理员给用户分配角色
+// 不能修改自己的角色，也不能让系统失去最后一个活跃的超级管理员。
+func (s *Service) AssignRole(actor *model.User, userID int, role string) error {
+	if !model.ValidRole(role) {
+		return fmt.Errorf("未知角色: %s", role)
+	}
+	if actor.ID == userID {
+		return errors.New("不能修改自己的角色")
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	if user.Role == role {
+		return nil
+	}
+	if user.IsAPIUser && role != model.RoleInvestor {
+		return errors.New("API用户只能是投资人角色")
+	}
+
+	if user.Role == model.RoleSuperAdmin && user.IsActive {
+		count, err := s.repo.CountActiveUsersByRole(model.RoleSuperAdmin)
+		if err != nil {
+			return fmt.Errorf("统计超级管理员失败: %v", err)
+		}
+		if count <= 1 {
+			return errors.New("至少需要保留一个超级管理员")
+		}
+	}
+
+	if err := s.repo.UpdateUserRole(userID, role, model.IsStaffRole(role)); err != nil {
+		return fmt.Errorf("更新角色失败: %v", err)
+	}
+
+	fmt.Printf("👤 用户 %d 角色: %s → %s（操作人 %d）\n", userID, user.Role, role, actor.ID)
+	return nil
+}
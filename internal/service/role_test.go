@@ -0,0 +1,64 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"testing"
+)
+
+// TestAssignRole 分配角色同步 is_admin；不能改自己、API用户只能是投资人、必须保留一个超级管理员
+func TestAssignRole(t *testing.T) {
+	s := newTestService(t, nil)
+	admin, err := s.repo.GetUserByID(1)
+	if err != nil || admin == nil || admin.Role != model.RoleSuperAdmin {
+		t.Fatalf("默认管理员 = %+v, %v", admin, err)
+	}
+	id, _ := s.AdminCreateUser("13800000000")
+	userID := int(id)
+
+	if err := s.AssignRole(admin, userID, model.RoleAuditor); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	user, _ := s.repo.GetUserByID(userID)
+	if user.Role != model.RoleAuditor || !user.IsAdmin {
+		t.Errorf("分配审计角色后 role=%s is_admin=%v", user.Role, user.IsAdmin)
+	}
+	if err := s.AssignRole(admin, userID, model.RoleInvestor); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	if user, _ = s.repo.GetUserByID(userID); user.IsAdmin {
+		t.Error("改回投资人后 is_admin 仍为 true")
+	}
+
+	apiID, err := s.CreateAPIUser("api-user", "password")
+	if err != nil {
+		t.Fatalf("CreateAPIUser: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		role   string
+	}{
+		{"未知角色", userID, "admin"},
+		{"修改自己", admin.ID, model.RoleOperator},
+		{"用户不存在", 999, model.RoleOperator},
+		{"API用户", int(apiID), model.RoleOperator},
+	}
+	for _, tt := range tests {
+		if err := s.AssignRole(admin, tt.userID, tt.role); err == nil {
+			t.Errorf("%s: 应该被拒绝", tt.name)
+		}
+	}
+
+	// 另一个超级管理员不能把唯一剩下的超级管理员降级；有两个时可以
+	if err := s.AssignRole(admin, userID, model.RoleSuperAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	other, _ := s.repo.GetUserByID(userID)
+	if err := s.AssignRole(other, admin.ID, model.RoleOperator); err != nil {
+		t.Fatalf("两个超级管理员时降级: %v", err)
+	}
+	if err := s.AssignRole(admin, other.ID, model.RoleOperator); err == nil {
+		t.Error("降级最后一个超级管理员应该被拒绝")
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/vault"
+	"errors"
+	"fmt"
+)
+
+// errNoKeyring 没有配置主密钥时无法读写交易所密钥
+var errNoKeyring = errors.New("未配置主密钥（MASTER_KEY）")
+
+// SetKeyring 设置加密交易所密钥用的主密钥
+func (ws *WalletService) SetKeyring(keyring *vault.Keyring) {
+	ws.keyring = keyring
+}
+
+// SealSecret 加密要保存在 context 位置（vault.Context）的密钥，空字符串原样返回
+func (ws *WalletService) SealSecret(plaintext, context string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if ws.keyring == nil {
+		return "", errNoKeyring
+	}
+	return ws.keyring.Seal(plaintext, context)
+}
+
+// openSecret 解密保存在 context 位置的密文，空字符串原样返回
+func (ws *WalletService) openSecret(sealed, context string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	if ws.keyring == nil {
+		return "", errNoKeyring
+	}
+	return ws.keyring.Open(sealed, context)
+}
+
+// secretContexts 账户的 API Secret、Passphrase 密文的存储位置
+// API用户的密钥在 users 表，Admin账户的在 admin_accounts 表。
+func secretContexts(account *model.AdminAccount) (secret, passphrase string) {
+	if account.OwnerUserID != 0 {
+		return vault.Context("users", "api_secret", account.OwnerUserID),
+			vault.Context("users", "api_passphrase", account.OwnerUserID)
+	}
+	return vault.Context("admin_accounts", "api_secret", account.ID),
+		vault.Context("admin_accounts", "passphrase", account.ID)
+}
+
+// apiUserAccount 用API用户保存的密钥构造账户，交给 WalletService 查询
+func apiUserAccount(user *model.User) *model.AdminAccount {
+	return &model.AdminAccount{
+		AccountType: user.APIType,
+		APIKey:      user.APIKey,
+		APISecret:   user.APISecret,
+		Passphrase:  user.APIPassphrase,
+		OwnerUserID: user.ID,
+	}
+}
+
+// signingAccount 复制一份解密了密钥的账户，只传给适配器用于签名，不回写、不外传
+func (ws *WalletService) signingAccount(account *model.AdminAccount) (*model.AdminAccount, error) {
+	if account.APISecret == "" && account.Passphrase == "" {
+		return account, nil
+	}
+	if ws.keyring == nil {
+		return nil, errNoKeyring
+	}
+
+	signing := *account
+	secretCtx, passphraseCtx := secretContexts(account)
+	var err error
+	if signing.APISecret, err = ws.keyring.Open(account.APISecret, secretCtx); err != nil {
+		return nil, fmt.Errorf("解密 %s API Secret 失败: %v", account.AccountType, err)
+	}
+	if signing.Passphrase, err = ws.keyring.Open(account.Passphrase, passphraseCtx); err != nil {
+		return nil, fmt.Errorf("解密 %s Passphrase 失败: %v", account.AccountType, err)
+	}
+	return &signing, nil
+}
+
+// EncryptStoredSecrets 加密数据库中遗留的明文密钥，并把 v1 密文升级为绑定存储位置的密文
+// 启动时调用，已经是 v2 的密文不变。
+func (s *Service) EncryptStoredSecrets() (int, error) {
+	return s.repo.RewriteSecrets(func(value, context string) (string, error) {
+		if vault.IsLegacy(value) {
+			if s.walletService.keyring == nil {
+				return "", errNoKeyring
+			}
+			upgraded, _, err := s.walletService.keyring.Rewrap(value, context)
+			return upgraded, err
+		}
+		if vault.IsSealed(value) {
+			return value, nil
+		}
+		return s.walletService.SealSecret(value, context)
+	})
+}
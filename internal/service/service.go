@@ -2,9 +2,9 @@ package service
 
 import (
 	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/password"
 	"crypto-final/internal/repository"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -14,13 +14,28 @@ type Service struct {
 	repo                *repository.Repository
 	walletService       *WalletService
 	userDefaultPassword string
+	sessionTTL          time.Duration
+	requireStaff2FA     bool
+	riskFreeRate        float64
+	priceSource         PriceSource
 }
 
 func NewService(repo *repository.Repository) *Service {
+	return NewServiceWithWallet(repo, NewWalletService())
+}
+
+// NewServiceWithWallet 使用指定的WalletService创建服务（可注入自定义或模拟的交易所适配器）
+func NewServiceWithWallet(repo *repository.Repository, walletService *WalletService) *Service {
 	return &Service{
 		repo:                repo,
-		walletService:       NewWalletService(),
+		walletService:       walletService,
 		userDefaultPassword: "user123456", // 默认值
+		sessionTTL:          defaultSessionTTL,
+		requireStaff2FA:     true,
+		priceSource: &binancePriceSource{
+			httpClient: walletService.httpClient,
+			baseURL:    walletService.Endpoints().BinanceSpot,
+		},
 	}
 }
 
@@ -29,16 +44,28 @@ func (s *Service) SetUserDefaultPassword(password string) {
 	s.userDefaultPassword = password
 }
 
-// HashPassword 密码哈希
-func (s *Service) HashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
-}
+// checkPassword 校验用户密码，旧格式或低成本的哈希在校验通过后自动升级
+func (s *Service) checkPassword(user *model.User, plain string) error {
+	ok, needsRehash := password.Verify(user.PasswordHash, plain)
+	if !ok {
+		return errors.New("密码错误")
+	}
+
+	if needsRehash {
+		newHash, err := password.Hash(plain)
+		if err == nil {
+			err = s.repo.UpdateUserPassword(user.ID, newHash)
+		}
+		if err != nil {
+			// 升级失败不影响本次登录，下次登录会再试
+			fmt.Printf("⚠️  用户%d 密码哈希升级失败: %v\n", user.ID, err)
+		} else {
+			user.PasswordHash = newHash
+			fmt.Printf("✓ 用户%d 密码哈希已升级为 bcrypt\n", user.ID)
+		}
+	}
 
-// hashPassword 计算密码哈希
-func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+	return nil
 }
 
 // Login 登录
@@ -54,9 +81,8 @@ func (s *Service) Login(credential, password string) (*model.User, error) {
 	}
 
 	// 验证密码
-	passwordHash := hashPassword(password)
-	if user.PasswordHash != passwordHash {
-		return nil, errors.New("密码错误")
+	if err := s.checkPassword(user, password); err != nil {
+		return nil, err
 	}
 
 	return user, nil
@@ -74,14 +100,17 @@ func (s *Service) AdminCreateUser(phone string) (int64, error) {
 	}
 
 	// 使用配置的默认密码
-	passwordHash := s.HashPassword(s.userDefaultPassword)
+	passwordHash, err := password.Hash(s.userDefaultPassword)
+	if err != nil {
+		return 0, err
+	}
 
 	return s.repo.CreateUser(phone, passwordHash)
 }
 
 // CreateAPIUser 创建API用户（独立Admin账户）
 // CreateAPIUser 创建API用户（简化版：不需要API密钥）
-func (s *Service) CreateAPIUser(username, password string) (int64, error) {
+func (s *Service) CreateAPIUser(username, plainPassword string) (int64, error) {
 	// 1. 检查用户名是否已存在
 	existingUser, err := s.repo.GetUserByUsername(username)
 	if err != nil {
@@ -92,7 +121,10 @@ func (s *Service) CreateAPIUser(username, password string) (int64, error) {
 	}
 
 	// 2. 创建API用户（不关联Admin账户，initial_balance=0）
-	passwordHash := hashPassword(password)
+	passwordHash, err := password.Hash(plainPassword)
+	if err != nil {
+		return 0, err
+	}
 	userID, err := s.repo.CreateAPIUser(username, passwordHash, 0, 0) // admin_account_id=0表示未绑定
 	if err != nil {
 		return 0, fmt.Errorf("创建用户失败: %v", err)
@@ -103,43 +135,72 @@ func (s *Service) CreateAPIUser(username, password string) (int64, error) {
 }
 
 // AdminRecharge 管理员给用户充值（从系统账户划转份额）
-func (s *Service) AdminRecharge(userID, adminAccountID int, amount float64, currency string) error {
+// 按该币种当前净值定价：获得份额 = 充值金额 / 净值
+func (s *Service) AdminRecharge(userID, adminAccountID int, amount money.Decimal, currency string) (int64, error) {
+	amount = money.Amount(amount, currency)
+	if amount.Sign() <= 0 {
+		return 0, errors.New("充值金额必须大于0")
+	}
+
+	adminAccount, err := s.repo.GetAdminAccountByID(adminAccountID)
+	if err != nil {
+		return 0, err
+	}
+	if adminAccount == nil {
+		return 0, errors.New("Admin账户不存在")
+	}
+
 	// 获取系统账户
 	systemRecharge, err := s.repo.GetSystemRecharge(adminAccountID, currency)
 	if err != nil {
-		return fmt.Errorf("获取系统账户失败: %v", err)
+		return 0, fmt.Errorf("获取系统账户失败: %v", err)
 	}
 	if systemRecharge == nil {
-		return errors.New("系统账户不存在")
+		return 0, errors.New("系统账户不存在")
 	}
 
-	// 🔥 简单模式：1:1分配份额
-	purchaseShares := amount
+	nav, err := s.strikeNAV(adminAccount, currency, model.NAVEventSubscription)
+	if err != nil {
+		return 0, err
+	}
 
-	// 创建用户充值记录
-	_, err = s.repo.CreateRechargeWithShares(
-		userID,
-		adminAccountID,
-		amount,
-		currency,
-		0, // base_balance 不需要了
-		purchaseShares,
-	)
+	purchaseShares, err := money.IssueSharesChecked(amount, nav.NAV)
 	if err != nil {
-		return fmt.Errorf("创建充值记录失败: %v", err)
+		return 0, fmt.Errorf("计算份额失败: %v", err)
+	}
+	if systemRecharge.Shares < purchaseShares {
+		return 0, fmt.Errorf("系统账户份额不足: 需要 %.4f，剩余 %.4f", purchaseShares, systemRecharge.Shares)
+	}
+
+	// 创建用户充值记录：份额从系统账户划转（总份额不变），本金记在资金池对方科目
+	rechargeID, err := s.repo.PostJournal(&model.JournalEntry{
+		EntryType:      model.JournalSubscription,
+		AdminAccountID: adminAccountID,
+		Currency:       currency,
+		Memo:           fmt.Sprintf("用户%d 充值", userID),
+		Postings: []*model.JournalPosting{
+			{Account: repository.NewRechargeAccount, Shares: purchaseShares, Amount: amount},
+			{Account: repository.RechargeAccount(systemRecharge.ID), Shares: purchaseShares.Neg()},
+			{Account: repository.PoolAccount(adminAccountID, currency), Amount: amount.Neg()},
+		},
+	}, &model.Recharge{UserID: userID, AdminAccountID: adminAccountID, Currency: currency})
+	if err != nil {
+		return 0, fmt.Errorf("创建充值记录失败: %v", err)
 	}
 
 	fmt.Printf("✓ 用户充值成功:\n")
 	fmt.Printf("  用户ID: %d\n", userID)
 	fmt.Printf("  充值金额: $%.2f %s\n", amount, currency)
-	fmt.Printf("  获得份额: %.2f\n", purchaseShares)
+	fmt.Printf("  净值: $%.4f\n", nav.NAV)
+	fmt.Printf("  获得份额: %.4f\n", purchaseShares)
 
-	return nil
+	return rechargeID, nil
 }
 
 // AdminDepositToExchange Admin充值到交易所（按币种独立计算）
-func (s *Service) AdminDepositToExchange(adminAccountID int, amount float64, currency string) error {
-	if amount <= 0 {
+func (s *Service) AdminDepositToExchange(adminAccountID int, amount money.Decimal, currency string) error {
+	amount = money.Amount(amount, currency)
+	if amount.Sign() <= 0 {
 		return errors.New("充值金额必须大于0")
 	}
 
@@ -162,69 +223,65 @@ func (s *Service) AdminDepositToExchange(adminAccountID int, amount float64, cur
 		return fmt.Errorf("获取系统账户失败: %v", err)
 	}
 
-	// 获取该币种的总份额
-	totalShares, err := s.repo.GetTotalSharesByCurrency(adminAccountID, currency)
+	// 🔥 按当前净值计算购买份额（资金到账前的余额 / 总份额）
+	nav, err := s.strikeNAV(adminAccount, currency, model.NAVEventDeposit)
 	if err != nil {
-		return fmt.Errorf("获取总份额失败: %v", err)
+		return err
 	}
 
-	// 🔥 计算购买份额（简化：净值=1）
-	// TODO: 未来可以改进为按实际汇率计算净值
-	var netValue float64
-	var purchasedShares float64
+	netValue := nav.NAV
+	purchasedShares, err := money.IssueSharesChecked(amount, netValue)
+	if err != nil {
+		return fmt.Errorf("计算份额失败: %v", err)
+	}
 
-	if totalShares == 0 {
-		// 该币种首次充值
-		netValue = 1.0
-		purchasedShares = amount
+	if nav.TotalShares.IsZero() {
 		fmt.Printf("\n  [%s首次充值] 净值: $1.00\n", currency)
 	} else {
-		// 该币种后续充值
-		// 简化处理：假设稳定币净值=1
-		netValue = 1.0
-		purchasedShares = amount
-		fmt.Printf("\n  [%s充值] 净值: $%.4f\n", currency, netValue)
+		fmt.Printf("\n  [%s充值] 余额: $%.2f, 总份额: %.4f, 净值: $%.4f\n", currency, nav.Balance, nav.TotalShares, netValue)
 	}
 
 	fmt.Printf("  购买份额: %.4f\n", purchasedShares)
 
-	// 创建或更新系统充值记录
+	// 发行份额给系统充值记录（没有则创建）
+	entry := &model.JournalEntry{
+		EntryType:      model.JournalDeposit,
+		AdminAccountID: adminAccountID,
+		Currency:       currency,
+		Memo:           "Admin充值到交易所",
+		Postings: []*model.JournalPosting{
+			{Account: repository.PoolAccount(adminAccountID, currency), Shares: purchasedShares.Neg(), Amount: amount.Neg()},
+		},
+	}
 	if systemRecharge == nil {
-		// 创建新的系统充值记录
-		rechargeID, err := s.repo.CreateRechargeWithShares(
-			0, // user_id = 0
-			adminAccountID,
-			amount,
-			currency,
-			0,
-			purchasedShares,
-		)
+		entry.Postings = append(entry.Postings, &model.JournalPosting{
+			Account: repository.NewRechargeAccount, Shares: purchasedShares, Amount: amount,
+		})
+		rechargeID, err := s.repo.PostJournal(entry, &model.Recharge{
+			UserID:         0, // 系统账户
+			AdminAccountID: adminAccountID,
+			Currency:       currency,
+		})
 		if err != nil {
 			return fmt.Errorf("创建系统充值记录失败: %v", err)
 		}
 		fmt.Printf("\n  ✓ 创建%s系统充值记录 (ID: %d)\n", currency, rechargeID)
 	} else {
-		// 更新现有记录
-		newAmount := systemRecharge.Amount + amount
-		newShares := systemRecharge.Shares + purchasedShares
-
-		err = s.repo.UpdateRechargeAmountAndShares(systemRecharge.ID, newAmount, newShares)
-		if err != nil {
+		entry.Postings = append(entry.Postings, &model.JournalPosting{
+			Account: repository.RechargeAccount(systemRecharge.ID), Shares: purchasedShares, Amount: amount,
+		})
+		if _, err := s.repo.PostJournal(entry, nil); err != nil {
 			return fmt.Errorf("更新系统充值记录失败: %v", err)
 		}
 		fmt.Printf("\n  ✓ 更新%s系统充值记录 (ID: %d)\n", currency, systemRecharge.ID)
-		fmt.Printf("    累计充值: $%.2f → $%.2f\n", systemRecharge.Amount, newAmount)
-		fmt.Printf("    累计份额: %.4f → %.4f\n", systemRecharge.Shares, newShares)
+		fmt.Printf("    累计充值: $%.2f → $%.2f\n", systemRecharge.Amount, systemRecharge.Amount+amount)
+		fmt.Printf("    累计份额: %.4f → %.4f\n", systemRecharge.Shares, systemRecharge.Shares+purchasedShares)
 	}
 
 	// 更新Admin账户总份额（所有币种之和）
-	allShares, err := s.repo.GetAllSharesByAccount(adminAccountID)
-	if err != nil {
-		return fmt.Errorf("获取总份额失败: %v", err)
-	}
-	err = s.repo.UpdateAdminAccountShares(adminAccountID, allShares)
+	allShares, err := s.refreshAccountShares(adminAccountID)
 	if err != nil {
-		return fmt.Errorf("更新总份额失败: %v", err)
+		return err
 	}
 
 	fmt.Printf("  账户总份额更新为: %.4f\n", allShares)
@@ -235,42 +292,61 @@ func (s *Service) AdminDepositToExchange(adminAccountID int, amount float64, cur
 }
 
 // UpdateRechargeAmount 修改充值金额
-func (s *Service) UpdateRechargeAmount(rechargeID int, newAmount float64) error {
+func (s *Service) UpdateRechargeAmount(rechargeID int, newAmount money.Decimal) error {
 	// 1. 获取充值记录
 	recharge, err := s.repo.GetRechargeByID(rechargeID)
 	if err != nil {
 		return err
 	}
+	if recharge == nil {
+		return errors.New("充值记录不存在")
+	}
+	if !recharge.IsActive {
+		return errors.New("该充值已停用")
+	}
+	newAmount = money.Amount(newAmount, recharge.Currency)
 
-	// 2. 重新计算份额
-	account, _ := s.repo.GetAdminAccountByID(recharge.AdminAccountID)
-	currentBalance, _ := s.walletService.GetBalance(account)
-	totalShares, _ := s.repo.GetTotalSharesByCurrency(recharge.AdminAccountID, recharge.Currency)
+	// 2. 按当前净值重新计算份额
+	account, err := s.repo.GetAdminAccountByID(recharge.AdminAccountID)
+	if err != nil || account == nil {
+		return errors.New("无法获取账户信息")
+	}
+	nav, err := s.strikeNAV(account, recharge.Currency, model.NAVEventCorrection)
+	if err != nil {
+		return err
+	}
 
-	netValue := currentBalance / totalShares
-	newShares := newAmount / netValue
+	newShares, err := money.IssueSharesChecked(newAmount, nav.NAV)
+	if err != nil {
+		return fmt.Errorf("计算份额失败: %v", err)
+	}
 
 	// 3. 计算份额差异
 	sharesDiff := newShares - recharge.Shares
 
 	// 4. 从系统账户调整份额
 	systemRecharge, _ := s.repo.GetSystemRecharge(recharge.AdminAccountID, recharge.Currency)
+	if systemRecharge == nil {
+		return errors.New("系统账户不存在")
+	}
 	if systemRecharge.Shares < sharesDiff {
 		return errors.New("系统账户份额不足")
 	}
 
-	// 5. 更新
-	err = s.repo.UpdateRechargeAmountAndShares(rechargeID, newAmount, newShares)
-	if err != nil {
-		return err
-	}
-
-	err = s.repo.UpdateRechargeShares(systemRecharge.ID, systemRecharge.Shares-sharesDiff)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// 5. 记一笔更正分录，差额与系统账户、资金池对冲
+	amountDiff := newAmount - recharge.Amount
+	_, err = s.repo.PostJournal(&model.JournalEntry{
+		EntryType:      model.JournalCorrection,
+		AdminAccountID: recharge.AdminAccountID,
+		Currency:       recharge.Currency,
+		Memo:           fmt.Sprintf("充值%d 金额 %s → %s", rechargeID, recharge.Amount, newAmount),
+		Postings: []*model.JournalPosting{
+			{Account: repository.RechargeAccount(rechargeID), Shares: sharesDiff, Amount: amountDiff},
+			{Account: repository.RechargeAccount(systemRecharge.ID), Shares: sharesDiff.Neg()},
+			{Account: repository.PoolAccount(recharge.AdminAccountID, recharge.Currency), Amount: amountDiff.Neg()},
+		},
+	}, nil)
+	return err
 }
 
 // DeleteRecharge 删除充值记录（返还份额到系统账户）
@@ -281,23 +357,33 @@ func (s *Service) DeleteRecharge(rechargeID int) error {
 		return err
 	}
 
+	if recharge == nil {
+		return errors.New("充值记录不存在")
+	}
 	if recharge.UserID == 0 {
 		return errors.New("不能删除系统账户")
 	}
+	if !recharge.IsActive {
+		return errors.New("该充值已停用")
+	}
 
-	// 2. 返还份额到系统账户
+	// 2. 停用充值记录并记冲销分录：份额返还系统账户，本金退回资金池
 	systemRecharge, _ := s.repo.GetSystemRecharge(recharge.AdminAccountID, recharge.Currency)
-	newSystemShares := systemRecharge.Shares + recharge.Shares
-
-	err = s.repo.UpdateRechargeShares(systemRecharge.ID, newSystemShares)
-	if err != nil {
-		return err
+	if systemRecharge == nil {
+		return errors.New("系统账户不存在")
 	}
 
-	// 3. 🔥 直接使用SQL更新，不调用DeactivateRecharge
-	// err = s.repo.DeactivateRecharge(rechargeID)  // 删除这行
-	// 改为：
-	err = s.repo.UpdateRechargeActive(rechargeID, false)
+	err = s.repo.PostClosingJournal(rechargeID, &model.JournalEntry{
+		EntryType:      model.JournalReversal,
+		AdminAccountID: recharge.AdminAccountID,
+		Currency:       recharge.Currency,
+		Memo:           fmt.Sprintf("删除充值%d", rechargeID),
+		Postings: []*model.JournalPosting{
+			{Account: repository.RechargeAccount(rechargeID), Shares: recharge.Shares.Neg(), Amount: recharge.Amount.Neg()},
+			{Account: repository.RechargeAccount(systemRecharge.ID), Shares: recharge.Shares},
+			{Account: repository.PoolAccount(recharge.AdminAccountID, recharge.Currency), Amount: recharge.Amount},
+		},
+	})
 	if err != nil {
 		return err
 	}
@@ -344,9 +430,8 @@ func (s *Service) LoginByUsername(username, password string) (*model.User, error
 	}
 
 	// 验证密码
-	passwordHash := hashPassword(password)
-	if user.PasswordHash != passwordHash {
-		return nil, errors.New("密码错误")
+	if err := s.checkPassword(user, password); err != nil {
+		return nil, err
 	}
 
 	return user, nil
@@ -375,16 +460,20 @@ func (s *Service) GetAdminAccountsStatus() ([]*model.AdminAccountStatusResponse,
 		address := ""
 
 		if acc.AccountType == "Wallet" {
-			isConfigured = acc.WalletAddress != ""
-			if isConfigured {
-				address = acc.WalletAddress // 显示完整钱包地址
-			} else {
+			addresses := walletChainAddresses(acc)
+			isConfigured = len(addresses) > 0
+			switch {
+			case len(addresses) == 1:
+				address = addresses[0].Address // 显示完整钱包地址
+			case len(addresses) > 1:
+				address = fmt.Sprintf("%d个链上地址", len(addresses))
+			default:
 				address = "未配置"
 			}
 		} else {
-			// 对于Binance和OKX，显示API Key的前8位作为标识
+			// 对于交易所账户，显示API Key的前8位作为标识
 			isConfigured = acc.APIKey != "" && acc.APISecret != ""
-			if acc.AccountType == "OKX" {
+			if acc.AccountType == "OKX" || acc.AccountType == "Bitget" {
 				isConfigured = isConfigured && acc.Passphrase != ""
 			}
 
@@ -424,12 +513,78 @@ func (s *Service) GetAdminAccountsStatus() ([]*model.AdminAccountStatusResponse,
 
 // ConfigAdminAccount 配置Admin账户
 func (s *Service) ConfigAdminAccount(accountType, apiKey, apiSecret, walletAddress, passphrase string) error {
-	return s.repo.UpdateAdminAccountConfig(accountType, apiKey, apiSecret, walletAddress, passphrase)
+	existing, err := s.repo.GetAdminAccountByType(accountType)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("账户不存在: %s", accountType)
+	}
+
+	// 密文绑定到 admin_accounts 中这个账户的行
+	secretCtx, passphraseCtx := secretContexts(existing)
+	sealedSecret, err := s.walletService.SealSecret(apiSecret, secretCtx)
+	if err != nil {
+		return fmt.Errorf("加密API Secret失败: %v", err)
+	}
+	sealedPassphrase, err := s.walletService.SealSecret(passphrase, passphraseCtx)
+	if err != nil {
+		return fmt.Errorf("加密Passphrase失败: %v", err)
+	}
+
+	if accountType == "Wallet" && walletAddress != "" {
+		ethereum, _ := chainByName(chainEthereum)
+		if _, ok := normalizeChainAddress(ethereum, walletAddress); !ok {
+			return fmt.Errorf("以太坊地址格式不正确: %s", walletAddress)
+		}
+	}
+
+	// 保存前向交易所校验凭证和权限
+	if apiKey != "" {
+		err := s.walletService.ValidateReadOnlyKey(&model.AdminAccount{
+			ID:            existing.ID,
+			AccountType:   accountType,
+			APIKey:        apiKey,
+			APISecret:     sealedSecret,
+			WalletAddress: walletAddress,
+			Passphrase:    sealedPassphrase,
+		})
+		if err != nil {
+			return fmt.Errorf("API密钥校验失败: %v", err)
+		}
+	}
+
+	if err := s.repo.UpdateAdminAccountConfig(accountType, apiKey, sealedSecret, walletAddress, sealedPassphrase); err != nil {
+		return err
+	}
+
+	// 旧版配置表单的钱包地址按以太坊地址绑定，其他链通过 /admin/accounts/:id/addresses 添加
+	if accountType == "Wallet" && walletAddress != "" {
+		if _, err := s.AddChainAddress(existing.ID, chainEthereum, walletAddress, ""); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // UpdateUserStatus 更新用户状态（直接设置）
 func (s *Service) UpdateUserStatus(userID int, isActive bool) error {
-	return s.repo.UpdateUserStatus(userID, isActive)
+	if !isActive {
+		user, err := s.repo.GetUserByID(userID)
+		if err != nil {
+			return err
+		}
+		if user != nil && user.Role == model.RoleSuperAdmin {
+			return errors.New("超级管理员不能停用")
+		}
+	}
+	if err := s.repo.UpdateUserStatus(userID, isActive); err != nil {
+		return err
+	}
+	if !isActive {
+		return s.RevokeUserSessions(userID)
+	}
+	return nil
 }
 
 // GetAllUsers 获取所有用户（含盈亏统计）
@@ -468,6 +623,7 @@ func (s *Service) GetAllUsers() ([]*model.UserSummary, error) {
 			result = append(result, &model.UserSummary{
 				UserID:        user.ID,
 				Phone:         displayName,
+				Role:          user.Role,
 				IsActive:      user.IsActive,
 				TotalRecharge: 0,
 				CurrentValue:  0,
@@ -483,6 +639,7 @@ func (s *Service) GetAllUsers() ([]*model.UserSummary, error) {
 		result = append(result, &model.UserSummary{
 			UserID:        user.ID,
 			Phone:         displayName,
+			Role:          user.Role,
 			IsActive:      user.IsActive,
 			TotalRecharge: summary.TotalRecharge,
 			CurrentValue:  summary.CurrentValue,
@@ -521,17 +678,12 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 			}, nil
 		}
 
-		userAccount := &model.AdminAccount{
-			AccountType: user.APIType,
-			APIKey:      user.APIKey,
-			APISecret:   user.APISecret,
-			Passphrase:  user.APIPassphrase,
-		}
+		userAccount := apiUserAccount(user)
 
 		var currency string
 		if user.APIType == "Binance" {
 			currency = "USDC"
-		} else if user.APIType == "OKX" {
+		} else if user.APIType == "OKX" || user.APIType == "Bybit" || user.APIType == "Bitget" || user.APIType == "Gate" {
 			currency = "USDT"
 		} else {
 			return nil, errors.New("不支持的API类型")
@@ -545,7 +697,7 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 		totalProfit := currentBalance - user.InitialBalance
 		profitRate := 0.0
 		if user.InitialBalance > 0 {
-			profitRate = (totalProfit / user.InitialBalance) * 100
+			profitRate = money.Ratio(totalProfit, user.InitialBalance) * 100
 		}
 
 		holdDays := int(time.Since(user.CreatedAt).Hours() / 24)
@@ -553,45 +705,36 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 			holdDays = 1
 		}
 
-		// 🔥 修正盈亏率计算逻辑
-		dailyRate := 0.0
-		monthlyRate := 0.0
-		quarterlyRate := 0.0
-		annualRate := 0.0
+		returns := apiUserReturns(user, currentBalance, holdDays, time.Now())
+		monthlyRate := compoundRate(1+profitRate/100, holdDays, 30)
+		quarterlyRate := compoundRate(1+profitRate/100, holdDays, 90)
+		annualRate := returns.TWRAnnualized
 
-		if holdDays > 0 {
-			// 日盈亏率 = 总盈亏率 / 持有天数
-			dailyRate = profitRate / float64(holdDays)
-			monthlyRate = dailyRate * 30
-			quarterlyRate = dailyRate * 90
-			annualRate = dailyRate * 365
-		}
-
-		fmt.Printf("[API用户 %d] 初始余额=$%.2f, 当前余额=$%.2f, 盈亏=$%.2f (%.2f%%), 日盈亏=%.4f%%, 年化=%.2f%%\n",
-			userID, user.InitialBalance, currentBalance, totalProfit, profitRate, dailyRate, annualRate)
+		fmt.Printf("[API用户 %d] 初始余额=$%.2f, 当前余额=$%.2f, 盈亏=$%.2f (%.2f%%), 年化=%.2f%%\n",
+			userID, user.InitialBalance, currentBalance, totalProfit, profitRate, annualRate)
 
 		// 🔥 获取API用户的里程碑数据
 		milestones, _ := s.GetHistoricalProfitFromMilestones(userID)
 
-		monthlyActual := 0.0
+		monthlyActual := money.Zero
 		monthlyActualRate := 0.0
-		quarterlyActual := 0.0
+		quarterlyActual := money.Zero
 		quarterlyActualRate := 0.0
-		yearlyActual := 0.0
+		yearlyActual := money.Zero
 		yearlyActualRate := 0.0
 
 		if milestones != nil {
-			if monthly, ok := milestones["monthly"]; ok && monthly["count"] > 0 {
-				monthlyActual = monthly["profit"]
-				monthlyActualRate = monthly["rate"]
+			if monthly, ok := milestones["monthly"]; ok && monthly.Count > 0 {
+				monthlyActual = monthly.Profit
+				monthlyActualRate = monthly.Rate
 			}
-			if quarterly, ok := milestones["quarterly"]; ok && quarterly["count"] > 0 {
-				quarterlyActual = quarterly["profit"]
-				quarterlyActualRate = quarterly["rate"]
+			if quarterly, ok := milestones["quarterly"]; ok && quarterly.Count > 0 {
+				quarterlyActual = quarterly.Profit
+				quarterlyActualRate = quarterly.Rate
 			}
-			if yearly, ok := milestones["yearly"]; ok && yearly["count"] > 0 {
-				yearlyActual = yearly["profit"]
-				yearlyActualRate = yearly["rate"]
+			if yearly, ok := milestones["yearly"]; ok && yearly.Count > 0 {
+				yearlyActual = yearly.Profit
+				yearlyActualRate = yearly.Rate
 			}
 		}
 		return &model.DashboardSummary{
@@ -610,6 +753,7 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 			QuarterlyActualRate: quarterlyActualRate, // 🔥 新增
 			YearlyActual:        yearlyActual,        // 🔥 新增
 			YearlyActualRate:    yearlyActualRate,    // 🔥 新增
+			Returns:             returns,
 			LastUpdateTime:      time.Now().Format("2006-01-02 15:04:05"),
 		}, nil
 	}
@@ -620,8 +764,8 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 		return nil, err
 	}
 
-	totalRecharge := 0.0
-	totalCurrentValue := 0.0
+	totalRecharge := money.Zero
+	totalCurrentValue := money.Zero
 	totalHoldDays := 0
 	activeCount := 0
 
@@ -631,47 +775,27 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 		}
 
 		activeCount++
-		totalRecharge += r.Amount
+		totalRecharge = totalRecharge.Add(r.Amount)
 
 		// 🔥 关键：基于份额计算当前价值
 		adminAccount, err := s.repo.GetAdminAccountByID(r.AdminAccountID)
 		if err != nil || adminAccount == nil {
 			fmt.Printf("⚠️  充值ID %d: 无法获取账户信息\n", r.ID)
-			totalCurrentValue += r.Amount
-			continue
-		}
-
-		// 🔥 1:1份额模式：获取总充值金额
-		fmt.Printf("🔍 [GetDashboardSummary] 调用GetTotalRechargeAmountByCurrency, adminAccountID=%d, currency=%s\n",
-			r.AdminAccountID, r.Currency)
-
-		totalRechargeAmount, err := s.repo.GetTotalRechargeAmountByCurrency(r.AdminAccountID, r.Currency)
-
-		fmt.Printf("🔍 [GetDashboardSummary] GetTotalRechargeAmountByCurrency返回: totalRechargeAmount=%.2f, err=%v\n",
-			totalRechargeAmount, err)
-
-		if err != nil || totalRechargeAmount <= 0 {
-			fmt.Printf("⚠️  充值ID %d: 无法获取总充值金额 (currency: %s), err=%v, totalRechargeAmount=%.2f\n",
-				r.ID, r.Currency, err, totalRechargeAmount)
-			totalCurrentValue += r.Amount
+			totalCurrentValue = totalCurrentValue.Add(r.Amount)
 			continue
 		}
 
-		// 🔥 按币种获取余额
-		currentBalance, err := s.walletService.GetBalanceByAsset(adminAccount, r.Currency)
+		// 🔥 份额模式：当前价值 = 持有份额 × 该币种净值
+		currentValue, nav, err := s.rechargeValue(adminAccount, r)
 		if err != nil {
-			fmt.Printf("⚠️  充值ID %d: 无法获取余额\n", r.ID)
-			totalCurrentValue += r.Amount
+			fmt.Printf("⚠️  充值ID %d: 无法估值 (currency: %s): %v\n", r.ID, r.Currency, err)
+			totalCurrentValue = totalCurrentValue.Add(r.Amount)
 			continue
 		}
+		totalCurrentValue = totalCurrentValue.Add(currentValue)
 
-		// 🔥 1:1份额模式：净值 = 当前余额 / 总充值金额
-		netValue := currentBalance / totalRechargeAmount
-		currentValue := r.Amount * netValue // 用户当前价值 = 用户充值 × 净值
-		totalCurrentValue += currentValue
-
-		fmt.Printf("  [充值ID %d] 币种=%s, 用户充值=$%.2f, 总充值=$%.2f, 余额=$%.2f, 净值=$%.4f, 当前价值=$%.2f\n",
-			r.ID, r.Currency, r.Amount, totalRechargeAmount, currentBalance, netValue, currentValue)
+		fmt.Printf("  [充值ID %d] 币种=%s, 用户充值=$%.2f, 份额=%.4f, 余额=$%.2f, 净值=$%.4f, 当前价值=$%.2f\n",
+			r.ID, r.Currency, r.Amount, r.Shares, nav.Balance, nav.NAV, currentValue)
 
 		// 计算持有天数
 		holdDays := int(time.Since(r.RechargeAt).Hours() / 24)
@@ -686,31 +810,28 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 	avgHoldDays := 0
 
 	if totalRecharge > 0 {
-		totalProfitRate = (totalProfit / totalRecharge) * 100
+		totalProfitRate = money.Ratio(totalProfit, totalRecharge) * 100
 	}
 
 	if activeCount > 0 {
 		avgHoldDays = totalHoldDays / activeCount
 	}
 
-	// 🔥 修正盈亏率计算逻辑
-	dailyRate := 0.0
+	// 月/季/年化收益率：时间加权收益率按复利折算，不受多笔充值、撤资的时点影响
 	monthlyRate := 0.0
 	quarterlyRate := 0.0
 	annualRate := 0.0
 
-	if avgHoldDays > 0 {
-		// 日盈亏率 = 总盈亏率 / 平均持有天数
-		dailyRate = totalProfitRate / float64(avgHoldDays)
-
-		// 月盈亏率 = 日盈亏率 × 30天
-		monthlyRate = dailyRate * 30
-
-		// 季度盈亏率 = 日盈亏率 × 90天
-		quarterlyRate = dailyRate * 90
-
-		// 年盈亏率 = 日盈亏率 × 365天
-		annualRate = dailyRate * 365
+	returns := s.userReturns(userID)
+	if returns != nil {
+		growth := 1 + returns.TWR/100
+		days := returns.Days
+		if days < 1 {
+			days = 1
+		}
+		monthlyRate = compoundRate(growth, days, 30)
+		quarterlyRate = compoundRate(growth, days, 90)
+		annualRate = compoundRate(growth, days, 365)
 	}
 
 	fmt.Printf("\n[Dashboard总览] 用户ID %d:\n", userID)
@@ -718,33 +839,45 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 	fmt.Printf("  当前价值: $%.2f\n", totalCurrentValue)
 	fmt.Printf("  总盈亏: $%.2f (%.2f%%)\n", totalProfit, totalProfitRate)
 	fmt.Printf("  平均持有天数: %d天\n", avgHoldDays)
-	fmt.Printf("  日盈亏率: %.4f%%\n", dailyRate)
+	if returns != nil {
+		fmt.Printf("  时间加权收益率: %.2f%% (%d天)\n", returns.TWR, returns.Days)
+		if returns.XIRR != nil {
+			fmt.Printf("  XIRR: %.2f%%\n", *returns.XIRR)
+		}
+	}
 	fmt.Printf("  月盈亏率: %.2f%%\n", monthlyRate)
 	fmt.Printf("  季度盈亏率: %.2f%%\n", quarterlyRate)
 	fmt.Printf("  年盈亏率: %.2f%%\n", annualRate)
 
+	// 已撤资部分的盈亏已经实现，单独汇总
+	withdrawn, err := s.repo.SumWithdrawals(model.WithdrawalFilter{UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("汇总撤资记录失败: %v", err)
+	}
+	fmt.Printf("  已实现盈亏: $%.2f (%d笔撤资)\n", withdrawn.RealizedProfit, withdrawn.Count)
+
 	// 🔥 获取历史里程碑数据
 	milestones, _ := s.GetHistoricalProfitFromMilestones(userID)
 
-	monthlyActual := 0.0
+	monthlyActual := money.Zero
 	monthlyActualRate := 0.0
-	quarterlyActual := 0.0
+	quarterlyActual := money.Zero
 	quarterlyActualRate := 0.0
-	yearlyActual := 0.0
+	yearlyActual := money.Zero
 	yearlyActualRate := 0.0
 
 	if milestones != nil {
-		if monthly, ok := milestones["monthly"]; ok && monthly["count"] > 0 {
-			monthlyActual = monthly["profit"]
-			monthlyActualRate = monthly["rate"]
+		if monthly, ok := milestones["monthly"]; ok && monthly.Count > 0 {
+			monthlyActual = monthly.Profit
+			monthlyActualRate = monthly.Rate
 		}
-		if quarterly, ok := milestones["quarterly"]; ok && quarterly["count"] > 0 {
-			quarterlyActual = quarterly["profit"]
-			quarterlyActualRate = quarterly["rate"]
+		if quarterly, ok := milestones["quarterly"]; ok && quarterly.Count > 0 {
+			quarterlyActual = quarterly.Profit
+			quarterlyActualRate = quarterly.Rate
 		}
-		if yearly, ok := milestones["yearly"]; ok && yearly["count"] > 0 {
-			yearlyActual = yearly["profit"]
-			yearlyActualRate = yearly["rate"]
+		if yearly, ok := milestones["yearly"]; ok && yearly.Count > 0 {
+			yearlyActual = yearly.Profit
+			yearlyActualRate = yearly.Rate
 		}
 	}
 	return &model.DashboardSummary{
@@ -763,6 +896,11 @@ func (s *Service) GetDashboardSummary(userID int) (*model.DashboardSummary, erro
 		QuarterlyActualRate: quarterlyActualRate, // 🔥 新增
 		YearlyActual:        yearlyActual,        // 🔥 新增
 		YearlyActualRate:    yearlyActualRate,    // 🔥 新增
+		RealizedProfit:      withdrawn.RealizedProfit,
+		UnrealizedProfit:    totalProfit,
+		TotalWithdrawn:      withdrawn.Withdrawn,
+		WithdrawalCount:     withdrawn.Count,
+		Returns:             returns,
 		LastUpdateTime:      time.Now().Format("2006-01-02 15:04:05"),
 	}, nil
 }
@@ -774,6 +912,11 @@ func (s *Service) GetUserRechargesWithProfit(userID int) ([]*model.RechargeWithP
 		return nil, err
 	}
 
+	ledger, err := s.loadReturnLedger(userID)
+	if err != nil {
+		fmt.Printf("⚠️  用户%d: 无法计算收益率: %v\n", userID, err)
+	}
+
 	var result []*model.RechargeWithProfit
 	for _, r := range recharges {
 		if !r.IsActive {
@@ -787,27 +930,17 @@ func (s *Service) GetUserRechargesWithProfit(userID int) ([]*model.RechargeWithP
 			continue
 		}
 
-		// 获取总充值金额（shares > 0）
-		totalRechargeAmount, err := s.repo.GetTotalRechargeAmountByCurrency(r.AdminAccountID, r.Currency)
-		if err != nil || totalRechargeAmount <= 0 {
-			fmt.Printf("⚠️  充值ID %d: 无法获取总充值金额\n", r.ID)
-			continue
-		}
-
-		// 获取当前余额
-		currentBalance, err := s.walletService.GetBalanceByAsset(account, r.Currency)
+		// 🔥 按份额和净值计算当前价值
+		currentValue, nav, err := s.rechargeValue(account, r)
 		if err != nil {
-			fmt.Printf("⚠️  充值ID %d: 无法获取余额\n", r.ID)
+			fmt.Printf("⚠️  充值ID %d: 无法估值: %v\n", r.ID, err)
 			continue
 		}
-
-		// 🔥 计算净值和当前价值
-		netValue := currentBalance / totalRechargeAmount
-		currentValue := r.Amount * netValue
+		netValue := nav.NAV
 		currentProfit := currentValue - r.Amount
 		profitRate := 0.0
 		if r.Amount > 0 {
-			profitRate = (currentProfit / r.Amount) * 100
+			profitRate = money.Ratio(currentProfit, r.Amount) * 100
 		}
 
 		// 计算持有天数
@@ -826,6 +959,12 @@ func (s *Service) GetUserRechargesWithProfit(userID int) ([]*model.RechargeWithP
 			CurrentRate:   profitRate,
 			DaysHeld:      daysHeld,
 		}
+		if ledger != nil {
+			item.Returns, err = s.computeReturns(ledger.filter(ledger.chain(r.ID)), time.Now())
+			if err != nil {
+				fmt.Printf("⚠️  充值ID %d: 无法计算收益率: %v\n", r.ID, err)
+			}
+		}
 		result = append(result, item)
 	}
 
@@ -856,27 +995,26 @@ func (s *Service) CalculateUserStats(userID int) *model.DashboardSummary {
 		return &model.DashboardSummary{}
 	}
 
-	totalRecharge := 0.0
-	totalCurrentValue := 0.0
+	totalRecharge := money.Zero
+	totalCurrentValue := money.Zero
 
 	for _, r := range recharges {
-		totalRecharge += r.Amount
+		totalRecharge = totalRecharge.Add(r.Amount)
 
 		// 获取最新盈亏
 		latestProfit, _ := s.repo.GetLatestRechargeProfit(r.ID)
 		if latestProfit != nil {
-			// 当前价值 = 充值金额 × (1 + 盈亏率)
-			currentValue := r.Amount * (1 + latestProfit.ProfitRate/100)
-			totalCurrentValue += currentValue
+			// 当前价值 = 充值金额 + 最近一次记录的盈亏
+			totalCurrentValue = totalCurrentValue.Add(r.Amount).Add(latestProfit.Profit)
 		} else {
-			totalCurrentValue += r.Amount
+			totalCurrentValue = totalCurrentValue.Add(r.Amount)
 		}
 	}
 
 	totalProfit := totalCurrentValue - totalRecharge
 	totalProfitRate := 0.0
 	if totalRecharge > 0 {
-		totalProfitRate = (totalProfit / totalRecharge) * 100
+		totalProfitRate = money.Ratio(totalProfit, totalRecharge) * 100
 	}
 
 	return &model.DashboardSummary{
@@ -907,11 +1045,21 @@ func (s *Service) UpdateDailyBalances() error {
 		return err
 	}
 
+	// 链上钱包的所有地址在每条链上读取同一区块，余额和各币种净值前后一致
+	pinnedBlocks := make(map[string]uint64)
+
 	for _, account := range accounts {
 		if !account.IsActive {
 			continue
 		}
 
+		if err := s.walletService.PinChainBlocks(account, pinnedBlocks); err != nil {
+			fmt.Printf("⚠️  固定%s区块高度失败，改为读取最新余额: %v\n", account.AccountType, err)
+		}
+		for chain, block := range account.PinnedBlocks {
+			fmt.Printf("  %s 固定在区块 %d\n", chain, block)
+		}
+
 		// 读取余额
 		balance, err := s.walletService.GetBalance(account)
 		if err != nil {
@@ -936,7 +1084,7 @@ func (s *Service) UpdateDailyBalances() error {
 		dailyChange := balance - yesterdayBalance
 		dailyChangeRate := 0.0
 		if yesterdayBalance > 0 {
-			dailyChangeRate = (dailyChange / yesterdayBalance) * 100
+			dailyChangeRate = money.Ratio(dailyChange, yesterdayBalance) * 100
 		}
 
 		// 保存余额记录
@@ -946,6 +1094,28 @@ func (s *Service) UpdateDailyBalances() error {
 		fmt.Printf("✓ %s 账户: $%.2f (变化: %+.2f, %+.2f%%)\n",
 			account.AccountType, balance, dailyChange, dailyChangeRate)
 		successCount++
+
+		// 记录该账户各币种的当日净值，后面的盈亏计算都读取这里的结果
+		currencies, err := s.repo.GetActiveCurrencies(account.ID)
+		if err != nil {
+			fmt.Printf("⚠️  获取%s币种列表失败: %v\n", account.AccountType, err)
+			continue
+		}
+		for _, currency := range currencies {
+			nav, err := s.closeDailyNAV(account, currency)
+			if err != nil {
+				fmt.Printf("⚠️  %s %s 净值记录失败: %v\n", account.AccountType, currency, err)
+				continue
+			}
+			fmt.Printf("  %s 净值: $%.4f (余额 $%.2f / 份额 %.4f)\n", currency, nav.NAV, nav.Balance, nav.TotalShares)
+
+			// 按日计提管理费（增发份额给管理人），之后的报酬和撤资都按计提后的净值
+			nav = s.accrueManagementFee(account, currency, nav)
+
+			// 每月第一次结算时计提上个月的业绩报酬，然后已批准的撤资申请按这次记录的净值成交
+			s.crystallizeMonthlyFees(account, currency, nav)
+			s.settleWithdrawalRequests(account, currency, nav)
+		}
 	}
 
 	// 步骤2: 计算每笔充值的盈亏（基于份额）
@@ -967,27 +1137,38 @@ func (s *Service) UpdateDailyBalances() error {
 			continue
 		}
 
-		currentBalance := adminAccount.CurrentBalance
-		totalShares := adminAccount.TotalShares
+		// 读取步骤1记录的净值
+		nav, err := s.latestNAV(adminAccount, recharge.Currency)
+		if err != nil {
+			fmt.Printf("⚠️  充值ID %d: 无法获取净值: %v\n", recharge.ID, err)
+			continue
+		}
+
+		currentBalance := nav.Balance
+		totalShares := nav.TotalShares
 
 		// 🔥 核心算法：基于份额计算盈亏
-		var currentValue float64
-		var profit float64
+		var currentValue money.Decimal
+		var profit money.Decimal
 		var profitRate float64
 
 		if totalShares > 0 && recharge.Shares > 0 {
-			// 当前净值 = 账户余额 / 总份额
-			netValue := currentBalance / totalShares
+			// 当前净值 = 该币种余额 / 该币种总份额
+			netValue := nav.NAV
 
 			// 用户当前价值 = 持有份额 × 净值
-			currentValue = recharge.Shares * netValue
+			currentValue, err = money.MarkValueChecked(recharge.Shares, netValue, recharge.Currency)
+			if err != nil {
+				fmt.Printf("⚠️  充值ID %d: 估值失败: %v\n", recharge.ID, err)
+				continue
+			}
 
 			// 盈亏 = 当前价值 - 本金
 			profit = currentValue - recharge.Amount
 
 			// 盈亏率 = 盈亏 / 本金 × 100%
 			if recharge.Amount > 0 {
-				profitRate = (profit / recharge.Amount) * 100
+				profitRate = money.Ratio(profit, recharge.Amount) * 100
 			}
 
 			// 获取用户信息用于日志
@@ -1008,7 +1189,7 @@ func (s *Service) UpdateDailyBalances() error {
 				recharge.Shares,
 				netValue,
 				currentValue,
-				formatSign(profit), abs(profit),
+				formatSign(profit.Float64()), profit.Abs(),
 				profitRate)
 		} else {
 			// 异常情况：份额为0
@@ -1027,6 +1208,10 @@ func (s *Service) UpdateDailyBalances() error {
 	}
 
 	fmt.Println("✓ 成功计算充值盈亏")
+
+	// 步骤3: 记录业绩比较基准的价格
+	s.RecordBenchmarkPrices()
+
 	fmt.Printf("\n========== 每日余额检查完成 (成功: %d, 失败: %d) ==========\n\n", successCount, errorCount)
 
 	return nil // ✅ 添加这行
@@ -1040,14 +1225,6 @@ func formatSign(value float64) string {
 	return ""
 }
 
-// abs 绝对值
-func abs(value float64) float64 {
-	if value < 0 {
-		return -value
-	}
-	return value
-}
-
 // ToggleUserStatus 切换用户状态
 func (s *Service) ToggleUserStatus(userID int) error {
 	user, err := s.repo.GetUserByID(userID)
@@ -1060,7 +1237,7 @@ func (s *Service) ToggleUserStatus(userID int) error {
 
 	// 切换状态
 	newStatus := !user.IsActive
-	return s.repo.UpdateUserStatus(userID, newStatus)
+	return s.UpdateUserStatus(userID, newStatus)
 }
 
 func (s *Service) GetDashboardRecharges(userID int) ([]*model.RechargeResponse, error) {
@@ -1101,7 +1278,7 @@ func (s *Service) GetDashboardRecharges(userID int) ([]*model.RechargeResponse,
 		// 获取最新盈亏
 		latestProfit, _ := s.repo.GetLatestRechargeProfit(r.ID)
 
-		currentProfit := 0.0
+		currentProfit := money.Zero
 		currentRate := 0.0
 
 		if latestProfit != nil {
@@ -1126,12 +1303,28 @@ func (s *Service) GetDashboardRecharges(userID int) ([]*model.RechargeResponse,
 
 // SaveUserAPIKeys 保存用户API密钥并验证
 func (s *Service) SaveUserAPIKeys(userID int, apiType, apiKey, apiSecret, passphrase string) error {
-	// 验证API密钥
+	// 先加密（绑定到 users 表中该用户的行），之后只传密文，由WalletService在签名时解密
 	testAccount := &model.AdminAccount{
 		AccountType: apiType,
 		APIKey:      apiKey,
-		APISecret:   apiSecret,
-		Passphrase:  passphrase,
+		OwnerUserID: userID,
+	}
+	secretCtx, passphraseCtx := secretContexts(testAccount)
+	sealedSecret, err := s.walletService.SealSecret(apiSecret, secretCtx)
+	if err != nil {
+		return fmt.Errorf("加密API Secret失败: %v", err)
+	}
+	sealedPassphrase, err := s.walletService.SealSecret(passphrase, passphraseCtx)
+	if err != nil {
+		return fmt.Errorf("加密Passphrase失败: %v", err)
+	}
+	testAccount.APISecret = sealedSecret
+	testAccount.Passphrase = sealedPassphrase
+
+	// 验证API密钥
+
+	if err := s.walletService.ValidateReadOnlyKey(testAccount); err != nil {
+		return fmt.Errorf("API验证失败: %v", err)
 	}
 
 	initialBalance, err := s.walletService.GetBalance(testAccount)
@@ -1140,7 +1333,7 @@ func (s *Service) SaveUserAPIKeys(userID int, apiType, apiKey, apiSecret, passph
 	}
 
 	// 保存API密钥和初始余额
-	err = s.repo.UpdateUserAPIKeys(userID, apiType, apiKey, apiSecret, passphrase, initialBalance)
+	err = s.repo.UpdateUserAPIKeys(userID, apiType, apiKey, sealedSecret, sealedPassphrase, initialBalance)
 	if err != nil {
 		return err
 	}
@@ -1182,23 +1375,18 @@ func (s *Service) GetUserDetail(userID int) (*model.UserDetailResponse, error) {
 		}
 
 		// 🔥 实时计算盈亏
-		currentProfit := 0.0
+		currentProfit := money.Zero
 		currentRate := 0.0
 		currentValue := r.Amount
 
 		if r.IsActive {
-			// 获取总充值金额（shares > 0）
-			totalRechargeAmount, err := s.repo.GetTotalRechargeAmountByCurrency(r.AdminAccountID, r.Currency)
-			if err == nil && totalRechargeAmount > 0 {
-				// 获取当前余额
-				currentBalance, err := s.walletService.GetBalanceByAsset(account, r.Currency)
-				if err == nil {
-					// 计算净值和当前价值
-					netValue := currentBalance / totalRechargeAmount
-					currentValue = r.Amount * netValue
+			// 按份额和净值计算当前价值
+			if account != nil {
+				if value, _, err := s.rechargeValue(account, r); err == nil {
+					currentValue = value
 					currentProfit = currentValue - r.Amount
 					if r.Amount > 0 {
-						currentRate = (currentProfit / r.Amount) * 100
+						currentRate = money.Ratio(currentProfit, r.Amount) * 100
 					}
 				}
 			}
@@ -1247,8 +1435,14 @@ func (s *Service) GetRechargeStatistics() (*model.RechargeStatistics, error) {
 		return nil, err
 	}
 
+	managementFees, err := s.repo.SumManagementFees()
+	if err != nil {
+		return nil, err
+	}
+
 	accountStatistics := make(map[string]*model.AccountStats)
-	totalRecharges := 0.0
+	totalRecharges := money.Zero
+	totalManagementFees := money.Zero
 
 	// 按账户类型汇总
 	for _, account := range accounts {
@@ -1262,22 +1456,41 @@ func (s *Service) GetRechargeStatistics() (*model.RechargeStatistics, error) {
 		if currencyStats, exists := stats[account.ID]; exists {
 			if usdc, ok := currencyStats["USDC"]; ok {
 				accountStats.USDC = usdc
-				accountStats.Total += usdc
-				totalRecharges += usdc
+				accountStats.Total = accountStats.Total.Add(usdc)
+				totalRecharges = totalRecharges.Add(usdc)
 			}
 			if usdt, ok := currencyStats["USDT"]; ok {
 				accountStats.USDT = usdt
-				accountStats.Total += usdt
-				totalRecharges += usdt
+				accountStats.Total = accountStats.Total.Add(usdt)
+				totalRecharges = totalRecharges.Add(usdt)
 			}
 		}
 
+		// 管理费计提
+		rate, err := s.repo.GetFeeRate(model.FeeManagement, account.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+		if rate != nil {
+			accountStats.ManagementFeeRate = rate.Rate
+		}
+		accountStats.ManagementFees = managementFees[account.ID]
+		totalManagementFees = totalManagementFees.Add(accountStats.ManagementFees)
+		accountStats.RecentAccruals, err = s.repo.QueryManagementFeeAccruals(model.ManagementFeeFilter{
+			AdminAccountID: account.ID,
+			Limit:          recentAccrualDays,
+		})
+		if err != nil {
+			return nil, err
+		}
+
 		accountStatistics[account.AccountType] = accountStats
 	}
 
 	return &model.RechargeStatistics{
-		TotalRecharges:    totalRecharges,
-		AccountStatistics: accountStatistics,
+		TotalRecharges:      totalRecharges,
+		TotalManagementFees: totalManagementFees,
+		AccountStatistics:   accountStatistics,
 	}, nil
 }
 
@@ -1302,15 +1515,10 @@ func (s *Service) GetAPIDashboardData(userID int) (*model.APIDashboardData, erro
 		}, nil
 	}
 
-	userAccount := &model.AdminAccount{
-		AccountType: user.APIType,
-		APIKey:      user.APIKey,
-		APISecret:   user.APISecret,
-		Passphrase:  user.APIPassphrase,
-	}
+	userAccount := apiUserAccount(user)
 
 	// 🔥 支持同时获取 USDC 和 USDT
-	balances := make(map[string]float64)
+	balances := make(map[string]money.Decimal)
 
 	switch user.APIType {
 	case "Binance":
@@ -1322,14 +1530,14 @@ func (s *Service) GetAPIDashboardData(userID int) (*model.APIDashboardData, erro
 		if err != nil {
 			balances["USDT"] = 0 // Binance可能没有USDT
 		}
-	case "OKX":
+	case "OKX", "Bybit", "Bitget", "Gate":
 		balances["USDT"], err = s.walletService.GetBalanceByAsset(userAccount, "USDT")
 		if err != nil {
 			return nil, fmt.Errorf("获取USDT余额失败: %v", err)
 		}
 		balances["USDC"], err = s.walletService.GetBalanceByAsset(userAccount, "USDC")
 		if err != nil {
-			balances["USDC"] = 0 // OKX可能没有USDC
+			balances["USDC"] = 0 // 以USDT为主的场所可能没有USDC
 		}
 	default:
 		return nil, errors.New("不支持的API类型")
@@ -1337,11 +1545,11 @@ func (s *Service) GetAPIDashboardData(userID int) (*model.APIDashboardData, erro
 
 	fmt.Printf("[API用户 %d] USDC=$%.2f, USDT=$%.2f\n", userID, balances["USDC"], balances["USDT"])
 
-	totalBalance := balances["USDC"] + balances["USDT"]
+	totalBalance := balances["USDC"].Add(balances["USDT"])
 	totalProfit := totalBalance - user.InitialBalance
 	profitRate := 0.0
 	if user.InitialBalance > 0 {
-		profitRate = (totalProfit / user.InitialBalance) * 100
+		profitRate = money.Ratio(totalProfit, user.InitialBalance) * 100
 	}
 
 	holdDays := int(time.Since(user.CreatedAt).Hours() / 24)
@@ -1349,16 +1557,11 @@ func (s *Service) GetAPIDashboardData(userID int) (*model.APIDashboardData, erro
 		holdDays = 1
 	}
 
-	dailyRate := 0.0
-	monthlyRate := 0.0
-	quarterlyRate := 0.0
-	annualRate := 0.0
-	if holdDays > 0 && profitRate != 0 {
-		dailyRate = profitRate / float64(holdDays)
-		monthlyRate = dailyRate * 30
-		quarterlyRate = dailyRate * 90
-		annualRate = dailyRate * 365
-	}
+	// 与 GetDashboardSummary 一样按持有天数复利折算
+	returns := apiUserReturns(user, totalBalance, holdDays, time.Now())
+	monthlyRate := compoundRate(1+profitRate/100, holdDays, 30)
+	quarterlyRate := compoundRate(1+profitRate/100, holdDays, 90)
+	annualRate := returns.TWRAnnualized
 
 	positions, _ := s.walletService.GetPositions(userAccount, 20)
 	orders, _ := s.walletService.GetOrders(userAccount, 20)
@@ -1375,6 +1578,7 @@ func (s *Service) GetAPIDashboardData(userID int) (*model.APIDashboardData, erro
 			QuarterlyRate:   quarterlyRate,
 			AnnualRate:      annualRate,
 			AvgHoldDays:     holdDays,
+			Returns:         returns,
 			LastUpdateTime:  time.Now().Format("2006-01-02 15:04:05"),
 		},
 		CurrentBalance: totalBalance,
@@ -1391,7 +1595,7 @@ func (s *Service) GetAPIDashboardData(userID int) (*model.APIDashboardData, erro
 }
 
 // UpdateAPIUserInitialBalance 更新API用户的初始余额
-func (s *Service) UpdateAPIUserInitialBalance(userID int, initialBalance float64) error {
+func (s *Service) UpdateAPIUserInitialBalance(userID int, initialBalance money.Decimal) error {
 	return s.repo.UpdateUserInitialBalance(userID, initialBalance)
 }
 
@@ -1412,243 +1616,208 @@ func (s *Service) checkNormalUserMilestones(user *model.User) {
 }
 
 
-// WithdrawRechargePartial 部分撤资（基于本金）
-func (s *Service) WithdrawRechargePartial(rechargeID, userID int, withdrawPrincipal float64) error {
-	// 1. 验证权限
-	recharge, err := s.repo.GetRechargeByID(rechargeID)
+// WithdrawRechargePartial 部分撤资（基于本金），立即按当前净值成交
+func (s *Service) WithdrawRechargePartial(rechargeID, userID int, withdrawPrincipal money.Decimal) error {
+	// 1. 验证权限和撤资本金
+	recharge, withdrawPrincipal, err := s.checkWithdrawal(rechargeID, userID, withdrawPrincipal)
 	if err != nil {
 		return err
 	}
+
+	// 2. 计算当前净值
+	account, err := s.repo.GetAdminAccountByID(recharge.AdminAccountID)
+	if err != nil || account == nil {
+		return errors.New("无法获取账户信息")
+	}
+
+	nav, err := s.strikeNAV(account, recharge.Currency, model.NAVEventWithdrawal)
+	if err != nil {
+		return err
+	}
+
+	// 3. 先按成交净值计提整笔持仓的业绩报酬，再按扣除报酬后的份额定价，报酬和撤资在同一事务中保存
+	fee, rd, err := s.priceRedemptionWithFee(recharge, withdrawPrincipal, nav)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RedeemWithFee(fee, rd); err != nil {
+		return err
+	}
+	if fee != nil {
+		logPerformanceFee(fee)
+	}
+	logRedemption(rd)
+
+	// 赎回的份额已注销，更新账户总份额
+	if _, err := s.refreshAccountShares(recharge.AdminAccountID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// withdrawalRejection 撤资本身不成立（充值不存在、已停用、本金超出等），重试也不会成功
+type withdrawalRejection string
+
+func (e withdrawalRejection) Error() string { return string(e) }
+
+// checkWithdrawal 校验充值记录归属和撤资本金，返回充值记录和按币种精度取整后的本金
+// 校验不通过返回 withdrawalRejection，读库失败等其他错误可以重试。
+func (s *Service) checkWithdrawal(rechargeID, userID int, withdrawPrincipal money.Decimal) (*model.Recharge, money.Decimal, error) {
+	recharge, err := s.repo.GetRechargeByID(rechargeID)
+	if err != nil {
+		return nil, 0, err
+	}
 	if recharge == nil {
-		return errors.New("充值记录不存在")
+		return nil, 0, withdrawalRejection("充值记录不存在")
 	}
 	if recharge.UserID != userID {
-		return errors.New("无权操作此充值记录")
+		return nil, 0, withdrawalRejection("无权操作此充值记录")
 	}
 	if !recharge.IsActive {
-		return errors.New("该充值已停用")
+		return nil, 0, withdrawalRejection("该充值已停用")
 	}
-	
-	// 2. 验证撤资本金
-	if withdrawPrincipal > recharge.Amount {
-		return fmt.Errorf("撤资本金 $%.2f 超过原充值 $%.2f", withdrawPrincipal, recharge.Amount)
+
+	withdrawPrincipal = money.Amount(withdrawPrincipal, recharge.Currency)
+	if withdrawPrincipal <= 0 {
+		return nil, 0, withdrawalRejection("撤资本金必须大于0")
 	}
-	
-	// 3. 计算当前净值
-	account, err := s.repo.GetAdminAccountByID(recharge.AdminAccountID)
-	if err != nil || account == nil {
-		return errors.New("无法获取账户信息")
+	if withdrawPrincipal > recharge.Amount {
+		return nil, 0, withdrawalRejection(fmt.Sprintf("撤资本金 $%s 超过原充值 $%s", withdrawPrincipal, recharge.Amount))
 	}
-	
-	totalRechargeAmount, err := s.repo.GetTotalRechargeAmountByCurrency(recharge.AdminAccountID, recharge.Currency)
-	if err != nil || totalRechargeAmount <= 0 {
-		return errors.New("无法获取总充值金额")
+
+	if recharge.Shares <= 0 {
+		return nil, 0, errors.New("份额数据异常，请联系管理员")
 	}
-	
-	currentBalance, err := s.walletService.GetBalanceByAsset(account, recharge.Currency)
+
+	return recharge, withdrawPrincipal, nil
+}
+
+// priceRedemption 按给定净值为撤资定价
+// 按本金比例赎回份额（向下取整），撤资金额 = 赎回份额 × 净值（向下取整到币种精度）。
+// 金额是定点数，撤资本金等于原充值即为全部撤资，不需要容差。
+func priceRedemption(recharge *model.Recharge, withdrawPrincipal money.Decimal, nav *model.NAVRecord) (*model.Redemption, error) {
+	rd := &model.Redemption{
+		RechargeID:     recharge.ID,
+		UserID:         recharge.UserID,
+		AdminAccountID: recharge.AdminAccountID,
+		Currency:       recharge.Currency,
+		RechargeAt:     recharge.RechargeAt,
+		NAVDate:        nav.RecordDate,
+		NAV:            nav.NAV,
+		Principal:      withdrawPrincipal,
+		Full:           withdrawPrincipal == recharge.Amount,
+		DaysHeld:       int(time.Since(recharge.RechargeAt).Hours() / 24),
+	}
+
+	shares, err := recharge.Shares.MulRatioChecked(withdrawPrincipal, recharge.Amount, money.RoundDown)
 	if err != nil {
-		return errors.New("无法获取余额")
+		return nil, fmt.Errorf("计算赎回份额失败: %v", err)
 	}
-	
-	netValue := currentBalance / totalRechargeAmount
-	
-	// 4. 计算撤资金额（本金 × 净值）
-	withdrawAmount := withdrawPrincipal * netValue
-	withdrawProfit := withdrawAmount - withdrawPrincipal
-	withdrawProfitRate := 0.0
-	if withdrawPrincipal > 0 {
-		withdrawProfitRate = (withdrawProfit / withdrawPrincipal) * 100
+	rd.Shares = shares
+	if rd.Full {
+		rd.Shares = recharge.Shares
 	}
-	
-	remainingPrincipal := recharge.Amount - withdrawPrincipal
-	
-	daysHeld := int(time.Since(recharge.RechargeAt).Hours() / 24)
-	
-	// 5. 判断是全部撤资还是部分撤资
-	isFull := withdrawPrincipal >= recharge.Amount*0.999
-	
-	if isFull {
-		// 全部撤资
-		err = s.repo.RecordWithdrawal(
-			recharge.ID,
-			userID,
-			recharge.Amount,
-			withdrawAmount,
-			withdrawProfit,
-			withdrawProfitRate,
-			daysHeld,
-		)
-		
-		if err != nil {
-			return err
+	if rd.Amount, err = money.RedeemValueChecked(rd.Shares, nav.NAV, recharge.Currency); err != nil {
+		return nil, fmt.Errorf("计算撤资金额失败: %v", err)
+	}
+	rd.Profit = rd.Amount.Sub(withdrawPrincipal)
+	rd.ProfitRate = money.Ratio(rd.Profit, withdrawPrincipal) * 100
+
+	rd.RemainingPrincipal = recharge.Amount.Sub(withdrawPrincipal)
+	rd.RemainingShares = recharge.Shares.Sub(rd.Shares)
+	if !rd.Full {
+		if rd.RemainingValue, err = money.MarkValueChecked(rd.RemainingShares, nav.NAV, recharge.Currency); err != nil {
+			return nil, fmt.Errorf("计算剩余价值失败: %v", err)
 		}
-		
+	}
+	return rd, nil
+}
+
+// logRedemption 打印撤资结果
+func logRedemption(rd *model.Redemption) {
+	if rd.Full {
 		fmt.Printf("✓ 用户%d 全部撤资: 本金$%.2f, 提取$%.2f, 盈亏$%.2f (%.2f%%), 持有%d天\n",
-			userID, recharge.Amount, withdrawAmount, withdrawProfit, withdrawProfitRate, daysHeld)
-		
-	} else {
-		// 部分撤资
-		remainingValue := remainingPrincipal * netValue
-		
-		err = s.repo.RecordPartialWithdrawal(
-			recharge.ID,
-			userID,
-			withdrawPrincipal,
-			withdrawAmount,
-			withdrawProfit,
-			withdrawProfitRate,
-			remainingPrincipal,
-			remainingValue,
-			daysHeld,
-			recharge.RechargeAt,
-			recharge.AdminAccountID,
-			recharge.Currency,
-		)
-		
-		if err != nil {
-			return err
-		}
-		
-		fmt.Printf("✓ 用户%d 部分撤资: 本金$%.2f, 提取$%.2f (盈亏$%.2f), 剩余本金$%.2f (价值$%.2f), 持有%d天\n",
-			userID, withdrawPrincipal, withdrawAmount, withdrawProfit, remainingPrincipal, remainingValue, daysHeld)
+			rd.UserID, rd.Principal, rd.Amount, rd.Profit, rd.ProfitRate, rd.DaysHeld)
+		return
 	}
-	
-	return nil
+	fmt.Printf("✓ 用户%d 部分撤资: 本金$%.2f, 赎回份额%.4f, 提取$%.2f (盈亏$%.2f), 剩余本金$%.2f (价值$%.2f), 持有%d天\n",
+		rd.UserID, rd.Principal, rd.Shares, rd.Amount, rd.Profit, rd.RemainingPrincipal, rd.RemainingValue, rd.DaysHeld)
+}
+
+// PeriodProfit 最近N个周期的实际盈亏汇总
+type PeriodProfit struct {
+	Profit money.Decimal // 周期盈亏之和
+	Rate   float64       // 周期盈亏率平均值
+	Count  int           // 参与统计的快照数
+}
+
+// add 累加一个月度快照
+func (p *PeriodProfit) add(snap map[string]interface{}) {
+	p.Profit = p.Profit.Add(snap["period_profit"].(money.Decimal))
+	p.Rate += snap["period_profit_rate"].(float64)
+	p.Count++
 }
 
 // GetHistoricalProfitFromMilestones 从月度快照获取历史实际盈亏
-func (s *Service) GetHistoricalProfitFromMilestones(userID int) (map[string]map[string]float64, error) {
+// 返回 monthly（近1期）、quarterly（近3期）、yearly（近12期）三个汇总，Rate 为各快照盈亏率的平均值
+func (s *Service) GetHistoricalProfitFromMilestones(userID int) (map[string]*PeriodProfit, error) {
 	user, err := s.repo.GetUserByID(userID)
 	if err != nil {
 		return nil, err
 	}
-	
-	result := map[string]map[string]float64{
-		"monthly":   {"profit": 0, "rate": 0, "count": 0},
-		"quarterly": {"profit": 0, "rate": 0, "count": 0},
-		"yearly":    {"profit": 0, "rate": 0, "count": 0},
+	if user == nil {
+		return nil, errors.New("用户不存在")
 	}
-	
+
+	periods := []struct {
+		key   string
+		limit int
+	}{
+		{"monthly", 1},   // 近30天 = 最近1个快照
+		{"quarterly", 3}, // 近90天 = 最近3个快照
+		{"yearly", 12},   // 近365天 = 最近12个快照
+	}
+
+	result := make(map[string]*PeriodProfit, len(periods))
+	for _, p := range periods {
+		result[p.key] = &PeriodProfit{}
+	}
+
+	// 🔥 API用户使用负数ID作为伪充值ID；普通用户累加所有活跃充值的快照
+	var rechargeIDs []int
 	if user.IsAPIUser {
-		// 🔥 API用户：使用负数ID
-		pseudoRechargeID := -userID
-		
-		// 近30天 = 最近1个快照
-		monthlySnapshots, err := s.repo.GetRecentSnapshots(pseudoRechargeID, 1)
-		if err == nil && len(monthlySnapshots) > 0 {
-			totalProfit := 0.0
-			totalRate := 0.0
-			for _, snap := range monthlySnapshots {
-				totalProfit += snap["period_profit"].(float64)
-				totalRate += snap["period_profit_rate"].(float64)
-			}
-			result["monthly"]["profit"] = totalProfit
-			result["monthly"]["rate"] = totalRate / float64(len(monthlySnapshots))
-			result["monthly"]["count"] = float64(len(monthlySnapshots))
-		}
-		
-		// 近90天 = 最近3个快照
-		quarterlySnapshots, err := s.repo.GetRecentSnapshots(pseudoRechargeID, 3)
-		if err == nil && len(quarterlySnapshots) > 0 {
-			totalProfit := 0.0
-			totalRate := 0.0
-			for _, snap := range quarterlySnapshots {
-				totalProfit += snap["period_profit"].(float64)
-				totalRate += snap["period_profit_rate"].(float64)
-			}
-			result["quarterly"]["profit"] = totalProfit
-			result["quarterly"]["rate"] = totalRate / float64(len(quarterlySnapshots))
-			result["quarterly"]["count"] = float64(len(quarterlySnapshots))
-		}
-		
-		// 近365天 = 最近12个快照
-		yearlySnapshots, err := s.repo.GetRecentSnapshots(pseudoRechargeID, 12)
-		if err == nil && len(yearlySnapshots) > 0 {
-			totalProfit := 0.0
-			totalRate := 0.0
-			for _, snap := range yearlySnapshots {
-				totalProfit += snap["period_profit"].(float64)
-				totalRate += snap["period_profit_rate"].(float64)
-			}
-			result["yearly"]["profit"] = totalProfit
-			result["yearly"]["rate"] = totalRate / float64(len(yearlySnapshots))
-			result["yearly"]["count"] = float64(len(yearlySnapshots))
-		}
-		
+		rechargeIDs = append(rechargeIDs, -userID)
 	} else {
-		// 🔥 普通用户：累加所有充值的快照
 		recharges, err := s.repo.GetRechargesByUserID(userID)
 		if err != nil {
 			return nil, err
 		}
-		
-		monthlyTotal := 0.0
-		monthlyRateSum := 0.0
-		monthlyCount := 0
-		
-		quarterlyTotal := 0.0
-		quarterlyRateSum := 0.0
-		quarterlyCount := 0
-		
-		yearlyTotal := 0.0
-		yearlyRateSum := 0.0
-		yearlyCount := 0
-		
 		for _, r := range recharges {
-			if !r.IsActive {
-				continue
+			if r.IsActive {
+				rechargeIDs = append(rechargeIDs, r.ID)
 			}
-			
-			// 近30天 = 最近1个快照
-			monthlySnapshots, err := s.repo.GetRecentSnapshots(r.ID, 1)
-			if err == nil && len(monthlySnapshots) > 0 {
-				for _, snap := range monthlySnapshots {
-					monthlyTotal += snap["period_profit"].(float64)
-					monthlyRateSum += snap["period_profit_rate"].(float64)
-					monthlyCount++
-				}
-			}
-			
-			// 近90天 = 最近3个快照
-			quarterlySnapshots, err := s.repo.GetRecentSnapshots(r.ID, 3)
-			if err == nil && len(quarterlySnapshots) > 0 {
-				for _, snap := range quarterlySnapshots {
-					quarterlyTotal += snap["period_profit"].(float64)
-					quarterlyRateSum += snap["period_profit_rate"].(float64)
-					quarterlyCount++
-				}
+		}
+	}
+
+	for _, rechargeID := range rechargeIDs {
+		for _, p := range periods {
+			snapshots, err := s.repo.GetRecentSnapshots(rechargeID, p.limit)
+			if err != nil {
+				continue
 			}
-			
-			// 近365天 = 最近12个快照
-			yearlySnapshots, err := s.repo.GetRecentSnapshots(r.ID, 12)
-			if err == nil && len(yearlySnapshots) > 0 {
-				for _, snap := range yearlySnapshots {
-					yearlyTotal += snap["period_profit"].(float64)
-					yearlyRateSum += snap["period_profit_rate"].(float64)
-					yearlyCount++
-				}
+			for _, snap := range snapshots {
+				result[p.key].add(snap)
 			}
 		}
-		
-		if monthlyCount > 0 {
-			result["monthly"]["profit"] = monthlyTotal
-			result["monthly"]["rate"] = monthlyRateSum / float64(monthlyCount)
-			result["monthly"]["count"] = float64(monthlyCount)
-		}
-		
-		if quarterlyCount > 0 {
-			result["quarterly"]["profit"] = quarterlyTotal
-			result["quarterly"]["rate"] = quarterlyRateSum / float64(quarterlyCount)
-			result["quarterly"]["count"] = float64(quarterlyCount)
-		}
-		
-		if yearlyCount > 0 {
-			result["yearly"]["profit"] = yearlyTotal
-			result["yearly"]["rate"] = yearlyRateSum / float64(yearlyCount)
-			result["yearly"]["count"] = float64(yearlyCount)
+	}
+
+	for _, p := range result {
+		if p.Count > 0 {
+			p.Rate = p.Rate / float64(p.Count)
 		}
 	}
-	
+
 	return result, nil
 }
 
@@ -1675,45 +1844,33 @@ func (s *Service) WithdrawRecharge(rechargeID, userID int) error {
 		return errors.New("无法获取账户信息")
 	}
 
-	totalRechargeAmount, err := s.repo.GetTotalRechargeAmountByCurrency(recharge.AdminAccountID, recharge.Currency)
-	if err != nil || totalRechargeAmount <= 0 {
-		return errors.New("无法获取总充值金额")
+	if recharge.Shares <= 0 {
+		return errors.New("份额数据异常，请联系管理员")
 	}
 
-	currentBalance, err := s.walletService.GetBalanceByAsset(account, recharge.Currency)
+	// 撤资金额 = 持有份额 × 当前净值（先扣除业绩报酬，报酬和撤资在同一事务中保存）
+	nav, err := s.strikeNAV(account, recharge.Currency, model.NAVEventWithdrawal)
 	if err != nil {
-		return errors.New("无法获取余额")
+		return err
 	}
-
-	netValue := currentBalance / totalRechargeAmount
-	withdrawnAmount := recharge.Amount * netValue
-	finalProfit := withdrawnAmount - recharge.Amount
-	finalProfitRate := 0.0
-	if recharge.Amount > 0 {
-		finalProfitRate = (finalProfit / recharge.Amount) * 100
+	fee, rd, err := s.priceRedemptionWithFee(recharge, recharge.Amount, nav)
+	if err != nil {
+		return err
 	}
 
-	daysHeld := int(time.Since(recharge.RechargeAt).Hours() / 24)
-
-	
-
 	// 4. 记录撤资并停用充值
-	err = s.repo.RecordWithdrawal(
-		recharge.ID,
-		userID,
-		recharge.Amount,
-		withdrawnAmount,
-		finalProfit,
-		finalProfitRate,
-		daysHeld,
-	)
-
-	if err != nil {
+	if err := s.repo.RedeemWithFee(fee, rd); err != nil {
 		return err
 	}
+	if fee != nil {
+		logPerformanceFee(fee)
+	}
+	logRedemption(rd)
 
-	fmt.Printf("✓ 用户%d 撤资成功: 充值$%.2f, 提取$%.2f, 盈亏$%.2f (%.2f%%), 持有%d天\n",
-		userID, recharge.Amount, withdrawnAmount, finalProfit, finalProfitRate, daysHeld)
+	// 赎回的份额已注销，更新账户总份额
+	if _, err := s.refreshAccountShares(recharge.AdminAccountID); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1723,6 +1880,19 @@ func (s *Service) GetWithdrawals(userID int) ([]*model.Withdrawal, error) {
 	return s.repo.GetWithdrawals(userID)
 }
 
+// GetWithdrawalHistory 按条件查询撤资记录及汇总
+func (s *Service) GetWithdrawalHistory(filter model.WithdrawalFilter) ([]*model.Withdrawal, *model.WithdrawalTotals, error) {
+	withdrawals, err := s.repo.QueryWithdrawals(filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取撤资记录失败: %v", err)
+	}
+	totals, err := s.repo.SumWithdrawals(filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("汇总撤资记录失败: %v", err)
+	}
+	return withdrawals, totals, nil
+}
+
 // CheckAndRecordMonthlySnapshots 检查并记录所有充值的月度快照
 func (s *Service) CheckAndRecordMonthlySnapshots() error {
 	users, err := s.repo.GetAllUsersBasic()
@@ -1818,20 +1988,17 @@ func (s *Service) recordMonthlySnapshot(recharge *model.Recharge, periodNumber i
 		return err
 	}
 	
-	totalRechargeAmount, err := s.repo.GetTotalRechargeAmountByCurrency(recharge.AdminAccountID, recharge.Currency)
-	if err != nil || totalRechargeAmount <= 0 {
-		return err
+	if account == nil {
+		return errors.New("Admin账户不存在")
 	}
 	
-	currentBalance, err := s.walletService.GetBalanceByAsset(account, recharge.Currency)
+	endValue, nav, err := s.rechargeValue(account, recharge)
 	if err != nil {
 		return err
 	}
+	netValue := nav.NAV
 	
-	netValue := currentBalance / totalRechargeAmount
-	endValue := recharge.Amount * netValue
-	
-	var startValue float64
+	var startValue money.Decimal
 	if periodNumber == 1 {
 		startValue = recharge.Amount
 	} else {
@@ -1839,13 +2006,13 @@ func (s *Service) recordMonthlySnapshot(recharge *model.Recharge, periodNumber i
 		if err != nil {
 			return err
 		}
-		startValue = prevSnapshot["end_value"].(float64)
+		startValue = prevSnapshot["end_value"].(money.Decimal)
 	}
 	
 	periodProfit := endValue - startValue
 	periodProfitRate := 0.0
 	if startValue > 0 {
-		periodProfitRate = (periodProfit / startValue) * 100
+		periodProfitRate = money.Ratio(periodProfit, startValue) * 100
 	}
 	
 	err = s.repo.SaveMonthlySnapshot(
@@ -1872,17 +2039,12 @@ func (s *Service) recordMonthlySnapshot(recharge *model.Recharge, periodNumber i
 
 // recordAPIUserMonthlySnapshot 记录API用户的月度快照
 func (s *Service) recordAPIUserMonthlySnapshot(user *model.User, pseudoRecharge *model.Recharge, periodNumber int) error {
-	userAccount := &model.AdminAccount{
-		AccountType: user.APIType,
-		APIKey:      user.APIKey,
-		APISecret:   user.APISecret,
-		Passphrase:  user.APIPassphrase,
-	}
+	userAccount := apiUserAccount(user)
 	
 	var currency string
 	if user.APIType == "Binance" {
 		currency = "USDC"
-	} else if user.APIType == "OKX" {
+	} else if user.APIType == "OKX" || user.APIType == "Bybit" || user.APIType == "Bitget" || user.APIType == "Gate" {
 		currency = "USDT"
 	} else {
 		return fmt.Errorf("不支持的API类型")
@@ -1895,7 +2057,7 @@ func (s *Service) recordAPIUserMonthlySnapshot(user *model.User, pseudoRecharge
 	
 	endValue := currentBalance
 	
-	var startValue float64
+	var startValue money.Decimal
 	if periodNumber == 1 {
 		startValue = user.InitialBalance
 	} else {
@@ -1903,16 +2065,19 @@ func (s *Service) recordAPIUserMonthlySnapshot(user *model.User, pseudoRecharge
 		if err != nil {
 			return err
 		}
-		startValue = prevSnapshot["end_value"].(float64)
+		startValue = prevSnapshot["end_value"].(money.Decimal)
 	}
 	
 	periodProfit := endValue - startValue
 	periodProfitRate := 0.0
 	if startValue > 0 {
-		periodProfitRate = (periodProfit / startValue) * 100
+		periodProfitRate = money.Ratio(periodProfit, startValue) * 100
 	}
 	
-	netValue := currentBalance / user.InitialBalance
+	netValue, err := money.NAVChecked(currentBalance, user.InitialBalance)
+	if err != nil {
+		return fmt.Errorf("计算净值失败: %v", err)
+	}
 	
 	err = s.repo.SaveMonthlySnapshot(
 		pseudoRecharge.ID,
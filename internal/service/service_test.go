@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/repository"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newTestRepository 每个测试一个独立的内存数据库（已执行迁移）
+func newTestRepository(t *testing.T) *repository.Repository {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	repo, err := repository.NewRepository(fmt.Sprintf("file:%s?mode=memory&cache=shared", name), "admin-password")
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// newTestService 使用内存数据库和指定的 WalletService 创建服务
+func newTestService(t *testing.T, ws *WalletService) *Service {
+	t.Helper()
+	if ws == nil {
+		ws = NewWalletService()
+	}
+	return NewServiceWithWallet(newTestRepository(t), ws)
+}
+
+// testPool Binance USDT 资金池：管理员入金 1000（净值1.0），投资人申购 500
+type testPool struct {
+	s          *Service
+	adapter    *fakeAdapter
+	account    *model.AdminAccount
+	userID     int
+	rechargeID int
+}
+
+func newTestPool(t *testing.T) *testPool {
+	t.Helper()
+	adapter := newFakeAdapter(0, 0)
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"Binance": adapter}))
+	s.SetPriceSource(nil)
+
+	account, err := s.repo.GetAdminAccountByType("Binance")
+	if err != nil || account == nil {
+		t.Fatalf("GetAdminAccountByType: %v, %v", account, err)
+	}
+	if err := s.AdminDepositToExchange(account.ID, money.FromFloat(1000), "USDT"); err != nil {
+		t.Fatalf("AdminDepositToExchange: %v", err)
+	}
+	adapter.balances["USDT"] = money.FromFloat(1000)
+
+	userID, err := s.AdminCreateUser("13800000000")
+	if err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+	rechargeID, err := s.AdminRecharge(int(userID), account.ID, money.FromFloat(500), "USDT")
+	if err != nil {
+		t.Fatalf("AdminRecharge: %v", err)
+	}
+	return &testPool{s: s, adapter: adapter, account: account, userID: int(userID), rechargeID: int(rechargeID)}
+}
+
+// setBalance 交易所上的 USDT 余额
+func (p *testPool) setBalance(v string) {
+	p.adapter.balances["USDT"] = money.MustParse(v)
+}
+
+func (p *testPool) recharge(t *testing.T) *model.Recharge {
+	t.Helper()
+	recharge, err := p.s.repo.GetRechargeByID(p.rechargeID)
+	if err != nil || recharge == nil {
+		t.Fatalf("GetRechargeByID: %v, %v", recharge, err)
+	}
+	return recharge
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultSessionTTL 会话默认有效期
+const defaultSessionTTL = 12 * time.Hour
+
+// SetSessionTTL 设置会话有效期
+func (s *Service) SetSessionTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.sessionTTL = ttl
+	}
+}
+
+// hashSessionToken 数据库中只保存令牌的哈希，泄露数据库也拿不到可用的令牌
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession 为已通过密码校验的用户签发会话令牌
+// 令牌是32字节随机数，只在这里返回一次。
+func (s *Service) CreateSession(user *model.User) (string, time.Time, error) {
+	if !user.IsActive {
+		return "", time.Time{}, errors.New("账户已停用")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("生成令牌失败: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	expiresAt := time.Now().Add(s.sessionTTL)
+
+	if _, err := s.repo.CreateSession(user.ID, hashSessionToken(token), expiresAt); err != nil {
+		return "", time.Time{}, fmt.Errorf("保存会话失败: %v", err)
+	}
+
+	// 顺带清理过期会话，失败不影响登录
+	if err := s.repo.DeleteExpiredSessions(); err != nil {
+		fmt.Printf("⚠️  清理过期会话失败: %v\n", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// Authenticate 校验会话令牌，返回令牌对应的用户和会话
+func (s *Service) Authenticate(token string) (*model.User, *model.Session, error) {
+	if token == "" {
+		return nil, nil, errors.New("缺少令牌")
+	}
+
+	session, err := s.repo.GetSessionByTokenHash(hashSessionToken(token))
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取会话失败: %v", err)
+	}
+	if session == nil {
+		return nil, nil, errors.New("令牌无效")
+	}
+	if session.Revoked {
+		return nil, nil, errors.New("会话已注销")
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, nil, errors.New("会话已过期")
+	}
+
+	user, err := s.repo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, errors.New("用户不存在")
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New("账户已停用")
+	}
+
+	return user, session, nil
+}
+
+// RefreshSession 用仍然有效的令牌换一个新令牌，旧令牌立即失效
+func (s *Service) RefreshSession(token string) (string, time.Time, error) {
+	user, session, err := s.Authenticate(token)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if err := s.repo.RevokeSession(session.ID); err != nil {
+		return "", time.Time{}, fmt.Errorf("注销旧会话失败: %v", err)
+	}
+
+	return s.CreateSession(user)
+}
+
+// Logout 注销令牌对应的会话
+func (s *Service) Logout(token string) error {
+	session, err := s.repo.GetSessionByTokenHash(hashSessionToken(token))
+	if err != nil {
+		return fmt.Errorf("读取会话失败: %v", err)
+	}
+	if session == nil {
+		return errors.New("令牌无效")
+	}
+	return s.repo.RevokeSession(session.ID)
+}
+
+// RevokeUserSessions 注销用户的所有会话（停用用户时调用）
+func (s *Service) RevokeUserSessions(userID int) error {
+	count, err := s.repo.RevokeUserSessions(userID)
+	if err != nil {
+		return fmt.Errorf("注销会话失败: %v", err)
+	}
+	if count > 0 {
+		fmt.Printf("🔒 用户 %d 的 %d 个会话已注销\n", userID, count)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSessionUser(t *testing.T, s *Service) *model.User {
+	t.Helper()
+	id, err := s.AdminCreateUser("13800000000")
+	if err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+	user, _ := s.repo.GetUserByID(int(id))
+	return user
+}
+
+// TestSessionTokenStoredAsHash 数据库只保存令牌的 SHA-256，明文令牌查不到会话
+func TestSessionTokenStoredAsHash(t *testing.T) {
+	s := newTestService(t, nil)
+	user := newSessionUser(t, s)
+
+	token, expiresAt, err := s.CreateSession(user)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if ttl := time.Until(expiresAt); ttl > defaultSessionTTL || ttl < defaultSessionTTL-time.Minute {
+		t.Errorf("有效期 %v, want %v", ttl, defaultSessionTTL)
+	}
+
+	if session, _ := s.repo.GetSessionByTokenHash(token); session != nil {
+		t.Error("数据库中保存了明文令牌")
+	}
+	session, err := s.repo.GetSessionByTokenHash(hashSessionToken(token))
+	if err != nil || session == nil || session.UserID != user.ID {
+		t.Fatalf("按哈希查询会话 = %+v, %v", session, err)
+	}
+
+	got, _, err := s.Authenticate(token)
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("Authenticate = %+v, %v", got, err)
+	}
+	for _, bad := range []string{"", token + "x", hashSessionToken(token)} {
+		if _, _, err := s.Authenticate(bad); err == nil {
+			t.Errorf("Authenticate(%q) 通过了校验", bad)
+		}
+	}
+}
+
+// TestSessionExpiry 过期的令牌被拒绝，签发新令牌时清理过期会话
+func TestSessionExpiry(t *testing.T) {
+	s := newTestService(t, nil)
+	user := newSessionUser(t, s)
+
+	if _, err := s.repo.CreateSession(user.ID, hashSessionToken("expired-token"), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Authenticate("expired-token"); err == nil || !strings.Contains(err.Error(), "过期") {
+		t.Errorf("过期令牌 err = %v, want 会话已过期", err)
+	}
+
+	s.SetSessionTTL(time.Hour)
+	_, expiresAt, err := s.CreateSession(user)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if ttl := time.Until(expiresAt); ttl > time.Hour || ttl < 59*time.Minute {
+		t.Errorf("SetSessionTTL 后有效期 %v, want 1h", ttl)
+	}
+	if session, _ := s.repo.GetSessionByTokenHash(hashSessionToken("expired-token")); session != nil {
+		t.Error("过期会话没有被清理")
+	}
+}
+
+// TestSessionRefreshAndRevoke 刷新后旧令牌失效；注销、停用用户后令牌失效
+func TestSessionRefreshAndRevoke(t *testing.T) {
+	s := newTestService(t, nil)
+	user := newSessionUser(t, s)
+
+	old, _, _ := s.CreateSession(user)
+	fresh, _, err := s.RefreshSession(old)
+	if err != nil || fresh == old {
+		t.Fatalf("RefreshSession = %q, %v", fresh, err)
+	}
+	if _, _, err := s.Authenticate(old); err == nil || !strings.Contains(err.Error(), "注销") {
+		t.Errorf("刷新后旧令牌 err = %v, want 会话已注销", err)
+	}
+	if _, _, err := s.RefreshSession(old); err == nil {
+		t.Error("旧令牌还能再次刷新")
+	}
+
+	if err := s.Logout(fresh); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, _, err := s.Authenticate(fresh); err == nil {
+		t.Error("注销后令牌仍然有效")
+	}
+
+	// 停用用户注销其全部会话，且不能再签发新令牌
+	a, _, _ := s.CreateSession(user)
+	b, _, _ := s.CreateSession(user)
+	if err := s.UpdateUserStatus(user.ID, false); err != nil {
+		t.Fatalf("UpdateUserStatus: %v", err)
+	}
+	for _, token := range []string{a, b} {
+		if _, _, err := s.Authenticate(token); err == nil {
+			t.Error("停用用户后令牌仍然有效")
+		}
+	}
+	user, _ = s.repo.GetUserByID(user.ID)
+	if _, _, err := s.CreateSession(user); err == nil {
+		t.Error("停用的用户签发了新令牌")
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+// solanaRPCProvider 通过 Solana JSON-RPC 的 getTokenAccountsByOwner 查询SPL代币余额
+// 同一个Mint可能有多个代币账户（ATA之外手动创建的），余额取合计。
+type solanaRPCProvider struct {
+	httpClient *http.Client
+	rpcURL     string
+}
+
+// TokenBalances 实现 ChainBalanceProvider
+func (p *solanaRPCProvider) TokenBalances(address string, tokens []model.ChainToken) (map[string]money.Decimal, error) {
+	balances := make(map[string]money.Decimal, len(tokens))
+	for _, token := range tokens {
+		var result struct {
+			Value []struct {
+				Account struct {
+					Data struct {
+						Parsed struct {
+							Info struct {
+								TokenAmount struct {
+									Amount   string `json:"amount"` // 最小单位
+									Decimals int    `json:"decimals"`
+								} `json:"tokenAmount"`
+							} `json:"info"`
+						} `json:"parsed"`
+					} `json:"data"`
+				} `json:"account"`
+			} `json:"value"`
+		}
+		params := []interface{}{
+			address,
+			map[string]string{"mint": token.Contract},
+			map[string]string{"encoding": "jsonParsed"},
+		}
+		if err := jsonRPCCall(p.httpClient, p.rpcURL, "getTokenAccountsByOwner", params, &result); err != nil {
+			return nil, fmt.Errorf("%s: %v", token.Symbol, err)
+		}
+
+		total := new(big.Int)
+		for _, acc := range result.Value {
+			raw, ok := new(big.Int).SetString(acc.Account.Data.Parsed.Info.TokenAmount.Amount, 10)
+			if !ok {
+				return nil, fmt.Errorf("%s: 无法解析的余额 %q", token.Symbol, acc.Account.Data.Parsed.Info.TokenAmount.Amount)
+			}
+			total.Add(total, raw)
+		}
+		balance, err := money.FromRawChecked(total, token.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("%s: 余额 %s 超出范围", token.Symbol, total)
+		}
+		balances[token.Symbol] = balance
+	}
+	return balances, nil
+}
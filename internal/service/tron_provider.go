@@ -0,0 +1,74 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+)
+
+// tronGridProvider 通过 TronGrid 风格的 REST 接口查询TRC20余额
+// GET /v1/accounts/{address} 一次返回账户持有的全部TRC20代币。
+type tronGridProvider struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// TokenBalances 实现 ChainBalanceProvider
+func (p *tronGridProvider) TokenBalances(address string, tokens []model.ChainToken) (map[string]money.Decimal, error) {
+	resp, err := p.httpClient.Get(p.baseURL + "/v1/accounts/" + address)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("TronGrid返回错误 [%d]: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+		Data    []struct {
+			TRC20 []map[string]string `json:"trc20"` // 每项为 {合约地址: 余额最小单位}
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("TronGrid错误: %s", result.Error)
+	}
+
+	// 未激活的地址 data 为空，余额按0处理
+	held := make(map[string]string)
+	for _, acc := range result.Data {
+		for _, item := range acc.TRC20 {
+			for contract, raw := range item {
+				held[contract] = raw
+			}
+		}
+	}
+
+	balances := make(map[string]money.Decimal, len(tokens))
+	for _, token := range tokens {
+		raw, ok := new(big.Int).SetString(held[token.Contract], 10)
+		if !ok {
+			balances[token.Symbol] = money.Zero
+			continue
+		}
+		balance, err := money.FromRawChecked(raw, token.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("%s: 余额 %s 超出范围", token.Symbol, raw)
+		}
+		balances[token.Symbol] = balance
+	}
+	return balances, nil
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/totp"
+	"crypto-final/internal/vault"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// totpIssuer 验证器应用中显示的名称
+const totpIssuer = "财务管理系统"
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// 验证码、恢复码连续错误 maxSecondFactorFailures 次后锁定 secondFactorLockout
+const (
+	maxSecondFactorFailures = 5
+	secondFactorLockout     = 15 * time.Minute
+)
+
+var (
+	// ErrTwoFactorRequired 密码正确，但还需要提交验证码或恢复码
+	ErrTwoFactorRequired = errors.New("需要两步验证码")
+	// ErrTwoFactorLocked 连续错误次数过多，暂时不能验证
+	ErrTwoFactorLocked = errors.New("两步验证错误次数过多，已暂时锁定")
+
+	errWrongTOTP         = errors.New("验证码错误")
+	errReusedTOTP        = errors.New("验证码已使用，请等待下一个")
+	errWrongRecoveryCode = errors.New("恢复码无效或已使用")
+)
+
+// SetRequireStaff2FA 设置管理后台角色是否必须启用两步验证（默认必须）
+func (s *Service) SetRequireStaff2FA(required bool) {
+	s.requireStaff2FA = required
+}
+
+// TwoFactorSetupRequired 用户属于必须启用两步验证的角色，但还没有启用
+func (s *Service) TwoFactorSetupRequired(user *model.User) bool {
+	return s.requireStaff2FA && model.IsStaffRole(user.Role) && !user.TOTPEnabled
+}
+
+// VerifySecondFactor 登录时的第二步校验，没有启用两步验证的用户直接通过
+func (s *Service) VerifySecondFactor(user *model.User, code, recoveryCode string) error {
+	if !user.TOTPEnabled {
+		return nil
+	}
+	if recoveryCode == "" && code == "" {
+		return ErrTwoFactorRequired
+	}
+	return s.throttleSecondFactor(user.ID, func() error {
+		if recoveryCode != "" {
+			return s.useRecoveryCode(user, recoveryCode)
+		}
+		return s.verifyTOTP(user.ID, code)
+	})
+}
+
+// throttleSecondFactor 限制验证码、恢复码的尝试次数
+// 锁定期间不再校验；验证码或恢复码错误时计数，连续错误达到上限后锁定，通过后清零。
+func (s *Service) throttleSecondFactor(userID int, verify func() error) error {
+	now := time.Now()
+	lockedUntil, err := s.repo.GetSecondFactorLockout(userID)
+	if err != nil {
+		return fmt.Errorf("读取两步验证状态失败: %v", err)
+	}
+	if now.Unix() < lockedUntil {
+		return fmt.Errorf("%w，请在 %s 后重试", ErrTwoFactorLocked, time.Unix(lockedUntil, 0).Format("15:04:05"))
+	}
+
+	err = verify()
+	if err == nil {
+		if err := s.repo.ResetSecondFactorFailures(userID); err != nil {
+			fmt.Printf("⚠️  清零用户 %d 两步验证失败次数失败: %v\n", userID, err)
+		}
+		return nil
+	}
+	if !errors.Is(err, errWrongTOTP) && !errors.Is(err, errReusedTOTP) && !errors.Is(err, errWrongRecoveryCode) {
+		return err
+	}
+
+	lockUntil := now.Add(secondFactorLockout).Unix()
+	lockedUntil, recordErr := s.repo.RecordSecondFactorFailure(userID, maxSecondFactorFailures, lockUntil)
+	if recordErr != nil {
+		return fmt.Errorf("记录两步验证失败次数失败: %v", recordErr)
+	}
+	if lockedUntil == lockUntil {
+		fmt.Printf("🔒 用户 %d 两步验证连续错误 %d 次，锁定到 %s\n",
+			userID, maxSecondFactorFailures, time.Unix(lockedUntil, 0).Format("15:04:05"))
+		return fmt.Errorf("%w，请在 %s 后重试", ErrTwoFactorLocked, time.Unix(lockedUntil, 0).Format("15:04:05"))
+	}
+	return err
+}
+
+// totpSecretContext 用户 TOTP 密钥密文的存储位置
+func totpSecretContext(userID int) string {
+	return vault.Context("users", "totp_secret", userID)
+}
+
+// loadTOTPSecret 读取并解密用户的 TOTP 密钥，返回密钥和是否已启用
+func (s *Service) loadTOTPSecret(userID int) (string, bool, error) {
+	sealed, enabled, _, err := s.repo.GetTOTPState(userID)
+	if err != nil {
+		return "", false, fmt.Errorf("读取两步验证状态失败: %v", err)
+	}
+	secret, err := s.walletService.openSecret(sealed, totpSecretContext(userID))
+	if err != nil {
+		return "", false, fmt.Errorf("解密 TOTP 密钥失败: %v", err)
+	}
+	return secret, enabled, nil
+}
+
+// verifyTOTP 校验验证码，同一时间步的验证码只能用一次
+func (s *Service) verifyTOTP(userID int, code string) error {
+	secret, _, err := s.loadTOTPSecret(userID)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return errors.New("未绑定验证器")
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return errWrongTOTP
+	}
+	fresh, err := s.repo.AdvanceTOTPStep(userID, step)
+	if err != nil {
+		return fmt.Errorf("记录验证码失败: %v", err)
+	}
+	if !fresh {
+		return errReusedTOTP
+	}
+	return nil
+}
+
+// useRecoveryCode 使用恢复码登录
+func (s *Service) useRecoveryCode(user *model.User, code string) error {
+	ok, err := s.repo.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("校验恢复码失败: %v", err)
+	}
+	if !ok {
+		return errWrongRecoveryCode
+	}
+
+	remaining, _ := s.repo.CountUnusedRecoveryCodes(user.ID)
+	fmt.Printf("🔑 用户 %d 使用了恢复码，剩余 %d 个\n", user.ID, remaining)
+	return nil
+}
+
+// BeginTOTPSetup 生成待绑定的密钥，返回密钥和 otpauth:// 地址（用于生成二维码）
+// 绑定在 EnableTOTP 校验第一个验证码后才生效。
+func (s *Service) BeginTOTPSetup(user *model.User) (string, string, error) {
+	if user.TOTPEnabled {
+		return "", "", errors.New("两步验证已启用")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := s.walletService.SealSecret(secret, totpSecretContext(user.ID))
+	if err != nil {
+		return "", "", fmt.Errorf("加密密钥失败: %v", err)
+	}
+	ok, err := s.repo.SetPendingTOTPSecret(user.ID, sealed)
+	if err != nil {
+		return "", "", fmt.Errorf("保存密钥失败: %v", err)
+	}
+	if !ok {
+		return "", "", errors.New("两步验证已启用")
+	}
+
+	account := user.Phone
+	if account == "" {
+		account = user.Username
+	}
+	return secret, totp.ProvisioningURI(totpIssuer, account, secret), nil
+}
+
+// EnableTOTP 用验证器上的第一个验证码确认绑定，返回恢复码（只显示这一次）
+func (s *Service) EnableTOTP(user *model.User, code string) ([]string, error) {
+	secret, enabled, err := s.loadTOTPSecret(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("两步验证已启用")
+	}
+	if secret == "" {
+		return nil, errors.New("请先获取绑定密钥")
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(user.ID, step, hashes); err != nil {
+		return nil, fmt.Errorf("启用两步验证失败: %v", err)
+	}
+
+	fmt.Printf("🔐 用户 %d 已启用两步验证\n", user.ID)
+	return codes, nil
+}
+
+// DisableTOTP 关闭两步验证（需要当前验证码或恢复码）
+// 必须启用两步验证的角色不能关闭。
+func (s *Service) DisableTOTP(user *model.User, code, recoveryCode string) error {
+	if !user.TOTPEnabled {
+		return errors.New("两步验证未启用")
+	}
+	if s.requireStaff2FA && model.IsStaffRole(user.Role) {
+		return errors.New("管理后台账户必须启用两步验证")
+	}
+	if code == "" && recoveryCode == "" {
+		return ErrTwoFactorRequired
+	}
+	if err := s.VerifySecondFactor(user, code, recoveryCode); err != nil {
+		return err
+	}
+
+	if err := s.repo.DisableTOTP(user.ID); err != nil {
+		return fmt.Errorf("关闭两步验证失败: %v", err)
+	}
+
+	fmt.Printf("🔓 用户 %d 已关闭两步验证\n", user.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（需要当前验证码），旧的全部作废
+func (s *Service) RegenerateRecoveryCodes(user *model.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, errors.New("两步验证未启用")
+	}
+	if code == "" {
+		return nil, ErrTwoFactorRequired
+	}
+	if err := s.throttleSecondFactor(user.ID, func() error { return s.verifyTOTP(user.ID, code) }); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, fmt.Errorf("保存恢复码失败: %v", err)
+	}
+	return codes, nil
+}
+
+// TwoFactorStatus 两步验证状态：是否启用、剩余恢复码数量
+func (s *Service) TwoFactorStatus(user *model.User) (bool, int, error) {
+	if !user.TOTPEnabled {
+		return false, 0, nil
+	}
+	remaining, err := s.repo.CountUnusedRecoveryCodes(user.ID)
+	if err != nil {
+		return true, 0, err
+	}
+	return true, remaining, nil
+}
+
+// generateRecoveryCodes 生成一组恢复码（xxxxx-xxxxx），返回明文和哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("生成恢复码失败: %v", err)
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 恢复码只存哈希，比较前去掉分隔符、统一小写
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
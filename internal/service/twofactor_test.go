@@ -0,0 +1,165 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/totp"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// twoFactorUser 已启用两步验证的用户，返回密钥、启用时的时间步和恢复码
+type twoFactorUser struct {
+	s             *Service
+	user          *model.User
+	secret        string
+	step          int64
+	recoveryCodes []string
+}
+
+func newTwoFactorUser(t *testing.T) *twoFactorUser {
+	t.Helper()
+	s := newTestService(t, newFakeWalletService(t, nil))
+	id, err := s.AdminCreateUser("13800000000")
+	if err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+	user, _ := s.repo.GetUserByID(int(id))
+
+	if err := s.VerifySecondFactor(user, "", ""); err != nil {
+		t.Errorf("未启用两步验证时 err = %v, want 直接通过", err)
+	}
+
+	secret, uri, err := s.BeginTOTPSetup(user)
+	if err != nil || !strings.Contains(uri, secret) {
+		t.Fatalf("BeginTOTPSetup = %s, %v", uri, err)
+	}
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(secret, step)
+	codes, err := s.EnableTOTP(user, code)
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("EnableTOTP = %v, %v", codes, err)
+	}
+	user, _ = s.repo.GetUserByID(int(id))
+	if !user.TOTPEnabled {
+		t.Fatal("启用后 TOTPEnabled = false")
+	}
+	return &twoFactorUser{s: s, user: user, secret: secret, step: step, recoveryCodes: codes}
+}
+
+func (u *twoFactorUser) code(step int64) string {
+	code, _ := totp.Code(u.secret, step)
+	return code
+}
+
+// wrongCode 当前窗口内都不匹配的验证码
+func (u *twoFactorUser) wrongCode() string {
+	current := totp.Step(time.Now())
+	valid := map[string]bool{}
+	for i := int64(-totp.Skew); i <= totp.Skew; i++ {
+		valid[u.code(current+i)] = true
+	}
+	for n := 0; ; n++ {
+		code := strings.Repeat("0", totp.Digits-1) + string(rune('0'+n))
+		if !valid[code] {
+			return code
+		}
+	}
+}
+
+// TestTOTPWindowAndReplay 接受相邻时间步的验证码，已用过（不大于上次）的时间步被拒绝
+func TestTOTPWindowAndReplay(t *testing.T) {
+	u := newTwoFactorUser(t)
+
+	if err := u.s.VerifySecondFactor(u.user, "", ""); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Errorf("没有提交验证码 err = %v, want ErrTwoFactorRequired", err)
+	}
+	// 启用时用掉了当前时间步
+	if err := u.s.VerifySecondFactor(u.user, u.code(u.step), ""); !errors.Is(err, errReusedTOTP) {
+		t.Errorf("重放启用时的验证码 err = %v, want errReusedTOTP", err)
+	}
+	// 手机时钟快一步
+	if err := u.s.VerifySecondFactor(u.user, u.code(u.step+1), ""); err != nil {
+		t.Fatalf("下一个时间步的验证码: %v", err)
+	}
+	if err := u.s.VerifySecondFactor(u.user, u.code(u.step+1), ""); !errors.Is(err, errReusedTOTP) {
+		t.Errorf("重放 err = %v, want errReusedTOTP", err)
+	}
+	// 更早的时间步在窗口内，但不大于上次使用的时间步
+	if err := u.s.VerifySecondFactor(u.user, u.code(u.step-1), ""); !errors.Is(err, errReusedTOTP) {
+		t.Errorf("更早的验证码 err = %v, want errReusedTOTP", err)
+	}
+	if err := u.s.VerifySecondFactor(u.user, u.code(u.step+3), ""); !errors.Is(err, errWrongTOTP) {
+		t.Errorf("窗口外的验证码 err = %v, want errWrongTOTP", err)
+	}
+}
+
+// TestRecoveryCodeSingleUse 恢复码只能用一次，忽略大小写和分隔符
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	u := newTwoFactorUser(t)
+
+	if err := u.s.VerifySecondFactor(u.user, "", u.recoveryCodes[0]); err != nil {
+		t.Fatalf("使用恢复码: %v", err)
+	}
+	if err := u.s.VerifySecondFactor(u.user, "", u.recoveryCodes[0]); !errors.Is(err, errWrongRecoveryCode) {
+		t.Errorf("重复使用恢复码 err = %v, want errWrongRecoveryCode", err)
+	}
+	normalized := strings.ToUpper(strings.ReplaceAll(u.recoveryCodes[1], "-", ""))
+	if err := u.s.VerifySecondFactor(u.user, "", normalized); err != nil {
+		t.Errorf("大写、不带分隔符的恢复码: %v", err)
+	}
+	if enabled, remaining, err := u.s.TwoFactorStatus(u.user); err != nil || !enabled || remaining != recoveryCodeCount-2 {
+		t.Errorf("TwoFactorStatus = %v, %d, %v, want 剩余 %d 个", enabled, remaining, err, recoveryCodeCount-2)
+	}
+
+	// 重新生成后旧的恢复码全部作废
+	codes, err := u.s.RegenerateRecoveryCodes(u.user, u.code(u.step+1))
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if err := u.s.VerifySecondFactor(u.user, "", u.recoveryCodes[2]); !errors.Is(err, errWrongRecoveryCode) {
+		t.Errorf("旧恢复码 err = %v, want errWrongRecoveryCode", err)
+	}
+	if err := u.s.VerifySecondFactor(u.user, "", codes[0]); err != nil {
+		t.Errorf("新恢复码: %v", err)
+	}
+}
+
+// TestSecondFactorLockout 连续错误5次锁定15分钟，锁定期间正确的恢复码也不接受；通过后重新计数
+func TestSecondFactorLockout(t *testing.T) {
+	u := newTwoFactorUser(t)
+	wrong := u.wrongCode()
+
+	// 通过一次后失败次数清零
+	for i := 0; i < maxSecondFactorFailures-1; i++ {
+		u.s.VerifySecondFactor(u.user, wrong, "")
+	}
+	if err := u.s.VerifySecondFactor(u.user, "", u.recoveryCodes[0]); err != nil {
+		t.Fatalf("未达到上限时使用恢复码: %v", err)
+	}
+
+	for i := 1; i <= maxSecondFactorFailures; i++ {
+		err := u.s.VerifySecondFactor(u.user, wrong, "")
+		if i < maxSecondFactorFailures && !errors.Is(err, errWrongTOTP) {
+			t.Fatalf("第 %d 次错误 err = %v, want errWrongTOTP", i, err)
+		}
+		if i == maxSecondFactorFailures && !errors.Is(err, ErrTwoFactorLocked) {
+			t.Fatalf("第 %d 次错误 err = %v, want ErrTwoFactorLocked", i, err)
+		}
+	}
+
+	lockedUntil, err := u.s.repo.GetSecondFactorLockout(u.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining := time.Until(time.Unix(lockedUntil, 0)); remaining > secondFactorLockout || remaining < secondFactorLockout-time.Minute {
+		t.Errorf("锁定剩余 %v, want 约 %v", remaining, secondFactorLockout)
+	}
+	if err := u.s.VerifySecondFactor(u.user, "", u.recoveryCodes[1]); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Errorf("锁定期间使用恢复码 err = %v, want ErrTwoFactorLocked", err)
+	}
+	if enabled, remaining, _ := u.s.TwoFactorStatus(u.user); !enabled || remaining != recoveryCodeCount-1 {
+		t.Errorf("锁定期间恢复码被消耗: 剩余 %d 个", remaining)
+	}
+}
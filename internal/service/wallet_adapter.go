@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+)
+
+// walletAdapter 链上钱包适配器
+// 一个Wallet账户可以绑定多条链上的多个地址，余额为全部地址的USDC+USDT之和；
+// 各链通过注册的 ChainBalanceProvider 查询，以太坊未注册节点时使用Etherscan；
+// 账户带有 PinnedBlocks 时，支持的链按固定区块读取。
+type walletAdapter struct {
+	httpClient   *http.Client
+	etherscanURL string
+	providers    map[string]ChainBalanceProvider // 与 WalletService.chainProviders 共用
+}
+
+// ==================== 区块链钱包 ====================
+
+// GetBalance 获取全部绑定地址的USDC+USDT余额
+func (a *walletAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	balances, err := a.balances(account)
+	if err != nil {
+		fmt.Printf("  ⚠️  获取链上钱包余额失败: %v\n", err)
+		return 0, err
+	}
+
+	totalBalance := balances["USDC"] + balances["USDT"]
+	fmt.Printf("  ✓ 链上钱包 总余额: $%.2f (USDC=$%.2f, USDT=$%.2f)\n",
+		totalBalance, balances["USDC"], balances["USDT"])
+	return totalBalance, nil
+}
+
+// GetBalanceByAsset 获取全部绑定地址上指定币种的余额
+func (a *walletAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	if currency != "USDT" && currency != "USDC" {
+		return 0, fmt.Errorf("不支持的币种: %s", currency)
+	}
+
+	balances, err := a.balances(account)
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("  ✓ Wallet %s 余额: $%.2f\n", currency, balances[currency])
+	return balances[currency], nil
+}
+
+// balances 按币种汇总全部地址的余额
+// 任意一个地址查询失败都整体报错，避免少算余额导致净值异常下跌。
+func (a *walletAdapter) balances(account *model.AdminAccount) (map[string]money.Decimal, error) {
+	addresses := walletChainAddresses(account)
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("未配置钱包地址")
+	}
+
+	totals := map[string]money.Decimal{"USDT": money.Zero, "USDC": money.Zero}
+	for _, addr := range addresses {
+		chain, ok := chainByName(addr.Chain)
+		if !ok {
+			return nil, fmt.Errorf("不支持的链: %s", addr.Chain)
+		}
+		provider, err := a.providerFor(chain, account)
+		if err != nil {
+			return nil, err
+		}
+
+		var balances map[string]money.Decimal
+		pinned, isPinnable := provider.(BlockPinnedProvider)
+		if block, ok := account.PinnedBlocks[chain.Name]; ok && isPinnable {
+			balances, err = pinned.TokenBalancesAt(addr.Address, chain.Tokens, block)
+		} else {
+			balances, err = provider.TokenBalances(addr.Address, chain.Tokens)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", chain.DisplayName, addr.Address, err)
+		}
+		for symbol, balance := range balances {
+			totals[symbol] += balance
+			if balance > 0 {
+				fmt.Printf("  链上钱包 %s %s %s: $%.2f\n", chain.DisplayName, shortAddress(addr.Address), symbol, balance)
+			}
+		}
+	}
+	return totals, nil
+}
+
+// providerFor 查找链的余额查询；以太坊未注册节点时用Etherscan（API Key存储在APISecret字段）
+func (a *walletAdapter) providerFor(chain model.Chain, account *model.AdminAccount) (ChainBalanceProvider, error) {
+	if provider, ok := a.providers[chain.Name]; ok {
+		return provider, nil
+	}
+	if chain.Name == chainEthereum {
+		if account.APISecret == "" {
+			return nil, fmt.Errorf("未配置Etherscan API Key（或设置 ETHEREUM_RPC_URL 直接查询以太坊节点）")
+		}
+		return &etherscanProvider{httpClient: a.httpClient, baseURL: a.etherscanURL, apiKey: account.APISecret}, nil
+	}
+	return nil, fmt.Errorf("未配置 %s 的节点地址", chain.DisplayName)
+}
+
+// walletChainAddresses Wallet账户绑定的地址；没有绑定任何地址时沿用旧版的以太坊地址
+func walletChainAddresses(account *model.AdminAccount) []model.ChainAddress {
+	if len(account.ChainAddresses) > 0 {
+		return account.ChainAddresses
+	}
+	if account.WalletAddress != "" {
+		return []model.ChainAddress{{Chain: chainEthereum, Address: account.WalletAddress}}
+	}
+	return nil
+}
+
+// shortAddress 日志里只显示地址首尾
+func shortAddress(address string) string {
+	if len(address) <= 12 {
+		return address
+	}
+	return address[:6] + "..." + address[len(address)-4:]
+}
+
+// GetPositions 链上钱包没有持仓
+func (a *walletAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	return []model.Position{}, nil
+}
+
+// GetOrders 链上钱包没有委托
+func (a *walletAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	return []model.Order{}, nil
+}
+
+// GetHistoryTrades 链上钱包没有成交记录
+func (a *walletAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	return []model.HistoryTrade{}, nil
+}
+
+// ==================== Etherscan ====================
+
+// etherscanProvider 通过Etherscan的 tokenbalance 接口查询以太坊ERC20余额
+type etherscanProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// TokenBalances 实现 ChainBalanceProvider
+func (p *etherscanProvider) TokenBalances(address string, tokens []model.ChainToken) (map[string]money.Decimal, error) {
+	balances := make(map[string]money.Decimal, len(tokens))
+	for _, token := range tokens {
+		balance, err := p.getERC20Balance(address, token.Contract, token.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", token.Symbol, err)
+		}
+		balances[token.Symbol] = balance
+	}
+	return balances, nil
+}
+
+// getERC20Balance 获取ERC20代币余额
+func (p *etherscanProvider) getERC20Balance(walletAddress, contractAddress string, decimals int) (money.Decimal, error) {
+	query := url.Values{}
+	query.Set("module", "account")
+	query.Set("action", "tokenbalance")
+	query.Set("contractaddress", contractAddress)
+	query.Set("address", walletAddress)
+	query.Set("tag", "latest")
+	query.Set("apikey", p.apiKey)
+
+	resp, err := p.httpClient.Get(p.baseURL + "/api?" + query.Encode())
+	if err != nil {
+		return 0, fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var result struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Result  string `json:"result"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析JSON失败: %v", err)
+	}
+
+	if result.Status != "1" {
+		return 0, fmt.Errorf("Etherscan API错误: %s - %s", result.Message, result.Result)
+	}
+
+	// 使用big.Int处理大数字
+	balance, ok := new(big.Int).SetString(result.Result, 10)
+	if !ok {
+		return 0, fmt.Errorf("无法解析的余额: %s", result.Result)
+	}
+
+	// 根据小数位数转换
+	value, err := money.FromRawChecked(balance, decimals)
+	if err != nil {
+		return 0, fmt.Errorf("余额 %s 超出范围", result.Result)
+	}
+	return value, nil
+}
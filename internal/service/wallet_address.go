@@ -0,0 +1,77 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GetChainAddresses 获取Wallet账户绑定的全部链上地址
+func (s *Service) GetChainAddresses(accountID int) ([]model.ChainAddress, error) {
+	if _, err := s.walletAccount(accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetChainAddresses(accountID)
+}
+
+// GetChainAddress 按ID获取链上地址，不存在时返回 nil
+func (s *Service) GetChainAddress(id int) (*model.ChainAddress, error) {
+	return s.repo.GetChainAddress(id)
+}
+
+// AddChainAddress 为Wallet账户绑定一个链上地址（同一地址重复绑定时只更新备注）
+func (s *Service) AddChainAddress(accountID int, chainName, address, label string) (*model.ChainAddress, error) {
+	account, err := s.walletAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, ok := chainByName(strings.ToLower(strings.TrimSpace(chainName)))
+	if !ok {
+		return nil, fmt.Errorf("不支持的链: %s", chainName)
+	}
+	normalized, ok := normalizeChainAddress(chain, address)
+	if !ok {
+		return nil, fmt.Errorf("%s 地址格式不正确: %s", chain.DisplayName, address)
+	}
+
+	addr := &model.ChainAddress{
+		AdminAccountID: account.ID,
+		Chain:          chain.Name,
+		Address:        normalized,
+		Label:          strings.TrimSpace(label),
+	}
+	if err := s.repo.AddChainAddress(addr); err != nil {
+		return nil, fmt.Errorf("保存地址失败: %v", err)
+	}
+	fmt.Printf("✓ Wallet账户 %d 绑定地址: %s %s\n", account.ID, chain.Name, normalized)
+	return addr, nil
+}
+
+// DeleteChainAddress 解绑Wallet账户的链上地址
+func (s *Service) DeleteChainAddress(accountID, addressID int) error {
+	addr, err := s.repo.GetChainAddress(addressID)
+	if err != nil {
+		return err
+	}
+	if addr == nil || addr.AdminAccountID != accountID {
+		return errors.New("地址不存在")
+	}
+	return s.repo.DeleteChainAddress(addressID)
+}
+
+// walletAccount 获取Wallet类型的Admin账户
+func (s *Service) walletAccount(accountID int) (*model.AdminAccount, error) {
+	account, err := s.repo.GetAdminAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, errors.New("账户不存在")
+	}
+	if account.AccountType != "Wallet" {
+		return nil, errors.New("只有Wallet账户可以绑定链上地址")
+	}
+	return account, nil
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/vault"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeAdapter 按币种返回固定余额的适配器，记录收到的（解密后的）账户
+type fakeAdapter struct {
+	balances map[string]money.Decimal
+	errs     map[string]error // 币种 → 查询该币种时返回的错误
+	seen     []*model.AdminAccount
+}
+
+func newFakeAdapter(usdt, usdc float64) *fakeAdapter {
+	return &fakeAdapter{
+		balances: map[string]money.Decimal{"USDT": money.FromFloat(usdt), "USDC": money.FromFloat(usdc)},
+		errs:     make(map[string]error),
+	}
+}
+
+func (f *fakeAdapter) GetBalance(account *model.AdminAccount) (money.Decimal, error) {
+	f.seen = append(f.seen, account)
+	total := money.Zero
+	for currency, balance := range f.balances {
+		if err := f.errs[currency]; err != nil {
+			return 0, err
+		}
+		total = total.Add(balance)
+	}
+	return total, nil
+}
+
+func (f *fakeAdapter) GetBalanceByAsset(account *model.AdminAccount, currency string) (money.Decimal, error) {
+	f.seen = append(f.seen, account)
+	if err := f.errs[currency]; err != nil {
+		return 0, err
+	}
+	return f.balances[currency], nil
+}
+
+func (f *fakeAdapter) GetPositions(account *model.AdminAccount, limit int) ([]model.Position, error) {
+	return nil, nil
+}
+
+func (f *fakeAdapter) GetOrders(account *model.AdminAccount, limit int) ([]model.Order, error) {
+	return nil, nil
+}
+
+func (f *fakeAdapter) GetHistoryTrades(account *model.AdminAccount, limit int) ([]model.HistoryTrade, error) {
+	return nil, nil
+}
+
+// newFakeWalletService 只注册了指定适配器、带测试主密钥的 WalletService
+func newFakeWalletService(t *testing.T, adapters map[string]ExchangeAdapter) *WalletService {
+	t.Helper()
+	ws := NewWalletService()
+	ws.adapters = make(map[string]ExchangeAdapter)
+	for accountType, adapter := range adapters {
+		ws.RegisterAdapter(accountType, adapter)
+	}
+	keyring, err := vault.NewKeyring(bytes.Repeat([]byte{7}, vault.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.SetKeyring(keyring)
+	return ws
+}
+
+// TestWalletServiceDispatchesToAdapter 按账户类型把查询交给注册的适配器，错误原样返回
+func TestWalletServiceDispatchesToAdapter(t *testing.T) {
+	okx := newFakeAdapter(1500, 250)
+	ws := newFakeWalletService(t, map[string]ExchangeAdapter{"OKX": okx})
+	account := &model.AdminAccount{ID: 1, AccountType: "OKX", APIKey: "key"}
+	secretCtx, passphraseCtx := secretContexts(account)
+	account.APISecret, _ = ws.SealSecret("secret", secretCtx)
+	account.Passphrase, _ = ws.SealSecret("pass", passphraseCtx)
+
+	if balance, err := ws.GetBalance(account); err != nil || balance != money.FromFloat(1750) {
+		t.Errorf("GetBalance = %v, %v, want 1750（USDT+USDC）", balance, err)
+	}
+	if balance, err := ws.GetBalanceByAsset(account, "USDC"); err != nil || balance != money.FromFloat(250) {
+		t.Errorf("GetBalanceByAsset(USDC) = %v, %v, want 250", balance, err)
+	}
+	if len(okx.seen) != 2 || okx.seen[0].APISecret != "secret" || okx.seen[0].Passphrase != "pass" {
+		t.Fatalf("适配器收到 %d 次调用, want 2 次解密后的账户", len(okx.seen))
+	}
+	if !vault.IsSealed(account.APISecret) {
+		t.Error("解密不应改写调用方的账户")
+	}
+
+	okx.errs["USDT"] = errors.New("交易所维护中")
+	if _, err := ws.GetBalance(account); err == nil || !strings.Contains(err.Error(), "交易所维护中") {
+		t.Errorf("GetBalance error = %v, want 交易所维护中", err)
+	}
+	if balance, err := ws.GetBalanceByAsset(account, "USDC"); err != nil || balance != money.FromFloat(250) {
+		t.Errorf("其他币种不受影响: %v, %v", balance, err)
+	}
+}
+
+func TestWalletServiceUnknownAccountType(t *testing.T) {
+	ws := newFakeWalletService(t, nil)
+	if _, err := ws.GetBalance(&model.AdminAccount{AccountType: "Kraken"}); err == nil || !strings.Contains(err.Error(), "不支持的账户类型") {
+		t.Errorf("未注册的场所 error = %v", err)
+	}
+	if ws.HasAdapter("Kraken") {
+		t.Error("HasAdapter(Kraken) = true")
+	}
+
+	ws.RegisterAdapter("Kraken", newFakeAdapter(1, 2))
+	if balance, err := ws.GetBalance(&model.AdminAccount{AccountType: "Kraken"}); err != nil || balance != money.FromFloat(3) {
+		t.Errorf("注册后 GetBalance = %v, %v, want 3", balance, err)
+	}
+
+	// 内置场所都已注册
+	for _, accountType := range []string{"Binance", "OKX", "Wallet"} {
+		if !NewWalletService().HasAdapter(accountType) {
+			t.Errorf("没有注册 %s", accountType)
+		}
+	}
+}
+
+func TestAPIUserBalanceAggregation(t *testing.T) {
+	okx := newFakeAdapter(1500, 250)
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"OKX": okx}))
+
+	userID, err := s.CreateAPIUser("trader", "trader-password")
+	if err != nil {
+		t.Fatalf("CreateAPIUser: %v", err)
+	}
+	if err := s.SaveUserAPIKeys(int(userID), "OKX", "key", "secret", "pass"); err != nil {
+		t.Fatalf("SaveUserAPIKeys: %v", err)
+	}
+
+	// 密钥加密保存，只有适配器拿到明文
+	user, _ := s.repo.GetUserByID(int(userID))
+	if user.APISecret == "secret" || !vault.IsSealed(user.APISecret) || !vault.IsSealed(user.APIPassphrase) {
+		t.Errorf("密钥未加密保存: %q / %q", user.APISecret, user.APIPassphrase)
+	}
+	last := okx.seen[len(okx.seen)-1]
+	if last.APISecret != "secret" || last.Passphrase != "pass" {
+		t.Errorf("适配器收到 %q / %q, want 解密后的密钥", last.APISecret, last.Passphrase)
+	}
+	if user.InitialBalance != money.FromFloat(1750) {
+		t.Errorf("初始余额 = %s, want 1750（USDT+USDC）", user.InitialBalance)
+	}
+
+	okx.balances["USDT"] = money.FromFloat(2000)
+	data, err := s.GetAPIDashboardData(int(userID))
+	if err != nil {
+		t.Fatalf("GetAPIDashboardData: %v", err)
+	}
+	if data.USDTBalance != money.FromFloat(2000) || data.USDCBalance != money.FromFloat(250) {
+		t.Errorf("分币种余额 = %s/%s, want 2000/250", data.USDTBalance, data.USDCBalance)
+	}
+	if data.CurrentBalance != money.FromFloat(2250) || data.TotalProfit != money.FromFloat(500) {
+		t.Errorf("合计 = %s, 盈亏 = %s, want 2250/500", data.CurrentBalance, data.TotalProfit)
+	}
+}
+
+func TestAPIUserBalanceErrors(t *testing.T) {
+	okx := newFakeAdapter(1000, 0)
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"OKX": okx}))
+
+	userID, err := s.CreateAPIUser("trader", "trader-password")
+	if err != nil {
+		t.Fatalf("CreateAPIUser: %v", err)
+	}
+	if err := s.SaveUserAPIKeys(int(userID), "OKX", "key", "secret", "pass"); err != nil {
+		t.Fatalf("SaveUserAPIKeys: %v", err)
+	}
+
+	// 辅助币种查询失败按0处理
+	okx.errs["USDC"] = errors.New("USDC 不可用")
+	data, err := s.GetAPIDashboardData(int(userID))
+	if err != nil {
+		t.Fatalf("GetAPIDashboardData: %v", err)
+	}
+	if data.CurrentBalance != money.FromFloat(1000) {
+		t.Errorf("合计 = %s, want 1000", data.CurrentBalance)
+	}
+
+	// 主币种查询失败返回错误
+	okx.errs["USDT"] = errors.New("交易所维护中")
+	if _, err := s.GetAPIDashboardData(int(userID)); err == nil || !strings.Contains(err.Error(), "交易所维护中") {
+		t.Errorf("GetAPIDashboardData error = %v, want 交易所维护中", err)
+	}
+
+	// 保存密钥时余额查询失败，不保存
+	other, _ := s.CreateAPIUser("other", "other-password")
+	if err := s.SaveUserAPIKeys(int(other), "OKX", "key2", "secret2", "pass2"); err == nil {
+		t.Error("SaveUserAPIKeys 应该失败")
+	}
+	if user, _ := s.repo.GetUserByID(int(other)); user.APIKey != "" {
+		t.Errorf("失败时不应保存密钥，得到 %q", user.APIKey)
+	}
+
+	// 没有注册的场所
+	if err := s.SaveUserAPIKeys(int(other), "Kraken", "key", "secret", ""); err == nil || !strings.Contains(err.Error(), "不支持的账户类型") {
+		t.Errorf("SaveUserAPIKeys(Kraken) error = %v", err)
+	}
+}
+
+// TestDailyBalancesUseAdapters 每日检查按适配器余额记录账户余额，单个账户失败不影响其他账户
+func TestDailyBalancesUseAdapters(t *testing.T) {
+	binance := newFakeAdapter(1100, 400)
+	okx := newFakeAdapter(0, 0)
+	okx.errs["USDT"] = errors.New("签名错误")
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"Binance": binance, "OKX": okx}))
+
+	if err := s.ConfigAdminAccount("Binance", "binance-key", "binance-secret", "", ""); err != nil {
+		t.Fatalf("ConfigAdminAccount: %v", err)
+	}
+	if err := s.ConfigAdminAccount("OKX", "okx-key", "okx-secret", "", "okx-pass"); err != nil {
+		t.Fatalf("ConfigAdminAccount: %v", err)
+	}
+	if err := s.UpdateDailyBalances(); err != nil {
+		t.Fatalf("UpdateDailyBalances: %v", err)
+	}
+
+	account, _ := s.repo.GetAdminAccountByType("Binance")
+	if account.CurrentBalance != money.FromFloat(1500) {
+		t.Errorf("Binance 余额 = %s, want 1500（USDT+USDC）", account.CurrentBalance)
+	}
+	if len(binance.seen) == 0 || binance.seen[0].APISecret != "binance-secret" {
+		t.Error("Binance 适配器没有收到解密后的密钥")
+	}
+	failed, _ := s.repo.GetAdminAccountByType("OKX")
+	if !failed.CurrentBalance.IsZero() {
+		t.Errorf("查询失败的 OKX 不应更新余额，得到 %s", failed.CurrentBalance)
+	}
+}
+
+func TestWalletServiceWithoutKeyring(t *testing.T) {
+	ws := NewWalletService()
+	ws.RegisterAdapter("OKX", newFakeAdapter(1, 0))
+
+	account := &model.AdminAccount{AccountType: "OKX", APIKey: "key", APISecret: "enc:v1:x:y:z"}
+	if _, err := ws.GetBalance(account); !errors.Is(err, errNoKeyring) {
+		t.Errorf("GetBalance error = %v, want errNoKeyring", err)
+	}
+	if _, err := ws.SealSecret("secret", vault.Context("admin_accounts", "api_secret", 1)); !errors.Is(err, errNoKeyring) {
+		t.Errorf("SealSecret error = %v, want errNoKeyring", err)
+	}
+}
+
+// TestAPIDashboardCompoundRates API用户 Dashboard 与 GetDashboardSummary 一样按复利折算
+func TestAPIDashboardCompoundRates(t *testing.T) {
+	okx := newFakeAdapter(1000, 0)
+	s := newTestService(t, newFakeWalletService(t, map[string]ExchangeAdapter{"OKX": okx}))
+	userID, _ := s.CreateAPIUser("trader", "trader-password")
+	if err := s.SaveUserAPIKeys(int(userID), "OKX", "key", "secret", "pass"); err != nil {
+		t.Fatalf("SaveUserAPIKeys: %v", err)
+	}
+
+	okx.balances["USDT"] = money.FromFloat(1100)
+	data, err := s.GetAPIDashboardData(int(userID))
+	if err != nil {
+		t.Fatalf("GetAPIDashboardData: %v", err)
+	}
+	summary := data.Summary
+	if summary.Returns == nil || summary.Returns.TWR != summary.TotalProfitRate {
+		t.Fatalf("Returns = %+v, want TWR = 累计收益率 %v", summary.Returns, summary.TotalProfitRate)
+	}
+	for _, c := range []struct {
+		name   string
+		got    float64
+		period int
+	}{
+		{"月化", summary.MonthlyRate, 30},
+		{"季化", summary.QuarterlyRate, 90},
+		{"年化", summary.AnnualRate, 365},
+	} {
+		if want := compoundRate(1.1, summary.AvgHoldDays, c.period); c.got != want {
+			t.Errorf("%s = %v, want 复利 %v", c.name, c.got, want)
+		}
+	}
+}
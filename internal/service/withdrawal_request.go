@@ -0,0 +1,176 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"crypto-final/internal/repository"
+	"errors"
+	"fmt"
+)
+
+// ErrWithdrawalStateChanged 撤资申请已被处理或撤回
+var ErrWithdrawalStateChanged = repository.ErrWithdrawalStateChanged
+
+// ErrOpenWithdrawalRequestExists 该充值已有未完成的撤资申请
+var ErrOpenWithdrawalRequestExists = repository.ErrOpenWithdrawalRequestExists
+
+// SubmitWithdrawalRequest 投资人提交撤资申请，等待管理员审核
+// 提交时只校验本金，不定价；批准后在下一次净值结算时按当时的净值成交。
+func (s *Service) SubmitWithdrawalRequest(userID, rechargeID int, principal money.Decimal, note string) (*model.WithdrawalRequest, error) {
+	recharge, principal, err := s.checkWithdrawal(rechargeID, userID, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := s.repo.CountOpenWithdrawalRequests(rechargeID)
+	if err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, ErrOpenWithdrawalRequestExists
+	}
+
+	req := &model.WithdrawalRequest{
+		UserID:         userID,
+		RechargeID:     rechargeID,
+		AdminAccountID: recharge.AdminAccountID,
+		Currency:       recharge.Currency,
+		Principal:      principal,
+		Note:           note,
+	}
+	// 并发提交时由唯一索引兜底
+	if err := s.repo.CreateWithdrawalRequest(req); err != nil {
+		if errors.Is(err, ErrOpenWithdrawalRequestExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("保存撤资申请失败: %v", err)
+	}
+
+	fmt.Printf("📝 用户%d 提交撤资申请 #%d: 充值%d 本金$%.2f %s\n", userID, req.ID, rechargeID, principal, recharge.Currency)
+	return req, nil
+}
+
+// GetUserWithdrawalRequests 投资人自己的撤资申请
+func (s *Service) GetUserWithdrawalRequests(userID int) ([]*model.WithdrawalRequest, error) {
+	return s.repo.ListWithdrawalRequests(userID, "")
+}
+
+// GetWithdrawalRequests 全部撤资申请，status 为空不限状态
+func (s *Service) GetWithdrawalRequests(status string) ([]*model.WithdrawalRequest, error) {
+	return s.repo.ListWithdrawalRequests(0, status)
+}
+
+// GetWithdrawalRequest 获取撤资申请，不存在时返回 nil
+func (s *Service) GetWithdrawalRequest(id int) (*model.WithdrawalRequest, error) {
+	return s.repo.GetWithdrawalRequest(id)
+}
+
+// CancelWithdrawalRequest 投资人撤回尚未结算的申请
+func (s *Service) CancelWithdrawalRequest(userID, id int) error {
+	req, err := s.repo.GetWithdrawalRequest(id)
+	if err != nil {
+		return err
+	}
+	if req == nil || req.UserID != userID {
+		return errors.New("撤资申请不存在")
+	}
+	return s.repo.TransitionWithdrawalRequest(id,
+		[]string{model.WithdrawalPending, model.WithdrawalApproved}, model.WithdrawalCancelled, 0, "")
+}
+
+// ApproveWithdrawalRequest 批准待审核的申请，下一次净值结算时成交
+func (s *Service) ApproveWithdrawalRequest(reviewerID, id int) error {
+	req, err := s.repo.GetWithdrawalRequest(id)
+	if err != nil {
+		return err
+	}
+	if req == nil {
+		return errors.New("撤资申请不存在")
+	}
+	if err := s.repo.TransitionWithdrawalRequest(id,
+		[]string{model.WithdrawalPending}, model.WithdrawalApproved, reviewerID, ""); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 撤资申请 #%d 已批准，等待下一次净值结算\n", id)
+	return nil
+}
+
+// RejectWithdrawalRequest 拒绝尚未结算的申请
+func (s *Service) RejectWithdrawalRequest(reviewerID, id int, reason string) error {
+	req, err := s.repo.GetWithdrawalRequest(id)
+	if err != nil {
+		return err
+	}
+	if req == nil {
+		return errors.New("撤资申请不存在")
+	}
+	if reason == "" {
+		return errors.New("请填写拒绝原因")
+	}
+	return s.repo.TransitionWithdrawalRequest(id,
+		[]string{model.WithdrawalPending, model.WithdrawalApproved}, model.WithdrawalRejected, reviewerID, reason)
+}
+
+// settleWithdrawalRequests 按刚记录的净值结算某账户某币种所有已批准的申请
+// 同一批申请都按这一个净值成交，不在两笔之间重新计算。
+// 已经不可能成交的申请（如充值已停用或已全部撤出）改为已拒绝并记录原因；其他原因失败的保持已批准，下次再试。
+func (s *Service) settleWithdrawalRequests(account *model.AdminAccount, currency string, nav *model.NAVRecord) {
+	requests, err := s.repo.GetApprovedWithdrawalRequests(account.ID, currency)
+	if err != nil {
+		fmt.Printf("⚠️  获取%s %s待结算撤资申请失败: %v\n", account.AccountType, currency, err)
+		return
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	settled := 0
+	for _, req := range requests {
+		recharge, principal, err := s.checkWithdrawal(req.RechargeID, req.UserID, req.Principal)
+		var rejection withdrawalRejection
+		if errors.As(err, &rejection) {
+			s.rejectUnsettleableRequest(req, rejection)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("⚠️  撤资申请 #%d 暂不结算: %v\n", req.ID, err)
+			continue
+		}
+
+		fee, rd, err := s.priceRedemptionWithFee(recharge, principal, nav)
+		if err != nil {
+			fmt.Printf("⚠️  撤资申请 #%d 暂不结算: %v\n", req.ID, err)
+			continue
+		}
+		if err := s.repo.SettleWithdrawalRequest(req.ID, fee, rd); err != nil {
+			fmt.Printf("❌ 撤资申请 #%d 结算失败: %v\n", req.ID, err)
+			continue
+		}
+
+		if fee != nil {
+			logPerformanceFee(fee)
+		}
+		fmt.Printf("✓ 撤资申请 #%d 已按 %s 净值 $%.4f 结算\n", req.ID, rd.NAVDate, rd.NAV)
+		logRedemption(rd)
+		settled++
+	}
+
+	if settled > 0 {
+		if _, err := s.refreshAccountShares(account.ID); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
+}
+
+// rejectUnsettleableRequest 把无法成交的已批准申请改为已拒绝，原因写入 reject_reason
+func (s *Service) rejectUnsettleableRequest(req *model.WithdrawalRequest, rejection withdrawalRejection) {
+	reason := "结算时无法成交: " + string(rejection)
+	err := s.repo.TransitionWithdrawalRequest(req.ID,
+		[]string{model.WithdrawalApproved}, model.WithdrawalRejected, 0, reason)
+	if err != nil {
+		fmt.Printf("❌ 撤资申请 #%d 无法结算，标记为已拒绝失败: %v\n", req.ID, err)
+		return
+	}
+	fmt.Printf("⚠️  撤资申请 #%d 已拒绝: %s\n", req.ID, reason)
+}
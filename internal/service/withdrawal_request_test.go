@@ -0,0 +1,114 @@
+package service
+
+import (
+	"crypto-final/internal/model"
+	"crypto-final/internal/money"
+	"errors"
+	"testing"
+)
+
+// TestWithdrawalRequestTransitions 待审核 → 批准/拒绝/撤回，已结束的申请不能再变更
+func TestWithdrawalRequestTransitions(t *testing.T) {
+	p := newTestPool(t)
+	other, _ := p.s.AdminCreateUser("13900000000")
+
+	invalid := []struct {
+		name      string
+		userID    int
+		principal string
+	}{
+		{"别人的充值", int(other), "100"},
+		{"本金为0", p.userID, "0"},
+		{"超过原充值", p.userID, "500.01"},
+	}
+	for _, tt := range invalid {
+		if _, err := p.s.SubmitWithdrawalRequest(tt.userID, p.rechargeID, money.MustParse(tt.principal), ""); err == nil {
+			t.Errorf("%s: 应该被拒绝", tt.name)
+		}
+	}
+
+	req, err := p.s.SubmitWithdrawalRequest(p.userID, p.rechargeID, money.FromFloat(100), "急用")
+	if err != nil {
+		t.Fatalf("SubmitWithdrawalRequest: %v", err)
+	}
+	if req.Status != model.WithdrawalPending || req.Currency != "USDT" || req.AdminAccountID != p.account.ID {
+		t.Errorf("新申请 = %+v", req)
+	}
+	if _, err := p.s.SubmitWithdrawalRequest(p.userID, p.rechargeID, money.FromFloat(50), ""); !errors.Is(err, ErrOpenWithdrawalRequestExists) {
+		t.Errorf("重复提交 err = %v, want ErrOpenWithdrawalRequestExists", err)
+	}
+
+	if err := p.s.CancelWithdrawalRequest(int(other), req.ID); err == nil {
+		t.Error("其他用户不能撤回")
+	}
+	if err := p.s.RejectWithdrawalRequest(1, req.ID, ""); err == nil {
+		t.Error("拒绝必须填写原因")
+	}
+	if err := p.s.ApproveWithdrawalRequest(1, req.ID); err != nil {
+		t.Fatalf("ApproveWithdrawalRequest: %v", err)
+	}
+	if err := p.s.ApproveWithdrawalRequest(1, req.ID); !errors.Is(err, ErrWithdrawalStateChanged) {
+		t.Errorf("重复批准 err = %v, want ErrWithdrawalStateChanged", err)
+	}
+	approved, _ := p.s.GetWithdrawalRequest(req.ID)
+	if approved.Status != model.WithdrawalApproved || approved.ReviewedBy != 1 {
+		t.Errorf("批准后 = %s (审核人 %d)", approved.Status, approved.ReviewedBy)
+	}
+
+	// 已批准的申请在结算前仍可撤回，撤回后不能再拒绝，可以重新提交
+	if err := p.s.CancelWithdrawalRequest(p.userID, req.ID); err != nil {
+		t.Fatalf("CancelWithdrawalRequest: %v", err)
+	}
+	if err := p.s.RejectWithdrawalRequest(1, req.ID, "太晚了"); !errors.Is(err, ErrWithdrawalStateChanged) {
+		t.Errorf("撤回后拒绝 err = %v, want ErrWithdrawalStateChanged", err)
+	}
+	again, err := p.s.SubmitWithdrawalRequest(p.userID, p.rechargeID, money.FromFloat(100), "")
+	if err != nil {
+		t.Fatalf("撤回后重新提交: %v", err)
+	}
+	if err := p.s.RejectWithdrawalRequest(1, again.ID, "资金锁定期内"); err != nil {
+		t.Fatalf("RejectWithdrawalRequest: %v", err)
+	}
+	rejected, _ := p.s.GetWithdrawalRequest(again.ID)
+	if rejected.Status != model.WithdrawalRejected || rejected.RejectReason != "资金锁定期内" {
+		t.Errorf("拒绝后 = %s (%q)", rejected.Status, rejected.RejectReason)
+	}
+
+	mine, _ := p.s.GetUserWithdrawalRequests(p.userID)
+	pending, _ := p.s.GetWithdrawalRequests(model.WithdrawalPending)
+	if len(mine) != 2 || len(pending) != 0 {
+		t.Errorf("用户申请 %d 个、待审核 %d 个, want 2 / 0", len(mine), len(pending))
+	}
+}
+
+// TestSettleApprovedWithdrawalRequests 已批准的申请按同一个净值结算，待审核的不结算
+func TestSettleApprovedWithdrawalRequests(t *testing.T) {
+	p := newTestPool(t)
+	otherUser, _ := p.s.AdminCreateUser("13900000000")
+	otherRecharge, err := p.s.AdminRecharge(int(otherUser), p.account.ID, money.FromFloat(100), "USDT")
+	if err != nil {
+		t.Fatalf("AdminRecharge: %v", err)
+	}
+
+	approved, _ := p.s.SubmitWithdrawalRequest(p.userID, p.rechargeID, money.FromFloat(500), "")
+	p.s.ApproveWithdrawalRequest(1, approved.ID)
+	pending, _ := p.s.SubmitWithdrawalRequest(int(otherUser), int(otherRecharge), money.FromFloat(100), "")
+
+	p.s.settleWithdrawalRequests(p.account, "USDT", navAt("1.1"))
+
+	settled, _ := p.s.GetWithdrawalRequest(approved.ID)
+	if settled.Status != model.WithdrawalSettled || settled.SettledNAV != money.MustParse("1.1") ||
+		settled.SettledShares != money.FromFloat(500) || settled.SettledAmount != money.FromFloat(550) {
+		t.Errorf("结算 = %s 净值%s %s份 $%s, want settled 1.1 / 500 / 550",
+			settled.Status, settled.SettledNAV, settled.SettledShares, settled.SettledAmount)
+	}
+	if recharge := p.recharge(t); recharge.IsActive {
+		t.Error("全部撤资后充值仍为有效")
+	}
+	if still, _ := p.s.GetWithdrawalRequest(pending.ID); still.Status != model.WithdrawalPending {
+		t.Errorf("待审核的申请 = %s, want 不结算", still.Status)
+	}
+	if mismatches, unbalanced, err := p.s.VerifyLedger(); err != nil || len(mismatches) != 0 || len(unbalanced) != 0 {
+		t.Errorf("VerifyLedger = %v, %v, %v", mismatches, unbalanced, err)
+	}
+}
@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238 附录B SHA1 测试密钥 "12345678901234567890" 的 Base32 编码
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 RFC 6238 附录B的测试向量（8位验证码取后6位）
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// 密钥大小写、首尾空白不影响结果
+	if got, _ := Code(" "+strings.ToLower(rfcSecret)+" ", Step(time.Unix(59, 0))); got != "287082" {
+		t.Errorf("小写密钥 Code = %s, want 287082", got)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("无效密钥应该返回错误")
+	}
+}
+
+// TestValidateWindow 只接受前后各 Skew 个时间步，返回匹配的时间步
+func TestValidateWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		code, _ := Code(rfcSecret, current+offset)
+		step, ok := Validate(rfcSecret, code, now)
+		inWindow := offset >= -Skew && offset <= Skew
+		if ok != inWindow {
+			t.Errorf("偏差 %d 步: ok = %v, want %v", offset, ok, inWindow)
+		}
+		if ok && step != current+offset {
+			t.Errorf("偏差 %d 步: 返回时间步 %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+// TestValidateFormat 验证码中的空格会被去掉，位数不对直接拒绝
+func TestValidateFormat(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	if _, ok := Validate(rfcSecret, " 005 924 ", now); !ok {
+		t.Error("带空格的验证码应该通过")
+	}
+	for _, code := range []string{"", "05924", "0005924", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate(%q) 不应通过", code)
+		}
+	}
+}
+
+// TestProvisioningURI otpauth 地址包含验证器需要的参数
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("财务管理系统", "13800000000", rfcSecret)
+	for _, want := range []string{"otpauth://totp/", "secret=" + rfcSecret, "digits=6", "period=30", "algorithm=SHA1"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("ProvisioningURI = %s, 缺少 %s", uri, want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 密文格式：enc:v2:<主密钥ID>:<被主密钥加密的数据密钥>:<被数据密钥加密的明文>
+// 每个密文有自己的随机数据密钥（信封加密），轮换主密钥时只需要重新加密数据密钥。
+// v2 加密明文时以存储位置（见 Context）作为附加数据，密文复制到其他行或列后无法解密；
+// v1 没有绑定位置，只能通过 Rewrap 升级，不能直接解密。
+const (
+	prefix       = "enc:v2:"
+	legacyPrefix = "enc:v1:"
+)
+
+// KeySize 主密钥长度（AES-256）
+const KeySize = 32
+
+// Keyring 主密钥集合：新密文用当前主密钥加密，解密时按密文中的密钥ID查找
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring 创建主密钥集合，previous 是轮换前的旧主密钥（只用于解密）
+func NewKeyring(primary []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	for _, key := range append([][]byte{primary}, previous...) {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("主密钥长度必须是%d字节，实际%d字节", KeySize, len(key))
+		}
+		k.keys[KeyID(key)] = key
+	}
+	k.primary = KeyID(primary)
+	return k, nil
+}
+
+// KeyID 主密钥的标识（SHA-256 前8位十六进制），写在密文中，不泄露密钥本身
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// PrimaryID 当前主密钥的标识
+func (k *Keyring) PrimaryID() string {
+	return k.primary
+}
+
+// GenerateKey 生成随机主密钥（Base64编码）
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey 解析 Base64 或十六进制编码的主密钥
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("主密钥必须是%d字节的Base64或十六进制编码", KeySize)
+}
+
+// LoadKey 从环境变量值或密钥文件读取主密钥，两者都为空时返回 nil
+func LoadKey(value, file string) ([]byte, error) {
+	if value != "" {
+		return ParseKey(value)
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+		return ParseKey(string(data))
+	}
+	return nil, nil
+}
+
+// Context 密文绑定的存储位置：表、列和行ID，如 "users.api_secret#12"
+func Context(table, column string, id int) string {
+	return fmt.Sprintf("%s.%s#%d", table, column, id)
+}
+
+// IsSealed 是否是本包生成的密文（含 v1）
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix) || IsLegacy(value)
+}
+
+// IsLegacy 是否是没有绑定存储位置的 v1 密文，需要用 Rewrap 升级
+func IsLegacy(value string) bool {
+	return strings.HasPrefix(value, legacyPrefix)
+}
+
+// Seal 加密并绑定到存储位置 context，空字符串原样返回
+func (k *Keyring) Seal(plaintext, context string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("生成数据密钥失败: %v", err)
+	}
+
+	data, err := gcmSeal(dek, []byte(plaintext), []byte(context))
+	if err != nil {
+		return "", err
+	}
+	return k.format(dek, data)
+}
+
+// Open 解密保存在 context 位置的密文，空字符串原样返回
+// 密文属于其他位置或是 v1 密文时报错。
+func (k *Keyring) Open(sealed, context string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	if IsLegacy(sealed) {
+		return "", errors.New("v1 密文没有绑定存储位置，请重启主程序或运行 rotatekeys 升级")
+	}
+
+	kid, wrapped, data, err := parse(sealed, prefix)
+	if err != nil {
+		return "", err
+	}
+	dek, err := k.unwrap(kid, wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcmOpen(dek, data, []byte(context))
+	if err != nil {
+		return "", errors.New("密文已损坏或不属于该位置")
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 用当前主密钥重新加密数据密钥，密文主体不变
+// v1 密文同时升级为绑定 context 的 v2 密文；已经是当前主密钥的 v2 密文原样返回，changed=false。
+func (k *Keyring) Rewrap(sealed, context string) (result string, changed bool, err error) {
+	if sealed == "" {
+		return "", false, nil
+	}
+
+	if IsLegacy(sealed) {
+		kid, wrapped, data, err := parse(sealed, legacyPrefix)
+		if err != nil {
+			return "", false, err
+		}
+		dek, err := k.unwrap(kid, wrapped)
+		if err != nil {
+			return "", false, err
+		}
+		plaintext, err := gcmOpen(dek, data, nil)
+		if err != nil {
+			return "", false, errors.New("密文已损坏")
+		}
+		upgraded, err := k.Seal(string(plaintext), context)
+		return upgraded, err == nil, err
+	}
+
+	kid, wrapped, data, err := parse(sealed, prefix)
+	if err != nil {
+		return "", false, err
+	}
+	if kid == k.primary {
+		return sealed, false, nil
+	}
+
+	dek, err := k.unwrap(kid, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	result, err = k.format(dek, data)
+	return result, err == nil, err
+}
+
+// format 用当前主密钥加密数据密钥，拼出 v2 密文
+func (k *Keyring) format(dek, data []byte) (string, error) {
+	wrapped, err := gcmSeal(k.keys[k.primary], dek, []byte(k.primary))
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.primary + ":" + encode(wrapped) + ":" + encode(data), nil
+}
+
+func (k *Keyring) unwrap(kid string, wrapped []byte) ([]byte, error) {
+	kek, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("找不到主密钥 %s", kid)
+	}
+	dek, err := gcmOpen(kek, wrapped, []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("主密钥 %s 无法解开数据密钥", kid)
+	}
+	return dek, nil
+}
+
+func parse(sealed, version string) (kid string, wrapped, data []byte, err error) {
+	if !strings.HasPrefix(sealed, version) {
+		return "", nil, nil, errors.New("不是加密后的密文")
+	}
+	parts := strings.Split(strings.TrimPrefix(sealed, version), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("密文格式错误")
+	}
+	if wrapped, err = decode(parts[1]); err != nil {
+		return "", nil, nil, errors.New("密文格式错误")
+	}
+	if data, err = decode(parts[2]); err != nil {
+		return "", nil, nil, errors.New("密文格式错误")
+	}
+	return parts[0], wrapped, data, nil
+}
+
+// gcmSeal AES-GCM 加密，输出 nonce||密文
+func gcmSeal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func gcmOpen(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("密文太短")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(s)
+}
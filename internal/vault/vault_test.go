@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func newTestKeyring(t *testing.T, primary []byte, previous ...[]byte) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(primary, previous...)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+// sealLegacy 按 v1 格式加密（明文不绑定存储位置）
+func sealLegacy(t *testing.T, k *Keyring, plaintext string) string {
+	t.Helper()
+	dek := testKey(9)
+	data, err := gcmSeal(dek, []byte(plaintext), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := gcmSeal(k.keys[k.primary], dek, []byte(k.primary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return legacyPrefix + k.primary + ":" + encode(wrapped) + ":" + encode(data)
+}
+
+func TestSealOpen(t *testing.T) {
+	k := newTestKeyring(t, testKey(1))
+	ctx := Context("users", "api_secret", 7)
+
+	sealed, err := k.Seal("s3cret", ctx)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !strings.HasPrefix(sealed, prefix) || !IsSealed(sealed) || IsLegacy(sealed) {
+		t.Fatalf("Seal = %q, want %s密文", sealed, prefix)
+	}
+	got, err := k.Open(sealed, ctx)
+	if err != nil || got != "s3cret" {
+		t.Fatalf("Open = %q, %v, want s3cret", got, err)
+	}
+
+	if sealed, _ := k.Seal("", ctx); sealed != "" {
+		t.Errorf("Seal(\"\") = %q, want empty", sealed)
+	}
+}
+
+func TestOpenRejectsOtherContext(t *testing.T) {
+	k := newTestKeyring(t, testKey(1))
+	sealed, err := k.Seal("s3cret", Context("users", "api_secret", 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ctx := range []string{
+		Context("users", "api_secret", 8),          // 其他行
+		Context("users", "api_passphrase", 7),      // 其他列
+		Context("admin_accounts", "api_secret", 7), // 其他表
+	} {
+		if _, err := k.Open(sealed, ctx); err == nil {
+			t.Errorf("Open(%s) 应该失败", ctx)
+		}
+	}
+}
+
+func TestLegacyUpgrade(t *testing.T) {
+	k := newTestKeyring(t, testKey(1))
+	ctx := Context("admin_accounts", "passphrase", 3)
+	legacy := sealLegacy(t, k, "pass")
+
+	if !IsSealed(legacy) || !IsLegacy(legacy) {
+		t.Fatalf("v1 密文识别错误: %q", legacy)
+	}
+	if _, err := k.Open(legacy, ctx); err == nil {
+		t.Fatal("v1 密文不应直接解密")
+	}
+
+	upgraded, changed, err := k.Rewrap(legacy, ctx)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v", changed, err)
+	}
+	if IsLegacy(upgraded) {
+		t.Fatalf("Rewrap 后仍是 v1: %q", upgraded)
+	}
+	got, err := k.Open(upgraded, ctx)
+	if err != nil || got != "pass" {
+		t.Fatalf("Open = %q, %v, want pass", got, err)
+	}
+	if _, err := k.Open(upgraded, Context("admin_accounts", "passphrase", 4)); err == nil {
+		t.Error("升级后的密文应绑定到原位置")
+	}
+}
+
+func TestRewrapRotation(t *testing.T) {
+	oldKey, newKey := testKey(1), testKey(2)
+	ctx := Context("users", "api_passphrase", 5)
+
+	sealed, err := newTestKeyring(t, oldKey).Seal("pass", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestKeyring(t, newKey, oldKey)
+	rewrapped, changed, err := rotated.Rewrap(sealed, ctx)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v", changed, err)
+	}
+	if again, changed, _ := rotated.Rewrap(rewrapped, ctx); changed || again != rewrapped {
+		t.Error("当前主密钥的密文不应重复加密")
+	}
+
+	// 换掉旧主密钥后仍能解密
+	got, err := newTestKeyring(t, newKey).Open(rewrapped, ctx)
+	if err != nil || got != "pass" {
+		t.Fatalf("Open = %q, %v, want pass", got, err)
+	}
+	if _, err := newTestKeyring(t, newKey).Open(sealed, ctx); err == nil {
+		t.Error("没有旧主密钥时旧密文应解密失败")
+	}
+}